@@ -0,0 +1,94 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	// register additional decoders
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultMaxPixels is the maximum number of pixels of images that are decoded,
+// if no other limit is configured
+const DefaultMaxPixels = 50000000
+
+// decodeConfig returns the dimensions and format of the given image, and
+// rejects images of more than maxPixels pixels, since decoding them would
+// allocate memory for every pixel regardless of the size of the content. If
+// maxPixels is not positive, DefaultMaxPixels is used.
+func decodeConfig(content []byte, maxPixels int) (image.Config, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		if f := sniffContainer(content); f != "" {
+			return cfg, "", fmt.Errorf("unsupported image format '%s'", f)
+		}
+		return cfg, "", fmt.Errorf("unrecognized image format: %s", err.Error())
+	}
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	if cfg.Width < 0 || cfg.Height < 0 ||
+		int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return cfg, "", fmt.Errorf("image of %dx%d pixels exceeds the limit of %d pixels",
+			cfg.Width, cfg.Height, maxPixels)
+	}
+	return cfg, format, nil
+}
+
+// toJPEG converts the given image to JPEG, which is the only format accepted by
+// the normalization graph. JPEG images are returned as is. Images of more than
+// maxPixels pixels are rejected - see decodeConfig.
+func toJPEG(content []byte, maxPixels int) ([]byte, error) {
+	_, format, err := decodeConfig(content, maxPixels)
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		return content, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image of format '%s': %s",
+			format, err.Error())
+	}
+	var out = new(bytes.Buffer)
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: 100}); err != nil {
+		return nil, fmt.Errorf("failed to convert image of format '%s': %s",
+			format, err.Error())
+	}
+	return out.Bytes(), nil
+}
+
+// Dimensions returns the width and height of the given image in pixels,
+// without decoding the full image
+func Dimensions(content []byte) (width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unrecognized image format: %s", err.Error())
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// sniffContainer attempts to name ISO base media file formats (ie HEIC, AVIF)
+// that we do not have decoders for
+func sniffContainer(content []byte) string {
+	if len(content) < 12 || string(content[4:8]) != "ftyp" {
+		return ""
+	}
+	switch string(content[8:12]) {
+	case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+		return "heic"
+	case "avif", "avis":
+		return "avif"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,99 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func Test_toJPEG(t *testing.T) {
+	jpg, err := ioutil.ReadFile("../../test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webp, err := ioutil.ReadFile("../../test/assets/image.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := ioutil.ReadFile("../../test/assets/text.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heic = append([]byte{0, 0, 0, 24}, []byte("ftypheic0000mif1heic")...)
+
+	tests := []struct {
+		name      string
+		content   []byte
+		maxPixels int
+		wantErr   string
+		wantSame  bool
+		wantWidth int
+	}{
+		{"jpeg is unchanged", jpg, 0, "", true, 0},
+		{"webp is converted", webp, 0, "", false, 1},
+		{"png is converted", png, 0, "", false, 0},
+		{"heic is unsupported", heic, 0, "'heic'", false, 0},
+		{"garbage", []byte("hello world"), 0, "unrecognized", false, 0},
+		{"png exceeds pixel limit", png, 16, "exceeds the limit", false, 0},
+		{"jpeg exceeds pixel limit", jpg, 16, "exceeds the limit", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toJPEG(tt.content, tt.maxPixels)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Errorf("toJPEG() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("toJPEG() error = %v, want mention of %s", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantSame != bytes.Equal(got, tt.content) {
+				t.Errorf("toJPEG() returned unchanged content = %v, want %v",
+					!tt.wantSame, tt.wantSame)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(got))
+			if err != nil || format != "jpeg" {
+				t.Errorf("toJPEG() produced format %s (error = %v), want jpeg", format, err)
+				return
+			}
+			if tt.wantWidth != 0 && cfg.Width != tt.wantWidth {
+				t.Errorf("toJPEG() width = %d, want %d", cfg.Width, tt.wantWidth)
+			}
+		})
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	var pixel = new(bytes.Buffer)
+	if err := png.Encode(pixel, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		content    []byte
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{"1x1 png", pixel.Bytes(), 1, 1, false},
+		{"garbage", []byte("hello world"), 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := Dimensions(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Dimensions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if w != tt.wantWidth || h != tt.wantHeight {
+				t.Errorf("Dimensions() = %dx%d, want %dx%d", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNoGPS is returned when an image holds no GPS location
+var ErrNoGPS = errors.New("no GPS location found")
+
+// EXIF tags used to locate GPS coordinates
+const (
+	tagGPSInfo      = 0x8825
+	tagLatitudeRef  = 0x0001
+	tagLatitude     = 0x0002
+	tagLongitudeRef = 0x0003
+	tagLongitude    = 0x0004
+)
+
+// exifTypeSizes is the size in bytes of each EXIF value type
+var exifTypeSizes = map[uint16]int64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// GPS returns the latitude and longitude recorded in the EXIF metadata of the
+// given JPEG or TIFF image, in decimal degrees. ErrNoGPS is returned if the
+// image has no GPS coordinates.
+func GPS(content []byte) (lat, lon float64, err error) {
+	var exif = content
+	if !IsTIFF(content) {
+		if len(content) < 2 || content[0] != 0xFF || content[1] != 0xD8 {
+			return 0, 0, errors.New("unsupported image format: EXIF is only read from JPEG and TIFF images")
+		}
+		if exif = jpegEXIF(content); exif == nil {
+			return 0, 0, ErrNoGPS
+		}
+	}
+	order, err := tiffByteOrder(exif)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ifd0, err := readIFD(exif, order, order.Uint32(exif[4:8]))
+	if err != nil {
+		return 0, 0, err
+	}
+	pointer, ok := ifd0[tagGPSInfo]
+	if !ok || len(pointer) < 4 {
+		return 0, 0, ErrNoGPS
+	}
+	gps, err := readIFD(exif, order, order.Uint32(pointer))
+	if err != nil {
+		return 0, 0, err
+	}
+	if lat, err = coordinate(gps[tagLatitude], gps[tagLatitudeRef], "S", order); err != nil {
+		return 0, 0, err
+	}
+	if lon, err = coordinate(gps[tagLongitude], gps[tagLongitudeRef], "W", order); err != nil {
+		return 0, 0, err
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid GPS location %v, %v", lat, lon)
+	}
+	return lat, lon, nil
+}
+
+// jpegEXIF returns the TIFF-formatted EXIF data of the given JPEG image, or nil
+// if it has none
+func jpegEXIF(content []byte) []byte {
+	// segments are a marker, a 2-byte length including itself, and data -
+	// metadata always precedes the image data
+	for i := 2; i+4 <= len(content) && content[i] == 0xFF; {
+		var marker = content[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		var end = i + 2 + int(binary.BigEndian.Uint16(content[i+2:]))
+		if end > len(content) {
+			break
+		}
+		var data = content[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+			return data[6:]
+		}
+		i = end
+	}
+	return nil
+}
+
+// readIFD returns the values of the entries of the image file directory at
+// the given offset, keyed by tag. Entries of unknown types, or with values
+// out of bounds, are omitted.
+func readIFD(content []byte, order binary.ByteOrder, offset uint32) (map[uint16][]byte, error) {
+	// a directory is a 2-byte entry count followed by 12-byte entries: a tag,
+	// a type, a count, and the value itself if it fits in 4 bytes, or the
+	// offset of the value otherwise
+	var start = int64(offset)
+	if start+2 > int64(len(content)) {
+		return nil, fmt.Errorf("invalid EXIF: directory offset %d out of bounds", offset)
+	}
+	var count = int64(order.Uint16(content[start:]))
+	if start+2+12*count > int64(len(content)) {
+		return nil, fmt.Errorf("invalid EXIF: directory at %d is truncated", offset)
+	}
+	var entries = make(map[uint16][]byte, count)
+	for i := int64(0); i < count; i++ {
+		var e = content[start+2+12*i : start+14+12*i]
+		var size = exifTypeSizes[order.Uint16(e[2:4])] * int64(order.Uint32(e[4:8]))
+		switch {
+		case size == 0:
+			continue
+		case size <= 4:
+			entries[order.Uint16(e[0:2])] = e[8 : 8+size]
+		default:
+			var at = int64(order.Uint32(e[8:12]))
+			if at+size > int64(len(content)) {
+				continue
+			}
+			entries[order.Uint16(e[0:2])] = content[at : at+size]
+		}
+	}
+	return entries, nil
+}
+
+// coordinate converts an EXIF GPS coordinate - degrees, minutes, and seconds
+// as three rationals - to decimal degrees, negated if its reference is the
+// given negative hemisphere
+func coordinate(value, ref []byte, negative string, order binary.ByteOrder) (float64, error) {
+	if value == nil || ref == nil {
+		return 0, ErrNoGPS
+	}
+	if len(value) != 24 {
+		return 0, errors.New("invalid EXIF: malformed GPS coordinate")
+	}
+	var deg float64
+	for i, unit := range []float64{1, 60, 3600} {
+		var num, den = order.Uint32(value[8*i:]), order.Uint32(value[8*i+4:])
+		if den == 0 {
+			return 0, errors.New("invalid EXIF: malformed GPS coordinate")
+		}
+		deg += float64(num) / float64(den) / unit
+	}
+	if string(bytes.TrimRight(ref, "\x00 ")) == negative {
+		deg = -deg
+	}
+	return deg, nil
+}
@@ -0,0 +1,96 @@
+package images
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// gpsTIFF creates an empty TIFF with the given GPS coordinates, each as
+// degrees, minutes, and seconds with a denominator of 100
+func gpsTIFF(order binary.ByteOrder, latRef string, lat [3]uint32, lonRef string, lon [3]uint32) []byte {
+	var b = make([]byte, 128)
+	copy(b, "II")
+	if order == binary.BigEndian {
+		copy(b, "MM")
+	}
+	order.PutUint16(b[2:], 42)
+	order.PutUint32(b[4:], 8)
+
+	// IFD0 at 8, pointing to the GPS IFD at 26
+	var entry = func(at int, tag, typ uint16, count uint32) {
+		order.PutUint16(b[at:], tag)
+		order.PutUint16(b[at+2:], typ)
+		order.PutUint32(b[at+4:], count)
+	}
+	order.PutUint16(b[8:], 1)
+	entry(10, tagGPSInfo, 4, 1)
+	order.PutUint32(b[18:], 26)
+
+	// GPS IFD at 26, with rationals at 80 and 104
+	order.PutUint16(b[26:], 4)
+	entry(28, tagLatitudeRef, 2, 2)
+	copy(b[36:], latRef)
+	entry(40, tagLatitude, 5, 3)
+	order.PutUint32(b[48:], 80)
+	entry(52, tagLongitudeRef, 2, 2)
+	copy(b[60:], lonRef)
+	entry(64, tagLongitude, 5, 3)
+	order.PutUint32(b[72:], 104)
+	for i := 0; i < 3; i++ {
+		order.PutUint32(b[80+8*i:], lat[i])
+		order.PutUint32(b[84+8*i:], 100)
+		order.PutUint32(b[104+8*i:], lon[i])
+		order.PutUint32(b[108+8*i:], 100)
+	}
+	return b
+}
+
+// exifJPEG wraps the given EXIF data in the APP1 segment of a JPEG, after an
+// unrelated APP0 segment
+func exifJPEG(exif []byte) []byte {
+	var b = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0, 4, 0, 0, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(b[10:], uint16(2+6+len(exif)))
+	b = append(b, "Exif\x00\x00"...)
+	b = append(b, exif...)
+	return append(b, 0xFF, 0xD9)
+}
+
+func TestGPS(t *testing.T) {
+	// 49°16'57.72"N 123°7'14.52"W
+	var vancouver = gpsTIFF(binary.LittleEndian, "N", [3]uint32{4900, 1600, 5772}, "W", [3]uint32{12300, 700, 1452})
+	var noGPS = emptyTIFF(1, 0)
+	tests := []struct {
+		name    string
+		content []byte
+		wantLat float64
+		wantLon float64
+		wantErr error
+	}{
+		{"tiff", vancouver, 49.2827, -123.1207, nil},
+		{"big endian", gpsTIFF(binary.BigEndian, "S", [3]uint32{3300, 5100, 5400}, "E", [3]uint32{15100, 1200, 3600}),
+			-33.865, 151.21, nil},
+		{"jpeg", exifJPEG(vancouver), 49.2827, -123.1207, nil},
+		{"jpeg without exif", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2, 0xFF, 0xD9}, 0, 0, ErrNoGPS},
+		{"tiff without gps", noGPS, 0, 0, ErrNoGPS},
+		{"out of range", gpsTIFF(binary.LittleEndian, "N", [3]uint32{9100, 0, 0}, "E", [3]uint32{0, 0, 0}), 0, 0, nil},
+		{"not an image", []byte("hello world"), 0, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, err := GPS(tt.content)
+			if tt.wantLat == 0 && tt.wantLon == 0 {
+				if err == nil || (tt.wantErr != nil && err != tt.wantErr) {
+					t.Errorf("GPS() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GPS() error = %v", err)
+			}
+			if math.Abs(lat-tt.wantLat) > 1e-4 || math.Abs(lon-tt.wantLon) > 1e-4 {
+				t.Errorf("GPS() = %v, %v, want %v, %v", lat, lon, tt.wantLat, tt.wantLon)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package images
+
+import (
+	"bytes"
+)
+
+var (
+	pdfObj       = []byte("obj")
+	pdfStream    = []byte("stream")
+	pdfEndStream = []byte("endstream")
+	jpegSOI      = []byte{0xff, 0xd8}
+)
+
+// PDFImages returns up to max JPEG images embedded in the given PDF. Only
+// images stored as DCT-encoded streams are found - images in other encodings
+// would have to be decoded from raw pixel data, and are skipped.
+func PDFImages(content []byte, max int) [][]byte {
+	var found = make([][]byte, 0)
+	for pos := 0; len(found) < max; {
+		var i = bytes.Index(content[pos:], pdfStream)
+		if i < 0 {
+			break
+		}
+		var start = pos + i
+		pos = start + len(pdfStream)
+		if bytes.HasSuffix(content[:start], []byte("end")) {
+			continue
+		}
+
+		// stream data begins after the end of line following the keyword
+		var dataStart = pos
+		if bytes.HasPrefix(content[dataStart:], []byte("\r\n")) {
+			dataStart += 2
+		} else if bytes.HasPrefix(content[dataStart:], []byte("\n")) {
+			dataStart++
+		} else {
+			continue
+		}
+		var end = bytes.Index(content[dataStart:], pdfEndStream)
+		if end < 0 {
+			break
+		}
+		var data = bytes.TrimRight(content[dataStart:dataStart+end], "\r\n")
+		pos = dataStart + end + len(pdfEndStream)
+
+		// the stream dictionary sits between the object header and the stream
+		var dict = content[:start]
+		if o := bytes.LastIndex(dict, pdfObj); o >= 0 {
+			dict = dict[o:]
+		}
+		if bytes.Contains(dict, []byte("/Image")) &&
+			bytes.Contains(dict, []byte("/DCTDecode")) &&
+			bytes.HasPrefix(data, jpegSOI) {
+			found = append(found, data)
+		}
+	}
+	return found
+}
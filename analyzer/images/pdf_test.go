@@ -0,0 +1,52 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestPDFImages(t *testing.T) {
+	jpg, err := ioutil.ReadFile("../../test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stream = func(id int, dict string, data []byte) string {
+		return fmt.Sprintf("%d 0 obj\n<< %s /Length %d >>\nstream\r\n%s\nendstream\nendobj\n",
+			id, dict, len(data), data)
+	}
+	var pdf = "%PDF-1.4\n" +
+		stream(1, "/Length 10", []byte("BT (hi) Tj ET")) +
+		stream(2, "/Type /XObject /Subtype /Image /Filter /DCTDecode", jpg) +
+		stream(3, "/Type /XObject /Subtype /Image /Filter /FlateDecode", []byte("xxxx")) +
+		stream(4, "/Type /XObject /Subtype /Image /Filter /DCTDecode", jpg) +
+		"%%EOF\n"
+
+	tests := []struct {
+		name string
+		max  int
+		want int
+	}{
+		{"disabled", 0, 0},
+		{"bounded", 1, 1},
+		{"all", 10, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got = PDFImages([]byte(pdf), tt.max)
+			if len(got) != tt.want {
+				t.Errorf("PDFImages() found %d images, want %d", len(got), tt.want)
+			}
+			for _, img := range got {
+				if !bytes.Equal(img, jpg) {
+					t.Error("PDFImages() returned image that does not match embedded image")
+				}
+			}
+		})
+	}
+
+	if got := PDFImages([]byte("hello world"), 10); len(got) != 0 {
+		t.Errorf("PDFImages() found %d images in non-PDF", len(got))
+	}
+}
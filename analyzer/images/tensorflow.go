@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"go.uber.org/zap"
 
@@ -19,41 +20,148 @@ import (
 // TensorflowAnalyzer represents a wrapper around a Tensorflow-based analyzer
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o ../../mocks/images.mock.go github.com/RTradeLtd/Lens/v2/analyzer/images.TensorflowAnalyzer
 type TensorflowAnalyzer interface {
-	Analyze(jobID string, content []byte) (category string, err error)
+	Analyze(jobID string, content []byte, modelHint string) (category string, err error)
+	Model(modelHint string) (name string)
 }
 
 // All credits for this go to the developers of the example in the following link
 // https://godoc.org/github.com/tensorflow/tensorflow/tensorflow/go
 // This is simply a modified version, intended to be run as a analyzer method by the Lens service
 
+// DefaultModel is the name of the model loaded from ConfigOpts.ModelLocation
+const DefaultModel = "default"
+
 // Analyzer is used to analyze images
 type Analyzer struct {
+	opts         ConfigOpts
+	defaultModel string
+
+	// models is nil until the configured models are loaded - guarded by mux
+	models map[string]*model
+	mux    sync.Mutex
+
+	l *zap.SugaredLogger
+}
+
+// model is a loaded classification model
+type model struct {
 	session    *tf.Session
 	graph      *tf.Graph
 	labelsFile string
-
-	l *zap.SugaredLogger
 }
 
 // ConfigOpts is used to configure our image analyzer
 type ConfigOpts struct {
 	ModelLocation string `json:"model_location"`
+
+	// Models declares additional named models, mapped to the directory they are
+	// located in. Each directory must contain a graph and labels file named
+	// the same way as the default model's.
+	Models map[string]string `json:"models"`
+	// DefaultModel is the name of the model to use when no hint is provided -
+	// leave blank to use the model at ModelLocation
+	DefaultModel string `json:"default_model"`
+
+	// Lazy defers loading models until the first image is analyzed, which
+	// speeds up startup for deployments that rarely index images at the cost
+	// of a slower first analysis. Configuration is still validated upfront.
+	Lazy bool `json:"lazy"`
+
+	// MaxPixels is the maximum number of pixels of images to classify - larger
+	// images are rejected before they are decoded. Leave at 0 to use
+	// DefaultMaxPixels.
+	MaxPixels int `json:"max_pixels"`
 }
 
-// NewAnalyzer is used to analyze an image and classify it
+// NewAnalyzer is used to analyze an image and classify it. Models are loaded
+// immediately unless opts.Lazy is set.
 func NewAnalyzer(opts ConfigOpts, logger *zap.SugaredLogger) (*Analyzer, error) {
-	// load a seralized graph definition
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	var defaultModel = opts.DefaultModel
+	if defaultModel == "" {
+		defaultModel = DefaultModel
+	}
+	var a = &Analyzer{
+		opts:         opts,
+		defaultModel: defaultModel,
+		l:            logger,
+	}
+
+	if opts.Lazy {
+		if err := opts.Validate(); err != nil {
+			return nil, err
+		}
+		logger.Info("deferring model loading until first analysis")
+		return a, nil
+	}
+	if err := a.Load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Load loads the configured models if they have not been loaded yet. It is
+// safe to call concurrently - models are only loaded once, and callers block
+// until loading completes. Failed loads are retried on the next call.
+func (a *Analyzer) Load() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.models != nil {
+		return nil
+	}
+	models, err := loadModels(a.opts, a.l)
+	if err != nil {
+		return err
+	}
+	if _, ok := models[a.defaultModel]; !ok {
+		return fmt.Errorf("default model '%s' is not configured", a.defaultModel)
+	}
+	a.models = models
+	return nil
+}
+
+// loadModels loads the default model, downloading it if necessary, and any
+// additional models
+func loadModels(opts ConfigOpts, logger *zap.SugaredLogger) (map[string]*model, error) {
+	var models = make(map[string]*model, len(opts.Models)+1)
+
+	// load the default model, downloading it if necessary
 	modelFile, labelsFile, err := modelFiles(opts.ModelLocation)
 	if err != nil {
 		return nil, err
 	}
-	model, err := ioutil.ReadFile(modelFile)
+	if models[DefaultModel], err = loadModel(modelFile, labelsFile); err != nil {
+		return nil, err
+	}
+
+	// load additional models
+	for name, dir := range opts.Models {
+		var (
+			modelFile  = filepath.Join(dir, modelFileName)
+			labelsFile = filepath.Join(dir, labelsFileName)
+		)
+		if err := filesExist(modelFile, labelsFile); err != nil {
+			return nil, fmt.Errorf("invalid model '%s': %v", name, err)
+		}
+		if models[name], err = loadModel(modelFile, labelsFile); err != nil {
+			return nil, fmt.Errorf("failed to load model '%s': %v", name, err)
+		}
+		logger.Infow("loaded model", "model", name, "location", dir)
+	}
+	return models, nil
+}
+
+func loadModel(modelFile, labelsFile string) (*model, error) {
+	// load a seralized graph definition
+	def, err := ioutil.ReadFile(modelFile)
 	if err != nil {
 		return nil, err
 	}
 	// create the graph in memory
 	graph := tf.NewGraph()
-	if err = graph.Import(model, ""); err != nil {
+	if err = graph.Import(def, ""); err != nil {
 		return nil, err
 	}
 	// create a session
@@ -61,25 +169,43 @@ func NewAnalyzer(opts ConfigOpts, logger *zap.SugaredLogger) (*Analyzer, error)
 	if err != nil {
 		return nil, err
 	}
-	return &Analyzer{
+	return &model{
 		session:    session,
 		labelsFile: labelsFile,
 		graph:      graph,
 	}, nil
 }
 
-// Analyze is used to run an image against the Inception v5 pre-trained model
-func (a *Analyzer) Analyze(jobID string, content []byte) (string, error) {
-	tensor, err := makeTensorFromImage(content)
+// Analyze is used to run an image against a pre-trained model. modelHint selects
+// the model to use - if it is blank or does not match a configured model, the
+// default model is used.
+func (a *Analyzer) Analyze(jobID string, content []byte, modelHint string) (string, error) {
+	if err := a.Load(); err != nil {
+		return "", fmt.Errorf("failed to load models: %v", err)
+	}
+	var name = a.Model(modelHint)
+	if name != modelHint && modelHint != "" {
+		a.l.Debugw("unknown model hint - using default model",
+			"job_id", jobID,
+			"hint", modelHint,
+			"model", name)
+	}
+	var m = a.models[name]
+
+	jpg, err := toJPEG(content, a.opts.MaxPixels)
+	if err != nil {
+		return "", err
+	}
+	tensor, err := makeTensorFromImage(jpg)
 	if err != nil {
 		return "", err
 	}
-	output, err := a.session.Run(
+	output, err := m.session.Run(
 		map[tf.Output]*tf.Tensor{
-			a.graph.Operation("input").Output(0): tensor,
+			m.graph.Operation("input").Output(0): tensor,
 		},
 		[]tf.Output{
-			a.graph.Operation("output").Output(0),
+			m.graph.Operation("output").Output(0),
 		},
 		nil,
 	)
@@ -87,7 +213,16 @@ func (a *Analyzer) Analyze(jobID string, content []byte) (string, error) {
 		return "", err
 	}
 	probabilities := output[0].Value().([][]float32)[0]
-	return a.classify(probabilities, a.labelsFile)
+	return a.classify(probabilities, m.labelsFile)
+}
+
+// Model returns the name of the model that Analyze uses for the given hint. It
+// does not require models to be loaded.
+func (a *Analyzer) Model(modelHint string) string {
+	if _, ok := a.opts.Models[modelHint]; ok || modelHint == DefaultModel {
+		return modelHint
+	}
+	return a.defaultModel
 }
 
 func (a *Analyzer) classify(probabilities []float32, labelsFile string) (string, error) {
@@ -184,11 +319,17 @@ func constructGraphToNormalizeImage() (graph *tf.Graph, input, output tf.Output,
 	return graph, input, output, err
 }
 
+// Expected file names within a model directory
+const (
+	modelFileName  = "tensorflow_inception_graph.pb"
+	labelsFileName = "imagenet_comp_graph_label_strings.txt"
+)
+
 func modelFiles(dir string) (modelfile, labelsfile string, err error) {
 	const URL = "https://storage.googleapis.com/download.tensorflow.org/models/inception5h.zip"
 	var (
-		model   = filepath.Join(dir, "tensorflow_inception_graph.pb")
-		labels  = filepath.Join(dir, "imagenet_comp_graph_label_strings.txt")
+		model   = filepath.Join(dir, modelFileName)
+		labels  = filepath.Join(dir, labelsFileName)
 		zipfile = filepath.Join(dir, "inception5h.zip")
 	)
 	if filesExist(model, labels) == nil {
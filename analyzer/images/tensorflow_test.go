@@ -2,6 +2,7 @@ package images_test
 
 import (
 	"io/ioutil"
+	"sync"
 	"testing"
 
 	"github.com/RTradeLtd/Lens/v2/analyzer/images"
@@ -26,9 +27,53 @@ func TestTendorize(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	guess, err := analyzer.Analyze("test", b)
+	if m := analyzer.Model("not_a_model"); m != images.DefaultModel {
+		t.Errorf("got model %s for unknown hint, want %s", m, images.DefaultModel)
+	}
+
+	guess, err := analyzer.Analyze("test", b, "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Log(guess)
 }
+
+func TestNewAnalyzer_lazy(t *testing.T) {
+	var l = zaptest.NewLogger(t)
+	if _, err := images.NewAnalyzer(images.ConfigOpts{
+		ModelLocation: "models",
+		DefaultModel:  "not_a_model",
+		Lazy:          true,
+	}, l.Sugar()); err == nil {
+		t.Fatal("expected invalid configuration to be rejected upfront")
+	}
+
+	analyzer, err := images.NewAnalyzer(images.ConfigOpts{
+		ModelLocation: "models",
+		Lazy:          true,
+	}, l.Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := analyzer.Model(""); m != images.DefaultModel {
+		t.Errorf("got model %s before loading, want %s", m, images.DefaultModel)
+	}
+
+	b, err := ioutil.ReadFile(testImg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent first analyses should share a single load
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := analyzer.Analyze("test", b, ""); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// Thumbnail generates a downscaled JPEG version of the given image, such that
+// neither dimension exceeds size. Images that are already small enough are
+// only re-encoded. Images of more than maxPixels pixels are rejected without
+// being decoded - if maxPixels is not positive, DefaultMaxPixels is used.
+func Thumbnail(content []byte, size, quality, maxPixels int) ([]byte, error) {
+	if size < 1 {
+		return nil, errors.New("invalid thumbnail size")
+	}
+	if _, _, err := decodeConfig(content, maxPixels); err != nil {
+		return nil, err
+	}
+	src, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %s", err.Error())
+	}
+
+	// scale down, preserving aspect ratio
+	var bounds = src.Bounds()
+	var w, h = bounds.Dx(), bounds.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	var dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var out = new(bytes.Buffer)
+	if err := jpeg.Encode(out, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail of image of format '%s': %s",
+			format, err.Error())
+	}
+	return out.Bytes(), nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
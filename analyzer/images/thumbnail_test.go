@@ -0,0 +1,60 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	jpg, err := ioutil.ReadFile("../../test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	png, err := ioutil.ReadFile("../../test/assets/text.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webp, err := ioutil.ReadFile("../../test/assets/image.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		content   []byte
+		size      int
+		maxPixels int
+		wantMax   int
+		wantErr   bool
+	}{
+		{"invalid size", jpg, 0, 0, 0, true},
+		{"not an image", []byte("hello world"), 64, 0, 0, true},
+		{"jpeg", jpg, 64, 0, 64, false},
+		{"png", png, 32, 0, 32, false},
+		{"small webp is not upscaled", webp, 64, 0, 1, false},
+		{"exceeds pixel limit", png, 32, 16, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Thumbnail(tt.content, tt.size, 75, tt.maxPixels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Thumbnail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(got))
+			if err != nil || format != "jpeg" {
+				t.Errorf("Thumbnail() produced format %s (error = %v), want jpeg", format, err)
+				return
+			}
+			if max(cfg.Width, cfg.Height) != tt.wantMax {
+				t.Errorf("Thumbnail() = %dx%d, want largest dimension %d",
+					cfg.Width, cfg.Height, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package images
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// TIFF provides access to the individual pages of a TIFF image, which most
+// decoders only read the first of
+type TIFF struct {
+	content []byte
+	order   binary.ByteOrder
+	ifds    []uint32 // offset of each page's image file directory
+
+	// Truncated is true if the image has more pages than were requested
+	Truncated bool
+}
+
+// IsTIFF checks for a TIFF header
+func IsTIFF(content []byte) bool {
+	_, err := tiffByteOrder(content)
+	return err == nil
+}
+
+// ParseTIFF locates up to maxPages pages in the given TIFF image
+func ParseTIFF(content []byte, maxPages int) (*TIFF, error) {
+	order, err := tiffByteOrder(content)
+	if err != nil {
+		return nil, err
+	}
+	var t = &TIFF{content: content, order: order}
+	var seen = make(map[uint32]bool)
+	for offset := order.Uint32(content[4:8]); offset != 0; {
+		if len(t.ifds) >= maxPages {
+			t.Truncated = true
+			break
+		}
+		if seen[offset] {
+			return nil, errors.New("invalid TIFF: image file directories form a loop")
+		}
+		seen[offset] = true
+
+		// each directory is a 2-byte entry count, 12-byte entries, and the
+		// 4-byte offset of the next directory
+		var start = int64(offset)
+		if start+2 > int64(len(content)) {
+			return nil, fmt.Errorf("invalid TIFF: directory offset %d out of bounds", offset)
+		}
+		var end = start + 2 + 12*int64(order.Uint16(content[start:])) + 4
+		if end > int64(len(content)) {
+			return nil, fmt.Errorf("invalid TIFF: directory at %d is truncated", offset)
+		}
+		t.ifds = append(t.ifds, offset)
+		offset = order.Uint32(content[end-4 : end])
+	}
+	if len(t.ifds) == 0 {
+		return nil, errors.New("invalid TIFF: no pages found")
+	}
+	return t, nil
+}
+
+// Pages returns the number of pages found
+func (t *TIFF) Pages() int { return len(t.ifds) }
+
+// Page returns a copy of the image with the given page as its first page, so
+// that it can be handled by regular decoders
+func (t *TIFF) Page(i int) []byte {
+	if i == 0 {
+		return t.content
+	}
+	var page = make([]byte, len(t.content))
+	copy(page, t.content)
+	t.order.PutUint32(page[4:8], t.ifds[i])
+	return page
+}
+
+func tiffByteOrder(content []byte) (binary.ByteOrder, error) {
+	if len(content) < 8 {
+		return nil, errors.New("invalid TIFF: header is too short")
+	}
+	var order binary.ByteOrder
+	switch string(content[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF: unknown byte order")
+	}
+	if order.Uint16(content[2:4]) != 42 {
+		return nil, errors.New("invalid TIFF: bad magic number")
+	}
+	return order, nil
+}
@@ -0,0 +1,96 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// emptyTIFF creates a little-endian TIFF with the given number of empty image
+// file directories, with the last directory pointing to loopTo
+func emptyTIFF(pages int, loopTo uint32) []byte {
+	var b = []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	for i := 0; i < pages; i++ {
+		var next = uint32(len(b) + 6)
+		if i == pages-1 {
+			next = loopTo
+		}
+		b = append(b, 0, 0)
+		b = append(b, make([]byte, 4)...)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], next)
+	}
+	return b
+}
+
+func TestParseTIFF(t *testing.T) {
+	var single = new(bytes.Buffer)
+	if err := tiff.Encode(single, image.NewGray(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		content       []byte
+		maxPages      int
+		wantPages     int
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{"not a tiff", []byte("hello world"), 10, 0, false, true},
+		{"too short", []byte("II*"), 10, 0, false, true},
+		{"bad offset", []byte{'M', 'M', 0, 42, 0, 0, 1, 0}, 10, 0, false, true},
+		{"loop", emptyTIFF(2, 8), 10, 0, false, true},
+		{"single page", single.Bytes(), 10, 1, false, false},
+		{"multiple pages", emptyTIFF(3, 0), 10, 3, false, false},
+		{"truncated", emptyTIFF(3, 0), 2, 2, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTIFF(tt.content, tt.maxPages)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTIFF() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.Pages() != tt.wantPages || got.Truncated != tt.wantTruncated {
+				t.Errorf("ParseTIFF() = %d pages (truncated %v), want %d (truncated %v)",
+					got.Pages(), got.Truncated, tt.wantPages, tt.wantTruncated)
+			}
+		})
+	}
+
+	t.Run("detect", func(t *testing.T) {
+		if !IsTIFF(single.Bytes()) || IsTIFF([]byte("hello world")) {
+			t.Error("IsTIFF() did not distinguish TIFF from text")
+		}
+	})
+
+	t.Run("page", func(t *testing.T) {
+		got, err := ParseTIFF(emptyTIFF(2, 0), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page = got.Page(1)
+		if offset := binary.LittleEndian.Uint32(page[4:8]); offset != 14 {
+			t.Errorf("Page() first directory at %d, want %d", offset, 14)
+		}
+		if first := got.Page(0); binary.LittleEndian.Uint32(first[4:8]) != 8 {
+			t.Error("Page() modified original image")
+		}
+	})
+
+	t.Run("decode single page", func(t *testing.T) {
+		got, err := ParseTIFF(single.Bytes(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := toJPEG(got.Page(0), 0); err != nil {
+			t.Errorf("toJPEG() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Validate checks that the configured models can be loaded without loading
+// them. The default model may be missing if its location is writable, since
+// it is downloaded on startup.
+func (opts ConfigOpts) Validate() error {
+	var (
+		model  = filepath.Join(opts.ModelLocation, modelFileName)
+		labels = filepath.Join(opts.ModelLocation, labelsFileName)
+	)
+	if filesExist(model, labels) != nil {
+		if err := writable(opts.ModelLocation); err != nil {
+			return fmt.Errorf("default model is missing and cannot be downloaded: %v", err)
+		}
+	}
+	for name, dir := range opts.Models {
+		if err := filesExist(
+			filepath.Join(dir, modelFileName),
+			filepath.Join(dir, labelsFileName),
+		); err != nil {
+			return fmt.Errorf("invalid model '%s': %v", name, err)
+		}
+	}
+	if opts.DefaultModel != "" && opts.DefaultModel != DefaultModel {
+		if _, ok := opts.Models[opts.DefaultModel]; !ok {
+			return fmt.Errorf("default model '%s' is not configured", opts.DefaultModel)
+		}
+	}
+	return nil
+}
+
+// writable checks that files can be created in the given directory, creating
+// it if necessary
+func writable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".lens-")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,52 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigOpts_Validate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lens-models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var model = filepath.Join(dir, "model")
+	os.MkdirAll(model, 0755)
+	for _, f := range []string{modelFileName, labelsFileName} {
+		if err := ioutil.WriteFile(filepath.Join(model, f), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var file = filepath.Join(dir, "file")
+	ioutil.WriteFile(file, []byte{}, 0644)
+
+	tests := []struct {
+		name    string
+		opts    ConfigOpts
+		wantErr bool
+	}{
+		{"default model present", ConfigOpts{ModelLocation: model}, false},
+		{"default model downloadable", ConfigOpts{ModelLocation: filepath.Join(dir, "new")}, false},
+		{"default model not downloadable", ConfigOpts{ModelLocation: filepath.Join(file, "new")}, true},
+		{"extra model present", ConfigOpts{
+			ModelLocation: model,
+			Models:        map[string]string{"other": model},
+			DefaultModel:  "other",
+		}, false},
+		{"extra model missing", ConfigOpts{
+			ModelLocation: model,
+			Models:        map[string]string{"other": dir},
+		}, true},
+		{"unknown default model", ConfigOpts{ModelLocation: model, DefaultModel: "other"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("ConfigOpts.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
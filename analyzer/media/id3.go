@@ -0,0 +1,204 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3Frames maps ID3v2 text frame IDs to the metadata fields they populate,
+// for both 4-character (v2.3, v2.4) and 3-character (v2.2) IDs
+var id3Frames = map[string]func(*Metadata) *string{
+	"TIT2": func(m *Metadata) *string { return &m.Title },
+	"TPE1": func(m *Metadata) *string { return &m.Artist },
+	"TALB": func(m *Metadata) *string { return &m.Album },
+	"TCON": func(m *Metadata) *string { return &m.Genre },
+	"TT2":  func(m *Metadata) *string { return &m.Title },
+	"TP1":  func(m *Metadata) *string { return &m.Artist },
+	"TAL":  func(m *Metadata) *string { return &m.Album },
+	"TCO":  func(m *Metadata) *string { return &m.Genre },
+}
+
+// parseID3v2 reads text frames from an ID3v2 tag at the start of content
+func parseID3v2(content []byte) (Metadata, error) {
+	var md Metadata
+	if len(content) < 10 {
+		return md, errors.New("invalid ID3v2 tag: header is too short")
+	}
+	var (
+		version = content[3]
+		flags   = content[5]
+		size    = synchsafe(content[6:10])
+	)
+	if version < 2 || version > 4 {
+		return md, fmt.Errorf("unsupported ID3v2 version 2.%d", version)
+	}
+	if 10+size > len(content) {
+		return md, errors.New("invalid ID3v2 tag: tag is truncated")
+	}
+	var tag = content[10 : 10+size]
+	if flags&0x80 != 0 {
+		// undo unsynchronisation, which inserts a zero byte after each 0xFF
+		tag = bytes.Replace(tag, []byte{0xFF, 0x00}, []byte{0xFF}, -1)
+	}
+	if flags&0x40 != 0 && version > 2 {
+		// skip the extended header - in v2.3 its size excludes itself
+		if len(tag) < 4 {
+			return md, errors.New("invalid ID3v2 tag: extended header is truncated")
+		}
+		var ext = synchsafe(tag[0:4])
+		if version == 3 {
+			ext = int(binary.BigEndian.Uint32(tag[0:4])) + 4
+		}
+		if ext > len(tag) {
+			return md, errors.New("invalid ID3v2 tag: extended header is truncated")
+		}
+		tag = tag[ext:]
+	}
+
+	var idLen, headerLen = 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(tag) >= headerLen && tag[0] != 0 {
+		var (
+			id        = string(tag[:idLen])
+			frameSize int
+		)
+		switch version {
+		case 2:
+			frameSize = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(tag[4:8]))
+		default:
+			frameSize = synchsafe(tag[4:8])
+		}
+		if frameSize < 0 || headerLen+frameSize > len(tag) {
+			return md, fmt.Errorf("invalid ID3v2 tag: frame '%s' is truncated", id)
+		}
+		if field, ok := id3Frames[id]; ok {
+			*field(&md) = id3Text(tag[headerLen : headerLen+frameSize])
+		}
+		tag = tag[headerLen+frameSize:]
+	}
+	md.Genre = id3Genre(md.Genre)
+	return md, nil
+}
+
+// parseID3v1 reads an ID3v1 tag from the last 128 bytes of content
+func parseID3v1(content []byte) (Metadata, error) {
+	if len(content) < 128 {
+		return Metadata{}, ErrNoMetadata
+	}
+	var tag = content[len(content)-128:]
+	if string(tag[:3]) != "TAG" {
+		return Metadata{}, ErrNoMetadata
+	}
+	var md = Metadata{
+		Title:  latin1(trimNull(tag[3:33])),
+		Artist: latin1(trimNull(tag[33:63])),
+		Album:  latin1(trimNull(tag[63:93])),
+	}
+	if g := int(tag[127]); g < len(id3v1Genres) {
+		md.Genre = id3v1Genres[g]
+	}
+	return md, nil
+}
+
+// id3Text decodes the contents of an ID3v2 text frame. Frames may hold
+// several null-separated values, of which only the first is used.
+func id3Text(frame []byte) string {
+	if len(frame) < 1 {
+		return ""
+	}
+	var encoding, text = frame[0], frame[1:]
+	switch encoding {
+	case 0: // ISO-8859-1
+		return latin1(trimNull(text))
+	case 1, 2: // UTF-16 with byte order mark, UTF-16BE without
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == 1 && len(text) >= 2 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			text = text[2:]
+		}
+		var units = make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			var u = order.Uint16(text[i:])
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		return strings.TrimSpace(string(utf16.Decode(units)))
+	default: // UTF-8
+		return strings.TrimSpace(string(trimNull(text)))
+	}
+}
+
+// id3Genre resolves numeric references to ID3v1 genres, ie '(17)' or '17',
+// which are used by many taggers in place of genre names
+func id3Genre(genre string) string {
+	var ref = genre
+	if strings.HasPrefix(ref, "(") {
+		if end := strings.Index(ref, ")"); end > 0 {
+			if rest := strings.TrimSpace(ref[end+1:]); rest != "" {
+				// refinements following the reference take precedence
+				return rest
+			}
+			ref = ref[1:end]
+		}
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n >= 0 && n < len(id3v1Genres) {
+			return id3v1Genres[n]
+		}
+		return ""
+	}
+	return genre
+}
+
+// synchsafe decodes a 4-byte integer that uses 7 bits per byte
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// trimNull truncates b at its first null byte
+func trimNull(b []byte) []byte {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i]
+	}
+	return b
+}
+
+// latin1 decodes ISO-8859-1 text, whose bytes map directly to code points
+func latin1(b []byte) string {
+	var r = make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return strings.TrimSpace(string(r))
+}
+
+// id3v1Genres are the standard genres referenced by index in ID3 tags
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge",
+	"Hip-Hop", "Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B",
+	"Rap", "Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska",
+	"Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient",
+	"Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance", "Classical",
+	"Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative",
+	"Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic", "Darkwave",
+	"Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap",
+	"Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave",
+	"Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal",
+	"Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll",
+	"Hard Rock",
+}
@@ -0,0 +1,46 @@
+package media
+
+import "testing"
+
+func Test_id3Text(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"latin1", []byte{0, 'c', 'a', 'f', 0xE9, 0}, "café"},
+		{"utf16 le bom", []byte{1, 0xFF, 0xFE, 'h', 0, 'i', 0, 0, 0, 'x', 0}, "hi"},
+		{"utf16 be bom", []byte{1, 0xFE, 0xFF, 0, 'h', 0, 'i'}, "hi"},
+		{"utf16be", []byte{2, 0, 'h', 0, 'i'}, "hi"},
+		{"utf8 multiple values", []byte("\x03caf\xc3\xa9\x00other"), "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id3Text(tt.frame); got != tt.want {
+				t.Errorf("id3Text() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_id3Genre(t *testing.T) {
+	tests := []struct {
+		genre string
+		want  string
+	}{
+		{"Modal Jazz", "Modal Jazz"},
+		{"(8)", "Jazz"},
+		{"17", "Rock"},
+		{"(8)Modal Jazz", "Modal Jazz"},
+		{"(255)", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.genre, func(t *testing.T) {
+			if got := id3Genre(tt.genre); got != tt.want {
+				t.Errorf("id3Genre() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package media extracts descriptive metadata, such as titles and artists,
+// from audio and video containers
+package media
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// Metadata denotes the descriptive fields found in a media container
+type Metadata struct {
+	Title  string
+	Artist string
+	Album  string
+	Genre  string
+}
+
+// Fields returns the non-empty fields of the metadata
+func (m Metadata) Fields() []string {
+	var fields = make([]string, 0, 4)
+	for _, f := range []string{m.Title, m.Artist, m.Album, m.Genre} {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// ErrNoMetadata is returned when a container holds no descriptive metadata
+var ErrNoMetadata = errors.New("no media metadata found")
+
+// Detect returns the mime type of media formats that are not recognized by
+// http.DetectContentType, or an empty string if content is not one of them
+func Detect(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("fLaC")):
+		return "audio/flac"
+	case len(content) >= 12 && string(content[4:8]) == "ftyp" &&
+		string(content[8:11]) == "M4A":
+		return "audio/mp4"
+	case len(content) >= 2 && content[0] == 0xFF && content[1]&0xE0 == 0xE0:
+		// MPEG audio frame sync, as found in MP3s without an ID3v2 tag
+		return "audio/mpeg"
+	default:
+		return ""
+	}
+}
+
+// Parse extracts metadata from ID3 tags, MP4 metadata atoms, or Vorbis
+// comments in Ogg and FLAC files. ErrNoMetadata is returned if the container
+// is recognized but holds no metadata.
+func Parse(content []byte) (Metadata, error) {
+	var (
+		md  Metadata
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(content, []byte("ID3")):
+		md, err = parseID3v2(content)
+		if err != nil || md == (Metadata{}) {
+			// fall back to a trailing ID3v1 tag
+			md, err = parseID3v1(content)
+		}
+	case bytes.HasPrefix(content, []byte("fLaC")):
+		md, err = parseFLAC(content)
+	case bytes.HasPrefix(content, []byte("OggS")):
+		md, err = parseOgg(content)
+	case len(content) >= 8 && string(content[4:8]) == "ftyp":
+		md, err = parseMP4(content)
+	default:
+		md, err = parseID3v1(content)
+	}
+	if err != nil {
+		return Metadata{}, err
+	}
+	if len(md.Fields()) == 0 {
+		return Metadata{}, ErrNoMetadata
+	}
+	return md, nil
+}
@@ -0,0 +1,194 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+var testMetadata = Metadata{
+	Title:  "Blue in Green",
+	Artist: "Miles Davis",
+	Album:  "Kind of Blue",
+	Genre:  "Jazz",
+}
+
+// id3v2Tag creates an ID3v2.3 tag with UTF-8 text frames
+func id3v2Tag(frames map[string]string) []byte {
+	var body bytes.Buffer
+	for _, id := range []string{"TIT2", "TPE1", "TALB", "TCON"} {
+		v, ok := frames[id]
+		if !ok {
+			continue
+		}
+		body.WriteString(id)
+		binary.Write(&body, binary.BigEndian, uint32(len(v)+1))
+		body.Write([]byte{0, 0, 3})
+		body.WriteString(v)
+	}
+	body.Write(make([]byte, 16)) // padding
+	var n = body.Len()
+	var tag = []byte{'I', 'D', '3', 3, 0, 0,
+		byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+	return append(tag, body.Bytes()...)
+}
+
+// id3v1Tag creates a trailing ID3v1 tag
+func id3v1Tag(md Metadata, genre byte) []byte {
+	var tag = make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], md.Title)
+	copy(tag[33:63], md.Artist)
+	copy(tag[63:93], md.Album)
+	tag[127] = genre
+	return tag
+}
+
+// mp4Atom creates an MP4 atom with the given type and contents
+func mp4Atom(kind string, contents ...[]byte) []byte {
+	var data = bytes.Join(contents, nil)
+	var b = make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(b, uint32(8+len(data)))
+	copy(b[4:], kind)
+	return append(b, data...)
+}
+
+// mp4File creates an M4A file with iTunes-style metadata items
+func mp4File(md Metadata) []byte {
+	var item = func(kind, value string) []byte {
+		return mp4Atom(kind, mp4Atom("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, []byte(value)))
+	}
+	var ilst = mp4Atom("ilst",
+		item("\xa9nam", md.Title), item("\xa9ART", md.Artist),
+		item("\xa9alb", md.Album),
+		mp4Atom("gnre", mp4Atom("data", make([]byte, 8), []byte{0, 9})))
+	return bytes.Join([][]byte{
+		mp4Atom("ftyp", []byte("M4A \x00\x00\x00\x00")),
+		mp4Atom("moov", mp4Atom("udta", mp4Atom("meta", make([]byte, 4), ilst))),
+		mp4Atom("mdat", make([]byte, 32)),
+	}, nil)
+}
+
+// vorbisComment creates a Vorbis comment structure
+func vorbisComment(comments ...string) []byte {
+	var b bytes.Buffer
+	var write = func(s string) {
+		binary.Write(&b, binary.LittleEndian, uint32(len(s)))
+		b.WriteString(s)
+	}
+	write("test vendor")
+	binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		write(c)
+	}
+	return b.Bytes()
+}
+
+// flacFile creates a FLAC file with a stream info block and the given comment
+func flacFile(comment []byte) []byte {
+	var b = []byte("fLaC")
+	b = append(b, 0, 0, 0, 34)
+	b = append(b, make([]byte, 34)...)
+	b = append(b, 0x84, byte(len(comment)>>16), byte(len(comment)>>8), byte(len(comment)))
+	return append(b, comment...)
+}
+
+// oggFile creates an Ogg stream with one page per packet
+func oggFile(packets ...[]byte) []byte {
+	var b []byte
+	for i, p := range packets {
+		var table []byte
+		for n := len(p); ; n -= 255 {
+			if n < 255 {
+				table = append(table, byte(n))
+				break
+			}
+			table = append(table, 255)
+		}
+		var header = make([]byte, 27)
+		copy(header, "OggS")
+		binary.LittleEndian.PutUint32(header[14:18], 1234)
+		binary.LittleEndian.PutUint32(header[18:22], uint32(i))
+		header[26] = byte(len(table))
+		b = append(b, header...)
+		b = append(b, table...)
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	var comments = []string{"title=Blue in Green", "ARTIST=Miles Davis",
+		"Album=Kind of Blue", "GENRE=Jazz", "GENRE=Modal Jazz", "invalid"}
+	var longComment = vorbisComment(append(comments,
+		"DESCRIPTION="+string(bytes.Repeat([]byte("a"), 600)))...)
+	tests := []struct {
+		name    string
+		content []byte
+		want    Metadata
+		wantErr bool
+	}{
+		{"id3v2", append(id3v2Tag(map[string]string{
+			"TIT2": "Blue in Green", "TPE1": "Miles Davis",
+			"TALB": "Kind of Blue", "TCON": "(8)"}), 0xFF, 0xFB),
+			testMetadata, false},
+		{"id3v2 without frames falls back to id3v1",
+			append(id3v2Tag(nil), id3v1Tag(testMetadata, 8)...),
+			testMetadata, false},
+		{"id3v2 truncated", id3v2Tag(map[string]string{"TIT2": "Blue in Green"})[:20],
+			Metadata{}, true},
+		{"id3v1", append([]byte{0xFF, 0xFB, 0x90}, id3v1Tag(testMetadata, 8)...),
+			testMetadata, false},
+		{"mp4", mp4File(testMetadata), testMetadata, false},
+		{"mp4 without metadata", mp4Atom("ftyp", []byte("isom")), Metadata{}, true},
+		{"flac", flacFile(vorbisComment(comments...)), testMetadata, false},
+		{"flac without comments", flacFile(nil)[:42], Metadata{}, true},
+		{"ogg vorbis", oggFile([]byte("\x01vorbis"),
+			append([]byte("\x03vorbis"), longComment...)), testMetadata, false},
+		{"ogg opus", oggFile([]byte("OpusHead"),
+			append([]byte("OpusTags"), vorbisComment(comments...)...)), testMetadata, false},
+		{"ogg truncated", oggFile([]byte("OpusHead"))[:30], Metadata{}, true},
+		{"no metadata", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), Metadata{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"flac", flacFile(nil), "audio/flac"},
+		{"m4a", mp4File(testMetadata), "audio/mp4"},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x00}, "audio/mpeg"},
+		{"text", []byte("hello world"), ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.content); got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadata_Fields(t *testing.T) {
+	var md = Metadata{Title: " Blue in Green ", Genre: "Jazz"}
+	if got := md.Fields(); !reflect.DeepEqual(got, []string{"Blue in Green", "Jazz"}) {
+		t.Errorf("Metadata.Fields() = %v", got)
+	}
+}
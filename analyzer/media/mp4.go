@@ -0,0 +1,116 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// mp4Items maps iTunes-style metadata item atoms to the fields they populate
+var mp4Items = map[string]func(*Metadata) *string{
+	"\xa9nam": func(m *Metadata) *string { return &m.Title },
+	"\xa9ART": func(m *Metadata) *string { return &m.Artist },
+	"\xa9alb": func(m *Metadata) *string { return &m.Album },
+	"\xa9gen": func(m *Metadata) *string { return &m.Genre },
+}
+
+// atom is a box in an MP4 container
+type atom struct {
+	kind string
+	data []byte // contents, excluding the header
+}
+
+// parseMP4 reads metadata items from moov.udta.meta.ilst in an MP4 container
+func parseMP4(content []byte) (Metadata, error) {
+	var md Metadata
+	ilst, err := findAtom(content, "moov", "udta", "meta", "ilst")
+	if err != nil {
+		return md, err
+	}
+	items, _ := atoms(ilst)
+	for _, item := range items {
+		field, ok := mp4Items[item.kind]
+		if !ok {
+			if item.kind == "gnre" && md.Genre == "" {
+				// genre stored as a 1-based index of ID3v1 genres
+				if data := mp4Data(item.data); len(data) == 2 {
+					if g := int(binary.BigEndian.Uint16(data)) - 1; g >= 0 && g < len(id3v1Genres) {
+						md.Genre = id3v1Genres[g]
+					}
+				}
+			}
+			continue
+		}
+		*field(&md) = string(mp4Data(item.data))
+	}
+	return md, nil
+}
+
+// findAtom descends through the atoms with the given types
+func findAtom(content []byte, path ...string) ([]byte, error) {
+	var data = content
+	for _, kind := range path {
+		// atoms preceding a malformed atom are still searched
+		children, err := atoms(data)
+		var found bool
+		for _, c := range children {
+			if c.kind == kind {
+				data, found = c.data, true
+				break
+			}
+		}
+		if !found && err != nil {
+			return nil, err
+		} else if !found {
+			return nil, ErrNoMetadata
+		}
+		if kind == "meta" {
+			// meta is a full box, with a 4-byte version and flags
+			if len(data) < 4 {
+				return nil, errors.New("invalid MP4: meta atom is truncated")
+			}
+			data = data[4:]
+		}
+	}
+	return data, nil
+}
+
+// atoms splits content into a sequence of atoms. If a malformed atom is
+// found, the atoms before it are returned along with an error.
+func atoms(content []byte) ([]atom, error) {
+	var list = make([]atom, 0)
+	for len(content) >= 8 {
+		var (
+			size   = uint64(binary.BigEndian.Uint32(content[0:4]))
+			kind   = string(content[4:8])
+			header = uint64(8)
+		)
+		switch size {
+		case 0: // extends to the end of the container
+			size = uint64(len(content))
+		case 1: // 64-bit size follows the type
+			if len(content) < 16 {
+				return list, fmt.Errorf("invalid MP4: atom '%s' is truncated", kind)
+			}
+			size, header = binary.BigEndian.Uint64(content[8:16]), 16
+		}
+		if size < header || size > uint64(len(content)) {
+			return list, fmt.Errorf("invalid MP4: atom '%s' has invalid size %d", kind, size)
+		}
+		list = append(list, atom{kind: kind, data: content[header:size]})
+		content = content[size:]
+	}
+	return list, nil
+}
+
+// mp4Data returns the value of the first data atom in a metadata item
+func mp4Data(item []byte) []byte {
+	children, _ := atoms(item)
+	for _, c := range children {
+		// data atoms hold a 4-byte type indicator and a 4-byte locale
+		if c.kind == "data" && len(c.data) >= 8 {
+			return c.data[8:]
+		}
+	}
+	return nil
+}
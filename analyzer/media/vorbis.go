@@ -0,0 +1,142 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// vorbisFields maps Vorbis comment names to the fields they populate
+var vorbisFields = map[string]func(*Metadata) *string{
+	"TITLE":  func(m *Metadata) *string { return &m.Title },
+	"ARTIST": func(m *Metadata) *string { return &m.Artist },
+	"ALBUM":  func(m *Metadata) *string { return &m.Album },
+	"GENRE":  func(m *Metadata) *string { return &m.Genre },
+}
+
+// maxOggPages bounds the number of pages read while looking for comments,
+// which appear in the second packet of a stream
+const maxOggPages = 64
+
+// parseFLAC reads the Vorbis comment metadata block of a FLAC file
+func parseFLAC(content []byte) (Metadata, error) {
+	var blocks = content[4:]
+	for len(blocks) >= 4 {
+		var (
+			last   = blocks[0]&0x80 != 0
+			kind   = blocks[0] & 0x7F
+			length = int(blocks[1])<<16 | int(blocks[2])<<8 | int(blocks[3])
+		)
+		if 4+length > len(blocks) {
+			return Metadata{}, errors.New("invalid FLAC: metadata block is truncated")
+		}
+		if kind == 4 {
+			return parseVorbisComment(blocks[4 : 4+length])
+		}
+		if last {
+			break
+		}
+		blocks = blocks[4+length:]
+	}
+	return Metadata{}, ErrNoMetadata
+}
+
+// parseOgg reads the comment header of the first logical stream in an Ogg
+// file, which may hold either Vorbis or Opus audio
+func parseOgg(content []byte) (Metadata, error) {
+	var (
+		serial  uint32
+		packets = make([][]byte, 0, 2)
+		packet  []byte
+	)
+	for page := 0; page < maxOggPages && len(packets) < 2; page++ {
+		if len(content) < 27 || string(content[:4]) != "OggS" {
+			return Metadata{}, errors.New("invalid Ogg: page header not found")
+		}
+		var segments = int(content[26])
+		if len(content) < 27+segments {
+			return Metadata{}, errors.New("invalid Ogg: page header is truncated")
+		}
+		var (
+			table = content[27 : 27+segments]
+			data  = content[27+segments:]
+		)
+		if page == 0 {
+			serial = binary.LittleEndian.Uint32(content[14:18])
+		}
+		var size int
+		for _, s := range table {
+			size += int(s)
+		}
+		if size > len(data) {
+			return Metadata{}, errors.New("invalid Ogg: page is truncated")
+		}
+		if binary.LittleEndian.Uint32(content[14:18]) == serial {
+			// packets are split into segments of 255 bytes, ending with a
+			// shorter segment
+			for _, s := range table {
+				packet = append(packet, data[:s]...)
+				data = data[s:]
+				if s < 255 {
+					packets = append(packets, packet)
+					packet = nil
+				}
+			}
+		}
+		content = content[27+segments+size:]
+	}
+	if len(packets) < 2 {
+		return Metadata{}, ErrNoMetadata
+	}
+	var comment = packets[1]
+	switch {
+	case bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		return parseVorbisComment(comment[7:])
+	case bytes.HasPrefix(comment, []byte("OpusTags")):
+		return parseVorbisComment(comment[8:])
+	default:
+		return Metadata{}, ErrNoMetadata
+	}
+}
+
+// parseVorbisComment reads a Vorbis comment structure - a vendor string
+// followed by a list of 'NAME=value' comments
+func parseVorbisComment(b []byte) (Metadata, error) {
+	var md Metadata
+	var next = func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		var n = binary.LittleEndian.Uint32(b[0:4])
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, false
+		}
+		var s = b[4 : 4+n]
+		b = b[4+n:]
+		return s, true
+	}
+	if _, ok := next(); !ok {
+		return md, errors.New("invalid Vorbis comment: vendor string is truncated")
+	}
+	if len(b) < 4 {
+		return md, errors.New("invalid Vorbis comment: comment count is missing")
+	}
+	var count = binary.LittleEndian.Uint32(b[0:4])
+	b = b[4:]
+	for i := uint32(0); i < count; i++ {
+		c, ok := next()
+		if !ok {
+			return md, errors.New("invalid Vorbis comment: comment is truncated")
+		}
+		var parts = strings.SplitN(string(c), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		// names are case-insensitive, and the first of repeated names is used
+		if field, ok := vorbisFields[strings.ToUpper(parts[0])]; ok && *field(&md) == "" {
+			*field(&md) = strings.TrimSpace(parts[1])
+		}
+	}
+	return md, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"image/png"
+	"strings"
 	"time"
 
 	"github.com/RTradeLtd/Lens/v2/logs"
@@ -18,13 +19,49 @@ import (
 // Analyzer is the OCR analysis class
 type Analyzer struct {
 	configPath string
+	opts       Options
 
 	l *zap.SugaredLogger
 }
 
+// Options configures OCR behaviour
+type Options struct {
+	// PDFTextThreshold is the minimum length of text a PDF page must yield for
+	// OCR to be skipped - defaults to 10
+	PDFTextThreshold int
+	// DisablePDFFallback prevents PDF pages with little or no extractable text
+	// from being rendered and run through OCR
+	DisablePDFFallback bool
+	// MaxPDFFallbackPages bounds the number of pages per PDF that may be run
+	// through OCR - leave at 0 for no limit
+	MaxPDFFallbackPages int
+	// MaxPDFPages bounds the number of pages per PDF that text is extracted
+	// from - later pages are ignored. Leave at 0 for no limit.
+	MaxPDFPages int
+	// MaxPDFText bounds the length in bytes of text extracted from each PDF -
+	// no further pages are extracted once it is reached, so the text may
+	// exceed it by at most one page. Leave at 0 for no limit.
+	MaxPDFText int
+	// MaxPDFOutline bounds the number of outline (bookmark) titles extracted
+	// from each PDF - leave at 0 to skip outline extraction
+	MaxPDFOutline int
+}
+
+// PDFInfo describes a PDF converted to text
+type PDFInfo struct {
+	// Pages is the total number of pages in the document
+	Pages int
+	// Truncated indicates that pages beyond Options.MaxPDFPages, or beyond
+	// Options.MaxPDFText bytes of text, were ignored
+	Truncated bool
+	// Outline holds the titles of the document's outline entries, in document
+	// order, up to Options.MaxPDFOutline entries
+	Outline []string
+}
+
 // NewAnalyzer creates a new OCR analyzer
-func NewAnalyzer(configPath string, logger *zap.SugaredLogger) *Analyzer {
-	return &Analyzer{configPath, logger}
+func NewAnalyzer(configPath string, opts Options, logger *zap.SugaredLogger) *Analyzer {
+	return &Analyzer{configPath, opts, logger}
 }
 
 // Version reports the version of Tesseract
@@ -38,13 +75,27 @@ func (a *Analyzer) Analyze(jobID string, content []byte, assetType string) (cont
 
 	switch assetType {
 	case "pdf":
-		return a.pdfToText(jobID, content, 10)
+		contents, _, err = a.PDFToText(jobID, content)
+		return contents, err
 	default:
 		return a.imageToText(jobID, content)
 	}
 }
 
-func (a *Analyzer) pdfToText(jobID string, content []byte, threshold int) (string, error) {
+// PDFToText extracts text from the given PDF, running pages with little or no
+// extractable text through OCR if allowed
+func (a *Analyzer) PDFToText(jobID string, content []byte) (string, PDFInfo, error) {
+	if len(content) < 1 {
+		return "", PDFInfo{}, errors.New("invalid asset provided")
+	}
+	var threshold = a.opts.PDFTextThreshold
+	if threshold < 1 {
+		threshold = 10
+	}
+	return a.pdfToText(jobID, content, threshold)
+}
+
+func (a *Analyzer) pdfToText(jobID string, content []byte, threshold int) (string, PDFInfo, error) {
 	var l = logs.NewProcessLogger(a.l, "pdf_to_text",
 		"job_id", jobID,
 		"threshold", threshold)
@@ -56,32 +107,62 @@ func (a *Analyzer) pdfToText(jobID string, content []byte, threshold int) (strin
 	if err != nil {
 		l.Warn("failed to create fitz document in memory from content",
 			"error", err)
-		return "", errors.New("failed to analyze PDF")
+		return "", PDFInfo{}, errors.New("failed to analyze PDF")
 	}
 	defer doc.Close()
 
-	var text string
+	var info = PDFInfo{Pages: doc.NumPage()}
+	if a.opts.MaxPDFOutline > 0 {
+		info.Outline = outline(doc, a.opts.MaxPDFOutline, l)
+	}
+	var pages = info.Pages
+	if a.opts.MaxPDFPages > 0 && pages > a.opts.MaxPDFPages {
+		l.Warnw("document exceeds page limit - remaining pages are ignored",
+			"pages", pages,
+			"max_pages", a.opts.MaxPDFPages)
+		pages = a.opts.MaxPDFPages
+		info.Truncated = true
+	}
+
+	// text is accumulated page by page, so that only the pages kept and the
+	// current page are held in memory
+	var text strings.Builder
 	var ocrPages int
 	var textPages int
-	for i := 0; i < doc.NumPage(); i++ {
+	var skippedPages int
+	for i := 0; i < pages; i++ {
+		if a.opts.MaxPDFText > 0 && text.Len() >= a.opts.MaxPDFText {
+			l.Warnw("document exceeds text limit - remaining pages are ignored",
+				"page", i,
+				"max_text", a.opts.MaxPDFText)
+			info.Truncated = true
+			break
+		}
+
 		// try pulling text
 		if page, err := doc.Text(i); err != nil {
 			l.Warnw("failed to convert document page to text",
 				"error", i, "error", err)
 		} else if len(page) > threshold {
 			textPages++
-			text += " " + page
+			text.WriteString(" ")
+			text.WriteString(page)
 			continue
 		}
 
-		// if text is unsatisfactory, perform OCR on image
+		// if text is unsatisfactory, perform OCR on image, if allowed
+		if a.opts.DisablePDFFallback ||
+			(a.opts.MaxPDFFallbackPages > 0 && ocrPages >= a.opts.MaxPDFFallbackPages) {
+			skippedPages++
+			continue
+		}
 		if image, _ := doc.Image(i); image != nil {
 			ocrPages++
 			var img = new(bytes.Buffer)
 			if err := png.Encode(img, image); err != nil {
 				l.Warnw("failed to convert document page to image",
 					"page", i, "error", err)
-				return "", fmt.Errorf("failed to analyze page %d of document", i)
+				return "", info, fmt.Errorf("failed to analyze page %d of document", i)
 			}
 			if img.Bytes() == nil || len(img.Bytes()) == 0 {
 				continue
@@ -89,19 +170,48 @@ func (a *Analyzer) pdfToText(jobID string, content []byte, threshold int) (strin
 			if page, err := a.imageToText(jobID, img.Bytes()); err != nil {
 				l.Warnw("failed to OCR document page",
 					"page", i, "error", err)
-				return "", fmt.Errorf("failed to analyze page %d of document", i)
+				return "", info, fmt.Errorf("failed to analyze page %d of document", i)
 			} else if page != "" {
-				text += " " + page
+				text.WriteString(" ")
+				text.WriteString(page)
 			}
 		}
 	}
 
 	l.Infow("PDF converted to text",
-		"converted.length", len(text),
+		"converted.length", text.Len(),
 		"converted.pages.text_extract", textPages,
-		"converted.pages.ocr", ocrPages)
+		"converted.pages.ocr", ocrPages,
+		"converted.pages.skipped", skippedPages,
+		"converted.truncated", info.Truncated,
+		"converted.outline", len(info.Outline))
+
+	return text.String(), info, nil
+}
 
-	return text, nil
+// outline returns the titles of up to max entries of the given document's
+// outline - documents without an outline yield none
+func outline(doc *fitz.Document, max int, l *zap.SugaredLogger) []string {
+	toc, err := doc.ToC()
+	if err != nil {
+		l.Warnw("failed to read document outline", "error", err)
+		return nil
+	}
+	if len(toc) > max {
+		l.Warnw("document exceeds outline limit - remaining entries are ignored",
+			"entries", len(toc),
+			"max_outline", max)
+	}
+	var titles []string
+	for _, entry := range toc {
+		if len(titles) >= max {
+			break
+		}
+		if title := strings.Join(strings.Fields(entry.Title), " "); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	return titles
 }
 
 func (a *Analyzer) imageToText(jobID string, asset []byte) (contents string, err error) {
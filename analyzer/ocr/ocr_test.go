@@ -1,12 +1,15 @@
 package ocr
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/otiai10/gosseract"
@@ -14,7 +17,7 @@ import (
 
 func TestNewAnalyzer(t *testing.T) {
 	var l = zaptest.NewLogger(t)
-	var a = NewAnalyzer("", l.Sugar())
+	var a = NewAnalyzer("", Options{}, l.Sugar())
 	if a.Version() != gosseract.Version() {
 		t.Errorf("expected version %s, got %s", gosseract.Version(), a.Version())
 	}
@@ -24,6 +27,7 @@ func TestAnalyzer_Analyze(t *testing.T) {
 	type args struct {
 		assetpath string
 		filetype  string
+		opts      Options
 	}
 	tests := []struct {
 		name         string
@@ -31,21 +35,25 @@ func TestAnalyzer_Analyze(t *testing.T) {
 		wantContents []string
 		wantErr      bool
 	}{
-		{"nil asset", args{"", ""}, nil, true},
-		{"not an image", args{"../../test/assets/text.pdf", "png"}, nil, true},
-		{"text png asset", args{"../../test/assets/text.png", ""},
+		{"nil asset", args{"", "", Options{}}, nil, true},
+		{"not an image", args{"../../test/assets/text.pdf", "png", Options{}}, nil, true},
+		{"text png asset", args{"../../test/assets/text.png", "", Options{}},
 			[]string{
 				// "TECHNOLOGIES", // this text is sometimes not recognized during OCR
 				"NORTH AMERICAS",
 				"LEADING BLOCKCHAIN SOLUTIONS COMPANY",
 			},
 			false},
-		{"pdf asset that uses to-text", args{"../../test/assets/text.pdf", "pdf"},
+		{"pdf asset that uses to-text", args{"../../test/assets/text.pdf", "pdf", Options{}},
 			[]string{"A Simple PDF File", "...continued from page 1"},
 			false},
-		{"pdf asset that uses OCR", args{"../../test/assets/scan.pdf", "pdf"},
+		{"pdf asset that uses OCR", args{"../../test/assets/scan.pdf", "pdf", Options{}},
 			[]string{"Dear Pete", "Probably you have"},
 			false},
+		{"pdf asset with OCR disabled", args{"../../test/assets/scan.pdf", "pdf",
+			Options{DisablePDFFallback: true}},
+			nil,
+			false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -56,7 +64,7 @@ func TestAnalyzer_Analyze(t *testing.T) {
 			b, _ := ioutil.ReadAll(f)
 
 			var l = zaptest.NewLogger(t).Sugar()
-			var a = NewAnalyzer("", l)
+			var a = NewAnalyzer("", tt.args.opts, l)
 
 			var start = time.Now()
 			gotContents, err := a.Analyze(t.Name(), b, tt.args.filetype)
@@ -76,3 +84,106 @@ func TestAnalyzer_Analyze(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalyzer_PDFToText(t *testing.T) {
+	b, err := ioutil.ReadFile("../../test/assets/pages.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		maxPages      int
+		maxText       int
+		wantContents  string
+		wantMissing   string
+		wantTruncated bool
+	}{
+		{"no limit", 0, 0, "Lens test page 12", "", false},
+		{"limit above page count", 20, 0, "Lens test page 12", "", false},
+		{"low limit", 3, 0, "Lens test page 3", "Lens test page 4", true},
+		{"text limit above text length", 0, 1 << 20, "Lens test page 12", "", false},
+		{"low text limit", 0, 1, "Lens test page 1", "Lens test page 2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a = NewAnalyzer("", Options{
+				MaxPDFPages: tt.maxPages,
+				MaxPDFText:  tt.maxText,
+			}, zaptest.NewLogger(t).Sugar())
+			got, info, err := a.PDFToText(t.Name(), b)
+			if err != nil {
+				t.Errorf("Analyzer.PDFToText() error = %v", err)
+				return
+			}
+			if !strings.Contains(got, tt.wantContents) {
+				t.Errorf("Analyzer.PDFToText() = '%v', want '%v'", got, tt.wantContents)
+			}
+			if tt.wantMissing != "" && strings.Contains(got, tt.wantMissing) {
+				t.Errorf("Analyzer.PDFToText() = '%v', want no '%v'", got, tt.wantMissing)
+			}
+			if info.Pages != 12 || info.Truncated != tt.wantTruncated {
+				t.Errorf("Analyzer.PDFToText() info = %+v, want 12 pages (truncated %v)",
+					info, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_PDFToText_outline(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		maxOutline int
+		want       []string
+	}{
+		{"disabled", "outline.pdf", 0, nil},
+		{"no outline", "pages.pdf", 10, nil},
+		{"limited", "outline.pdf", 3, []string{"Overview", "Getting Started", "Installation"}},
+		{"all entries", "outline.pdf", 10, []string{"Overview", "Getting Started", "Installation", "Search Syntax"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ioutil.ReadFile("../../test/assets/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var a = NewAnalyzer("", Options{
+				DisablePDFFallback: true,
+				MaxPDFOutline:      tt.maxOutline,
+			}, zaptest.NewLogger(t).Sugar())
+			_, info, err := a.PDFToText(t.Name(), b)
+			if err != nil {
+				t.Errorf("Analyzer.PDFToText() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(info.Outline, tt.want) {
+				t.Errorf("Analyzer.PDFToText() outline = %v, want %v", info.Outline, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkAnalyzer_PDFToText measures the memory allocated while extracting
+// text from a PDF, with and without a text limit. Compare allocations against
+// earlier revisions to gauge the cost of accumulating page text.
+func BenchmarkAnalyzer_PDFToText(b *testing.B) {
+	content, err := ioutil.ReadFile("../../test/assets/pages.pdf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, max := range []int{0, 64} {
+		b.Run(fmt.Sprintf("max_text=%d", max), func(b *testing.B) {
+			var a = NewAnalyzer("", Options{
+				DisablePDFFallback: true,
+				MaxPDFText:         max,
+			}, zap.NewNop().Sugar())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := a.PDFToText("bench", content); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
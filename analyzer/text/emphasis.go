@@ -0,0 +1,82 @@
+package text
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Emphasis configures the weighting of words emphasized in HTML and Markdown
+// documents, such as headings and bold text, which signal what a document is
+// about. Weights of 1 or less leave words unweighted.
+type Emphasis struct {
+	// Heading is the weight of words in headings
+	Heading float64
+	// Strong is the weight of words in bold or italic text
+	Strong float64
+}
+
+var (
+	htmlHeading     = regexp.MustCompile(`(?is)<h[1-6]\b[^>]*>(.*?)</h[1-6]\s*>`)
+	htmlStrong      = regexp.MustCompile(`(?is)<(?:b|strong|em|i)\b[^>]*>(.*?)</(?:b|strong|em|i)\s*>`)
+	markdownHeading = regexp.MustCompile(`(?m)^[ \t]*#{1,6}[ \t]+(.+?)[ \t#]*$`)
+	markdownStrong  = regexp.MustCompile(`\*\*([^*\n]+)\*\*|__([^_\n]+)__|\*([^*\s][^*\n]*)\*`)
+)
+
+// Weights returns the weights of the emphasized words of the given HTML or
+// Markdown document, keyed by lowercase word. Words emphasized in several ways
+// get the highest of their weights. Documents without emphasis yield none.
+func (e Emphasis) Weights(content string, isHTML bool) map[string]float64 {
+	var heading, strong = markdownHeading, markdownStrong
+	if isHTML {
+		heading, strong = htmlHeading, htmlStrong
+	}
+	var weights = make(map[string]float64)
+	for _, span := range []struct {
+		pattern *regexp.Regexp
+		weight  float64
+	}{
+		{heading, e.Heading},
+		{strong, e.Strong},
+	} {
+		if span.weight <= 1 {
+			continue
+		}
+		for _, m := range span.pattern.FindAllStringSubmatch(content, -1) {
+			for _, group := range m[1:] {
+				if isHTML {
+					group = html.UnescapeString(htmlTag.ReplaceAllString(group, " "))
+				}
+				for _, w := range words(group) {
+					if span.weight > weights[w] {
+						weights[w] = span.weight
+					}
+				}
+			}
+		}
+	}
+	if len(weights) == 0 {
+		return nil
+	}
+	return weights
+}
+
+// Weight returns the weight of the given keyword - the highest weight of its
+// words, or 1 if none of them are weighted
+func Weight(keyword string, weights map[string]float64) float64 {
+	var weight = 1.0
+	for _, w := range words(keyword) {
+		if weights[w] > weight {
+			weight = weights[w]
+		}
+	}
+	return weight
+}
+
+// words splits s into lowercase words
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
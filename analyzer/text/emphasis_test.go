@@ -0,0 +1,75 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmphasis_Weights(t *testing.T) {
+	var e = Emphasis{Heading: 3, Strong: 2}
+	tests := []struct {
+		name     string
+		emphasis Emphasis
+		content  string
+		isHTML   bool
+		want     map[string]float64
+	}{
+		{"plain", e, "distributed web", false, nil},
+		{"disabled", Emphasis{}, "# Distributed Web", false, nil},
+		{"markdown heading", e, "intro\n## Distributed Web\nbody", false,
+			map[string]float64{"distributed": 3, "web": 3}},
+		{"markdown strong", e, "a **fast** and *simple* __tool__", false,
+			map[string]float64{"fast": 2, "simple": 2, "tool": 2}},
+		{"highest weight", e, "# IPFS\n\nuse **ipfs**", false,
+			map[string]float64{"ipfs": 3}},
+		{"unpaired asterisk", e, "2 * 3 = 6", false, nil},
+		{"html", e, `<h1>Distributed <i>Web</i></h1><p>a <strong>fast</strong> tool</p><br>`, true,
+			map[string]float64{"distributed": 3, "web": 3, "fast": 2}},
+		{"html entities", e, `<b>Q&amp;A</b>`, true,
+			map[string]float64{"q": 2, "a": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.emphasis.Weights(tt.content, tt.isHTML); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Emphasis.Weights() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type weightedSummarizer struct{ weights map[string]float64 }
+
+func (s *weightedSummarizer) Summarize(text string, ratio float64) []string { return nil }
+
+func (s *weightedSummarizer) SummarizeWeighted(text string, ratio float64, weights map[string]float64) []string {
+	s.weights = weights
+	return []string{"weighted"}
+}
+
+func TestSummarizeWeighted(t *testing.T) {
+	var plain = SummarizerFunc(func(text string, ratio float64) []string {
+		return []string{"peer", "distributed web", "ipfs", "network"}
+	})
+	var weights = map[string]float64{"web": 2, "ipfs": 3}
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		want    []string
+	}{
+		{"no weights", nil, []string{"peer", "distributed web", "ipfs", "network"}},
+		{"weighted", weights, []string{"ipfs", "distributed web", "peer", "network"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeWeighted(plain, "", DefaultRatio, tt.weights); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SummarizeWeighted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var ws = &weightedSummarizer{}
+	if got := SummarizeWeighted(ws, "", DefaultRatio, weights); !reflect.DeepEqual(got, []string{"weighted"}) ||
+		!reflect.DeepEqual(ws.weights, weights) {
+		t.Errorf("SummarizeWeighted() = %v with weights %v, want weights passed to summarizer", got, ws.weights)
+	}
+}
@@ -0,0 +1,87 @@
+package text
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxTitleLength is the maximum length of titles and authors, in bytes
+const maxTitleLength = 200
+
+var (
+	htmlTitle      = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+	htmlAuthor     = regexp.MustCompile(`(?is)<meta\s[^>]*?name\s*=\s*["']author["'][^>]*?content\s*=\s*["']([^"']*)["']`)
+	markdownHeader = regexp.MustCompile(`^#[ \t]+(.+?)[ \t#]*$`)
+	htmlGeotag     = regexp.MustCompile(`(?is)<meta\s[^>]*?name\s*=\s*["'](?:geo\.position|icbm)["'][^>]*?content\s*=\s*["']([^"']*)["']`)
+)
+
+// Title returns the title of the given HTML or Markdown document - the
+// contents of the title element, or a level one heading on the first line of
+// a Markdown document - or an empty string if it has none
+func Title(content string, isHTML bool) string {
+	if isHTML {
+		if m := htmlTitle.FindStringSubmatch(content); m != nil {
+			return cleanField(htmlTag.ReplaceAllString(m[1], " "))
+		}
+		return ""
+	}
+	var first = strings.TrimSpace(content)
+	if i := strings.IndexByte(first, '\n'); i >= 0 {
+		first = first[:i]
+	}
+	if m := markdownHeader.FindStringSubmatch(strings.TrimSpace(first)); m != nil {
+		return cleanField(m[1])
+	}
+	return ""
+}
+
+// Author returns the author named in the metadata of the given HTML document,
+// or an empty string if it names none
+func Author(content string, isHTML bool) string {
+	if !isHTML {
+		return ""
+	}
+	if m := htmlAuthor.FindStringSubmatch(content); m != nil {
+		return cleanField(m[1])
+	}
+	return ""
+}
+
+// Location returns the latitude and longitude given in the 'geo.position' or
+// 'ICBM' metadata of the given HTML document, in decimal degrees. ok is false
+// if it has no valid location.
+func Location(content string, isHTML bool) (lat, lon float64, ok bool) {
+	if !isHTML {
+		return 0, 0, false
+	}
+	var m = htmlGeotag.FindStringSubmatch(content)
+	if m == nil {
+		return 0, 0, false
+	}
+	// geo.position separates coordinates with a semicolon, and ICBM with a comma
+	var coords = strings.FieldsFunc(html.UnescapeString(m[1]), func(r rune) bool {
+		return r == ';' || r == ','
+	})
+	if len(coords) != 2 {
+		return 0, 0, false
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(coords[0]), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(coords[1]), 64)
+	if latErr != nil || lonErr != nil ||
+		lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// cleanField unescapes and collapses whitespace in a document field, dropping
+// fields that are too long to be a title or name
+func cleanField(field string) string {
+	field = strings.Join(strings.Fields(html.UnescapeString(field)), " ")
+	if len(field) > maxTitleLength {
+		return ""
+	}
+	return field
+}
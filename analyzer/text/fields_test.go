@@ -0,0 +1,79 @@
+package text
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isHTML  bool
+		want    string
+	}{
+		{"html", "<html><head><TITLE>\n  Lens &amp; IPFS </TITLE></head></html>", true, "Lens & IPFS"},
+		{"html without title", "<html><body><h1>Lens</h1></body></html>", true, ""},
+		{"markdown", "\n# Lens   #\n\nsearch for ipfs", false, "Lens"},
+		{"markdown heading after first line", "search for ipfs\n# Lens\n", false, ""},
+		{"markdown second level heading", "## Lens\n", false, ""},
+		{"plain text", "distributed web", false, ""},
+		{"too long", "# " + strings.Repeat("a", maxTitleLength+1), false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Title(tt.content, tt.isHTML); got != tt.want {
+				t.Errorf("Title() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthor(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isHTML  bool
+		want    string
+	}{
+		{"html", `<head><meta name="author" content="Jane  Doe"></head>`, true, "Jane Doe"},
+		{"other meta", `<head><meta name="description" content="Jane Doe"></head>`, true, ""},
+		{"markdown", `<meta name="author" content="Jane Doe">`, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Author(tt.content, tt.isHTML); got != tt.want {
+				t.Errorf("Author() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isHTML  bool
+		wantLat float64
+		wantLon float64
+		wantOK  bool
+	}{
+		{"geo.position", `<head><meta name="geo.position" content="49.2827;-123.1207"></head>`, true,
+			49.2827, -123.1207, true},
+		{"icbm", `<head><meta name="ICBM" content="49.2827, -123.1207"></head>`, true,
+			49.2827, -123.1207, true},
+		{"none", `<head><meta name="author" content="Jane Doe"></head>`, true, 0, 0, false},
+		{"malformed", `<head><meta name="geo.position" content="north"></head>`, true, 0, 0, false},
+		{"out of range", `<head><meta name="geo.position" content="91;0"></head>`, true, 0, 0, false},
+		{"markdown", `<meta name="geo.position" content="49.2827;-123.1207">`, false, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, ok := Location(tt.content, tt.isHTML)
+			if lat != tt.wantLat || lon != tt.wantLon || ok != tt.wantOK {
+				t.Errorf("Location() = %v, %v, %v, want %v, %v, %v",
+					lat, lon, ok, tt.wantLat, tt.wantLon, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package text
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Hyphenation configures the normalization of hyphenated words, so that they
+// are matched the same way however they were written or extracted
+type Hyphenation struct {
+	// Prefixes are joined to the words they are hyphenated to, ie 'e' joins
+	// 'e-mail' into 'email'. Other hyphenated words are left as is, and are
+	// split into separate words when indexed.
+	Prefixes []string
+}
+
+// hyphenationRules are the Hyphenation rules for each supported language
+var hyphenationRules = map[string]Hyphenation{
+	"en": {Prefixes: []string{"co", "e", "non", "pre", "re"}},
+	"de": {Prefixes: []string{"e"}},
+	"fr": {Prefixes: []string{"e"}},
+}
+
+// HyphenationFor returns the hyphenation rules for the given language code,
+// ie 'en'
+func HyphenationFor(lang string) (Hyphenation, error) {
+	h, ok := hyphenationRules[strings.ToLower(lang)]
+	if !ok {
+		return Hyphenation{}, fmt.Errorf("no hyphenation rules for language '%s'", lang)
+	}
+	return h, nil
+}
+
+var (
+	// ie 'inter-\nnational' - a hyphen at the end of a line, followed by a
+	// lowercase letter
+	lineBreakHyphen = regexp.MustCompile(`(\pL)-[ \t]*\r?\n[ \t]*(\p{Ll})`)
+	// ie 'e-mail'
+	hyphenated = regexp.MustCompile(`\pL+(?:-\pL+)+`)
+)
+
+// softHyphen is an invisible hyphenation point, common in extracted text
+const softHyphen = "\u00ad"
+
+// Normalize rejoins words hyphenated across line breaks or containing soft
+// hyphens, and joins hyphenated words with one of the configured prefixes.
+// A line break hyphen is only removed if the next line continues with a
+// lowercase letter. It should be applied to both indexed text and queries.
+func (h Hyphenation) Normalize(s string) string {
+	s = strings.Replace(s, softHyphen, "", -1)
+	s = lineBreakHyphen.ReplaceAllString(s, "$1$2")
+	if len(h.Prefixes) == 0 {
+		return s
+	}
+	return hyphenated.ReplaceAllStringFunc(s, func(word string) string {
+		var i = strings.IndexByte(word, '-')
+		if !h.isPrefix(word[:i]) {
+			return word
+		}
+		return word[:i] + word[i+1:]
+	})
+}
+
+// isPrefix checks if the given word is one of the configured prefixes
+func (h Hyphenation) isPrefix(word string) bool {
+	for _, p := range h.Prefixes {
+		if strings.EqualFold(p, word) {
+			return true
+		}
+	}
+	return false
+}
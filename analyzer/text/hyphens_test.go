@@ -0,0 +1,54 @@
+package text
+
+import "testing"
+
+func TestHyphenationFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		wantErr bool
+	}{
+		{"english", "en", false},
+		{"case insensitive", "EN", false},
+		{"unsupported", "xx", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := HyphenationFor(tt.lang); (err != nil) != tt.wantErr {
+				t.Errorf("HyphenationFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHyphenation_Normalize(t *testing.T) {
+	var en, _ = HyphenationFor("en")
+	tests := []struct {
+		name string
+		h    Hyphenation
+		text string
+		want string
+	}{
+		{"plain", en, "distributed web", "distributed web"},
+		{"line break", en, "inter-\nnational trade", "international trade"},
+		{"line break with spaces", en, "inter- \r\n  national", "international"},
+		{"line break before capital", en, "Lens-\nIPFS", "Lens-\nIPFS"},
+		{"line break before number", en, "page-\n12", "page-\n12"},
+		{"soft hyphen", en, "inter\u00adnational", "international"},
+		{"prefix", en, "send an e-mail", "send an email"},
+		{"prefix case", en, "E-Mail", "EMail"},
+		{"prefix only joins first word", en, "e-mail-address", "email-address"},
+		{"compound", en, "state-of-the-art search", "state-of-the-art search"},
+		{"prefix inside compound", en, "state-of-the-e-mail", "state-of-the-e-mail"},
+		{"no prefixes", Hyphenation{}, "e-mail inter-\nnational", "e-mail international"},
+		{"unicode", Hyphenation{Prefixes: []string{"é"}}, "é-mail", "émail"},
+		{"dash", en, "e - mail", "e - mail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.h.Normalize(tt.text); got != tt.want {
+				t.Errorf("Hyphenation.Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
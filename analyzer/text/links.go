@@ -0,0 +1,68 @@
+package text
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxLinks is the maximum number of links extracted from a document
+const maxLinks = 100
+
+// Link denotes a hyperlink in a document
+type Link struct {
+	Text   string
+	Target string
+}
+
+// Domain returns the host name the link points to, without any 'www.' prefix,
+// or an empty string for relative links
+func (l Link) Domain() string {
+	u, err := url.Parse(l.Target)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+var (
+	htmlLink     = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a\s*>`)
+	htmlTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+	markdownLink = regexp.MustCompile(`\[([^\[\]]+)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+)
+
+// Links returns the hyperlinks in the given HTML or Markdown document, in order
+// of first appearance. Links that repeat an earlier link's text and target,
+// such as navigation menus, are only returned once, and at most maxLinks
+// links are returned.
+func Links(content string, isHTML bool) []Link {
+	var matches [][]string
+	if isHTML {
+		matches = htmlLink.FindAllStringSubmatch(content, -1)
+	} else {
+		matches = markdownLink.FindAllStringSubmatch(content, -1)
+	}
+	var (
+		links = make([]Link, 0)
+		seen  = make(map[Link]bool)
+	)
+	for _, m := range matches {
+		var l Link
+		if isHTML {
+			l = Link{Text: htmlTag.ReplaceAllString(m[2], " "), Target: m[1]}
+		} else {
+			l = Link{Text: m[1], Target: m[2]}
+		}
+		l.Text = strings.Join(strings.Fields(html.UnescapeString(l.Text)), " ")
+		l.Target = strings.TrimSpace(html.UnescapeString(l.Target))
+		if seen[l] {
+			continue
+		}
+		seen[l] = true
+		if links = append(links, l); len(links) >= maxLinks {
+			break
+		}
+	}
+	return links
+}
@@ -0,0 +1,56 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isHTML  bool
+		want    []Link
+	}{
+		{"no links", "distributed web", false, []Link{}},
+		{"markdown", `See [the docs](https://docs.ipfs.io/guide "Guide") and [Lens](<../README.md>).`, false, []Link{
+			{"the docs", "https://docs.ipfs.io/guide"},
+			{"Lens", "../README.md"},
+		}},
+		{"html", `<nav><a href="/">Home</a></nav><p>Read <A class="x" HREF='https://www.ipfs.io'>about <b>IPFS</b> &amp; more</a></p>`, true, []Link{
+			{"Home", "/"},
+			{"about IPFS & more", "https://www.ipfs.io"},
+		}},
+		{"repeated navigation", `<a href="/">Home</a><a href="/about">About</a><a href="/">Home</a>`, true, []Link{
+			{"Home", "/"},
+			{"About", "/about"},
+		}},
+		{"markdown syntax in html is ignored", `[docs](https://docs.ipfs.io)`, true, []Link{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Links(tt.content, tt.isHTML); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Links() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLink_Domain(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"https://www.IPFS.io/docs", "ipfs.io"},
+		{"http://docs.ipfs.io:8080", "docs.ipfs.io"},
+		{"../README.md", ""},
+		{"mailto:hello@ipfs.io", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := (Link{Target: tt.target}).Domain(); got != tt.want {
+				t.Errorf("Link.Domain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
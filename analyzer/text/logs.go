@@ -0,0 +1,174 @@
+package text
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Log formats recognized by DetectLog
+const (
+	// LogSyslog denotes RFC 3164 or RFC 5424 syslog messages
+	LogSyslog = "syslog"
+	// LogJSON denotes JSON objects, one per line
+	LogJSON = "json"
+	// LogPlain denotes plain-text lines prefixed with a timestamp
+	LogPlain = "plain"
+)
+
+const (
+	// logSampleLines is the number of lines DetectLog examines
+	logSampleLines = 20
+	// logMinLines is the minimum number of lines for content to be a log
+	logMinLines = 2
+	// logMaxTags is the maximum number of host and service tags returned
+	logMaxTags = 50
+)
+
+var (
+	// ie 'Jan  2 15:04:05 host program[123]: message'
+	syslogBSD = regexp.MustCompile(
+		`^(?:<\d{1,3}>)?[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} (\S+) ([^\s:\[]+)(?:\[\d+\])?: ?(.*)$`)
+	// ie '<34>1 2003-10-11T22:14:15.003Z host app 123 ID47 - message'
+	syslogIETF = regexp.MustCompile(
+		`^<\d{1,3}>\d \S+ (\S+) (\S+) \S+ \S+ (?:-|\[.*?\]) ?(.*)$`)
+	// ie '2019-01-02 15:04:05,000 [ERROR] message'
+	plainLog = regexp.MustCompile(
+		`^\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}\S*\]?\s+(?:\[?(?i:trace|debug|info|warn|warning|error|fatal|panic|critical)\]?:?\s+)?(.*)$`)
+)
+
+// Fields of JSON log lines that hold messages, service names, and host names
+var (
+	jsonMessageFields = []string{"msg", "message", "error", "err"}
+	jsonServiceFields = []string{"service", "app", "application", "program", "component", "logger"}
+	jsonHostFields    = []string{"host", "hostname"}
+)
+
+// DetectLog checks if the given text is a log in one of the recognized
+// formats, based on its first lines, and returns the format - or an empty
+// string if it is not a log.
+func DetectLog(content string) string {
+	var lines = logLines(content, logSampleLines)
+	if len(lines) < logMinLines {
+		return ""
+	}
+	for _, format := range []string{LogJSON, LogSyslog, LogPlain} {
+		var matched int
+		for _, line := range lines {
+			if _, _, ok := parseLogLine(line, format); ok {
+				matched++
+			}
+		}
+		// allow for the odd unstructured line, such as stack traces
+		if matched*5 >= len(lines)*4 {
+			return format
+		}
+	}
+	return ""
+}
+
+// ParseLog extracts the meaningful parts of a log in the given format. It
+// returns the messages of the log with timestamps, levels, and tokens
+// containing digits, such as IDs and addresses, removed, and the host and
+// service names the log refers to as lowercase tags.
+func ParseLog(content, format string) (string, []string, error) {
+	switch format {
+	case LogSyslog, LogJSON, LogPlain:
+	default:
+		return "", nil, fmt.Errorf("unsupported log format '%s'", format)
+	}
+	var (
+		messages = make([]string, 0)
+		tags     = make([]string, 0)
+		seen     = make(map[string]bool)
+	)
+	for _, line := range logLines(content, -1) {
+		message, names, ok := parseLogLine(line, format)
+		if !ok {
+			// continuation lines, ie stack traces
+			message = line
+		}
+		if message = cleanLogMessage(message); message != "" {
+			messages = append(messages, message)
+		}
+		for _, n := range names {
+			n = strings.ToLower(strings.TrimSpace(n))
+			if n == "" || n == "-" || seen[n] || len(tags) >= logMaxTags {
+				continue
+			}
+			seen[n] = true
+			tags = append(tags, n)
+		}
+	}
+	return strings.Join(messages, "\n"), tags, nil
+}
+
+// logLines returns up to max non-empty lines of content - all lines if max is
+// negative
+func logLines(content string, max int) []string {
+	var lines = make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		if max >= 0 && len(lines) >= max {
+			break
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseLogLine returns the message of a line in the given format, and the host
+// and service names it refers to
+func parseLogLine(line, format string) (message string, names []string, ok bool) {
+	switch format {
+	case LogSyslog:
+		if m := syslogBSD.FindStringSubmatch(line); m != nil {
+			return m[3], []string{m[1], m[2]}, true
+		}
+		if m := syslogIETF.FindStringSubmatch(line); m != nil {
+			return m[3], []string{m[1], m[2]}, true
+		}
+	case LogPlain:
+		if m := plainLog.FindStringSubmatch(line); m != nil {
+			return m[1], nil, true
+		}
+	case LogJSON:
+		if !strings.HasPrefix(line, "{") {
+			return "", nil, false
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			return "", nil, false
+		}
+		var messages = jsonStrings(fields, jsonMessageFields)
+		names = append(jsonStrings(fields, jsonHostFields), jsonStrings(fields, jsonServiceFields)...)
+		return strings.Join(messages, " "), names, true
+	}
+	return "", nil, false
+}
+
+// jsonStrings returns the string values of the given keys
+func jsonStrings(fields map[string]interface{}, keys []string) []string {
+	var values = make([]string, 0)
+	for _, k := range keys {
+		if v, ok := fields[k].(string); ok && v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// cleanLogMessage removes tokens containing digits, such as timestamps, IDs,
+// and addresses, from a log message
+func cleanLogMessage(message string) string {
+	var words = make([]string, 0)
+	for _, w := range strings.Fields(message) {
+		if strings.IndexFunc(w, unicode.IsDigit) < 0 {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
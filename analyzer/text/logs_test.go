@@ -0,0 +1,85 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"prose", "The quick brown fox.\nJumps over the lazy dog.\n", ""},
+		{"single line", "Jan  2 15:04:05 web01 nginx[123]: started\n", ""},
+		{"bsd syslog", "Jan  2 15:04:05 web01 nginx[123]: started\n" +
+			"Jan  2 15:04:06 web01 sshd: session opened for user root\n", LogSyslog},
+		{"ietf syslog", "<34>1 2003-10-11T22:14:15.003Z db01 postgres 42 - - checkpoint starting\n" +
+			"<34>1 2003-10-11T22:14:16.003Z db01 postgres 42 - - checkpoint complete\n", LogSyslog},
+		{"json lines", `{"level":"info","ts":1546441445.1,"msg":"server started"}` + "\n" +
+			`{"level":"error","ts":1546441446.2,"msg":"connection refused"}` + "\n", LogJSON},
+		{"plain", "2019-01-02 15:04:05,123 INFO worker started\n" +
+			"2019-01-02 15:04:06,456 [ERROR] disk full\n", LogPlain},
+		{"mostly log lines", "2019-01-02 15:04:05 panic: nil map\n" +
+			"2019-01-02 15:04:05 goroutine stopped\n" +
+			"2019-01-02 15:04:05 retrying\n" +
+			"2019-01-02 15:04:05 retrying\n" +
+			"\tmain.go:12 +0x1f\n", LogPlain},
+		{"mostly prose", "2019-01-02 15:04:05 meeting notes\nwe discussed\nthe roadmap\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLog(tt.content); got != tt.want {
+				t.Errorf("DetectLog() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		format   string
+		wantText string
+		wantTags []string
+		wantErr  bool
+	}{
+		{"unsupported format", "", "xml", "", nil, true},
+		{"syslog", "Jan  2 15:04:05 Web01 nginx[123]: upstream timed out from 10.0.0.1\n" +
+			"Jan  2 15:04:06 web01 sshd: session opened for user root\n" +
+			"<34>1 2003-10-11T22:14:15.003Z db01 postgres 42 - - checkpoint starting\n",
+			LogSyslog,
+			"upstream timed out from\nsession opened for user root\ncheckpoint starting",
+			[]string{"web01", "nginx", "sshd", "db01", "postgres"}, false},
+		{"json", `{"level":"error","ts":1546441445.1,"msg":"connection refused","error":"dial tcp 10.0.0.1:5432","service":"Billing","host":"api01"}` + "\n" +
+			`{"level":"info","msg":"request id 8f3a2c served","logger":"http"}` + "\n",
+			LogJSON,
+			"connection refused dial tcp\nrequest id served",
+			[]string{"api01", "billing", "http"}, false},
+		{"plain with continuation lines", "2019-01-02 15:04:05,123 [ERROR] worker crashed\n" +
+			"\tat com.example.Worker.run(Worker.java:42)\n",
+			LogPlain,
+			"worker crashed\nat",
+			[]string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, tags, err := ParseLog(tt.content, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLog() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if text != tt.wantText {
+				t.Errorf("ParseLog() text = %q, want %q", text, tt.wantText)
+			}
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("ParseLog() tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
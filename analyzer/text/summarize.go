@@ -0,0 +1,45 @@
+// Package text provides analysis of plain-text content
+package text
+
+import "sort"
+
+// DefaultRatio is the default proportion of text to keep when summarizing
+const DefaultRatio = 0.2
+
+// Summarizer extracts the most significant keywords or sentences from text.
+// The ratio denotes the approximate proportion of the text to keep.
+type Summarizer interface {
+	Summarize(text string, ratio float64) []string
+}
+
+// SummarizerFunc allows an ordinary function to be used as a Summarizer
+type SummarizerFunc func(text string, ratio float64) []string
+
+// Summarize calls f(text, ratio)
+func (f SummarizerFunc) Summarize(text string, ratio float64) []string { return f(text, ratio) }
+
+// WeightedSummarizer is a Summarizer that can also weigh individual words, ie
+// words emphasized in the text, so that they are more likely to be kept. The
+// weights are keyed by lowercase word, and words without a weight have a
+// weight of 1.
+type WeightedSummarizer interface {
+	Summarizer
+	SummarizeWeighted(text string, ratio float64, weights map[string]float64) []string
+}
+
+// SummarizeWeighted summarizes text with the given word weights, if s supports
+// them. Otherwise, the keywords s extracts are ordered by their weight - see
+// Weight - keeping the order of keywords with the same weight.
+func SummarizeWeighted(s Summarizer, text string, ratio float64, weights map[string]float64) []string {
+	if len(weights) == 0 {
+		return s.Summarize(text, ratio)
+	}
+	if ws, ok := s.(WeightedSummarizer); ok {
+		return ws.SummarizeWeighted(text, ratio, weights)
+	}
+	var keywords = s.Summarize(text, ratio)
+	sort.SliceStable(keywords, func(i, j int) bool {
+		return Weight(keywords[i], weights) > Weight(keywords[j], weights)
+	})
+	return keywords
+}
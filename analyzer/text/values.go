@@ -0,0 +1,150 @@
+package text
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values configures the retention of numbers and dates as keywords, which are
+// otherwise discarded as noise. Retained values are normalized, so that ie
+// '1/2/2021' and '2021-01-02' match.
+type Values struct {
+	// Numbers retains numbers and currency amounts, without currency symbols
+	// or thousands separators, ie '$1,234.50' as '1234.5'
+	Numbers bool
+	// Dates retains dates in ISO 8601 format, ie 'Jan 2, 2021' as
+	// '2021-01-02'. Numeric dates are read as month/day/year.
+	Dates bool
+}
+
+// isoDate is the format dates are normalized to
+const isoDate = "2006-01-02"
+
+var (
+	// ie '2021-01-02'
+	isoDatePattern = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	// ie '1/2/2021'
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+	// ie 'Jan 2, 2021' or 'January 2 2021'
+	namedDatePattern = regexp.MustCompile(`(?i)\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.? (\d{1,2}),? (\d{4})\b`)
+	// ie '$1,234.50' - boundaries are checked by numbers
+	numberPattern = regexp.MustCompile(`[$€£]?(?:\d{1,3}(?:,\d{3})+|\d+)(?:\.\d+)?`)
+)
+
+// months maps the abbreviated month names matched by namedDatePattern
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March,
+	"apr": time.April, "may": time.May, "jun": time.June,
+	"jul": time.July, "aug": time.August, "sep": time.September,
+	"oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// Normalize rewrites the numbers and dates in s that are configured to be
+// retained into their normalized forms. It should be applied to both indexed
+// text and queries.
+func (o Values) Normalize(s string) string {
+	if o.Dates {
+		s = isoDatePattern.ReplaceAllStringFunc(s, func(m string) string {
+			var p = isoDatePattern.FindStringSubmatch(m)
+			return formatDate(p[1], p[2], p[3], m)
+		})
+		s = numericDatePattern.ReplaceAllStringFunc(s, func(m string) string {
+			var p = numericDatePattern.FindStringSubmatch(m)
+			return formatDate(p[3], p[1], p[2], m)
+		})
+		s = namedDatePattern.ReplaceAllStringFunc(s, func(m string) string {
+			var p = namedDatePattern.FindStringSubmatch(m)
+			var month = months[strings.ToLower(p[1])]
+			return formatDate(p[3], strconv.Itoa(int(month)), p[2], m)
+		})
+	}
+	if o.Numbers {
+		var b strings.Builder
+		var last int
+		for _, loc := range numbers(s) {
+			b.WriteString(s[last:loc[0]])
+			b.WriteString(normalizeNumber(s[loc[0]:loc[1]]))
+			last = loc[1]
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// Keywords returns the normalized numbers and dates in s that are configured
+// to be retained, in order of appearance
+func (o Values) Keywords(s string) []string {
+	if !o.Numbers && !o.Dates {
+		return nil
+	}
+	s = o.Normalize(s)
+	var keywords []string
+	var seen = make(map[string]bool)
+	var add = func(k string) {
+		if !seen[k] {
+			seen[k] = true
+			keywords = append(keywords, k)
+		}
+	}
+	if o.Dates {
+		for _, m := range isoDatePattern.FindAllString(s, -1) {
+			if _, err := time.Parse(isoDate, m); err == nil {
+				add(m)
+			}
+		}
+	}
+	if o.Numbers {
+		for _, loc := range numbers(s) {
+			add(s[loc[0]:loc[1]])
+		}
+	}
+	return keywords
+}
+
+// formatDate returns the given date in ISO 8601 format, or fallback if it is
+// not a valid date
+func formatDate(year, month, day, fallback string) string {
+	var d, err = time.Parse("2006-1-2", year+"-"+month+"-"+day)
+	if err != nil {
+		return fallback
+	}
+	return d.Format(isoDate)
+}
+
+// numbers returns the locations of standalone numbers in s - numbers that
+// are part of words, dates, or other numbers are ignored
+func numbers(s string) [][]int {
+	var locs [][]int
+	for _, loc := range numberPattern.FindAllStringIndex(s, -1) {
+		if before, _ := utf8.DecodeLastRuneInString(s[:loc[0]]); isNumberPart(before) ||
+			before == '.' || before == ',' {
+			continue
+		}
+		if after, _ := utf8.DecodeRuneInString(s[loc[1]:]); isNumberPart(after) {
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs
+}
+
+// isNumberPart checks if r joins an adjacent number to a word or date
+func isNumberPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '/'
+}
+
+// normalizeNumber removes currency symbols, thousands separators, and
+// trailing decimal zeroes from the given number
+func normalizeNumber(n string) string {
+	n = strings.TrimLeft(n, "$€£")
+	n = strings.Replace(n, ",", "", -1)
+	if strings.Contains(n, ".") {
+		n = strings.TrimRight(strings.TrimRight(n, "0"), ".")
+	}
+	return n
+}
@@ -0,0 +1,57 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValues_Normalize(t *testing.T) {
+	var all = Values{Numbers: true, Dates: true}
+	tests := []struct {
+		name   string
+		values Values
+		text   string
+		want   string
+	}{
+		{"disabled", Values{}, "due 1/2/2021: $1,234.50", "due 1/2/2021: $1,234.50"},
+		{"iso date", all, "due 2021-1-2", "due 2021-01-02"},
+		{"numeric date", all, "due 1/2/2021", "due 2021-01-02"},
+		{"named date", all, "due Jan. 2, 2021 or January 2 2021", "due 2021-01-02 or 2021-01-02"},
+		{"invalid date", all, "due 13/45/2021", "due 13/45/2021"},
+		{"currency", all, "total $1,234.50.", "total 1234.5."},
+		{"decimal zeroes", all, "12.00 or 0.10", "12 or 0.1"},
+		{"leading zeroes", all, "invoice 00123", "invoice 00123"},
+		{"part of word", all, "mp3 and 3d", "mp3 and 3d"},
+		{"dates only", Values{Dates: true}, "$1,234 on 1/2/2021", "$1,234 on 2021-01-02"},
+		{"numbers only", Values{Numbers: true}, "$1,234 on 1/2/2021", "1234 on 1/2/2021"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.values.Normalize(tt.text); got != tt.want {
+				t.Errorf("Values.Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValues_Keywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		values Values
+		text   string
+		want   []string
+	}{
+		{"disabled", Values{}, "$1,234.50 due 1/2/2021", nil},
+		{"numbers", Values{Numbers: true}, "$1,234.50 and 1234.5 for 3 items", []string{"1234.5", "3"}},
+		{"dates", Values{Dates: true}, "due 1/2/2021, paid 2021-01-02 and 2021-02-30", []string{"2021-01-02"}},
+		{"both", Values{Numbers: true, Dates: true}, "$20 due Feb 1, 2021", []string{"2021-02-01", "20"}},
+		{"none found", Values{Numbers: true, Dates: true}, "distributed web", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.values.Keywords(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Values.Keywords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
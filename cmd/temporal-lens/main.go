@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,6 +20,8 @@ import (
 
 	lens "github.com/RTradeLtd/Lens/v2"
 	"github.com/RTradeLtd/Lens/v2/analyzer/images"
+	"github.com/RTradeLtd/Lens/v2/analyzer/ocr"
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
 	"github.com/RTradeLtd/Lens/v2/engine"
 	"github.com/RTradeLtd/Lens/v2/engine/queue"
 	"github.com/RTradeLtd/Lens/v2/server"
@@ -33,10 +39,158 @@ var (
 		"path to Temporal configuration")
 	modelPath = flag.String("models", "/tmp",
 		"path to TensorFlow models")
+	extraModels = flag.String("models.extra", "",
+		"additional named TensorFlow models, as comma-separated name=path pairs")
+	defaultModel = flag.String("models.default", "",
+		"name of the TensorFlow model to use when no hint is available")
+	lazyModels = flag.Bool("models.lazy", false,
+		"defer loading TensorFlow models until the first image is analyzed")
 	logPath = flag.String("logpath", "",
 		"path to write logs to - leave blank for stdout")
 	devMode = flag.Bool("dev", false,
 		"enable dev mode")
+	indexConcurrency = flag.Int("index.concurrency", 0,
+		"maximum number of concurrent index requests - 0 for no limit")
+	indexQueue = flag.Int("index.queue", 0,
+		"maximum number of index requests waiting when concurrency limit is reached")
+	indexTimeout = flag.Duration("index.timeout", 0,
+		"maximum time to spend processing each index request - 0 for no limit")
+	recordSource = flag.Bool("index.record-source", false,
+		"record the address of the IPFS node content was retrieved through in object provenance")
+	geotags = flag.Bool("index.geotags", false,
+		"record the locations in image GPS metadata and HTML geotags, for searches by location")
+	asyncWorkers = flag.Int("index.async-workers", 0,
+		"number of background workers for asynchronous index requests - 0 to disable")
+	asyncQueue = flag.Int("index.async-queue", 100,
+		"maximum number of asynchronous index requests waiting for a worker")
+	asyncStore = flag.String("index.async-store", "",
+		"path of the database asynchronous index jobs are persisted in - empty to only track jobs in memory")
+	sweepInterval = flag.Duration("sweep.interval", 0,
+		"interval between reachability checks of indexed objects - 0 to disable")
+	sweepBatch = flag.Int("sweep.batch", 10,
+		"number of indexed objects to check for reachability on each interval")
+	softDelete = flag.Bool("remove.soft", false,
+		"move removed objects to the trash, where they can be restored until purged, instead of deleting them")
+	trashRetention = flag.Duration("trash.retention", 0,
+		"how long removed objects are kept in the trash before they are purged - 0 to keep them")
+	trashInterval = flag.Duration("trash.interval", time.Hour,
+		"interval between purges of expired objects in the trash")
+	excludeStale = flag.Bool("search.exclude-stale", false,
+		"omit objects flagged as unreachable from search results unless overridden per request")
+	minImageWidth = flag.Int("images.min-width", 0,
+		"minimum width in pixels of images to classify - smaller images are indexed without keywords")
+	minImageHeight = flag.Int("images.min-height", 0,
+		"minimum height in pixels of images to classify - smaller images are indexed without keywords")
+	rejectSmallImages = flag.Bool("images.reject-small", false,
+		"reject images below the minimum dimensions instead of indexing them without keywords")
+	thumbnailSize = flag.Int("thumbnails.size", 0,
+		"maximum dimension of generated image thumbnails - 0 to disable")
+	thumbnailQuality = flag.Int("thumbnails.quality", 75,
+		"JPEG quality of generated image thumbnails")
+	thumbnailInline = flag.Int("thumbnails.inline", 8192,
+		"maximum size in bytes of thumbnails stored inline - larger thumbnails are added to IPFS")
+	maxImagePixels = flag.Int("images.max-pixels", images.DefaultMaxPixels,
+		"maximum number of pixels of images to decode for classification and thumbnails")
+	archiveEntries = flag.Int("archives.max-entries", 100,
+		"maximum number of members to index from zip and tar archives - 0 to disable")
+	archiveSize = flag.Int64("archives.max-size", 64<<20,
+		"maximum total size in bytes of members to extract from an archive")
+	synonymsPath = flag.String("search.synonyms", "",
+		"path to a synonym dictionary to expand required search words with - disabled if empty")
+	sampleSize = flag.Int("index.sample-size", 0,
+		"maximum bytes of text to index from each document or PDF - 0 to index all text")
+	linkAnchors = flag.Bool("links.anchors", false,
+		"add words from the text of links in HTML and Markdown documents to tags")
+	linkDomains = flag.Bool("links.domains", false,
+		"add the domain names of links in HTML and Markdown documents to tags")
+	urlHosts = flag.String("url.allow-hosts", "",
+		"comma-separated hosts content may be fetched from for indexing, '.example.com' to include subdomains - disabled if empty")
+	urlMaxSize = flag.Int64("url.max-size", 16<<20,
+		"maximum size in bytes of content fetched from URLs")
+	urlTimeout = flag.Duration("url.timeout", 30*time.Second,
+		"maximum time to spend fetching content from a URL")
+	allowContent = flag.String("index.allow", "",
+		"comma-separated categories or mime type prefixes to index - all content is indexed if empty")
+	denyContent = flag.String("index.deny", "",
+		"comma-separated categories or mime type prefixes to never index")
+	reuseAnalysis = flag.Bool("index.reuse-analysis", false,
+		"skip analysis when reindexing objects analyzed by this version of Lens - disable to force full analysis")
+	hyphenation = flag.String("text.hyphenation", "",
+		"language code of hyphenation rules to normalize indexed text and queries with, ie 'en' - disabled if empty")
+	headingWeight = flag.Float64("text.heading-weight", 0,
+		"weight of words in headings of HTML and Markdown documents when summarizing - 1 or less to disable")
+	strongWeight = flag.Float64("text.strong-weight", 0,
+		"weight of bold or italic words in HTML and Markdown documents when summarizing - 1 or less to disable")
+	keepNumbers = flag.Bool("text.numbers", false,
+		"retain normalized numbers and currency amounts in indexed text as keywords")
+	keepDates = flag.Bool("text.dates", false,
+		"retain dates in indexed text as keywords, normalized to ISO 8601")
+	detectLogs = flag.Bool("index.detect-logs", false,
+		"index the messages, hosts, and services of syslog, JSON, and timestamped logs instead of their full text")
+	countRejections = flag.Bool("index.count-rejections", false,
+		"log running counts of rejected content by mime type, to help prioritize support for new formats")
+	storeExtracted = flag.Bool("index.store-text", false,
+		"add text extracted from indexed content to IPFS and record its hash")
+	pinContent = flag.Bool("index.pin", false,
+		"pin indexed content on the IPFS node, and unpin it when removed from the index")
+	nameTags = flag.Bool("tags.from-names", false,
+		"add words from file names and archive member paths to tags")
+	mergeTagCase = flag.Bool("tags.merge-case", false,
+		"merge tags of an object that differ only in case, keeping the most common form")
+	rawText = flag.String("index.raw-categories", "",
+		"comma-separated categories to index without stop word removal, for exact phrase matching")
+	categories = flag.String("categories", "",
+		"category overrides for content types, as comma-separated type=category pairs")
+	summaryRatios = flag.String("summary.ratios", "",
+		"summarization ratios for content types or categories, as comma-separated type=ratio pairs with ratios in (0,1]")
+	categoryRules = flag.String("category-rules", "",
+		"sub-category rules, as comma-separated category/sub=keyword|keyword pairs applied in order, ie 'document/legal=contract|lawsuit'")
+	policies = flag.String("analysis.policies", "",
+		"per-category analysis, as comma-separated category=option|option pairs, where options are ratio:<ratio>, verbatim, raw, or max:<keywords>, ie 'code=verbatim|max:50'")
+	pdfOCR = flag.Bool("ocr.pdf-fallback", true,
+		"run PDF pages with little or no extractable text through OCR")
+	pdfOCRPages = flag.Int("ocr.pdf-max-pages", 0,
+		"maximum number of pages per PDF to run through OCR - 0 for no limit")
+	pdfPages = flag.Int("pdf.max-pages", 0,
+		"maximum number of pages per PDF to extract text from - 0 for no limit")
+	pdfText = flag.Int("pdf.max-text", 0,
+		"maximum bytes of text per PDF to extract, after which remaining pages are ignored - 0 for index.sample-size, if set")
+	pdfOutline = flag.Int("pdf.outline", 20,
+		"maximum number of PDF outline (bookmark) titles to index as keywords - 0 to disable")
+	pdfImages = flag.Int("pdf.max-images", 0,
+		"maximum number of embedded images per PDF to classify and OCR - 0 to disable")
+	maintenance = flag.Bool("maintenance", false,
+		"start in maintenance mode, rejecting writes while serving reads - toggle with SIGUSR1")
+	pprofAddr = flag.String("pprof", "",
+		"address to serve runtime profiles and the index queue depth on, ie 'localhost:6060' - disabled if empty")
+	maxRecvSize = flag.Int("grpc.max-recv", 0,
+		"maximum size of received messages in bytes - 0 for gRPC default")
+	maxSendSize = flag.Int("grpc.max-send", 0,
+		"maximum size of sent messages in bytes - 0 for gRPC default")
+	searchOrder = flag.String("search.order", engine.OrderRelevance,
+		"default order of search results - one of 'relevance', 'indexed', or 'name'")
+	searchDirection = flag.String("search.direction", "",
+		"direction of search result ordering, 'asc' or 'desc' - defaults to the natural direction of search.order")
+	indexRate = flag.Float64("grpc.index-rate", 0,
+		"index requests per second allowed from each client - 0 for no limit")
+	indexBurst = flag.Int("grpc.index-burst", 1,
+		"index requests each client may make at once")
+	searchRate = flag.Float64("grpc.search-rate", 0,
+		"search requests per second allowed from each client - 0 for no limit")
+	searchBurst = flag.Int("grpc.search-burst", 10,
+		"search requests each client may make at once")
+	maxKeywords = flag.Int("search.max-keywords", 0,
+		"maximum number of required words and tags in a search - 0 for no limit")
+	truncateKeywords = flag.Bool("search.truncate-keywords", false,
+		"drop keywords beyond search.max-keywords instead of rejecting the search")
+	searchCacheSize = flag.Int("search.cache-size", 0,
+		"maximum number of distinct searches to cache results for - 0 to disable")
+	searchCacheTTL = flag.Duration("search.cache-ttl", 10*time.Second,
+		"how long to cache search results for")
+	suggestMinFreq = flag.Int("search.suggest-min-freq", 1,
+		"minimum number of documents a term must appear in to be suggested as a correction")
+	maxHistory = flag.Int("engine.max-history", 10,
+		"number of previous metadata revisions to keep per object - 0 to disable")
 )
 
 var commands = map[string]cmd.Cmd{
@@ -51,8 +205,13 @@ var commands = map[string]cmd.Cmd{
 			l := logger.Sugar()
 			defer l.Sync()
 
+			// check configuration before setting anything up
+			if err := lens.Validate(validateOpts(cfg)); err != nil {
+				l.Fatalw("configuration check failed", "error", err)
+			}
+
 			// instantiate ipfs connection
-			var ipfsURL = fmt.Sprintf("%s:%s", cfg.IPFS.APIConnection.Host, cfg.IPFS.APIConnection.Port)
+			var ipfsURL = ipfsAddress(cfg)
 			l.Infow("instantiating IPFS connection", "ipfs.url", ipfsURL)
 			manager, err := rtfs.NewManager(ipfsURL, "", 1*time.Minute)
 			if err != nil {
@@ -61,18 +220,116 @@ var commands = map[string]cmd.Cmd{
 
 			// instantiate tensorflow wrapper
 			l.Infow("instantiating tensorflow wrappers", "tensorflow.models", *modelPath)
-			tf, err := images.NewAnalyzer(images.ConfigOpts{
-				ModelLocation: *modelPath,
-			}, l.Named("analyzer").Named("images"))
+			tf, err := images.NewAnalyzer(modelOpts(), l.Named("analyzer").Named("images"))
 			if err != nil {
 				l.Fatalw("failed to instantiate image analyzer", "error", err)
 			}
 
+			// load synonyms
+			var synonyms engine.Synonyms
+			if *synonymsPath != "" {
+				if synonyms, err = engine.LoadSynonyms(*synonymsPath); err != nil {
+					l.Fatalw("failed to load synonyms", "error", err, "path", *synonymsPath)
+				}
+			}
+
+			// load hyphenation rules
+			var hyphens *text.Hyphenation
+			if *hyphenation != "" {
+				h, err := text.HyphenationFor(*hyphenation)
+				if err != nil {
+					l.Fatalw("failed to load hyphenation rules", "error", err)
+				}
+				hyphens = &h
+			}
+
 			// create lens v2 service
 			l.Info("instantiating Lens V2")
 			srv, err := lens.NewV2(lens.V2Options{
+				Version: Version,
+				OCR: ocr.Options{
+					DisablePDFFallback:  !*pdfOCR,
+					MaxPDFFallbackPages: *pdfOCRPages,
+					MaxPDFPages:         *pdfPages,
+					MaxPDFText:          *pdfText,
+					MaxPDFOutline:       *pdfOutline,
+				},
+				MaxIndexInFlight: *indexConcurrency,
+				MaxIndexQueued:   *indexQueue,
+				IndexTimeout:     *indexTimeout,
+				AsyncIndex: lens.AsyncOpts{
+					Workers:   *asyncWorkers,
+					QueueSize: *asyncQueue,
+					StorePath: *asyncStore,
+				},
+				ExcludeStale:      *excludeStale,
+				SoftDelete:        *softDelete,
+				PDFImages:         *pdfImages,
+				MergeTagCase:      *mergeTagCase,
+				NameTags:          *nameTags,
+				ReuseAnalysis:     *reuseAnalysis,
+				PinContent:        *pinContent,
+				StoreExtracted:    *storeExtracted,
+				RawTextCategories: parseList(*rawText),
+				SampleSize:        *sampleSize,
+				ExpandSynonyms:    synonyms != nil,
+				CategoryOverrides: parsePairs(*categories),
+				CategoryRules:     parseCategoryRules(*categoryRules),
+				AnalysisPolicies:  parsePolicies(*policies),
+				SummaryRatios:     parseRatios(*summaryRatios),
+				CountRejections:   *countRejections,
+				DetectLogs:        *detectLogs,
+				Hyphenation:       hyphens,
+				Values:            text.Values{Numbers: *keepNumbers, Dates: *keepDates},
+				Emphasis:          text.Emphasis{Heading: *headingWeight, Strong: *strongWeight},
+				RecordSource:      *recordSource,
+				Geotags:           *geotags,
+				ContentFilter: lens.ContentFilter{
+					Allow: parseList(*allowContent),
+					Deny:  parseList(*denyContent),
+				},
+				SearchOrder: engine.Order{
+					By:        *searchOrder,
+					Direction: *searchDirection,
+				},
+				SearchCache: lens.SearchCacheOpts{
+					Size: *searchCacheSize,
+					TTL:  *searchCacheTTL,
+				},
+				KeywordLimit: lens.KeywordLimit{
+					Max:      *maxKeywords,
+					Truncate: *truncateKeywords,
+				},
+				Archives: lens.ArchiveOpts{
+					MaxEntries: *archiveEntries,
+					MaxSize:    *archiveSize,
+				},
+				Links: lens.LinkOpts{
+					Anchors: *linkAnchors,
+					Domains: *linkDomains,
+				},
+				URLs: lens.URLOpts{
+					AllowedHosts: parseList(*urlHosts),
+					MaxSize:      *urlMaxSize,
+					Timeout:      *urlTimeout,
+				},
+				Thumbnails: lens.ThumbnailOpts{
+					Size:        *thumbnailSize,
+					Quality:     *thumbnailQuality,
+					InlineLimit: *thumbnailInline,
+					MaxPixels:   *maxImagePixels,
+				},
+				MinImageSize: lens.ImageSizeOpts{
+					Width:  *minImageWidth,
+					Height: *minImageHeight,
+					Reject: *rejectSmallImages,
+				},
 				Engine: engine.Opts{
-					StorePath: cfg.Lens.Options.Engine.StorePath,
+					StorePath:  cfg.Lens.Options.Engine.StorePath,
+					MaxHistory: *maxHistory,
+
+					MinSuggestFrequency: *suggestMinFreq,
+					Synonyms:            synonyms,
 					Queue: queue.Options{
 						Rate:      time.Duration(cfg.Lens.Options.Engine.Queue.Rate) * time.Second,
 						BatchSize: cfg.Lens.Options.Engine.Queue.Batch,
@@ -83,6 +340,16 @@ var commands = map[string]cmd.Cmd{
 				l.Fatalw("failed to instantiate Lens V2", "error", err)
 			}
 
+			// toggle maintenance mode on SIGUSR1, ie for datastore backups
+			srv.SetMaintenance(*maintenance)
+			var toggles = make(chan os.Signal, 1)
+			signal.Notify(toggles, syscall.SIGUSR1)
+			go func() {
+				for range toggles {
+					srv.SetMaintenance(!srv.Maintenance())
+				}
+			}()
+
 			// set up interrupts
 			var stop = make(chan bool)
 			var signals = make(chan os.Signal)
@@ -94,11 +361,156 @@ var commands = map[string]cmd.Cmd{
 
 			// go!
 			l.Infow("spinning up server", "config", cfg.Services.Lens)
-			if err := server.RunV2(stop, l, srv, cfg.Services.Lens); err != nil {
+			var sweeper = func(ctx context.Context) {
+				srv.Sweep(ctx, lens.SweepOpts{
+					Interval:  *sweepInterval,
+					BatchSize: *sweepBatch,
+				})
+			}
+			var trashSweeper = func(ctx context.Context) {
+				srv.SweepTrash(ctx, lens.TrashOpts{
+					Retention: *trashRetention,
+					Interval:  *trashInterval,
+				})
+			}
+			var jobs = []server.Job{sweeper, trashSweeper}
+			if *pprofAddr != "" {
+				expvar.Publish("index_queue_depth", expvar.Func(func() interface{} {
+					return srv.IndexQueueDepth()
+				}))
+				jobs = append(jobs, server.Pprof(*pprofAddr, l.Named("pprof")))
+			}
+			if err := server.RunV2(stop, l, srv, cfg.Services.Lens, server.Limits{
+				MaxRecvMsgSize: *maxRecvSize,
+				MaxSendMsgSize: *maxSendSize,
+				IndexRate:      server.RateLimit{Rate: *indexRate, Burst: *indexBurst},
+				SearchRate:     server.RateLimit{Rate: *searchRate, Burst: *searchBurst},
+			}, jobs...); err != nil {
 				l.Fatalw("error encountered on server run", "error", err)
 			}
 		},
 	},
+	"validate": {
+		Blurb: "check Lens V2 configuration without starting the server",
+		Action: func(cfg config.TemporalConfig, args map[string]string) {
+			if err := lens.Validate(validateOpts(cfg)); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("configuration is valid")
+		},
+	},
+}
+
+// ipfsAddress returns the host:port of the configured IPFS API
+func ipfsAddress(cfg config.TemporalConfig) string {
+	return fmt.Sprintf("%s:%s", cfg.IPFS.APIConnection.Host, cfg.IPFS.APIConnection.Port)
+}
+
+// modelOpts returns the configured TensorFlow models
+func modelOpts() images.ConfigOpts {
+	return images.ConfigOpts{
+		ModelLocation: *modelPath,
+		Models:        parsePairs(*extraModels),
+		DefaultModel:  *defaultModel,
+		Lazy:          *lazyModels,
+		MaxPixels:     *maxImagePixels,
+	}
+}
+
+// validateOpts returns the resources to check before starting Lens
+func validateOpts(cfg config.TemporalConfig) lens.ValidateOpts {
+	return lens.ValidateOpts{
+		Models:      modelOpts(),
+		StorePath:   cfg.Lens.Options.Engine.StorePath,
+		IPFSAddress: ipfsAddress(cfg),
+	}
+}
+
+// parseList parses comma-separated values
+func parseList(s string) []string {
+	var list = make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// parsePairs parses comma-separated key=value pairs
+func parsePairs(s string) map[string]string {
+	var pairs = make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+			pairs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return pairs
+}
+
+// parseRatios parses comma-separated key=ratio pairs. Ratios that are not
+// numbers are parsed as 0, so that they are rejected when validated.
+func parseRatios(s string) map[string]float64 {
+	var ratios = make(map[string]float64)
+	for key, value := range parsePairs(s) {
+		ratios[key], _ = strconv.ParseFloat(value, 64)
+	}
+	return ratios
+}
+
+// parseCategoryRules parses comma-separated category/sub=keyword|keyword
+// rules, where the last segment of each category path is the sub-category
+// assigned to objects of its parent category with any of the keywords
+func parseCategoryRules(s string) []lens.CategoryRule {
+	var rules = make([]lens.CategoryRule, 0)
+	for _, rule := range strings.Split(s, ",") {
+		var kv = strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		var path = strings.Trim(strings.TrimSpace(kv[0]), "/")
+		var sep = strings.LastIndex(path, "/")
+		if sep < 0 {
+			continue
+		}
+		rules = append(rules, lens.CategoryRule{
+			Category:    path[:sep],
+			SubCategory: path[sep+1:],
+			Keywords:    strings.Split(strings.TrimSpace(kv[1]), "|"),
+		})
+	}
+	return rules
+}
+
+// parsePolicies parses comma-separated category=option|option analysis
+// policies. Values that are not numbers are parsed as -1, so that they are
+// rejected when validated.
+func parsePolicies(s string) map[string]lens.AnalysisPolicy {
+	var policies = make(map[string]lens.AnalysisPolicy)
+	for category, options := range parsePairs(s) {
+		var p lens.AnalysisPolicy
+		for _, o := range strings.Split(options, "|") {
+			var kv = strings.SplitN(strings.TrimSpace(o), ":", 2)
+			switch {
+			case kv[0] == "verbatim":
+				p.Verbatim = true
+			case kv[0] == "raw":
+				p.RawText = true
+			case kv[0] == "ratio" && len(kv) == 2:
+				var err error
+				if p.SummaryRatio, err = strconv.ParseFloat(kv[1], 64); err != nil {
+					p.SummaryRatio = -1
+				}
+			case kv[0] == "max" && len(kv) == 2:
+				var err error
+				if p.MaxKeywords, err = strconv.Atoi(kv[1]); err != nil {
+					p.MaxKeywords = -1
+				}
+			}
+		}
+		policies[category] = p
+	}
+	return policies
 }
 
 func main() {
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/search/query"
+)
+
+// Cursor denotes the position of a result in search results sorted in a given
+// order, so that a search can be resumed after it. Unlike an offset, a cursor
+// stays valid as documents are added to or removed from the index between
+// searches - results that sort before it are never returned again.
+//
+// Results are sorted by the key of their Order, and ties are broken by hash,
+// so the position is the sort key of the last result seen and its hash. Names
+// are compared by the same key the index sorts them by - see nameKey. Relevance scores may change as the index
+// changes, so resuming searches sorted by relevance is only approximate.
+type Cursor struct {
+	Order   string    `json:"o,omitempty"`
+	Score   float64   `json:"s,omitempty"`
+	Indexed time.Time `json:"i,omitempty"`
+	Name    string    `json:"n,omitempty"`
+	Hash    string    `json:"h"`
+}
+
+// NewCursor returns the position of the given result in results sorted in the
+// given order
+func NewCursor(r Result, o Order) *Cursor {
+	var c = &Cursor{Order: o.key(), Hash: r.Hash}
+	switch o.By {
+	case OrderIndexed:
+		c.Indexed = r.Indexed
+	case OrderName:
+		c.Name = nameKey(r.MD.DisplayName)
+	default:
+		c.Score = r.Score
+	}
+	return c
+}
+
+// ParseCursor decodes a cursor encoded with Cursor.String
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Hash == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	return &c, nil
+}
+
+// String encodes the cursor as an opaque token
+func (c *Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Validate checks that the cursor was created with the given order
+func (c *Cursor) Validate(o Order) error {
+	if c.Order != o.key() {
+		return errors.New("cursor does not match the order of results")
+	}
+	return nil
+}
+
+// After returns the results that sort after the cursor in the given order,
+// which must be the order the results are sorted in and the order the cursor
+// was created with
+func (c *Cursor) After(results []Result, o Order) ([]Result, error) {
+	if err := c.Validate(o); err != nil {
+		return nil, err
+	}
+	// resume directly after the last result seen if it is still present
+	for i, r := range results {
+		if r.Hash == c.Hash {
+			return results[i+1:], nil
+		}
+	}
+	for i, r := range results {
+		if c.before(r, o) {
+			return results[i:], nil
+		}
+	}
+	return results[len(results):], nil
+}
+
+// indexedRange matches documents indexed no earlier than the cursor in the
+// given order, which sorts by date indexed. Documents indexed at the same time
+// as the cursor are matched as well, since ties are broken by hash.
+func (c *Cursor) indexedRange(o Order) query.Query {
+	var inclusive = true
+	var dq *query.DateRangeQuery
+	if o.descending() {
+		dq = query.NewDateRangeInclusiveQuery(time.Time{}, c.Indexed, nil, &inclusive)
+	} else {
+		dq = query.NewDateRangeInclusiveQuery(c.Indexed, time.Time{}, &inclusive, nil)
+	}
+	dq.SetField(fieldIndexed)
+	return dq
+}
+
+// key identifies the field and direction results are sorted by
+func (o Order) key() string {
+	var by = o.By
+	if by == "" {
+		by = OrderRelevance
+	}
+	if o.descending() {
+		return by + " " + Descending
+	}
+	return by + " " + Ascending
+}
+
+// before checks if the cursor sorts before the given result
+func (c *Cursor) before(r Result, o Order) bool {
+	var cmp int
+	switch o.By {
+	case OrderIndexed:
+		switch {
+		case c.Indexed.Before(r.Indexed):
+			cmp = -1
+		case c.Indexed.After(r.Indexed):
+			cmp = 1
+		}
+	case OrderName:
+		cmp = strings.Compare(c.Name, nameKey(r.MD.DisplayName))
+	default:
+		switch {
+		case c.Score < r.Score:
+			cmp = -1
+		case c.Score > r.Score:
+			cmp = 1
+		}
+	}
+	if cmp == 0 {
+		// ties are always broken by ascending hash
+		return c.Hash < r.Hash
+	}
+	if o.descending() {
+		return cmp > 0
+	}
+	return cmp < 0
+}
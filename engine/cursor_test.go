@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestParseCursor(t *testing.T) {
+	var c = NewCursor(Result{Hash: "b", Score: 0.5}, Order{})
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *Cursor
+		wantErr bool
+	}{
+		{"valid", c.String(), c, false},
+		{"not base64", "!!!", nil, true},
+		{"not json", "YXNkZg", nil, true},
+		{"no hash", (&Cursor{Order: "relevance desc"}).String(), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCursor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor_After(t *testing.T) {
+	var now = time.Now().UTC()
+	var byScore = []Result{
+		{Hash: "a", Score: 3}, {Hash: "b", Score: 2}, {Hash: "c", Score: 2}, {Hash: "d", Score: 1},
+	}
+	var byIndexed = []Result{
+		{Hash: "a", Indexed: now}, {Hash: "b", Indexed: now.Add(time.Second)}, {Hash: "c", Indexed: now.Add(2 * time.Second)},
+	}
+	var byName = []Result{
+		{Hash: "c", MD: models.MetaDataV2{DisplayName: "Apple"}},
+		{Hash: "a", MD: models.MetaDataV2{DisplayName: "banana"}},
+		{Hash: "b", MD: models.MetaDataV2{DisplayName: "Cherry"}},
+	}
+	tests := []struct {
+		name    string
+		cursor  *Cursor
+		order   Order
+		results []Result
+		want    []string
+		wantErr bool
+	}{
+		{"present", NewCursor(byScore[1], Order{}), Order{}, byScore, []string{"c", "d"}, false},
+		{"removed", NewCursor(byScore[1], Order{}), Order{}, append([]Result{byScore[0]}, byScore[2:]...),
+			[]string{"c", "d"}, false},
+		{"inserted before", NewCursor(byScore[1], Order{}), Order{},
+			append([]Result{{Hash: "e", Score: 4}}, byScore...), []string{"c", "d"}, false},
+		{"last", NewCursor(byScore[3], Order{}), Order{}, byScore, []string{}, false},
+		{"ascending indexed removed", NewCursor(byIndexed[0], Order{By: OrderIndexed, Direction: Ascending}),
+			Order{By: OrderIndexed, Direction: Ascending}, byIndexed[1:], []string{"b", "c"}, false},
+		{"name removed", NewCursor(byName[1], Order{By: OrderName}), Order{By: OrderName},
+			[]Result{byName[0], byName[2]}, []string{"b"}, false},
+		{"order mismatch", NewCursor(byScore[1], Order{}), Order{By: OrderName}, byScore, nil, true},
+		{"direction mismatch", NewCursor(byScore[1], Order{}), Order{Direction: Ascending}, byScore, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cursor.After(tt.results, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Cursor.After() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var hashes = make([]string, 0, len(got))
+			for _, r := range got {
+				hashes = append(hashes, r.Hash)
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Cursor.After() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+}
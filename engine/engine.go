@@ -2,11 +2,15 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
 
 	"go.uber.org/zap"
 
@@ -19,19 +23,52 @@ import (
 type Searcher interface {
 	Index(doc Document) error
 	Search(ctx context.Context, query Query) ([]Result, error)
+	Count(ctx context.Context, query Query) (uint64, error)
+	Facet(ctx context.Context, query Query) (*Facets, error)
+	Histogram(ctx context.Context, from, to time.Time, width time.Duration) ([]Bucket, error)
+	Stats(ctx context.Context) (*Stats, error)
+	Suggest(text string) ([]Suggestion, error)
+	List(ctx context.Context, offset, size int) ([]Result, error)
+	ListMatching(ctx context.Context, query Query) ([]string, error)
+	ListPrefix(ctx context.Context, prefix string) ([]string, error)
 
 	IsIndexed(hash string) bool
+	Get(hash string) (*Document, error)
 	Remove(hash string) error
+	Unindex(hash string) error
+	RemoveMatching(ctx context.Context, query Query) (int, error)
+	RemovePrefix(ctx context.Context, prefix string) (int, error)
 
 	Close()
 }
 
-// Engine implements Lens V2's core search functionality
+// ErrNotFound is returned when a requested document is not in the index
+var ErrNotFound = errors.New("no such document in index")
+
+// Engine implements Lens V2's core search functionality. It is safe for
+// concurrent use: reads go directly to the underlying bleve index, which
+// supports concurrent readers alongside a writer, while writes are queued and
+// applied in batches by a single goroutine, so they never block reads.
+//
+// Writes become visible once their batch is flushed, and queued writes to the
+// same document are applied in order, with the last one winning. Metadata
+// history is recorded against the flushed state of a document, so concurrent
+// reindexes of a document that has not yet been flushed may record the same
+// previous revision.
 type Engine struct {
 	l *zap.SugaredLogger
 
-	index bleve.Index
-	q     *queue.Queue
+	index     bleve.Index
+	storePath string
+	q         *queue.Queue
+
+	maxHistory     int
+	minSuggestFreq int
+	synonyms       Synonyms
+
+	// rawText is true if the index mapping supports raw text mode - indexes
+	// created by older versions of Lens do not
+	rawText bool
 
 	stop chan bool
 }
@@ -40,11 +77,26 @@ type Engine struct {
 type Opts struct {
 	StorePath string
 	Queue     queue.Options
+
+	// MaxHistory is the number of previous metadata revisions to retain for
+	// each document - if 0, no history is kept
+	MaxHistory int
+
+	// MinSuggestFrequency excludes terms that appear in fewer documents than
+	// this from suggestions - such terms can still be searched for directly
+	MinSuggestFrequency int
+
+	// Synonyms is used to expand required words in queries that enable it
+	Synonyms Synonyms
 }
 
 // New instantiates a new Engine
 func New(l *zap.SugaredLogger, opts Opts) (*Engine, error) {
-	index, err := bleve.New(opts.StorePath, newLensIndex())
+	mapping, err := newLensIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index mapping: %s", err.Error())
+	}
+	index, err := bleve.New(opts.StorePath, mapping)
 	if err != nil {
 		if err == bleve.ErrorIndexPathExists {
 			l.Infow("opening existing index",
@@ -54,6 +106,12 @@ func New(l *zap.SugaredLogger, opts Opts) (*Engine, error) {
 				return nil, fmt.Errorf("failed to open existing index at %s: %s",
 					opts.StorePath, err.Error())
 			}
+			if outdated := outdatedFields(index.Mapping()); len(outdated) > 0 {
+				l.Warnw("existing index predates exact matching on some fields - "+
+					"filters and name sorting may be inaccurate until all objects "+
+					"are reindexed into a new index",
+					"path", opts.StorePath, "fields", outdated)
+			}
 		} else {
 			return nil, fmt.Errorf("failed to instantiate index: %s", err.Error())
 		}
@@ -66,7 +124,8 @@ func New(l *zap.SugaredLogger, opts Opts) (*Engine, error) {
 	return &Engine{
 		l: l,
 
-		index: index,
+		index:     index,
+		storePath: opts.StorePath,
 
 		q: queue.New(queueLogger,
 			func(items []*queue.Item) error {
@@ -88,6 +147,12 @@ func New(l *zap.SugaredLogger, opts Opts) (*Engine, error) {
 			index.Close,
 			opts.Queue),
 
+		maxHistory:     opts.MaxHistory,
+		minSuggestFreq: opts.MinSuggestFrequency,
+		synonyms:       opts.Synonyms,
+
+		rawText: index.Mapping().AnalyzerNamed(rawAnalyzer) != nil,
+
 		stop: make(chan bool, 1),
 	}, nil
 }
@@ -121,7 +186,8 @@ func (e *Engine) Index(doc Document) error {
 	if doc.Object == nil || doc.Object.Hash == "" {
 		return errors.New("no object details provided")
 	}
-	if e.IsIndexed(doc.Object.Hash) && !doc.Reindex {
+	var exists = e.IsIndexed(doc.Object.Hash)
+	if exists && !doc.Reindex {
 		return fmt.Errorf("document with hash '%s' already exists", doc.Object.Hash)
 	}
 	var l = e.l.With("hash", doc.Object.Hash)
@@ -135,6 +201,28 @@ func (e *Engine) Index(doc Document) error {
 		l.Debug("defaulting to category = 'unknown'")
 		doc.Object.MD.Category = "unknown"
 	}
+	if p := doc.Object.MD.Provenance; p != nil && p.TextMode == models.TextModeRaw && !e.rawText {
+		l.Warn("index does not support raw text mode - using standard analysis")
+		p.TextMode = ""
+	}
+
+	// metadata-only updates keep the original indexing time
+	var indexed = doc.Object.Indexed
+	if indexed.IsZero() {
+		indexed = time.Now()
+	}
+
+	// record previous metadata if it is being replaced
+	var history string
+	if exists && e.maxHistory > 0 {
+		revisions, err := e.revisions(doc.Object)
+		if err != nil {
+			l.Warnw("failed to update document history", "error", err)
+		} else if len(revisions) > 0 {
+			b, _ := json.Marshal(revisions)
+			history = string(b)
+		}
+	}
 
 	// queue for index flush
 	if e.q.IsStopped() {
@@ -145,8 +233,11 @@ func (e *Engine) Index(doc Document) error {
 		Content:  doc.Content,
 		Metadata: &doc.Object.MD,
 		Properties: &DocProps{
-			Indexed: time.Now().String(),
+			Indexed: indexed.Format(time.RFC3339),
+			History: history,
 		},
+		Categories: categoryPaths(doc.Object.MD.Category),
+		NameSort:   nameKey(doc.Object.MD.DisplayName),
 	}}); err != nil {
 		return fmt.Errorf("could not index object: %s", err.Error())
 	}
@@ -168,15 +259,101 @@ func (e *Engine) IsIndexed(hash string) bool {
 	return false
 }
 
+// Get retrieves the stored contents and metadata of the given content hash.
+// ErrNotFound is returned if the hash is not indexed.
+func (e *Engine) Get(hash string) (*Document, error) {
+	if hash == "" {
+		return nil, errors.New("no hash provided")
+	}
+	out, err := e.index.Search(&bleve.SearchRequest{
+		Query:  query.NewDocIDQuery([]string{hash}),
+		Fields: []string{"*"},
+		Size:   1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve document '%s': %s", hash, err.Error())
+	}
+	if out.Hits.Len() < 1 {
+		return nil, ErrNotFound
+	}
+
+	var d = out.Hits[0]
+	var content, _ = d.Fields[fieldContent].(string)
+	var indexed, _ = d.Fields[fieldIndexed].(string)
+	var t, _ = time.Parse(time.RFC3339, indexed)
+	var history []models.Revision
+	if h, ok := d.Fields[fieldHistory].(string); ok && h != "" {
+		if err := json.Unmarshal([]byte(h), &history); err != nil {
+			e.l.Warnw("failed to read document history",
+				"hash", hash, "error", err)
+		}
+	}
+	return &Document{
+		Object: &models.ObjectV2{
+			Hash:    d.ID,
+			MD:      newMetadata(d.Fields),
+			History: history,
+			Indexed: t,
+		},
+		Content: content,
+	}, nil
+}
+
+// revisions returns the history of the given object with its currently
+// indexed metadata appended, if that metadata is about to change. At most
+// maxHistory of the most recent revisions are returned.
+func (e *Engine) revisions(obj *models.ObjectV2) ([]models.Revision, error) {
+	prev, err := e.Get(obj.Hash)
+	if err != nil {
+		return nil, err
+	}
+	var history = prev.Object.History
+	if !reflect.DeepEqual(revisable(prev.Object.MD), revisable(obj.MD)) {
+		history = append(history, models.Revision{
+			Replaced: time.Now(),
+			MD:       prev.Object.MD,
+		})
+	}
+	if len(history) > e.maxHistory {
+		history = history[len(history)-e.maxHistory:]
+	}
+	return history, nil
+}
+
+// revisable returns the given metadata without the fields that track an
+// object's state rather than describe it, since changes to them do not warrant
+// a revision
+func revisable(md models.MetaDataV2) models.MetaDataV2 {
+	md.Stale = false
+	md.Deleted = false
+	md.DeletedAt = ""
+	return md
+}
+
 // Search performs a query
 func (e *Engine) Search(ctx context.Context, q Query) ([]Result, error) {
 	var l = e.l.With("query_id", q.Hash())
 	var start = time.Now()
+	if err := q.Order.Validate(); err != nil {
+		return nil, err
+	}
+	if q.After != nil {
+		if err := q.After.Validate(q.Order); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.prepare(&q); err != nil {
+		return nil, err
+	}
 	var request = bleve.SearchRequest{
 		Query:  newBleveQuery(&q),
 		Fields: allMetaFields,
 		Size:   1000,
+
+		// required to report matched keywords
+		IncludeLocations: true,
 	}
+	request.SortBy(q.Order.sortBy())
 	l.Debugw("search constructed",
 		"query", q,
 		"request", request)
@@ -192,31 +369,155 @@ func (e *Engine) Search(ctx context.Context, q Query) ([]Result, error) {
 			"duration.total", time.Since(start))
 	}()
 
-	// execute request
+	// execute request, moving on to the following results until a full set
+	// of results after the cursor has been found, if there is one
 	timeout, cancel := context.WithDeadline(ctx, time.Now().Add(30*time.Second))
-	var err error
-	out, err = e.index.SearchInContext(timeout, &request)
-	cancel()
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute search: %s", err.Error())
+	defer cancel()
+	for {
+		var err error
+		out, err = e.index.SearchInContext(timeout, &request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute search: %s", err.Error())
+		}
+		if out.Size() == 0 && request.From == 0 {
+			return nil, errors.New("no results found")
+		}
+
+		// check returned docs
+		l.Debugw("search returned", "hits", out.Hits, "from", request.From)
+		var page = make([]Result, 0, len(out.Hits))
+		for _, d := range out.Hits {
+			page = append(page, newResult(d))
+		}
+		boostCategories(page, &q)
+		if q.After != nil {
+			page, _ = q.After.After(page, q.Order)
+		}
+		results = append(results, page...)
+
+		if q.After == nil || len(results) >= request.Size || len(out.Hits) < request.Size {
+			if len(results) > request.Size {
+				results = results[:request.Size]
+			}
+			return results, nil
+		}
+		request.From += request.Size
+	}
+}
+
+// prepare applies query options that require index access
+func (e *Engine) prepare(q *Query) error {
+	q.raw = e.rawText
+	if q.Synonyms && len(e.synonyms) > 0 && len(q.Required) > 0 {
+		// expand terms individually so that synonyms inherit weights
+		var expanded = make([]string, 0, len(q.Required))
+		var weights = make(map[string]float64)
+		for _, t := range q.Required {
+			var terms = e.synonyms.Expand([]string{t})
+			var w, ok = q.Weights[strings.ToLower(strings.TrimSpace(t))]
+			for _, term := range terms {
+				if ok {
+					weights[strings.ToLower(strings.TrimSpace(term))] = w
+				}
+				if !contains(expanded, term) {
+					expanded = append(expanded, term)
+				}
+			}
+		}
+		q.Required = expanded
+		if len(q.Weights) > 0 {
+			q.Weights = weights
+		}
 	}
-	if out.Size() == 0 {
-		return nil, errors.New("no results found")
+	if q.Prefix && len(q.Required) > 0 {
+		// expand terms individually so that expansions inherit weights
+		var expanded = make([]string, 0, len(q.Required))
+		var weights = make(map[string]float64)
+		for _, t := range q.Required {
+			terms, err := e.expandPrefixes(fieldContent, []string{t})
+			if err != nil {
+				return fmt.Errorf("failed to expand prefixes: %s", err.Error())
+			}
+			if w, ok := q.Weights[strings.ToLower(strings.TrimSpace(t))]; ok {
+				for _, term := range terms {
+					weights[term] = w
+				}
+			}
+			expanded = append(expanded, terms...)
+		}
+		q.Required = expanded
+		if len(q.Weights) > 0 {
+			q.Weights = weights
+		}
+	}
+	if q.Prefix && len(q.Tags) > 0 {
+		tags, err := e.expandPrefixes(fieldTags, q.Tags)
+		if err != nil {
+			return fmt.Errorf("failed to expand prefixes: %s", err.Error())
+		}
+		q.Tags = tags
 	}
+	return nil
+}
 
-	// check returned docs
-	l.Debugw("search returned", "hits", out.Hits)
-	for _, d := range out.Hits {
-		results = append(results, newResult(d))
+// Count returns the number of documents that match the given query
+func (e *Engine) Count(ctx context.Context, q Query) (uint64, error) {
+	if err := e.prepare(&q); err != nil {
+		return 0, err
+	}
+	var request = bleve.SearchRequest{
+		Query: newBleveQuery(&q),
+		Size:  0,
 	}
+	timeout, cancel := context.WithDeadline(ctx, time.Now().Add(30*time.Second))
+	out, err := e.index.SearchInContext(timeout, &request)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute count: %s", err.Error())
+	}
+	e.l.Debugw("count ended",
+		"query_id", q.Hash(),
+		"found", out.Total,
+		"duration.search", out.Took)
+	return out.Total, nil
+}
 
+// List retrieves a page of indexed documents, ordered by hash
+func (e *Engine) List(ctx context.Context, offset, size int) ([]Result, error) {
+	var request = bleve.NewSearchRequestOptions(query.NewMatchAllQuery(), size, offset, false)
+	request.Fields = allMetaFields
+	request.SortBy([]string{"_id"})
+	out, err := e.index.SearchInContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %s", err.Error())
+	}
+	var results = make([]Result, len(out.Hits))
+	for i, d := range out.Hits {
+		results[i] = newResult(d)
+	}
 	return results, nil
 }
 
-// Remove deletes an indexed object from the engine
+// Remove deletes an indexed object from the engine, returning ErrNotFound if
+// it is not indexed
 func (e *Engine) Remove(hash string) error {
 	if !e.IsIndexed(hash) {
-		return fmt.Errorf("no document '%s' in index", hash)
+		return ErrNotFound
+	}
+	if e.q.IsStopped() {
+		e.l.Warnw("queue stopped - waiting and trying again",
+			"hash", hash)
+		time.Sleep(3 * time.Second)
+	}
+	return e.q.Queue(&queue.Item{Key: hash, Val: nil})
+}
+
+// Unindex is like Remove, but also removes a document that has only been
+// queued for indexing, and does not fail if the document does not exist. It
+// is used to undo writes that may not have been flushed yet.
+func (e *Engine) Unindex(hash string) error {
+	if hash == "" {
+		return errors.New("no hash provided")
 	}
 	if e.q.IsStopped() {
 		e.l.Warnw("queue stopped - waiting and trying again",
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// BenchmarkEngine_Search measures query latency against an index of generated
+// documents
+func BenchmarkEngine_Search(b *testing.B) {
+	e, err := New(zap.NewNop().Sugar(), Opts{
+		StorePath: filepath.Join("tmp", b.Name()),
+		Queue: queue.Options{
+			Rate:      100 * time.Millisecond,
+			BatchSize: 100,
+		}})
+	if err != nil {
+		b.Fatal("failed to create engine: " + err.Error())
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+	defer e.Close()
+
+	var words = []string{"ipfs", "distributed", "storage", "network", "search",
+		"engine", "content", "discovery", "temporal", "lens"}
+	const docs = 1000
+	for i := 0; i < docs; i++ {
+		e.Index(Document{&models.ObjectV2{
+			Hash: fmt.Sprintf("doc%04d", i),
+			MD:   models.MetaDataV2{Category: []string{"document", "pdf", "image"}[i%3]},
+		}, fmt.Sprintf("%s %s %s", words[i%10], words[(i/10)%10], words[(i/100)%10]), true})
+	}
+	for !e.IsIndexed(fmt.Sprintf("doc%04d", docs-1)) {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var queries = map[string]Query{
+		"text":     {Text: "distributed storage"},
+		"required": {Required: []string{"ipfs", "lens"}},
+		"prefix":   {Required: []string{"dis"}, Prefix: true},
+		"filtered": {Required: []string{"ipfs"}, Categories: []string{"pdf"}},
+	}
+	for name, q := range queries {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := e.Search(context.Background(), q); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -114,3 +116,152 @@ func TestEngine_parallel(t *testing.T) {
 
 	e.Close()
 }
+
+// TestEngine_concurrent hammers the engine with concurrent writes and reads -
+// run with -race to check for data races
+func TestEngine_concurrent(t *testing.T) {
+	const (
+		writers   = 4
+		readers   = 4
+		docs      = 20
+		revisions = 3
+	)
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath:  filepath.Join("tmp", t.Name()),
+		MaxHistory: revisions,
+		Queue: queue.Options{
+			Rate:      100 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Fatal("failed to create engine: " + err.Error())
+	}
+	go e.Run()
+	defer e.Close()
+
+	var content = func(w, d, r int) string {
+		return fmt.Sprintf("writer%d document%d revision%d", w, d, r)
+	}
+	var done = make(chan struct{})
+	var wg, rg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < revisions; r++ {
+				for d := 0; d < docs; d++ {
+					var hash = fmt.Sprintf("w%d-d%d", w, d)
+					if err := e.Index(Document{&models.ObjectV2{
+						Hash: hash,
+						MD: models.MetaDataV2{
+							DisplayName: hash,
+							Tags:        []string{fmt.Sprintf("revision%d", r)},
+						},
+					}, content(w, d, r), true}); err != nil {
+						t.Errorf("Index() error = %v", err)
+					}
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < readers; r++ {
+		rg.Add(1)
+		go func(r int) {
+			defer rg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := e.Search(context.Background(), Query{Text: "document1"}); err != nil {
+					t.Errorf("Search() error = %v", err)
+				}
+				if _, err := e.Count(context.Background(), Query{Tags: []string{"revision0"}}); err != nil {
+					t.Errorf("Count() error = %v", err)
+				}
+				if doc, err := e.Get(fmt.Sprintf("w%d-d0", r%writers)); err == nil &&
+					doc.Object.MD.DisplayName != doc.Object.Hash {
+					t.Errorf("Get() returned corrupted metadata %+v", doc.Object.MD)
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	close(done)
+	rg.Wait()
+
+	// every document should eventually reflect its last write
+	var deadline = time.Now().Add(30 * time.Second)
+	for w := 0; w < writers; w++ {
+		for d := 0; d < docs; d++ {
+			var hash = fmt.Sprintf("w%d-d%d", w, d)
+			var want = content(w, d, revisions-1)
+			for {
+				doc, err := e.Get(hash)
+				if err == nil && doc.Content == want {
+					if doc.Object.MD.DisplayName != hash {
+						t.Errorf("Get(%s) returned corrupted metadata %+v", hash, doc.Object.MD)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Get(%s) = (%+v, %v), want content %q", hash, doc, err, want)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+	}
+}
+
+func TestEngine_concurrent_sharedTag(t *testing.T) {
+	const objects = 8
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      100 * time.Millisecond,
+			BatchSize: objects,
+		}})
+	if err != nil {
+		t.Fatal("failed to create engine: " + err.Error())
+	}
+	go e.Run()
+	defer e.Close()
+
+	// objects indexed at the same time with a tag that has not been seen
+	// before should all be found by that tag
+	var start = make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < objects; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if err := e.Index(Document{&models.ObjectV2{
+				Hash: fmt.Sprintf("object%d", i),
+				MD:   models.MetaDataV2{Tags: []string{"brandnew"}},
+			}, "", true}); err != nil {
+				t.Errorf("Index() error = %v", err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var deadline = time.Now().Add(10 * time.Second)
+	for {
+		results, err := e.Search(context.Background(), Query{Tags: []string{"brandnew"}})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(results) == objects {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Search() found %d objects with shared tag, want %d", len(results), objects)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -196,6 +197,34 @@ func TestEngine_Search(t *testing.T) {
 				Required: []string{"ubc launch pad"},
 			}},
 			false},
+		{"ok: find test obj with prefix",
+			args{Query{
+				Required: []string{"interplan"},
+				Prefix:   true,
+			}},
+			true},
+		{"fail: do NOT find test obj with prefix if not enabled",
+			args{Query{
+				Required: []string{"interplan"},
+			}},
+			false},
+		{"ok: find test obj with tag prefix",
+			args{Query{
+				Tags:   []string{"obj"},
+				Prefix: true,
+			}},
+			true},
+		{"fail: do NOT find test obj with tag prefix if not enabled",
+			args{Query{
+				Tags: []string{"obj"},
+			}},
+			false},
+		{"fail: do NOT find test obj with prefix that is too short",
+			args{Query{
+				Required: []string{"te"},
+				Prefix:   true,
+			}},
+			false},
 		{"ok: find test obj with mime type",
 			args{Query{
 				MimeTypes: []string{testObj.MD.MimeType},
@@ -236,6 +265,13 @@ func TestEngine_Search(t *testing.T) {
 				return
 			}
 
+			// count should agree with search
+			if count, err := e.Count(context.Background(), tt.args.q); err != nil {
+				t.Error("got count error: " + err.Error())
+			} else if count != uint64(len(got)) {
+				t.Errorf("Engine.Count() = %d, want %d", count, len(got))
+			}
+
 			// check for document
 			if tt.wantDoc {
 				if len(got) < 1 {
@@ -255,3 +291,926 @@ func TestEngine_Search(t *testing.T) {
 	e.Close()
 	os.RemoveAll("tmp")
 }
+
+func TestEngine_Get(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var obj = models.ObjectV2{
+		Hash: "abcde",
+		MD: models.MetaDataV2{
+			DisplayName: "rtrade",
+			MimeType:    "text",
+			Category:    "startup",
+			Tags:        []string{"ipfs"},
+			Properties:  map[string]string{"city": "vancouver"},
+			Pages:       12,
+			Truncated:   true,
+			Provenance: &models.Provenance{
+				LensVersion:  "v2.1.0",
+				Method:       "text",
+				SummaryRatio: 0.2,
+			},
+		},
+	}
+	if err = e.Index(Document{&obj, "rtrade technologies", true}); err != nil {
+		t.Errorf("wanted Index error = nil, got %v", err)
+	}
+	time.Sleep(time.Second)
+
+	if _, err = e.Get("not_my_hash"); err != ErrNotFound {
+		t.Errorf("wanted Get error ErrNotFound for unknown hash, got %v", err)
+	}
+	got, err := e.Get(obj.Hash)
+	e.Close()
+	if err != nil {
+		t.Errorf("wanted Get error = nil, got %v", err)
+		return
+	}
+	if got.Content != "rtrade technologies" {
+		t.Errorf("Engine.Get() content = %s, want %s", got.Content, "rtrade technologies")
+	}
+	if !reflect.DeepEqual(got.Object.MD, obj.MD) {
+		t.Errorf("Engine.Get() = %v, want %v", got.Object.MD, obj.MD)
+	}
+}
+
+func TestEngine_Get_history(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		},
+		MaxHistory: 2,
+	})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	// index three distinct versions, then one unchanged version
+	for _, c := range []string{"one", "two", "three", "three"} {
+		var obj = models.ObjectV2{
+			Hash: "abcde",
+			MD:   models.MetaDataV2{MimeType: "text", Category: c},
+		}
+		if err = e.Index(Document{&obj, "rtrade technologies", true}); err != nil {
+			t.Errorf("wanted Index error = nil, got %v", err)
+		}
+		time.Sleep(time.Second)
+	}
+
+	got, err := e.Get("abcde")
+	e.Close()
+	if err != nil {
+		t.Errorf("wanted Get error = nil, got %v", err)
+		return
+	}
+	if got.Object.MD.Category != "three" {
+		t.Errorf("Engine.Get() category = %s, want %s", got.Object.MD.Category, "three")
+	}
+	var categories []string
+	for _, r := range got.Object.History {
+		categories = append(categories, r.MD.Category)
+		if r.Replaced.IsZero() {
+			t.Errorf("Engine.Get() revision %v has no replacement time", r)
+		}
+	}
+	if !reflect.DeepEqual(categories, []string{"one", "two"}) {
+		t.Errorf("Engine.Get() history = %v, want %v", categories, []string{"one", "two"})
+	}
+}
+
+func TestEngine_Index_metadataUpdate(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		},
+		MaxHistory: 2,
+	})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+	defer e.Close()
+
+	var obj = models.ObjectV2{Hash: "abcde", MD: models.MetaDataV2{Category: "one"}}
+	if err = e.Index(Document{&obj, "rtrade technologies", false}); err != nil {
+		t.Errorf("wanted Index error = nil, got %v", err)
+	}
+	time.Sleep(time.Second)
+	original, err := e.Get("abcde")
+	if err != nil || original.Object.Indexed.IsZero() {
+		t.Errorf("Engine.Get() = %v, %v, want document with indexing time", original, err)
+		return
+	}
+
+	// state changes keep the indexing time, and are not revisions
+	time.Sleep(time.Second)
+	original.Object.MD.Stale = true
+	original.Reindex = true
+	if err = e.Index(*original); err != nil {
+		t.Errorf("wanted Index error = nil, got %v", err)
+	}
+	time.Sleep(time.Second)
+	got, err := e.Get("abcde")
+	if err != nil || !got.Object.MD.Stale {
+		t.Errorf("Engine.Get() = %v, %v, want stale document", got, err)
+		return
+	}
+	if !got.Object.Indexed.Equal(original.Object.Indexed) {
+		t.Errorf("Engine.Get() indexed = %v, want %v", got.Object.Indexed, original.Object.Indexed)
+	}
+	if len(got.Object.History) > 0 {
+		t.Errorf("Engine.Get() history = %v, want none", got.Object.History)
+	}
+
+	// new content is indexed at the current time
+	obj = models.ObjectV2{Hash: "abcde", MD: models.MetaDataV2{Category: "two"}}
+	if err = e.Index(Document{&obj, "rtrade technologies", true}); err != nil {
+		t.Errorf("wanted Index error = nil, got %v", err)
+	}
+	time.Sleep(time.Second)
+	if got, err = e.Get("abcde"); err != nil || !got.Object.Indexed.After(original.Object.Indexed) {
+		t.Errorf("Engine.Get() = %v, %v, want later indexing time", got, err)
+	}
+}
+
+func TestEngine_List(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	for _, h := range []string{"c", "a", "b"} {
+		e.Index(Document{&models.ObjectV2{Hash: h}, "", true})
+		time.Sleep(time.Second)
+	}
+	// flag one document as stale
+	e.Index(Document{&models.ObjectV2{Hash: "c", MD: models.MetaDataV2{Stale: true}}, "", true})
+	time.Sleep(time.Second)
+
+	first, err := e.List(context.Background(), 0, 2)
+	if err != nil {
+		t.Errorf("wanted List error = nil, got %v", err)
+	}
+	second, err := e.List(context.Background(), 2, 2)
+	e.Close()
+	if err != nil {
+		t.Errorf("wanted List error = nil, got %v", err)
+	}
+	var got []string
+	for _, r := range append(first, second...) {
+		got = append(got, r.Hash)
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Engine.List() = %v, want %v", got, []string{"a", "b", "c"})
+	}
+	if len(second) != 1 || !second[0].MD.Stale {
+		t.Errorf("Engine.List() = %v, wanted stale document", second)
+	}
+}
+
+func TestEngine_Search_stale(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "reachable"}, "hello world", true})
+	e.Index(Document{&models.ObjectV2{
+		Hash: "stale",
+		MD:   models.MetaDataV2{Stale: true},
+	}, "hello world", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name         string
+		excludeStale bool
+		want         []string
+	}{
+		{"include stale", false, []string{"reachable", "stale"}},
+		{"exclude stale", true, []string{"reachable"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Text:         "hello",
+				ExcludeStale: tt.excludeStale,
+			})
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			sort.Strings(hashes)
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_deleted(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "present"}, "hello world", true})
+	e.Index(Document{&models.ObjectV2{
+		Hash: "deleted",
+		MD:   models.MetaDataV2{Deleted: true, DeletedAt: "2019-05-01T00:00:00Z"},
+	}, "hello world", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"exclude deleted", Query{Text: "hello"}, []string{"present"}},
+		{"exclude deleted and stale", Query{Text: "hello", ExcludeStale: true}, []string{"present"}},
+		{"only deleted", Query{Text: "hello", Deleted: true}, []string{"deleted"}},
+		{"all deleted", Query{Deleted: true}, []string{"deleted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	// deletion is reported in metadata
+	doc, err := e.Get("deleted")
+	if err != nil {
+		t.Fatalf("Engine.Get() error = %v", err)
+	}
+	if md := doc.Object.MD; !md.Deleted || md.DeletedAt != "2019-05-01T00:00:00Z" {
+		t.Errorf("Engine.Get() metadata = %+v, want deleted", md)
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_near(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var vancouver = models.Location{Latitude: 49.2827, Longitude: -123.1207}
+	for hash, doc := range map[string]struct {
+		content  string
+		location *models.Location
+	}{
+		"vancouver": {"hello world", &vancouver},
+		"seattle":   {"hello there", &models.Location{Latitude: 47.6062, Longitude: -122.3321}},
+		"london":    {"hello world", &models.Location{Latitude: 51.5074, Longitude: -0.1278}},
+		"nowhere":   {"hello world", nil},
+	} {
+		e.Index(Document{&models.ObjectV2{
+			Hash: hash,
+			MD:   models.MetaDataV2{Location: doc.location},
+		}, doc.content, true})
+	}
+	time.Sleep(3 * time.Second)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"nearby", Query{Near: &Area{Center: vancouver, Radius: 250}}, []string{"seattle", "vancouver"}},
+		{"close", Query{Near: &Area{Center: vancouver, Radius: 1}}, []string{"vancouver"}},
+		{"everywhere", Query{Near: &Area{Center: vancouver, Radius: 20100}},
+			[]string{"london", "seattle", "vancouver"}},
+		{"with text", Query{Text: "world", Near: &Area{Center: vancouver, Radius: 250}}, []string{"vancouver"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			sort.Strings(hashes)
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	// locations are reported in metadata
+	doc, err := e.Get("vancouver")
+	if err != nil {
+		t.Fatalf("Engine.Get() error = %v", err)
+	}
+	if loc := doc.Object.MD.Location; loc == nil || loc.Distance(vancouver) > 0.01 {
+		t.Errorf("Engine.Get() location = %v, want %v", loc, vancouver)
+	}
+
+	e.Close()
+}
+
+func TestEngine_corruptHistory(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath:  filepath.Join("tmp", t.Name()),
+		MaxHistory: 10,
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "valid"}, "hello world", true})
+	time.Sleep(time.Second)
+	// write a document with malformed history directly, bypassing the queue
+	if err := e.index.Index("corrupt", DocData{
+		Content:    "hello world",
+		Metadata:   &models.MetaDataV2{DisplayName: "corrupt"},
+		Properties: &DocProps{History: "{not json"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a corrupt document should not affect other results
+	results, err := e.Search(context.Background(), Query{Text: "hello"})
+	if err != nil {
+		t.Errorf("Engine.Search() error = %v", err)
+	} else if len(results) != 2 {
+		t.Errorf("Engine.Search() found %d documents, want 2", len(results))
+	}
+
+	// the readable parts of a corrupt document are still returned
+	doc, err := e.Get("corrupt")
+	if err != nil {
+		t.Errorf("Engine.Get() error = %v", err)
+	} else if doc.Object.MD.DisplayName != "corrupt" || doc.Object.History != nil {
+		t.Errorf("Engine.Get() = %+v", doc.Object)
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_categoryBoosts(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	// identical content, so both documents match equally
+	e.Index(Document{&models.ObjectV2{
+		Hash: "a",
+		MD:   models.MetaDataV2{Category: "document"},
+	}, "hello world", true})
+	e.Index(Document{&models.ObjectV2{
+		Hash: "b",
+		MD:   models.MetaDataV2{Category: "image/photo"},
+	}, "hello world", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name   string
+		boosts map[string]float64
+		want   []string
+	}{
+		{"neutral", nil, []string{"a", "b"}},
+		{"neutral boost", map[string]float64{"image": 1}, []string{"a", "b"}},
+		{"boost parent category", map[string]float64{"image": 1.5}, []string{"b", "a"}},
+		{"most specific category applies",
+			map[string]float64{"image": 1.5, "Image/Photo": 0.5}, []string{"a", "b"}},
+		{"demote category", map[string]float64{"document": 0.5}, []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Text:           "hello",
+				CategoryBoosts: tt.boosts,
+			})
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_properties(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{
+		Hash: "abcde",
+		MD: models.MetaDataV2{
+			Properties: map[string]string{"project": "alpha team", "owner": "rtrade"},
+		},
+	}, "", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name    string
+		props   map[string]string
+		wantDoc bool
+	}{
+		{"ok: single property", map[string]string{"project": "alpha team"}, true},
+		{"ok: all properties", map[string]string{"project": "alpha team", "owner": "rtrade"}, true},
+		{"fail: partial value", map[string]string{"project": "alpha"}, false},
+		{"fail: one property mismatched", map[string]string{"project": "alpha team", "owner": "ubc"}, false},
+		{"fail: unknown key", map[string]string{"team": "alpha team"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := e.Search(context.Background(), Query{Properties: tt.props})
+			if (len(got) > 0) != tt.wantDoc {
+				t.Errorf("Engine.Search() = %v, wantDoc %v", got, tt.wantDoc)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_weights(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "a"}, "ipfs is a distributed file system", true})
+	time.Sleep(time.Second)
+	e.Index(Document{&models.ObjectV2{Hash: "b"}, "filecoin is a distributed storage network", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name      string
+		query     Query
+		wantFirst string
+	}{
+		{"weight ipfs", Query{
+			Required: []string{"ipfs", "filecoin"},
+			Weights:  map[string]float64{"ipfs": 10},
+		}, "a"},
+		{"weight filecoin", Query{
+			Required: []string{"ipfs", "filecoin"},
+			Weights:  map[string]float64{"filecoin": 10},
+		}, "b"},
+		{"weight expanded prefix", Query{
+			Required: []string{"ipf", "filec"},
+			Weights:  map[string]float64{"filec": 10},
+			Prefix:   true,
+		}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			if len(got) != 2 || got[0].Hash != tt.wantFirst {
+				t.Errorf("Engine.Search() = %v, want %s first", got, tt.wantFirst)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_order(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	// index dates have a resolution of one second
+	for _, d := range []struct{ hash, name string }{
+		// names are sorted as a whole, not by one of their words
+		{"a", "cherry"}, {"b", "apple"}, {"c", "Cherry apple"}, {"d", "apple Pie"}, {"e", ""},
+	} {
+		e.Index(Document{&models.ObjectV2{
+			Hash: d.hash,
+			MD:   models.MetaDataV2{DisplayName: d.name},
+		}, "distributed web", true})
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name    string
+		order   Order
+		want    []string
+		wantErr bool
+	}{
+		{"default", Order{}, []string{"a", "b", "c", "d", "e"}, false},
+		{"newest first", Order{By: OrderIndexed}, []string{"e", "d", "c", "b", "a"}, false},
+		{"oldest first", Order{By: OrderIndexed, Direction: Ascending}, []string{"a", "b", "c", "d", "e"}, false},
+		{"name", Order{By: OrderName}, []string{"e", "b", "d", "a", "c"}, false},
+		{"name descending", Order{By: OrderName, Direction: Descending}, []string{"c", "a", "d", "b", "e"}, false},
+		{"invalid order", Order{By: "size"}, nil, true},
+		{"invalid direction", Order{Direction: "up"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Required: []string{"distributed"},
+				Order:    tt.order,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Engine.Search() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			if !tt.wantErr && !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	// resume after a cursor
+	var cursor = func(hash string, o Order) *Cursor {
+		doc, err := e.Get(hash)
+		if err != nil {
+			t.Fatalf("Engine.Get() error = %v", err)
+		}
+		return NewCursor(Result{Hash: hash, Indexed: doc.Object.Indexed, MD: doc.Object.MD}, o)
+	}
+	var newest, oldest = Order{By: OrderIndexed}, Order{By: OrderIndexed, Direction: Ascending}
+	afterTests := []struct {
+		name    string
+		order   Order
+		after   *Cursor
+		want    []string
+		wantErr bool
+	}{
+		{"default", Order{}, cursor("a", Order{}), []string{"b", "c", "d", "e"}, false},
+		{"newest first", newest, cursor("b", newest), []string{"a"}, false},
+		{"oldest first", oldest, cursor("a", oldest), []string{"b", "c", "d", "e"}, false},
+		{"name", Order{By: OrderName}, cursor("a", Order{By: OrderName}), []string{"c"}, false},
+		{"last", newest, cursor("a", newest), []string{}, false},
+		{"mismatched cursor", oldest, cursor("a", newest), nil, true},
+	}
+	for _, tt := range afterTests {
+		t.Run("after "+tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Required: []string{"distributed"},
+				Order:    tt.order,
+				After:    tt.after,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Engine.Search() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			if !tt.wantErr && !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	// page through results two at a time, resuming after the last result of
+	// each page as if it had been removed since, so that pages are resumed
+	// from the sort key of the cursor rather than the position of its result
+	for _, tt := range tests[:5] {
+		t.Run("pages "+tt.name, func(t *testing.T) {
+			var hashes = make([]string, 0, len(tt.want))
+			var after *Cursor
+			for page := 0; page <= len(tt.want); page++ {
+				got, err := e.Search(context.Background(), Query{
+					Required: []string{"distributed"},
+					Order:    tt.order,
+					After:    after,
+				})
+				if err != nil {
+					t.Fatalf("Engine.Search() error = %v", err)
+				}
+				if len(got) > 2 {
+					got = got[:2]
+				}
+				if len(got) == 0 {
+					break
+				}
+				for _, r := range got {
+					hashes = append(hashes, r.Hash)
+				}
+				after = NewCursor(got[len(got)-1], tt.order)
+				after.Hash += "-removed"
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() pages = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_termFrequency(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	// documents have the same number of terms, so only the frequency of
+	// 'bitcoin' differs - both are tagged with it, which should not even out
+	// their scores
+	for hash, content := range map[string]string{
+		"once":  "bitcoin is money in a wallet of coins on an exchange at a price",
+		"often": "bitcoin is money, bitcoin in a wallet, bitcoin on an exchange, bitcoin",
+	} {
+		e.Index(Document{&models.ObjectV2{
+			Hash: hash,
+			MD:   models.MetaDataV2{Tags: []string{"bitcoin"}},
+		}, content, true})
+		time.Sleep(time.Second)
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{"required word", Query{Required: []string{"bitcoin"}}},
+		{"text", Query{Text: "bitcoin"}},
+		{"required word and tag", Query{Required: []string{"bitcoin"}, Tags: []string{"bitcoin"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			if len(got) != 2 || got[0].Hash != "often" || got[0].Score <= got[1].Score {
+				t.Errorf("Engine.Search() = %+v, want 'often' ranked above 'once'", got)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_rawText(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{
+		Hash: "raw",
+		MD: models.MetaDataV2{
+			Provenance: &models.Provenance{TextMode: models.TextModeRaw},
+		},
+	}, "to be or not to be", true})
+	e.Index(Document{&models.ObjectV2{
+		Hash: "standard",
+	}, "the question is whether to be", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"phrase of stop words", Query{Text: "to be or not"}, []string{"raw"}},
+		{"stop word phrase in standard document", Query{Text: "whether to be"}, []string{"standard"}},
+		{"required stop word", Query{Required: []string{"not"}}, []string{"raw"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	doc, err := e.Get("raw")
+	if err != nil {
+		t.Error(err)
+	} else if p := doc.Object.MD.Provenance; p == nil || p.TextMode != models.TextModeRaw {
+		t.Errorf("Engine.Get() provenance = %+v, want raw text mode", p)
+	}
+
+	e.Close()
+}
+
+func TestEngine_Search_categories(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	for hash, category := range map[string]string{
+		"contract":  "document/legal/contract",
+		"document":  "document",
+		"documents": "documents",
+		"image":     "image",
+	} {
+		e.Index(Document{&models.ObjectV2{
+			Hash: hash,
+			MD:   models.MetaDataV2{Category: category},
+		}, "hello world", true})
+	}
+	time.Sleep(2 * time.Second)
+
+	tests := []struct {
+		name       string
+		categories []string
+		want       []string
+	}{
+		{"top level category", []string{"document"}, []string{"contract", "document"}},
+		{"sub-category", []string{"Document/Legal"}, []string{"contract"}},
+		{"full path", []string{"document/legal/contract"}, []string{"contract"}},
+		{"unrelated category", []string{"image"}, []string{"image"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Text:       "hello",
+				Categories: tt.categories,
+			})
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			sort.Strings(hashes)
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
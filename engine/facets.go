@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+)
+
+// facetBatchSize is the number of documents to scan at a time when counting
+// facets
+const facetBatchSize = 1000
+
+// Facets denotes the number of documents matching a query, grouped by
+// metadata values
+type Facets struct {
+	Total      uint64
+	Categories map[string]int
+	MimeTypes  map[string]int
+}
+
+// Facet counts the documents matching the given query by category and mime
+// type. Mime type parameters, such as charsets, are ignored. Every matching
+// document is scanned, so this is considerably more expensive than Count.
+func (e *Engine) Facet(ctx context.Context, q Query) (*Facets, error) {
+	if err := e.prepare(&q); err != nil {
+		return nil, err
+	}
+	var bq = newBleveQuery(&q)
+	var facets = &Facets{
+		Categories: make(map[string]int),
+		MimeTypes:  make(map[string]int),
+	}
+	for offset := 0; ; offset += facetBatchSize {
+		var request = bleve.NewSearchRequestOptions(bq, facetBatchSize, offset, false)
+		request.Fields = []string{fieldCategory, fieldMimeType}
+		request.SortBy([]string{"_id"})
+		out, err := e.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute facet search: %s", err.Error())
+		}
+		facets.Total = out.Total
+		for _, d := range out.Hits {
+			var category, _ = d.Fields[fieldCategory].(string)
+			var mimeType, _ = d.Fields[fieldMimeType].(string)
+			facets.Categories[category]++
+			facets.MimeTypes[strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0])]++
+		}
+		if len(out.Hits) < facetBatchSize {
+			break
+		}
+	}
+	e.l.Debugw("facet search ended",
+		"query_id", q.Hash(),
+		"found", facets.Total)
+	return facets, nil
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestEngine_Facet(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var docs = map[string]models.MetaDataV2{
+		"a": {Category: "document", MimeType: "text/plain; charset=utf-8"},
+		"b": {Category: "document", MimeType: "text/html; charset=utf-8"},
+		"c": {Category: "image", MimeType: "image/png"},
+		"d": {Category: "pdf", MimeType: "application/pdf"},
+	}
+	for h, md := range docs {
+		e.Index(Document{&models.ObjectV2{Hash: h, MD: md}, "distributed web", true})
+		time.Sleep(time.Second)
+	}
+
+	got, err := e.Facet(context.Background(), Query{
+		Text:       "distributed",
+		Categories: []string{"document", "image"},
+	})
+	e.Close()
+	if err != nil {
+		t.Errorf("wanted Facet error = nil, got %v", err)
+		return
+	}
+	var want = &Facets{
+		Total:      3,
+		Categories: map[string]int{"document": 2, "image": 1},
+		MimeTypes:  map[string]int{"text/plain": 1, "text/html": 1, "image/png": 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.Facet() = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_Histogram(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var start = time.Now().Truncate(time.Second)
+	for _, h := range []string{"a", "b"} {
+		e.Index(Document{&models.ObjectV2{Hash: h}, "distributed web", true})
+	}
+	time.Sleep(time.Second)
+	var end = time.Now().Add(time.Second)
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		to      time.Time
+		width   time.Duration
+		want    []int
+		wantErr bool
+	}{
+		{"single bucket", start, end, time.Hour, []int{2}, false},
+		{"empty buckets before", start.Add(-3 * time.Hour), end, time.Hour, []int{0, 0, 0, 2}, false},
+		{"nothing in range", start.Add(-time.Hour), start, time.Hour, []int{0}, false},
+		{"invalid width", start, end, 0, nil, true},
+		{"invalid range", end, start, time.Hour, nil, true},
+		{"too many buckets", start, end, time.Nanosecond, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Histogram(context.Background(), tt.from, tt.to, tt.width)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Engine.Histogram() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var counts []int
+			for i, b := range got {
+				if !b.Start.Equal(tt.from.Add(time.Duration(i) * tt.width)) {
+					t.Errorf("bucket %d starts at %v", i, b.Start)
+				}
+				counts = append(counts, b.Count)
+			}
+			if !reflect.DeepEqual(counts, tt.want) {
+				t.Errorf("Engine.Histogram() counts = %v, want %v", counts, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Stats(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var docs = map[string]models.MetaDataV2{
+		"a": {Category: "document", Tags: []string{"ipfs", "web"}},
+		"b": {Category: "document", Tags: []string{"ipfs"}},
+		"c": {Category: "image"},
+	}
+	for h, md := range docs {
+		e.Index(Document{&models.ObjectV2{Hash: h, MD: md}, "distributed web", true})
+		time.Sleep(time.Second)
+	}
+
+	got, err := e.Stats(context.Background())
+	e.Close()
+	if err != nil {
+		t.Errorf("Engine.Stats() error = %v", err)
+		return
+	}
+	if got.Objects != 3 || got.Tags != 2 || got.Terms != 2 {
+		t.Errorf("Engine.Stats() = %+v, want 3 objects, 2 tags, and 2 terms", got)
+	}
+	if want := map[string]int{"document": 2, "image": 1}; !reflect.DeepEqual(got.Categories, want) {
+		t.Errorf("Engine.Stats() categories = %v, want %v", got.Categories, want)
+	}
+	if got.DiskSize <= 0 {
+		t.Errorf("Engine.Stats() disk size = %d, want positive", got.DiskSize)
+	}
+}
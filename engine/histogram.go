@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+)
+
+// MaxHistogramBuckets is the maximum number of buckets a histogram may have
+const MaxHistogramBuckets = 10000
+
+// Bucket denotes the number of documents indexed within a span of time,
+// starting at Start
+type Bucket struct {
+	Start time.Time
+	Count int
+}
+
+// Histogram counts documents by the time they were last indexed, in buckets of
+// the given width covering [from, to). Buckets without any documents are
+// included, so the result is a continuous series. Documents are scanned in
+// batches of facetBatchSize.
+func (e *Engine) Histogram(ctx context.Context, from, to time.Time, width time.Duration) ([]Bucket, error) {
+	if width <= 0 {
+		return nil, errors.New("bucket width must be positive")
+	}
+	if !to.After(from) {
+		return nil, errors.New("end of histogram must be after its start")
+	}
+	var n = to.Sub(from) / width
+	if to.Sub(from)%width != 0 {
+		n++
+	}
+	if n > MaxHistogramBuckets {
+		return nil, fmt.Errorf("histogram would have %d buckets, more than the maximum of %d",
+			n, MaxHistogramBuckets)
+	}
+	var buckets = make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Start = from.Add(time.Duration(i) * width)
+	}
+
+	var inclusive, exclusive = true, false
+	var dq = query.NewDateRangeInclusiveQuery(from, to, &inclusive, &exclusive)
+	dq.SetField(fieldIndexed)
+	for offset := 0; ; offset += facetBatchSize {
+		var request = bleve.NewSearchRequestOptions(dq, facetBatchSize, offset, false)
+		request.Fields = []string{fieldIndexed}
+		request.SortBy([]string{"_id"})
+		out, err := e.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute histogram search: %s", err.Error())
+		}
+		for _, d := range out.Hits {
+			var v, _ = d.Fields[fieldIndexed].(string)
+			indexed, err := time.Parse(time.RFC3339, v)
+			if err != nil || indexed.Before(from) || !indexed.Before(to) {
+				continue
+			}
+			buckets[indexed.Sub(from)/width].Count++
+		}
+		if len(out.Hits) < facetBatchSize {
+			break
+		}
+	}
+	e.l.Debugw("histogram search ended",
+		"from", from, "to", to, "buckets", len(buckets))
+	return buckets, nil
+}
@@ -1,8 +1,15 @@
 package engine
 
 import (
+	"strings"
+
 	"github.com/RTradeLtd/Lens/v2/models"
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/unicode"
 	"github.com/blevesearch/bleve/mapping"
 )
 
@@ -13,7 +20,22 @@ const (
 	fieldMimeType    = "metadata.mime_type"
 	fieldCategory    = "metadata.category"
 	fieldTags        = "metadata.tags"
+	fieldProperties  = "metadata.properties"
+	fieldLocation    = "metadata.location"
+	fieldStale       = "metadata.stale"
+	fieldDeleted     = "metadata.deleted"
+	fieldDeletedAt   = "metadata.deleted_at"
+	fieldThumbnail   = "metadata.thumbnail"
+	fieldExtracted   = "metadata.extracted_text"
+	fieldClassified  = "metadata.classification"
+	fieldPages       = "metadata.pages"
+	fieldTruncated   = "metadata.truncated"
+	fieldSampled     = "metadata.sampled"
+	fieldProvenance  = "metadata.provenance"
+	fieldCategories  = "categories"
+	fieldNameSort    = "name_sort"
 	fieldIndexed     = "properties.indexed"
+	fieldHistory     = "properties.history"
 )
 
 // allMetaFields includes all fields except 'content'
@@ -22,39 +44,177 @@ var allMetaFields = []string{
 	fieldMimeType,
 	fieldCategory,
 	fieldTags,
+	fieldStale,
+	fieldDeleted,
+	fieldDeletedAt,
+	fieldThumbnail,
+	fieldExtracted,
+	fieldClassified,
+	fieldPages,
+	fieldTruncated,
+	fieldSampled,
+	fieldLocation,
+	fieldProvenance + ".lens_version",
+	fieldProvenance + ".method",
+	fieldProvenance + ".image_model",
+	fieldProvenance + ".summary_ratio",
+	fieldProvenance + ".summary_truncated",
+	fieldProvenance + ".summary_fallback",
+	fieldProvenance + ".short_content",
+	fieldProvenance + ".text_mode",
+	fieldProvenance + ".source",
 	fieldIndexed,
 }
 
+// Names of the document mappings used for DocData
+const (
+	docType    = "objects"
+	docTypeRaw = "objects_raw"
+)
+
+// rawAnalyzer tokenizes and lowercases text, without removing stop words
+const rawAnalyzer = "lens_raw"
+
 // DocData defines the structure of indexed objects
 type DocData struct {
 	Content    string             `json:"content"`
 	Metadata   *models.MetaDataV2 `json:"metadata"`
 	Properties *DocProps          `json:"properties"`
+
+	// Categories is the lowercase path of the document's category and each of
+	// its parent categories, for matching categories by prefix
+	Categories []string `json:"categories,omitempty"`
+
+	// NameSort is the sort key of the document's display name - see nameKey
+	NameSort string `json:"name_sort"`
+}
+
+// nameKey returns the key documents with the given display name are sorted by.
+// Display names are split into words when indexed, so results are sorted by
+// this key instead, which keeps the whole name.
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// outdatedFields lists the fields that are matched exactly, but which the given
+// mapping does not analyze as keywords. Mappings are fixed when an index is
+// created, so indexes created before these fields were added need to be
+// rebuilt for filters and sorting on them to behave as expected.
+func outdatedFields(m mapping.IndexMapping) []string {
+	var outdated []string
+	for _, f := range []string{
+		fieldCategories,
+		fieldNameSort,
+		// properties are mapped by a default analyzer for any key
+		fieldProperties + ".key",
+	} {
+		if m.AnalyzerNameForPath(f) != keyword.Name {
+			outdated = append(outdated, f)
+		}
+	}
+	return outdated
+}
+
+// Type implements bleve's mapping.Classifier, which ensures the Lens document
+// mapping is used instead of the default dynamic mapping
+func (d DocData) Type() string {
+	if d.Metadata != nil && d.Metadata.Provenance != nil &&
+		d.Metadata.Provenance.TextMode == models.TextModeRaw {
+		return docTypeRaw
+	}
+	return docType
 }
 
 // DocProps denotes additional information about a document
 type DocProps struct {
-	Indexed string `json:"indexed"` // date indexed
+	Indexed string `json:"indexed"`           // date indexed
+	History string `json:"history,omitempty"` // JSON-encoded []models.Revision
+}
+
+func newLensIndex() (mapping.IndexMapping, error) {
+	var m = bleve.NewIndexMapping()
+	if err := m.AddCustomAnalyzer(rawAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	}); err != nil {
+		return nil, err
+	}
+	m.AddDocumentMapping(docType, newDocMapping(standard.Name))
+	m.AddDocumentMapping(docTypeRaw, newDocMapping(rawAnalyzer))
+	m.DefaultField = "content"
+	return m, nil
 }
 
-func newLensIndex() mapping.IndexMapping {
+// newDocMapping creates a mapping for DocData, with content analyzed by the
+// given analyzer
+func newDocMapping(contentAnalyzer string) *mapping.DocumentMapping {
 	var docData = bleve.NewDocumentMapping()
 
 	// DocData::Content
-	docData.AddFieldMappingsAt("content", bleve.NewTextFieldMapping())
+	var content = bleve.NewTextFieldMapping()
+	content.Analyzer = contentAnalyzer
+	docData.AddFieldMappingsAt("content", content)
+
+	// DocData::Categories - matched exactly, and only used for filtering
+	var categories = bleve.NewTextFieldMapping()
+	categories.Analyzer = keyword.Name
+	categories.Store = false
+	categories.IncludeInAll = false
+	docData.AddFieldMappingsAt("categories", categories)
+
+	// DocData::NameSort - only used for sorting
+	var nameSort = bleve.NewTextFieldMapping()
+	nameSort.Analyzer = keyword.Name
+	nameSort.Store = false
+	nameSort.IncludeInAll = false
+	docData.AddFieldMappingsAt("name_sort", nameSort)
 
 	// DocData::Metadata
 	var mdIndex = bleve.NewDocumentMapping()
+	mdIndex.AddFieldMappingsAt("stale", bleve.NewBooleanFieldMapping())
+	mdIndex.AddFieldMappingsAt("deleted", bleve.NewBooleanFieldMapping())
+	var deletedAt = bleve.NewTextFieldMapping()
+	deletedAt.Index = false
+	mdIndex.AddFieldMappingsAt("deleted_at", deletedAt)
+	mdIndex.AddFieldMappingsAt("pages", bleve.NewNumericFieldMapping())
+	mdIndex.AddFieldMappingsAt("truncated", bleve.NewBooleanFieldMapping())
+	mdIndex.AddFieldMappingsAt("sampled", bleve.NewBooleanFieldMapping())
+	mdIndex.AddFieldMappingsAt("location", bleve.NewGeoPointFieldMapping())
+	var thumbnail = bleve.NewTextFieldMapping()
+	thumbnail.Index = false
+	mdIndex.AddFieldMappingsAt("thumbnail", thumbnail)
+	var extracted = bleve.NewTextFieldMapping()
+	extracted.Index = false
+	mdIndex.AddFieldMappingsAt("extracted_text", extracted)
+
+	// DocData::Metadata::Properties - values are matched exactly
+	var propsIndex = bleve.NewDocumentMapping()
+	propsIndex.DefaultAnalyzer = keyword.Name
+	mdIndex.AddSubDocumentMapping("properties", propsIndex)
+
+	// DocData::Metadata::Provenance - explicitly mapped so that version strings
+	// are never detected as dates
+	var provIndex = bleve.NewDocumentMapping()
+	for _, f := range []string{"lens_version", "method", "image_model", "text_mode", "source"} {
+		var fm = bleve.NewTextFieldMapping()
+		fm.Analyzer = keyword.Name
+		provIndex.AddFieldMappingsAt(f, fm)
+	}
+	provIndex.AddFieldMappingsAt("summary_ratio", bleve.NewNumericFieldMapping())
+	provIndex.AddFieldMappingsAt("summary_truncated", bleve.NewBooleanFieldMapping())
+	provIndex.AddFieldMappingsAt("summary_fallback", bleve.NewBooleanFieldMapping())
+	provIndex.AddFieldMappingsAt("short_content", bleve.NewBooleanFieldMapping())
+	mdIndex.AddSubDocumentMapping("provenance", provIndex)
 	docData.AddSubDocumentMapping("metadata", mdIndex)
 
 	// DocData::Properties
 	var pIndex = bleve.NewDocumentMapping()
 	pIndex.AddFieldMappingsAt("indexed", bleve.NewDateTimeFieldMapping())
+	var history = bleve.NewTextFieldMapping()
+	history.Index = false
+	pIndex.AddFieldMappingsAt("history", history)
 	docData.AddSubDocumentMapping("properties", pIndex)
 
-	// construct overall index
-	var m = bleve.NewIndexMapping()
-	m.AddDocumentMapping("objects", docData)
-	m.DefaultField = "content"
-	return m
+	return docData
 }
@@ -0,0 +1,23 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blevesearch/bleve"
+)
+
+func Test_outdatedFields(t *testing.T) {
+	current, err := newLensIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := outdatedFields(current); len(got) != 0 {
+		t.Errorf("outdatedFields() = %v, want none", got)
+	}
+
+	var want = []string{fieldCategories, fieldNameSort, fieldProperties + ".key"}
+	if got := outdatedFields(bleve.NewIndexMapping()); !reflect.DeepEqual(got, want) {
+		t.Errorf("outdatedFields() = %v, want %v", got, want)
+	}
+}
@@ -4,26 +4,168 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/blevesearch/bleve"
-
+	"github.com/blevesearch/bleve/analysis/analyzer/standard"
 	"github.com/blevesearch/bleve/search/query"
+
+	"github.com/RTradeLtd/Lens/v2/models"
 )
 
 // Query denotes options for a search
+//
+// Text and Required are matched against the full content of documents, and
+// matches are scored by how often the matched words occur in each document
+// relative to its length, and how rare they are across the index - so a
+// document that mentions a word many times outranks one that mentions it once.
+// Tags, categories, and other metadata are matched exactly, regardless of
+// frequency.
 type Query struct {
 	Text     string
 	Required []string
 
+	// Weights boosts matches on individual required words, keyed by lowercase
+	// word. Words without a weight have a weight of 1.
+	Weights map[string]float64
+
 	// Query metadata
 	Tags       []string
 	Categories []string
 	MimeTypes  []string
 
+	// Properties requires documents to have all the given user properties,
+	// matched exactly
+	Properties map[string]string
+
 	// Hashes restricts what documents to include in query - this is only a
 	// filtering option, so some other query fields must be provided as well
 	Hashes []string
+
+	// CategoryBoosts multiplies the relevance score of documents in each of the
+	// given categories, or their sub-categories, by the given factor, ranking
+	// them higher (or lower) without excluding other documents. The boost of
+	// the most specific matching category applies.
+	CategoryBoosts map[string]float64
+
+	// ExcludeStale omits documents that have been flagged as unreachable
+	ExcludeStale bool
+
+	// Near restricts results to documents located within the given area.
+	// Documents without a location never match.
+	Near *Area
+
+	// Deleted matches only documents that have been soft-deleted, which are
+	// otherwise always omitted
+	Deleted bool
+
+	// Prefix expands each required word and tag to all indexed words of the
+	// same field it is a prefix of, ie 'crypt' to 'cryptography'
+	Prefix bool
+
+	// Synonyms also matches synonyms of each required word, if the engine has
+	// a synonym dictionary
+	Synonyms bool
+
+	// Order sorts results - results are sorted by relevance by default
+	Order Order
+
+	// After only returns results that sort after the given cursor, which must
+	// have been created with the same Order. Results sorted by date indexed
+	// are bounded by the cursor within the index itself, and results in other
+	// orders are skipped until the cursor is passed, so every page of a large
+	// result set can be reached.
+	After *Cursor
+
+	// raw also matches Text against documents indexed in raw text mode
+	raw bool
+}
+
+// Area denotes a circular geographic area
+type Area struct {
+	Center models.Location
+	// Radius is the distance from Center to the edge of the area, in
+	// kilometres
+	Radius float64
+}
+
+// Supported result orderings
+const (
+	OrderRelevance = "relevance"
+	OrderIndexed   = "indexed"
+	OrderName      = "name"
+)
+
+// Supported sort directions
+const (
+	Ascending  = "asc"
+	Descending = "desc"
+)
+
+// Order denotes how search results are sorted. Ties are broken by hash, so
+// that the order of results is stable.
+type Order struct {
+	// By is one of OrderRelevance, OrderIndexed, or OrderName
+	By string
+	// Direction is one of Ascending or Descending - if unset, relevance and
+	// date indexed are descending, and names are ascending
+	Direction string
+}
+
+// Validate checks that the ordering and direction are supported
+func (o Order) Validate() error {
+	switch o.By {
+	case "", OrderRelevance, OrderIndexed, OrderName:
+	default:
+		return fmt.Errorf("unsupported order '%s'", o.By)
+	}
+	switch o.Direction {
+	case "", Ascending, Descending:
+	default:
+		return fmt.Errorf("unsupported sort direction '%s'", o.Direction)
+	}
+	return nil
+}
+
+// descending checks if results are sorted in descending order
+func (o Order) descending() bool {
+	if o.Direction != "" {
+		return o.Direction == Descending
+	}
+	return o.By != OrderName
+}
+
+// sortBy returns the bleve sort order for o
+func (o Order) sortBy() []string {
+	var field string
+	switch o.By {
+	case OrderIndexed:
+		field = fieldIndexed
+	case OrderName:
+		field = fieldNameSort
+	default:
+		field = "_score"
+	}
+	if o.descending() {
+		field = "-" + field
+	}
+	return []string{field, "_id"}
+}
+
+// categoryBoost returns the boost for documents of the given category - 1 if
+// no boost applies
+func (q *Query) categoryBoost(category string) float64 {
+	var boost, depth = 1.0, 0
+	for c, b := range q.CategoryBoosts {
+		var paths = categoryPaths(c)
+		if b <= 0 || len(paths) <= depth || !models.InCategory(category, c) {
+			continue
+		}
+		boost, depth = b, len(paths)
+	}
+	return boost
 }
 
 // Hash generates a checksum hash for the query
@@ -34,7 +176,7 @@ func (q *Query) Hash() string {
 }
 
 func newBleveQuery(q *Query) query.Query {
-	return query.NewConjunctionQuery(
+	var conj = query.NewConjunctionQuery(
 		func() []query.Query {
 			var qs = make([]query.Query, 0)
 
@@ -42,12 +184,22 @@ func newBleveQuery(q *Query) query.Query {
 			if q.Text != "" {
 				var tq = query.NewMatchPhraseQuery(q.Text)
 				tq.SetField(fieldContent)
-				qs = append(qs, tq)
+				if !q.raw {
+					qs = append(qs, tq)
+				} else {
+					// the phrase must be analyzed the same way as each document's
+					// content for positions to line up
+					tq.Analyzer = standard.Name
+					var rq = query.NewMatchPhraseQuery(q.Text)
+					rq.SetField(fieldContent)
+					rq.Analyzer = rawAnalyzer
+					qs = append(qs, query.NewDisjunctionQuery([]query.Query{tq, rq}))
+				}
 			}
 
 			// require required words
 			if len(q.Required) > 0 {
-				var bq = newFieldTermsQuery(fieldContent, q.Required)
+				var bq = newWeightedTermsQuery(fieldContent, q.Required, q.Weights)
 				bq.SetBoost(100)
 				qs = append(qs, bq)
 			}
@@ -57,9 +209,23 @@ func newBleveQuery(q *Query) query.Query {
 				qs = append(qs, newFieldTermsQuery(fieldTags, q.Tags))
 			}
 
-			// require one of provided categories
+			// require one of provided categories, or their sub-categories -
+			// documents indexed before categories were hierarchical only have
+			// the category field
 			if len(q.Categories) > 0 {
-				qs = append(qs, newFieldTermsQuery(fieldCategory, q.Categories))
+				var paths = make([]string, 0, len(q.Categories))
+				for _, c := range q.Categories {
+					if p := categoryPaths(c); len(p) > 0 {
+						paths = append(paths, p[len(p)-1])
+					}
+				}
+				var cq = newFieldTermsQuery(fieldCategory, q.Categories)
+				if len(paths) > 0 {
+					qs = append(qs, query.NewDisjunctionQuery([]query.Query{
+						cq, newExactTermsQuery(fieldCategories, paths)}))
+				} else {
+					qs = append(qs, cq)
+				}
 			}
 
 			// require one of provided mimetypes
@@ -67,36 +233,153 @@ func newBleveQuery(q *Query) query.Query {
 				qs = append(qs, newFieldTermsQuery(fieldMimeType, q.MimeTypes))
 			}
 
+			// require all provided properties
+			for k, v := range q.Properties {
+				var tq = query.NewTermQuery(v)
+				tq.SetField(fieldProperties + "." + k)
+				qs = append(qs, tq)
+			}
+
 			// require hashses
 			if len(q.Hashes) > 0 {
 				qs = append(qs, query.NewDocIDQuery(q.Hashes))
 			}
 
+			// require location within area
+			if q.Near != nil {
+				var gq = query.NewGeoDistanceQuery(q.Near.Center.Longitude, q.Near.Center.Latitude,
+					strconv.FormatFloat(q.Near.Radius, 'f', -1, 64)+"km")
+				gq.SetField(fieldLocation)
+				qs = append(qs, gq)
+			}
+
+			// require documents that do not sort before the cursor - ties
+			// are resolved once results are retrieved
+			if q.After != nil && q.Order.By == OrderIndexed {
+				qs = append(qs, q.After.indexedRange(q.Order))
+			}
+
+			// require soft-deleted documents
+			if q.Deleted {
+				var dq = query.NewBoolFieldQuery(true)
+				dq.SetField(fieldDeleted)
+				qs = append(qs, dq)
+			}
+
 			return qs
 		}(),
 	)
+
+	// reject soft-deleted documents, and stale documents if requested
+	var reject = make([]query.Query, 0, 2)
+	if !q.Deleted {
+		var dq = query.NewBoolFieldQuery(true)
+		dq.SetField(fieldDeleted)
+		reject = append(reject, dq)
+	}
+	if q.ExcludeStale {
+		var sq = query.NewBoolFieldQuery(true)
+		sq.SetField(fieldStale)
+		reject = append(reject, sq)
+	}
+	if len(reject) > 0 {
+		return query.NewBooleanQuery([]query.Query{conj}, nil, reject)
+	}
+
+	return conj
 }
 
 func stringSplitter(c rune) bool { return c == ' ' }
 
 func newFieldTermsQuery(field string, should []string) *query.BooleanQuery {
+	return newWeightedTermsQuery(field, should, nil)
+}
+
+// newExactTermsQuery matches any of the given terms without splitting them
+func newExactTermsQuery(field string, should []string) *query.BooleanQuery {
 	var bq = bleve.NewBooleanQuery()
 	for _, s := range should {
+		var tq = query.NewTermQuery(s)
+		tq.SetField(field)
+		bq.AddShould(tq)
+	}
+	return bq
+}
+
+// categoryPaths returns the lowercase paths of the given category and its
+// parent categories
+func categoryPaths(category string) []string {
+	return models.CategoryPaths(strings.ToLower(category))
+}
+
+// newWeightedTermsQuery matches any of the given terms, boosting each term by
+// its weight if one is provided
+func newWeightedTermsQuery(field string, should []string, weights map[string]float64) *query.BooleanQuery {
+	var bq = bleve.NewBooleanQuery()
+	var add = func(term string, weight float64) {
+		var tq = query.NewTermQuery(term)
+		tq.SetField(field)
+		if weight > 0 && weight != 1 {
+			tq.SetBoost(weight)
+		}
+		bq.AddShould(tq)
+	}
+	for _, s := range should {
+		var weight = weights[strings.ToLower(strings.TrimSpace(s))]
 		if parts := strings.FieldsFunc(s, stringSplitter); len(parts) > 1 {
 			for _, p := range parts {
 				if len(p) > 1 {
-					var tq = query.NewTermQuery(strings.ToLower(p))
-					tq.SetField(field)
-					bq.AddShould(tq)
+					add(strings.ToLower(p), weight)
 				}
 			}
 		} else {
 			if stripped := strings.TrimSpace(s); len(stripped) > 1 {
-				var tq = query.NewTermQuery(strings.ToLower(stripped))
-				tq.SetField(field)
-				bq.AddShould(tq)
+				add(strings.ToLower(stripped), weight)
 			}
 		}
 	}
 	return bq
 }
+
+// Bounds on prefix expansion
+const (
+	minPrefixLength     = 3
+	maxPrefixExpansions = 50
+)
+
+// expandPrefixes replaces each term with the terms indexed in the given field
+// that it is a prefix of. Terms shorter than minPrefixLength are not expanded,
+// and at most maxPrefixExpansions terms are added per prefix.
+func (e *Engine) expandPrefixes(field string, terms []string) ([]string, error) {
+	var expanded = make([]string, 0, len(terms))
+	for _, t := range terms {
+		for _, p := range strings.FieldsFunc(strings.ToLower(t), stringSplitter) {
+			if len(p) < minPrefixLength {
+				expanded = append(expanded, p)
+				continue
+			}
+			dict, err := e.index.FieldDictPrefix(field, []byte(p))
+			if err != nil {
+				return nil, err
+			}
+			var found int
+			for found < maxPrefixExpansions {
+				entry, err := dict.Next()
+				if err != nil {
+					dict.Close()
+					return nil, err
+				}
+				if entry == nil {
+					break
+				}
+				expanded = append(expanded, entry.Term)
+				found++
+			}
+			dict.Close()
+			if found == 0 {
+				expanded = append(expanded, p)
+			}
+		}
+	}
+	return expanded, nil
+}
@@ -28,6 +28,7 @@ type Queue struct {
 	batchSize    int
 
 	stopC   chan bool
+	doneC   chan struct{} // closed when the queue begins stopping
 	stopped bool
 	smux    sync.RWMutex
 }
@@ -72,12 +73,14 @@ func New(
 		batchSize:    opts.BatchSize,
 
 		stopC:   make(chan bool, 1),
+		doneC:   make(chan struct{}),
 		stopped: true,
 	}
 }
 
 // Queue indicates that a new item is pending insertion. A nil value indicates
-// the item should be deleted.
+// the item should be deleted. It is safe to call from multiple goroutines, and
+// items accepted before the queue is closed are always flushed.
 func (q *Queue) Queue(item *Item) error {
 	if item == nil || item.Key == "" {
 		return errors.New("item requires valid key")
@@ -85,9 +88,13 @@ func (q *Queue) Queue(item *Item) error {
 
 	q.smux.RLock()
 	if !q.stopped {
-		q.pendingC <- item
-		q.smux.RUnlock()
-		return nil
+		// a full queue must not block a concurrent stop, which needs the lock
+		select {
+		case q.pendingC <- item:
+			q.smux.RUnlock()
+			return nil
+		case <-q.doneC:
+		}
 	}
 	q.smux.RUnlock()
 	q.l.Error("queue failed: queue is stopped, cannot queue more elements")
@@ -151,12 +158,25 @@ func (q *Queue) flushIfNeeded() {
 }
 
 func (q *Queue) stop() {
+	// release writers blocked on a full queue before waiting for the lock
+	close(q.doneC)
 	q.smux.Lock()
 
+	// collect items accepted since the last flush
+	for draining := true; draining; {
+		select {
+		case item := <-q.pendingC:
+			q.pendingItems = append(q.pendingItems[:q.pending], item)
+			q.pending++
+		default:
+			draining = false
+		}
+	}
+
 	q.l.Infow("executing close",
 		"items", q.pending)
 	var now = time.Now()
-	if err := q.flushFunc(q.pendingItems); err != nil {
+	if err := q.flushFunc(q.pendingItems[:q.pending]); err != nil {
 		q.l.Errorw("unable to flush", "error", err)
 	}
 	if err := q.closeFunc(); err != nil {
@@ -1,6 +1,9 @@
 package queue
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -57,3 +60,52 @@ func TestQueue_IsStopped(t *testing.T) {
 		t.Errorf("IsStopped = '%v', got '%v'", b, q.stopped)
 	}
 }
+
+func TestQueue_concurrent(t *testing.T) {
+	var flushed int64
+	var q = New(zaptest.NewLogger(t).Sugar(),
+		func(items []*Item) error {
+			for _, item := range items {
+				if item != nil {
+					atomic.AddInt64(&flushed, 1)
+				}
+			}
+			return nil
+		}, nil, Options{
+			Rate:      time.Hour, // only flush on full batches and close
+			BatchSize: 3,
+		})
+	go q.Run()
+	for q.IsStopped() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// queue from many writers while closing - all accepted items must be
+	// flushed, and closing must not deadlock with writers
+	var accepted int64
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if q.Queue(&Item{Key: fmt.Sprintf("%d-%d", w, i)}) == nil {
+					atomic.AddInt64(&accepted, 1)
+				}
+			}
+		}(w)
+	}
+	time.Sleep(time.Millisecond)
+
+	var closed = make(chan struct{})
+	go func() { q.Close(); close(closed) }()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Queue.Close() did not return")
+	}
+	wg.Wait()
+	if a, f := atomic.LoadInt64(&accepted), atomic.LoadInt64(&flushed); a != f {
+		t.Errorf("accepted %d items, but flushed %d", a, f)
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+)
+
+// removeBatchSize is the number of documents to collect at a time for bulk
+// removals
+const removeBatchSize = 1000
+
+// RemoveMatching deletes every document that matches the given query, and
+// returns the number of documents removed. Documents are removed individually,
+// so if an error occurs, documents removed prior to the error remain removed.
+func (e *Engine) RemoveMatching(ctx context.Context, q Query) (int, error) {
+	hashes, err := e.ListMatching(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return e.removeAll(hashes)
+}
+
+// ListMatching returns the hashes of all documents that match the given query,
+// in order
+func (e *Engine) ListMatching(ctx context.Context, q Query) ([]string, error) {
+	return e.collect(ctx, newBleveQuery(&q), func(string) (bool, bool) {
+		return true, false
+	})
+}
+
+// RemovePrefix deletes every document with a hash that begins with the given
+// prefix, and returns the number of documents removed. Documents are removed
+// individually, so if an error occurs, documents removed prior to the error
+// remain removed.
+func (e *Engine) RemovePrefix(ctx context.Context, prefix string) (int, error) {
+	hashes, err := e.ListPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	return e.removeAll(hashes)
+}
+
+// ListPrefix returns the hashes of all documents that begin with the given
+// prefix, in order
+func (e *Engine) ListPrefix(ctx context.Context, prefix string) ([]string, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("no prefix provided")
+	}
+	return e.collect(ctx, query.NewMatchAllQuery(), func(hash string) (bool, bool) {
+		// hashes are sorted, so we can stop once we have passed the prefix
+		return strings.HasPrefix(hash, prefix), hash > prefix && !strings.HasPrefix(hash, prefix)
+	})
+}
+
+// collect gathers the hashes of all documents matching the given query, in
+// order. match reports whether a hash should be collected, and whether to stop
+// collecting.
+func (e *Engine) collect(
+	ctx context.Context,
+	q query.Query,
+	match func(hash string) (collect bool, done bool),
+) ([]string, error) {
+	var hashes = make([]string, 0)
+	for offset := 0; ; offset += removeBatchSize {
+		var request = bleve.NewSearchRequestOptions(q, removeBatchSize, offset, false)
+		request.SortBy([]string{"_id"})
+		out, err := e.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find documents: %s", err.Error())
+		}
+		for _, d := range out.Hits {
+			collect, done := match(d.ID)
+			if done {
+				return hashes, nil
+			}
+			if collect {
+				hashes = append(hashes, d.ID)
+			}
+		}
+		if len(out.Hits) < removeBatchSize {
+			return hashes, nil
+		}
+	}
+}
+
+func (e *Engine) removeAll(hashes []string) (int, error) {
+	for i, h := range hashes {
+		if err := e.Remove(h); err != nil {
+			e.l.Errorw("bulk removal interrupted",
+				"error", err,
+				"hash", h,
+				"removed", i,
+				"remaining", len(hashes)-i)
+			return i, fmt.Errorf("failed to remove document '%s': %s", h, err.Error())
+		}
+	}
+	e.l.Infow("bulk removal requested", "removed", len(hashes))
+	return len(hashes), nil
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestEngine_bulkRemove(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	var docs = map[string][]string{
+		"QmBadBatch1": {"spam"},
+		"QmBadBatch2": nil,
+		"QmGood1":     {"spam", "ipfs"},
+		"QmGood2":     {"ipfs"},
+		"QmGood3":     nil,
+	}
+	for h, tags := range docs {
+		e.Index(Document{&models.ObjectV2{Hash: h, MD: models.MetaDataV2{Tags: tags}}, "", true})
+		time.Sleep(time.Second)
+	}
+
+	// list by prefix
+	if got, err := e.ListPrefix(context.Background(), "QmGood"); err != nil ||
+		!reflect.DeepEqual(got, []string{"QmGood1", "QmGood2", "QmGood3"}) {
+		t.Errorf("ListPrefix() = (%v, %v), want all good hashes", got, err)
+	}
+
+	// list by tag
+	if got, err := e.ListMatching(context.Background(), Query{Tags: []string{"spam"}}); err != nil ||
+		!reflect.DeepEqual(got, []string{"QmBadBatch1", "QmGood1"}) {
+		t.Errorf("ListMatching() = (%v, %v), want all spam hashes", got, err)
+	}
+
+	// remove by prefix
+	if _, err := e.RemovePrefix(context.Background(), ""); err == nil {
+		t.Error("wanted RemovePrefix error for empty prefix, got nil")
+	}
+	if n, err := e.RemovePrefix(context.Background(), "QmBadBatch"); err != nil || n != 2 {
+		t.Errorf("RemovePrefix() = (%d, %v), want (2, nil)", n, err)
+	}
+	time.Sleep(time.Second)
+
+	// remove by tag
+	if n, err := e.RemoveMatching(context.Background(), Query{Tags: []string{"spam"}}); err != nil || n != 1 {
+		t.Errorf("RemoveMatching() = (%d, %v), want (1, nil)", n, err)
+	}
+	time.Sleep(time.Second)
+
+	for h, want := range map[string]bool{
+		"QmBadBatch1": false,
+		"QmBadBatch2": false,
+		"QmGood1":     false,
+		"QmGood2":     true,
+		"QmGood3":     true,
+	} {
+		if got := e.IsIndexed(h); got != want {
+			t.Errorf("IsIndexed(%s) = %v, want %v", h, got, want)
+		}
+	}
+
+	e.Close()
+}
+
+func TestEngine_Unindex(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 10,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	if err := e.Unindex(""); err == nil {
+		t.Error("wanted Unindex error for empty hash, got nil")
+	}
+	if err := e.Unindex("QmNotIndexed"); err != nil {
+		t.Errorf("Unindex() of missing document error = %v", err)
+	}
+
+	// undo a write that has not been flushed yet
+	if err := e.Index(Document{&models.ObjectV2{Hash: "QmQueued"}, "", false}); err != nil {
+		t.Errorf("Index() error = %v", err)
+	}
+	if err := e.Remove("QmQueued"); err != ErrNotFound {
+		t.Errorf("Remove() of queued document error = %v, want ErrNotFound", err)
+	}
+	if err := e.Unindex("QmQueued"); err != nil {
+		t.Errorf("Unindex() of queued document error = %v", err)
+	}
+	time.Sleep(time.Second)
+	if e.IsIndexed("QmQueued") {
+		t.Error("Unindex() did not remove queued document")
+	}
+}
@@ -2,6 +2,9 @@ package engine
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/blevesearch/bleve/search"
 
@@ -10,31 +13,156 @@ import (
 
 // Result denotes a found document
 type Result struct {
+	// Hash is the content hash the document was indexed with
 	Hash string
 	MD   models.MetaDataV2
 
 	Score float64
+
+	// Indexed is when the document was last indexed
+	Indexed time.Time
+
+	// MatchedKeywords are the normalized query terms that matched this document
+	MatchedKeywords []string
 }
 
 func newResult(d *search.DocumentMatch) Result {
+	var indexed, _ = d.Fields[fieldIndexed].(string)
+	var t, _ = time.Parse(time.RFC3339, indexed)
+	return Result{
+		Hash:    d.ID,
+		Score:   d.Score,
+		MD:      newMetadata(d.Fields),
+		Indexed: t,
+
+		MatchedKeywords: matchedTerms(d.Locations),
+	}
+}
+
+// boostCategories applies the category boosts of the given query to the
+// scores of results, and sorts results by their new scores if they are
+// ordered by relevance
+func boostCategories(results []Result, q *Query) {
+	if len(q.CategoryBoosts) == 0 {
+		return
+	}
+	for i := range results {
+		results[i].Score *= q.categoryBoost(results[i].MD.Category)
+	}
+	if q.Order.By != "" && q.Order.By != OrderRelevance {
+		return
+	}
+	var asc = q.Order.Direction == Ascending
+	sort.SliceStable(results, func(i, j int) bool {
+		if asc {
+			return results[i].Score < results[j].Score
+		}
+		return results[i].Score > results[j].Score
+	})
+}
+
+// newMetadata reconstructs document metadata from stored fields
+func newMetadata(fields map[string]interface{}) models.MetaDataV2 {
 	var md models.MetaDataV2
-	if d.Fields != nil {
-		var fields = d.Fields
-		md.DisplayName, _ = fields[fieldDisplayName].(string)
-		md.Category, _ = fields[fieldCategory].(string)
-		md.MimeType, _ = fields[fieldMimeType].(string)
-		rawTags, _ := fields[fieldTags].([]interface{})
-		if len(rawTags) > 0 {
-			md.Tags = make([]string, len(rawTags))
-			for i, v := range rawTags {
-				md.Tags[i] = fmt.Sprint(v)
+	if fields == nil {
+		return md
+	}
+	md.DisplayName, _ = fields[fieldDisplayName].(string)
+	md.Category, _ = fields[fieldCategory].(string)
+	md.MimeType, _ = fields[fieldMimeType].(string)
+	md.Thumbnail, _ = fields[fieldThumbnail].(string)
+	md.ExtractedText, _ = fields[fieldExtracted].(string)
+	md.Classification, _ = fields[fieldClassified].(string)
+	md.Tags = toStrings(fields[fieldTags])
+	md.Stale, _ = fields[fieldStale].(bool)
+	md.Deleted, _ = fields[fieldDeleted].(bool)
+	md.DeletedAt, _ = fields[fieldDeletedAt].(string)
+	md.Truncated, _ = fields[fieldTruncated].(bool)
+	md.Sampled, _ = fields[fieldSampled].(bool)
+	if pages, ok := fields[fieldPages].(float64); ok {
+		md.Pages = int(pages)
+	}
+	md.Location = toLocation(fields[fieldLocation])
+	var prov models.Provenance
+	prov.LensVersion, _ = fields[fieldProvenance+".lens_version"].(string)
+	prov.Method, _ = fields[fieldProvenance+".method"].(string)
+	prov.ImageModel, _ = fields[fieldProvenance+".image_model"].(string)
+	prov.SummaryRatio, _ = fields[fieldProvenance+".summary_ratio"].(float64)
+	prov.TextMode, _ = fields[fieldProvenance+".text_mode"].(string)
+	prov.Source, _ = fields[fieldProvenance+".source"].(string)
+	prov.SummaryTruncated, _ = fields[fieldProvenance+".summary_truncated"].(bool)
+	prov.SummaryFallback, _ = fields[fieldProvenance+".summary_fallback"].(bool)
+	prov.ShortContent, _ = fields[fieldProvenance+".short_content"].(bool)
+	if prov != (models.Provenance{}) {
+		md.Provenance = &prov
+	}
+	for k, v := range fields {
+		if strings.HasPrefix(k, fieldProperties+".") {
+			if md.Properties == nil {
+				md.Properties = make(map[string]string)
 			}
+			md.Properties[strings.TrimPrefix(k, fieldProperties+".")] = fmt.Sprint(v)
 		}
 	}
+	return md
+}
 
-	return Result{
-		Hash:  d.ID,
-		Score: d.Score,
-		MD:    md,
+// toLocation converts a stored geopoint field value, which is a longitude and
+// latitude pair, into a location
+func toLocation(v interface{}) *models.Location {
+	var coords []float64
+	switch val := v.(type) {
+	case []float64:
+		coords = val
+	case []interface{}:
+		for _, c := range val {
+			if f, ok := c.(float64); ok {
+				coords = append(coords, f)
+			}
+		}
+	}
+	if len(coords) != 2 {
+		return nil
+	}
+	return &models.Location{Latitude: coords[1], Longitude: coords[0]}
+}
+
+// toStrings converts a stored field value into a string slice - fields with
+// a single value are not stored as arrays
+func toStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+		var out = make([]string, len(val))
+		for i, s := range val {
+			out[i] = fmt.Sprint(s)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// matchedTerms collects the unique set of terms that were located in any field
+// of a match. Terms are reported as they appear in the index, ie after analysis.
+func matchedTerms(locations search.FieldTermLocationMap) []string {
+	if len(locations) == 0 {
+		return nil
+	}
+	var seen = make(map[string]bool)
+	var terms = make([]string, 0)
+	for _, tlm := range locations {
+		for term := range tlm {
+			if !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
 	}
+	sort.Strings(terms)
+	return terms
 }
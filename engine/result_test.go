@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blevesearch/bleve/search"
+)
+
+func Test_newResult(t *testing.T) {
+	type args struct {
+		d *search.DocumentMatch
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantKeywords []string
+	}{
+		{"no locations",
+			args{&search.DocumentMatch{ID: "abcde"}},
+			nil},
+		{"matched in multiple fields",
+			args{&search.DocumentMatch{
+				ID: "abcde",
+				Locations: search.FieldTermLocationMap{
+					fieldContent: search.TermLocationMap{
+						"interplanetary": search.Locations{&search.Location{Pos: 1}},
+						"file":           search.Locations{&search.Location{Pos: 2}},
+					},
+					fieldTags: search.TermLocationMap{
+						"file": search.Locations{&search.Location{Pos: 1}},
+					},
+				},
+			}},
+			[]string{"file", "interplanetary"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got = newResult(tt.args.d)
+			if got.Hash != tt.args.d.ID {
+				t.Errorf("newResult().Hash = %v, want %v", got.Hash, tt.args.d.ID)
+			}
+			if !reflect.DeepEqual(got.MatchedKeywords, tt.wantKeywords) {
+				t.Errorf("newResult().MatchedKeywords = %v, want %v",
+					got.MatchedKeywords, tt.wantKeywords)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/blevesearch/bleve"
+)
+
+// Stats denotes the size of the index
+type Stats struct {
+	// Objects is the number of indexed documents
+	Objects uint64
+	// Tags is the number of distinct indexed tags
+	Tags int
+	// Terms is the number of distinct terms in indexed content
+	Terms int
+	// Categories is the number of documents in each category
+	Categories map[string]int
+	// DiskSize is the total size in bytes of the index on disk
+	DiskSize int64
+}
+
+// Stats reports the size of the index. Every document is scanned to count
+// categories, so results should be cached by callers that poll.
+func (e *Engine) Stats(ctx context.Context) (*Stats, error) {
+	objects, err := e.index.DocCount()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count documents: %s", err.Error())
+	}
+	var stats = &Stats{
+		Objects:    objects,
+		Categories: make(map[string]int),
+	}
+	if stats.Tags, err = e.countTerms(fieldTags); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %s", err.Error())
+	}
+	if stats.Terms, err = e.countTerms(fieldContent); err != nil {
+		return nil, fmt.Errorf("failed to count terms: %s", err.Error())
+	}
+
+	for offset := 0; ; offset += facetBatchSize {
+		var request = bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), facetBatchSize, offset, false)
+		request.Fields = []string{fieldCategory}
+		request.SortBy([]string{"_id"})
+		out, err := e.index.SearchInContext(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count categories: %s", err.Error())
+		}
+		for _, d := range out.Hits {
+			var category, _ = d.Fields[fieldCategory].(string)
+			stats.Categories[category]++
+		}
+		if len(out.Hits) < facetBatchSize {
+			break
+		}
+	}
+
+	if e.storePath != "" {
+		if stats.DiskSize, err = diskSize(e.storePath); err != nil {
+			e.l.Warnw("failed to measure index size on disk",
+				"error", err, "path", e.storePath)
+		}
+	}
+	return stats, nil
+}
+
+// countTerms returns the number of distinct terms indexed in the given field
+func (e *Engine) countTerms(field string) (int, error) {
+	dict, err := e.index.FieldDict(field)
+	if err != nil {
+		return 0, err
+	}
+	defer dict.Close()
+	var count int
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return 0, err
+		}
+		if entry == nil {
+			return count, nil
+		}
+		count++
+	}
+}
+
+// diskSize returns the total size of the files under the given path
+func diskSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxSuggestDistance bounds the edit distance of suggested corrections
+const maxSuggestDistance = 2
+
+// minSuggestLength is the minimum length of terms to look up corrections for
+const minSuggestLength = 3
+
+// Suggestion denotes a possible correction for a query term that does not
+// appear in the index
+type Suggestion struct {
+	Term       string
+	Suggestion string
+}
+
+// Suggest looks up the closest indexed term for each term in the given text
+// that does not appear in the index. Candidates are limited to terms sharing
+// the same first character, within a bounded edit distance, that appear in at
+// least the configured minimum number of documents.
+func (e *Engine) Suggest(text string) ([]Suggestion, error) {
+	var suggestions = make([]Suggestion, 0)
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), isTermSeparator) {
+		if len([]rune(term)) < minSuggestLength {
+			continue
+		}
+		suggestion, err := e.suggestTerm(term)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up suggestions for '%s': %s",
+				term, err.Error())
+		}
+		if suggestion != "" {
+			suggestions = append(suggestions, Suggestion{
+				Term:       term,
+				Suggestion: suggestion,
+			})
+		}
+	}
+	return suggestions, nil
+}
+
+// suggestTerm returns the closest indexed term, or an empty string if the term
+// is indexed or no term is close enough
+func (e *Engine) suggestTerm(term string) (string, error) {
+	dict, err := e.index.FieldDictPrefix(fieldContent, []byte(term[:1]))
+	if err != nil {
+		return "", err
+	}
+	defer dict.Close()
+
+	var (
+		best      string
+		bestDist  = maxSuggestDistance + 1
+		bestCount uint64
+	)
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return "", err
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Term == term {
+			return "", nil
+		}
+		if int(entry.Count) < e.minSuggestFreq {
+			continue
+		}
+		var d = editDistance(term, entry.Term)
+		if d < bestDist || (d == bestDist && entry.Count > bestCount) {
+			best, bestDist, bestCount = entry.Term, d, entry.Count
+		}
+	}
+	return best, nil
+}
+
+func isTermSeparator(c rune) bool { return !unicode.IsLetter(c) && !unicode.IsNumber(c) }
+
+// editDistance computes the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	var ra, rb = []rune(a), []rune(b)
+	var prev = make([]int, len(rb)+1)
+	var curr = make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			var cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min(vals ...int) int {
+	var m = vals[0]
+	for _, v := range vals[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
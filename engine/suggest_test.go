@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"cat", "cat", 0},
+		{"cat", "cats", 1},
+		{"kitten", "sitting", 3},
+		{"décentralisé", "decentralise", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("editDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Suggest(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		}})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "abcde"},
+		"the interplanetary file system is a decentralized storage network", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name string
+		text string
+		want []Suggestion
+	}{
+		{"no typos", "interplanetary storage", []Suggestion{}},
+		{"short terms ignored", "fs", []Suggestion{}},
+		{"typo", "interplanatery storgae",
+			[]Suggestion{{"interplanatery", "interplanetary"}, {"storgae", "storage"}}},
+		{"nothing close", "xylophone", []Suggestion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Suggest(tt.text)
+			if err != nil {
+				t.Errorf("Engine.Suggest() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Engine.Suggest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
+
+func TestEngine_Suggest_minFrequency(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		},
+		MinSuggestFrequency: 2,
+	})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	// 'storage' appears in both documents, 'storages' only in one
+	e.Index(Document{&models.ObjectV2{Hash: "abcde"}, "decentralized storage", true})
+	time.Sleep(time.Second)
+	e.Index(Document{&models.ObjectV2{Hash: "fghij"}, "storage storages", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name string
+		text string
+		want []Suggestion
+	}{
+		{"frequent term suggested", "storaeg", []Suggestion{{"storaeg", "storage"}}},
+		{"rare term not suggested", "decentralised", []Suggestion{}},
+		{"rare term still indexed", "storages", []Suggestion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Suggest(tt.text)
+			if err != nil {
+				t.Errorf("Engine.Suggest() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Engine.Suggest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxSynonyms bounds the number of synonyms each query term is expanded to
+const maxSynonyms = 10
+
+// Synonyms maps lowercase words to words that should also match them in
+// queries. Synonyms are applied at query time only, so the dictionary can be
+// changed without reindexing.
+type Synonyms map[string][]string
+
+// LoadSynonyms reads a synonym dictionary from the file at the given path. See
+// ParseSynonyms for the expected format.
+func LoadSynonyms(path string) (Synonyms, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSynonyms(f)
+}
+
+// ParseSynonyms reads a synonym dictionary with one group of equivalent words
+// per line, separated by commas - for example 'car, automobile, auto'. Blank
+// lines and lines starting with '#' are ignored. A word that appears in
+// several groups is a synonym of the words in all of them.
+func ParseSynonyms(r io.Reader) (Synonyms, error) {
+	var s = make(Synonyms)
+	var scanner = bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		var text = strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var group = make([]string, 0)
+		for _, w := range strings.Split(text, ",") {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				group = append(group, w)
+			}
+		}
+		if len(group) < 2 {
+			return nil, fmt.Errorf("line %d: synonym group needs at least two words", line)
+		}
+		for _, w := range group {
+			for _, o := range group {
+				if o != w && !contains(s[w], o) {
+					s[w] = append(s[w], o)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Expand returns the given terms followed by their synonyms. Synonyms of
+// synonyms are not included, and each term gains at most maxSynonyms
+// synonyms.
+func (s Synonyms) Expand(terms []string) []string {
+	var expanded = append(make([]string, 0, len(terms)), terms...)
+	for _, t := range terms {
+		var syns = s[strings.ToLower(strings.TrimSpace(t))]
+		if len(syns) > maxSynonyms {
+			syns = syns[:maxSynonyms]
+		}
+		for _, syn := range syns {
+			if !contains(expanded, syn) {
+				expanded = append(expanded, syn)
+			}
+		}
+	}
+	return expanded
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestParseSynonyms(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Synonyms
+		wantErr bool
+	}{
+		{"empty", "", Synonyms{}, false},
+		{"comments and blank lines", "# vehicles\n\ncar, Automobile\n", Synonyms{
+			"car":        {"automobile"},
+			"automobile": {"car"},
+		}, false},
+		{"overlapping groups", "car, automobile\ncar, auto", Synonyms{
+			"car":        {"automobile", "auto"},
+			"automobile": {"car"},
+			"auto":       {"car"},
+		}, false},
+		{"single word", "car\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSynonyms(strings.NewReader(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSynonyms() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSynonyms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynonyms_Expand(t *testing.T) {
+	var many = make([]string, 0)
+	for i := 0; i < maxSynonyms*2; i++ {
+		many = append(many, strings.Repeat("x", i+1))
+	}
+	var s = Synonyms{
+		"car":        {"automobile"},
+		"automobile": {"car", "motorcar"},
+		"thing":      many,
+	}
+	tests := []struct {
+		name  string
+		terms []string
+		want  []string
+	}{
+		{"no synonyms", []string{"boat"}, []string{"boat"}},
+		{"not transitive", []string{"Car"}, []string{"Car", "automobile"}},
+		{"no duplicates", []string{"car", "automobile"}, []string{"car", "automobile", "motorcar"}},
+		{"bounded", []string{"thing"}, append([]string{"thing"}, many[:maxSynonyms]...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Expand(tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Synonyms.Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Search_synonyms(t *testing.T) {
+	var l = zaptest.NewLogger(t).Sugar()
+	e, err := New(l, Opts{
+		StorePath: filepath.Join("tmp", t.Name()),
+		Queue: queue.Options{
+			Rate:      500 * time.Millisecond,
+			BatchSize: 1,
+		},
+		Synonyms: Synonyms{"car": {"automobile"}, "automobile": {"car"}},
+	})
+	if err != nil {
+		t.Error("failed to create engine: " + err.Error())
+		return
+	}
+	defer os.RemoveAll("tmp")
+	go e.Run()
+
+	e.Index(Document{&models.ObjectV2{Hash: "a"}, "a fast car", true})
+	e.Index(Document{&models.ObjectV2{Hash: "b"}, "a vintage automobile", true})
+	time.Sleep(time.Second)
+
+	tests := []struct {
+		name     string
+		synonyms bool
+		want     []string
+	}{
+		{"disabled", false, []string{"a"}},
+		{"enabled", true, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.Search(context.Background(), Query{
+				Required: []string{"car"},
+				Synonyms: tt.synonyms,
+			})
+			if err != nil {
+				t.Errorf("Engine.Search() error = %v", err)
+				return
+			}
+			var hashes = make([]string, len(got))
+			for i, r := range got {
+				hashes[i] = r.Hash
+			}
+			sort.Strings(hashes)
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("Engine.Search() = %v, want %v", hashes, tt.want)
+			}
+		})
+	}
+
+	e.Close()
+}
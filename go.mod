@@ -50,8 +50,9 @@ require (
 	github.com/syndtr/goleveldb v1.0.0 // indirect
 	github.com/tecbot/gorocksdb v0.0.0-20181010114359-8752a9433481 // indirect
 	github.com/tensorflow/tensorflow v1.12.0
-	go.etcd.io/bbolt v1.3.2 // indirect
+	go.etcd.io/bbolt v1.3.2
 	go.uber.org/zap v1.9.1
+	golang.org/x/image v0.0.0-20190227222117-0694c2d4d067
 	golang.org/x/lint v0.0.0-20190409202823-959b441ac422
 	google.golang.org/genproto v0.0.0-20190425155659-357c62f0e4bb // indirect
 	google.golang.org/grpc v1.20.1
@@ -4,6 +4,7 @@ package mocks
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/RTradeLtd/Lens/v2/engine"
 )
@@ -13,6 +14,63 @@ type FakeSearcher struct {
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct {
 	}
+	CountStub        func(context.Context, engine.Query) (uint64, error)
+	countMutex       sync.RWMutex
+	countArgsForCall []struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}
+	countReturns struct {
+		result1 uint64
+		result2 error
+	}
+	countReturnsOnCall map[int]struct {
+		result1 uint64
+		result2 error
+	}
+	FacetStub        func(context.Context, engine.Query) (*engine.Facets, error)
+	facetMutex       sync.RWMutex
+	facetArgsForCall []struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}
+	facetReturns struct {
+		result1 *engine.Facets
+		result2 error
+	}
+	facetReturnsOnCall map[int]struct {
+		result1 *engine.Facets
+		result2 error
+	}
+	GetStub        func(string) (*engine.Document, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		arg1 string
+	}
+	getReturns struct {
+		result1 *engine.Document
+		result2 error
+	}
+	getReturnsOnCall map[int]struct {
+		result1 *engine.Document
+		result2 error
+	}
+	HistogramStub        func(context.Context, time.Time, time.Time, time.Duration) ([]engine.Bucket, error)
+	histogramMutex       sync.RWMutex
+	histogramArgsForCall []struct {
+		arg1 context.Context
+		arg2 time.Time
+		arg3 time.Time
+		arg4 time.Duration
+	}
+	histogramReturns struct {
+		result1 []engine.Bucket
+		result2 error
+	}
+	histogramReturnsOnCall map[int]struct {
+		result1 []engine.Bucket
+		result2 error
+	}
 	IndexStub        func(engine.Document) error
 	indexMutex       sync.RWMutex
 	indexArgsForCall []struct {
@@ -35,6 +93,49 @@ type FakeSearcher struct {
 	isIndexedReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	ListStub        func(context.Context, int, int) ([]engine.Result, error)
+	listMutex       sync.RWMutex
+	listArgsForCall []struct {
+		arg1 context.Context
+		arg2 int
+		arg3 int
+	}
+	listReturns struct {
+		result1 []engine.Result
+		result2 error
+	}
+	listReturnsOnCall map[int]struct {
+		result1 []engine.Result
+		result2 error
+	}
+	ListMatchingStub        func(context.Context, engine.Query) ([]string, error)
+	listMatchingMutex       sync.RWMutex
+	listMatchingArgsForCall []struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}
+	listMatchingReturns struct {
+		result1 []string
+		result2 error
+	}
+	listMatchingReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	ListPrefixStub        func(context.Context, string) ([]string, error)
+	listPrefixMutex       sync.RWMutex
+	listPrefixArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	listPrefixReturns struct {
+		result1 []string
+		result2 error
+	}
+	listPrefixReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	RemoveStub        func(string) error
 	removeMutex       sync.RWMutex
 	removeArgsForCall []struct {
@@ -46,6 +147,34 @@ type FakeSearcher struct {
 	removeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RemoveMatchingStub        func(context.Context, engine.Query) (int, error)
+	removeMatchingMutex       sync.RWMutex
+	removeMatchingArgsForCall []struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}
+	removeMatchingReturns struct {
+		result1 int
+		result2 error
+	}
+	removeMatchingReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	RemovePrefixStub        func(context.Context, string) (int, error)
+	removePrefixMutex       sync.RWMutex
+	removePrefixArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	removePrefixReturns struct {
+		result1 int
+		result2 error
+	}
+	removePrefixReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
 	SearchStub        func(context.Context, engine.Query) ([]engine.Result, error)
 	searchMutex       sync.RWMutex
 	searchArgsForCall []struct {
@@ -60,6 +189,43 @@ type FakeSearcher struct {
 		result1 []engine.Result
 		result2 error
 	}
+	StatsStub        func(context.Context) (*engine.Stats, error)
+	statsMutex       sync.RWMutex
+	statsArgsForCall []struct {
+		arg1 context.Context
+	}
+	statsReturns struct {
+		result1 *engine.Stats
+		result2 error
+	}
+	statsReturnsOnCall map[int]struct {
+		result1 *engine.Stats
+		result2 error
+	}
+	SuggestStub        func(string) ([]engine.Suggestion, error)
+	suggestMutex       sync.RWMutex
+	suggestArgsForCall []struct {
+		arg1 string
+	}
+	suggestReturns struct {
+		result1 []engine.Suggestion
+		result2 error
+	}
+	suggestReturnsOnCall map[int]struct {
+		result1 []engine.Suggestion
+		result2 error
+	}
+	UnindexStub        func(string) error
+	unindexMutex       sync.RWMutex
+	unindexArgsForCall []struct {
+		arg1 string
+	}
+	unindexReturns struct {
+		result1 error
+	}
+	unindexReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -87,6 +253,263 @@ func (fake *FakeSearcher) CloseCalls(stub func()) {
 	fake.CloseStub = stub
 }
 
+func (fake *FakeSearcher) Count(arg1 context.Context, arg2 engine.Query) (uint64, error) {
+	fake.countMutex.Lock()
+	ret, specificReturn := fake.countReturnsOnCall[len(fake.countArgsForCall)]
+	fake.countArgsForCall = append(fake.countArgsForCall, struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}{arg1, arg2})
+	fake.recordInvocation("Count", []interface{}{arg1, arg2})
+	fake.countMutex.Unlock()
+	if fake.CountStub != nil {
+		return fake.CountStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.countReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) CountCallCount() int {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	return len(fake.countArgsForCall)
+}
+
+func (fake *FakeSearcher) CountCalls(stub func(context.Context, engine.Query) (uint64, error)) {
+	fake.countMutex.Lock()
+	defer fake.countMutex.Unlock()
+	fake.CountStub = stub
+}
+
+func (fake *FakeSearcher) CountArgsForCall(i int) (context.Context, engine.Query) {
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	argsForCall := fake.countArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) CountReturns(result1 uint64, result2 error) {
+	fake.countMutex.Lock()
+	defer fake.countMutex.Unlock()
+	fake.CountStub = nil
+	fake.countReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) CountReturnsOnCall(i int, result1 uint64, result2 error) {
+	fake.countMutex.Lock()
+	defer fake.countMutex.Unlock()
+	fake.CountStub = nil
+	if fake.countReturnsOnCall == nil {
+		fake.countReturnsOnCall = make(map[int]struct {
+			result1 uint64
+			result2 error
+		})
+	}
+	fake.countReturnsOnCall[i] = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) Facet(arg1 context.Context, arg2 engine.Query) (*engine.Facets, error) {
+	fake.facetMutex.Lock()
+	ret, specificReturn := fake.facetReturnsOnCall[len(fake.facetArgsForCall)]
+	fake.facetArgsForCall = append(fake.facetArgsForCall, struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}{arg1, arg2})
+	fake.recordInvocation("Facet", []interface{}{arg1, arg2})
+	fake.facetMutex.Unlock()
+	if fake.FacetStub != nil {
+		return fake.FacetStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.facetReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) FacetCallCount() int {
+	fake.facetMutex.RLock()
+	defer fake.facetMutex.RUnlock()
+	return len(fake.facetArgsForCall)
+}
+
+func (fake *FakeSearcher) FacetCalls(stub func(context.Context, engine.Query) (*engine.Facets, error)) {
+	fake.facetMutex.Lock()
+	defer fake.facetMutex.Unlock()
+	fake.FacetStub = stub
+}
+
+func (fake *FakeSearcher) FacetArgsForCall(i int) (context.Context, engine.Query) {
+	fake.facetMutex.RLock()
+	defer fake.facetMutex.RUnlock()
+	argsForCall := fake.facetArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) FacetReturns(result1 *engine.Facets, result2 error) {
+	fake.facetMutex.Lock()
+	defer fake.facetMutex.Unlock()
+	fake.FacetStub = nil
+	fake.facetReturns = struct {
+		result1 *engine.Facets
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) FacetReturnsOnCall(i int, result1 *engine.Facets, result2 error) {
+	fake.facetMutex.Lock()
+	defer fake.facetMutex.Unlock()
+	fake.FacetStub = nil
+	if fake.facetReturnsOnCall == nil {
+		fake.facetReturnsOnCall = make(map[int]struct {
+			result1 *engine.Facets
+			result2 error
+		})
+	}
+	fake.facetReturnsOnCall[i] = struct {
+		result1 *engine.Facets
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) Get(arg1 string) (*engine.Document, error) {
+	fake.getMutex.Lock()
+	ret, specificReturn := fake.getReturnsOnCall[len(fake.getArgsForCall)]
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Get", []interface{}{arg1})
+	fake.getMutex.Unlock()
+	if fake.GetStub != nil {
+		return fake.GetStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *FakeSearcher) GetCalls(stub func(string) (*engine.Document, error)) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = stub
+}
+
+func (fake *FakeSearcher) GetArgsForCall(i int) string {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	argsForCall := fake.getArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSearcher) GetReturns(result1 *engine.Document, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 *engine.Document
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) GetReturnsOnCall(i int, result1 *engine.Document, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	if fake.getReturnsOnCall == nil {
+		fake.getReturnsOnCall = make(map[int]struct {
+			result1 *engine.Document
+			result2 error
+		})
+	}
+	fake.getReturnsOnCall[i] = struct {
+		result1 *engine.Document
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) Histogram(arg1 context.Context, arg2 time.Time, arg3 time.Time, arg4 time.Duration) ([]engine.Bucket, error) {
+	fake.histogramMutex.Lock()
+	ret, specificReturn := fake.histogramReturnsOnCall[len(fake.histogramArgsForCall)]
+	fake.histogramArgsForCall = append(fake.histogramArgsForCall, struct {
+		arg1 context.Context
+		arg2 time.Time
+		arg3 time.Time
+		arg4 time.Duration
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Histogram", []interface{}{arg1, arg2, arg3, arg4})
+	fake.histogramMutex.Unlock()
+	if fake.HistogramStub != nil {
+		return fake.HistogramStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.histogramReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) HistogramCallCount() int {
+	fake.histogramMutex.RLock()
+	defer fake.histogramMutex.RUnlock()
+	return len(fake.histogramArgsForCall)
+}
+
+func (fake *FakeSearcher) HistogramCalls(stub func(context.Context, time.Time, time.Time, time.Duration) ([]engine.Bucket, error)) {
+	fake.histogramMutex.Lock()
+	defer fake.histogramMutex.Unlock()
+	fake.HistogramStub = stub
+}
+
+func (fake *FakeSearcher) HistogramArgsForCall(i int) (context.Context, time.Time, time.Time, time.Duration) {
+	fake.histogramMutex.RLock()
+	defer fake.histogramMutex.RUnlock()
+	argsForCall := fake.histogramArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeSearcher) HistogramReturns(result1 []engine.Bucket, result2 error) {
+	fake.histogramMutex.Lock()
+	defer fake.histogramMutex.Unlock()
+	fake.HistogramStub = nil
+	fake.histogramReturns = struct {
+		result1 []engine.Bucket
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) HistogramReturnsOnCall(i int, result1 []engine.Bucket, result2 error) {
+	fake.histogramMutex.Lock()
+	defer fake.histogramMutex.Unlock()
+	fake.HistogramStub = nil
+	if fake.histogramReturnsOnCall == nil {
+		fake.histogramReturnsOnCall = make(map[int]struct {
+			result1 []engine.Bucket
+			result2 error
+		})
+	}
+	fake.histogramReturnsOnCall[i] = struct {
+		result1 []engine.Bucket
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSearcher) Index(arg1 engine.Document) error {
 	fake.indexMutex.Lock()
 	ret, specificReturn := fake.indexReturnsOnCall[len(fake.indexArgsForCall)]
@@ -207,6 +630,199 @@ func (fake *FakeSearcher) IsIndexedReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
+func (fake *FakeSearcher) List(arg1 context.Context, arg2 int, arg3 int) ([]engine.Result, error) {
+	fake.listMutex.Lock()
+	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
+	fake.listArgsForCall = append(fake.listArgsForCall, struct {
+		arg1 context.Context
+		arg2 int
+		arg3 int
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("List", []interface{}{arg1, arg2, arg3})
+	fake.listMutex.Unlock()
+	if fake.ListStub != nil {
+		return fake.ListStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) ListCallCount() int {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return len(fake.listArgsForCall)
+}
+
+func (fake *FakeSearcher) ListCalls(stub func(context.Context, int, int) ([]engine.Result, error)) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = stub
+}
+
+func (fake *FakeSearcher) ListArgsForCall(i int) (context.Context, int, int) {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	argsForCall := fake.listArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeSearcher) ListReturns(result1 []engine.Result, result2 error) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = nil
+	fake.listReturns = struct {
+		result1 []engine.Result
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) ListReturnsOnCall(i int, result1 []engine.Result, result2 error) {
+	fake.listMutex.Lock()
+	defer fake.listMutex.Unlock()
+	fake.ListStub = nil
+	if fake.listReturnsOnCall == nil {
+		fake.listReturnsOnCall = make(map[int]struct {
+			result1 []engine.Result
+			result2 error
+		})
+	}
+	fake.listReturnsOnCall[i] = struct {
+		result1 []engine.Result
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) ListMatching(arg1 context.Context, arg2 engine.Query) ([]string, error) {
+	fake.listMatchingMutex.Lock()
+	ret, specificReturn := fake.listMatchingReturnsOnCall[len(fake.listMatchingArgsForCall)]
+	fake.listMatchingArgsForCall = append(fake.listMatchingArgsForCall, struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}{arg1, arg2})
+	fake.recordInvocation("ListMatching", []interface{}{arg1, arg2})
+	fake.listMatchingMutex.Unlock()
+	if fake.ListMatchingStub != nil {
+		return fake.ListMatchingStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listMatchingReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) ListMatchingCallCount() int {
+	fake.listMatchingMutex.RLock()
+	defer fake.listMatchingMutex.RUnlock()
+	return len(fake.listMatchingArgsForCall)
+}
+
+func (fake *FakeSearcher) ListMatchingCalls(stub func(context.Context, engine.Query) ([]string, error)) {
+	fake.listMatchingMutex.Lock()
+	defer fake.listMatchingMutex.Unlock()
+	fake.ListMatchingStub = stub
+}
+
+func (fake *FakeSearcher) ListMatchingArgsForCall(i int) (context.Context, engine.Query) {
+	fake.listMatchingMutex.RLock()
+	defer fake.listMatchingMutex.RUnlock()
+	argsForCall := fake.listMatchingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) ListMatchingReturns(result1 []string, result2 error) {
+	fake.listMatchingMutex.Lock()
+	defer fake.listMatchingMutex.Unlock()
+	fake.ListMatchingStub = nil
+	fake.listMatchingReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) ListMatchingReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.listMatchingMutex.Lock()
+	defer fake.listMatchingMutex.Unlock()
+	fake.ListMatchingStub = nil
+	if fake.listMatchingReturnsOnCall == nil {
+		fake.listMatchingReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listMatchingReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) ListPrefix(arg1 context.Context, arg2 string) ([]string, error) {
+	fake.listPrefixMutex.Lock()
+	ret, specificReturn := fake.listPrefixReturnsOnCall[len(fake.listPrefixArgsForCall)]
+	fake.listPrefixArgsForCall = append(fake.listPrefixArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("ListPrefix", []interface{}{arg1, arg2})
+	fake.listPrefixMutex.Unlock()
+	if fake.ListPrefixStub != nil {
+		return fake.ListPrefixStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.listPrefixReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) ListPrefixCallCount() int {
+	fake.listPrefixMutex.RLock()
+	defer fake.listPrefixMutex.RUnlock()
+	return len(fake.listPrefixArgsForCall)
+}
+
+func (fake *FakeSearcher) ListPrefixCalls(stub func(context.Context, string) ([]string, error)) {
+	fake.listPrefixMutex.Lock()
+	defer fake.listPrefixMutex.Unlock()
+	fake.ListPrefixStub = stub
+}
+
+func (fake *FakeSearcher) ListPrefixArgsForCall(i int) (context.Context, string) {
+	fake.listPrefixMutex.RLock()
+	defer fake.listPrefixMutex.RUnlock()
+	argsForCall := fake.listPrefixArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) ListPrefixReturns(result1 []string, result2 error) {
+	fake.listPrefixMutex.Lock()
+	defer fake.listPrefixMutex.Unlock()
+	fake.ListPrefixStub = nil
+	fake.listPrefixReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) ListPrefixReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.listPrefixMutex.Lock()
+	defer fake.listPrefixMutex.Unlock()
+	fake.ListPrefixStub = nil
+	if fake.listPrefixReturnsOnCall == nil {
+		fake.listPrefixReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listPrefixReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSearcher) Remove(arg1 string) error {
 	fake.removeMutex.Lock()
 	ret, specificReturn := fake.removeReturnsOnCall[len(fake.removeArgsForCall)]
@@ -267,6 +883,134 @@ func (fake *FakeSearcher) RemoveReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeSearcher) RemoveMatching(arg1 context.Context, arg2 engine.Query) (int, error) {
+	fake.removeMatchingMutex.Lock()
+	ret, specificReturn := fake.removeMatchingReturnsOnCall[len(fake.removeMatchingArgsForCall)]
+	fake.removeMatchingArgsForCall = append(fake.removeMatchingArgsForCall, struct {
+		arg1 context.Context
+		arg2 engine.Query
+	}{arg1, arg2})
+	fake.recordInvocation("RemoveMatching", []interface{}{arg1, arg2})
+	fake.removeMatchingMutex.Unlock()
+	if fake.RemoveMatchingStub != nil {
+		return fake.RemoveMatchingStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.removeMatchingReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) RemoveMatchingCallCount() int {
+	fake.removeMatchingMutex.RLock()
+	defer fake.removeMatchingMutex.RUnlock()
+	return len(fake.removeMatchingArgsForCall)
+}
+
+func (fake *FakeSearcher) RemoveMatchingCalls(stub func(context.Context, engine.Query) (int, error)) {
+	fake.removeMatchingMutex.Lock()
+	defer fake.removeMatchingMutex.Unlock()
+	fake.RemoveMatchingStub = stub
+}
+
+func (fake *FakeSearcher) RemoveMatchingArgsForCall(i int) (context.Context, engine.Query) {
+	fake.removeMatchingMutex.RLock()
+	defer fake.removeMatchingMutex.RUnlock()
+	argsForCall := fake.removeMatchingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) RemoveMatchingReturns(result1 int, result2 error) {
+	fake.removeMatchingMutex.Lock()
+	defer fake.removeMatchingMutex.Unlock()
+	fake.RemoveMatchingStub = nil
+	fake.removeMatchingReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) RemoveMatchingReturnsOnCall(i int, result1 int, result2 error) {
+	fake.removeMatchingMutex.Lock()
+	defer fake.removeMatchingMutex.Unlock()
+	fake.RemoveMatchingStub = nil
+	if fake.removeMatchingReturnsOnCall == nil {
+		fake.removeMatchingReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.removeMatchingReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) RemovePrefix(arg1 context.Context, arg2 string) (int, error) {
+	fake.removePrefixMutex.Lock()
+	ret, specificReturn := fake.removePrefixReturnsOnCall[len(fake.removePrefixArgsForCall)]
+	fake.removePrefixArgsForCall = append(fake.removePrefixArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("RemovePrefix", []interface{}{arg1, arg2})
+	fake.removePrefixMutex.Unlock()
+	if fake.RemovePrefixStub != nil {
+		return fake.RemovePrefixStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.removePrefixReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) RemovePrefixCallCount() int {
+	fake.removePrefixMutex.RLock()
+	defer fake.removePrefixMutex.RUnlock()
+	return len(fake.removePrefixArgsForCall)
+}
+
+func (fake *FakeSearcher) RemovePrefixCalls(stub func(context.Context, string) (int, error)) {
+	fake.removePrefixMutex.Lock()
+	defer fake.removePrefixMutex.Unlock()
+	fake.RemovePrefixStub = stub
+}
+
+func (fake *FakeSearcher) RemovePrefixArgsForCall(i int) (context.Context, string) {
+	fake.removePrefixMutex.RLock()
+	defer fake.removePrefixMutex.RUnlock()
+	argsForCall := fake.removePrefixArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeSearcher) RemovePrefixReturns(result1 int, result2 error) {
+	fake.removePrefixMutex.Lock()
+	defer fake.removePrefixMutex.Unlock()
+	fake.RemovePrefixStub = nil
+	fake.removePrefixReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) RemovePrefixReturnsOnCall(i int, result1 int, result2 error) {
+	fake.removePrefixMutex.Lock()
+	defer fake.removePrefixMutex.Unlock()
+	fake.RemovePrefixStub = nil
+	if fake.removePrefixReturnsOnCall == nil {
+		fake.removePrefixReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.removePrefixReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeSearcher) Search(arg1 context.Context, arg2 engine.Query) ([]engine.Result, error) {
 	fake.searchMutex.Lock()
 	ret, specificReturn := fake.searchReturnsOnCall[len(fake.searchArgsForCall)]
@@ -331,19 +1075,229 @@ func (fake *FakeSearcher) SearchReturnsOnCall(i int, result1 []engine.Result, re
 	}{result1, result2}
 }
 
+func (fake *FakeSearcher) Stats(arg1 context.Context) (*engine.Stats, error) {
+	fake.statsMutex.Lock()
+	ret, specificReturn := fake.statsReturnsOnCall[len(fake.statsArgsForCall)]
+	fake.statsArgsForCall = append(fake.statsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("Stats", []interface{}{arg1})
+	fake.statsMutex.Unlock()
+	if fake.StatsStub != nil {
+		return fake.StatsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.statsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) StatsCallCount() int {
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	return len(fake.statsArgsForCall)
+}
+
+func (fake *FakeSearcher) StatsCalls(stub func(context.Context) (*engine.Stats, error)) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = stub
+}
+
+func (fake *FakeSearcher) StatsArgsForCall(i int) context.Context {
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	argsForCall := fake.statsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSearcher) StatsReturns(result1 *engine.Stats, result2 error) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	fake.statsReturns = struct {
+		result1 *engine.Stats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) StatsReturnsOnCall(i int, result1 *engine.Stats, result2 error) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	if fake.statsReturnsOnCall == nil {
+		fake.statsReturnsOnCall = make(map[int]struct {
+			result1 *engine.Stats
+			result2 error
+		})
+	}
+	fake.statsReturnsOnCall[i] = struct {
+		result1 *engine.Stats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) Suggest(arg1 string) ([]engine.Suggestion, error) {
+	fake.suggestMutex.Lock()
+	ret, specificReturn := fake.suggestReturnsOnCall[len(fake.suggestArgsForCall)]
+	fake.suggestArgsForCall = append(fake.suggestArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Suggest", []interface{}{arg1})
+	fake.suggestMutex.Unlock()
+	if fake.SuggestStub != nil {
+		return fake.SuggestStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.suggestReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSearcher) SuggestCallCount() int {
+	fake.suggestMutex.RLock()
+	defer fake.suggestMutex.RUnlock()
+	fake.unindexMutex.RLock()
+	defer fake.unindexMutex.RUnlock()
+	return len(fake.suggestArgsForCall)
+}
+
+func (fake *FakeSearcher) SuggestCalls(stub func(string) ([]engine.Suggestion, error)) {
+	fake.suggestMutex.Lock()
+	defer fake.suggestMutex.Unlock()
+	fake.SuggestStub = stub
+}
+
+func (fake *FakeSearcher) SuggestArgsForCall(i int) string {
+	fake.suggestMutex.RLock()
+	defer fake.suggestMutex.RUnlock()
+	argsForCall := fake.suggestArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSearcher) SuggestReturns(result1 []engine.Suggestion, result2 error) {
+	fake.suggestMutex.Lock()
+	defer fake.suggestMutex.Unlock()
+	fake.SuggestStub = nil
+	fake.suggestReturns = struct {
+		result1 []engine.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) SuggestReturnsOnCall(i int, result1 []engine.Suggestion, result2 error) {
+	fake.suggestMutex.Lock()
+	defer fake.suggestMutex.Unlock()
+	fake.SuggestStub = nil
+	if fake.suggestReturnsOnCall == nil {
+		fake.suggestReturnsOnCall = make(map[int]struct {
+			result1 []engine.Suggestion
+			result2 error
+		})
+	}
+	fake.suggestReturnsOnCall[i] = struct {
+		result1 []engine.Suggestion
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSearcher) Unindex(arg1 string) error {
+	fake.unindexMutex.Lock()
+	ret, specificReturn := fake.unindexReturnsOnCall[len(fake.unindexArgsForCall)]
+	fake.unindexArgsForCall = append(fake.unindexArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Unindex", []interface{}{arg1})
+	fake.unindexMutex.Unlock()
+	if fake.UnindexStub != nil {
+		return fake.UnindexStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.unindexReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeSearcher) UnindexCallCount() int {
+	fake.unindexMutex.RLock()
+	defer fake.unindexMutex.RUnlock()
+	return len(fake.unindexArgsForCall)
+}
+
+func (fake *FakeSearcher) UnindexCalls(stub func(string) error) {
+	fake.unindexMutex.Lock()
+	defer fake.unindexMutex.Unlock()
+	fake.UnindexStub = stub
+}
+
+func (fake *FakeSearcher) UnindexArgsForCall(i int) string {
+	fake.unindexMutex.RLock()
+	defer fake.unindexMutex.RUnlock()
+	argsForCall := fake.unindexArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSearcher) UnindexReturns(result1 error) {
+	fake.unindexMutex.Lock()
+	defer fake.unindexMutex.Unlock()
+	fake.UnindexStub = nil
+	fake.unindexReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSearcher) UnindexReturnsOnCall(i int, result1 error) {
+	fake.unindexMutex.Lock()
+	defer fake.unindexMutex.Unlock()
+	fake.UnindexStub = nil
+	if fake.unindexReturnsOnCall == nil {
+		fake.unindexReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.unindexReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeSearcher) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
+	fake.countMutex.RLock()
+	defer fake.countMutex.RUnlock()
+	fake.facetMutex.RLock()
+	defer fake.facetMutex.RUnlock()
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	fake.histogramMutex.RLock()
+	defer fake.histogramMutex.RUnlock()
 	fake.indexMutex.RLock()
 	defer fake.indexMutex.RUnlock()
 	fake.isIndexedMutex.RLock()
 	defer fake.isIndexedMutex.RUnlock()
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	fake.listMatchingMutex.RLock()
+	defer fake.listMatchingMutex.RUnlock()
+	fake.listPrefixMutex.RLock()
+	defer fake.listPrefixMutex.RUnlock()
 	fake.removeMutex.RLock()
 	defer fake.removeMutex.RUnlock()
+	fake.removeMatchingMutex.RLock()
+	defer fake.removeMatchingMutex.RUnlock()
+	fake.removePrefixMutex.RLock()
+	defer fake.removePrefixMutex.RUnlock()
 	fake.searchMutex.RLock()
 	defer fake.searchMutex.RUnlock()
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	fake.suggestMutex.RLock()
+	defer fake.suggestMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
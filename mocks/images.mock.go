@@ -8,11 +8,12 @@ import (
 )
 
 type FakeTensorflowAnalyzer struct {
-	AnalyzeStub        func(string, []byte) (string, error)
+	AnalyzeStub        func(string, []byte, string) (string, error)
 	analyzeMutex       sync.RWMutex
 	analyzeArgsForCall []struct {
 		arg1 string
 		arg2 []byte
+		arg3 string
 	}
 	analyzeReturns struct {
 		result1 string
@@ -22,11 +23,22 @@ type FakeTensorflowAnalyzer struct {
 		result1 string
 		result2 error
 	}
+	ModelStub        func(string) string
+	modelMutex       sync.RWMutex
+	modelArgsForCall []struct {
+		arg1 string
+	}
+	modelReturns struct {
+		result1 string
+	}
+	modelReturnsOnCall map[int]struct {
+		result1 string
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeTensorflowAnalyzer) Analyze(arg1 string, arg2 []byte) (string, error) {
+func (fake *FakeTensorflowAnalyzer) Analyze(arg1 string, arg2 []byte, arg3 string) (string, error) {
 	var arg2Copy []byte
 	if arg2 != nil {
 		arg2Copy = make([]byte, len(arg2))
@@ -37,11 +49,12 @@ func (fake *FakeTensorflowAnalyzer) Analyze(arg1 string, arg2 []byte) (string, e
 	fake.analyzeArgsForCall = append(fake.analyzeArgsForCall, struct {
 		arg1 string
 		arg2 []byte
-	}{arg1, arg2Copy})
-	fake.recordInvocation("Analyze", []interface{}{arg1, arg2Copy})
+		arg3 string
+	}{arg1, arg2Copy, arg3})
+	fake.recordInvocation("Analyze", []interface{}{arg1, arg2Copy, arg3})
 	fake.analyzeMutex.Unlock()
 	if fake.AnalyzeStub != nil {
-		return fake.AnalyzeStub(arg1, arg2)
+		return fake.AnalyzeStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -56,17 +69,17 @@ func (fake *FakeTensorflowAnalyzer) AnalyzeCallCount() int {
 	return len(fake.analyzeArgsForCall)
 }
 
-func (fake *FakeTensorflowAnalyzer) AnalyzeCalls(stub func(string, []byte) (string, error)) {
+func (fake *FakeTensorflowAnalyzer) AnalyzeCalls(stub func(string, []byte, string) (string, error)) {
 	fake.analyzeMutex.Lock()
 	defer fake.analyzeMutex.Unlock()
 	fake.AnalyzeStub = stub
 }
 
-func (fake *FakeTensorflowAnalyzer) AnalyzeArgsForCall(i int) (string, []byte) {
+func (fake *FakeTensorflowAnalyzer) AnalyzeArgsForCall(i int) (string, []byte, string) {
 	fake.analyzeMutex.RLock()
 	defer fake.analyzeMutex.RUnlock()
 	argsForCall := fake.analyzeArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeTensorflowAnalyzer) AnalyzeReturns(result1 string, result2 error) {
@@ -95,11 +108,73 @@ func (fake *FakeTensorflowAnalyzer) AnalyzeReturnsOnCall(i int, result1 string,
 	}{result1, result2}
 }
 
+func (fake *FakeTensorflowAnalyzer) Model(arg1 string) string {
+	fake.modelMutex.Lock()
+	ret, specificReturn := fake.modelReturnsOnCall[len(fake.modelArgsForCall)]
+	fake.modelArgsForCall = append(fake.modelArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Model", []interface{}{arg1})
+	fake.modelMutex.Unlock()
+	if fake.ModelStub != nil {
+		return fake.ModelStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.modelReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeTensorflowAnalyzer) ModelCallCount() int {
+	fake.modelMutex.RLock()
+	defer fake.modelMutex.RUnlock()
+	return len(fake.modelArgsForCall)
+}
+
+func (fake *FakeTensorflowAnalyzer) ModelCalls(stub func(string) string) {
+	fake.modelMutex.Lock()
+	defer fake.modelMutex.Unlock()
+	fake.ModelStub = stub
+}
+
+func (fake *FakeTensorflowAnalyzer) ModelArgsForCall(i int) string {
+	fake.modelMutex.RLock()
+	defer fake.modelMutex.RUnlock()
+	argsForCall := fake.modelArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTensorflowAnalyzer) ModelReturns(result1 string) {
+	fake.modelMutex.Lock()
+	defer fake.modelMutex.Unlock()
+	fake.ModelStub = nil
+	fake.modelReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeTensorflowAnalyzer) ModelReturnsOnCall(i int, result1 string) {
+	fake.modelMutex.Lock()
+	defer fake.modelMutex.Unlock()
+	fake.ModelStub = nil
+	if fake.modelReturnsOnCall == nil {
+		fake.modelReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.modelReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
 func (fake *FakeTensorflowAnalyzer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.analyzeMutex.RLock()
 	defer fake.analyzeMutex.RUnlock()
+	fake.modelMutex.RLock()
+	defer fake.modelMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,43 @@
+package models
+
+import "strings"
+
+// CategorySeparator separates the segments of hierarchical categories, ie
+// 'document/legal/contract'. Flat categories are single-segment paths.
+const CategorySeparator = "/"
+
+// CategoryPaths returns the given category and each of its parent categories,
+// from the top level down - ie 'document', 'document/legal', and
+// 'document/legal/contract'. Empty segments are dropped.
+func CategoryPaths(category string) []string {
+	var segments = strings.Split(category, CategorySeparator)
+	var paths = make([]string, 0, len(segments))
+	var path string
+	for _, s := range segments {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if path != "" {
+			path += CategorySeparator
+		}
+		path += s
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// InCategory checks if category is parent, or one of its sub-categories.
+// Categories are compared case-insensitively.
+func InCategory(category, parent string) bool {
+	var paths = CategoryPaths(parent)
+	if len(paths) == 0 {
+		return false
+	}
+	parent = paths[len(paths)-1]
+	for _, p := range CategoryPaths(category) {
+		if strings.EqualFold(p, parent) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryPaths(t *testing.T) {
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{"", []string{}},
+		{"document", []string{"document"}},
+		{"document/legal/contract", []string{"document", "document/legal", "document/legal/contract"}},
+		{"/document//legal /", []string{"document", "document/legal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := CategoryPaths(tt.category); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CategoryPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		parent   string
+		want     bool
+	}{
+		{"document", "document", true},
+		{"document/legal/contract", "document", true},
+		{"document/legal/contract", "Document/Legal/", true},
+		{"documents", "document", false},
+		{"document", "document/legal", false},
+		{"document", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category+" in "+tt.parent, func(t *testing.T) {
+			if got := InCategory(tt.category, tt.parent); got != tt.want {
+				t.Errorf("InCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
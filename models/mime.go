@@ -13,4 +13,8 @@ const (
 	MimeTypeDocument = "document"
 	// MimeTypeImage is an image asset
 	MimeTypeImage = "image"
+	// MimeTypeArchive is a zip or tar archive
+	MimeTypeArchive = "archive"
+	// MimeTypeMedia is an audio or video file
+	MimeTypeMedia = "media"
 )
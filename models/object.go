@@ -1,10 +1,33 @@
 package models
 
+import "time"
+
 // ObjectV2 is a distributed web object (ie, ipld)
 type ObjectV2 struct {
-	// Hash is how you identify the object on its network, ie content hash
+	// Hash is how you identify the object on its network, ie content hash. It
+	// is always the hash the object was indexed with, and can be used to fetch
+	// the original content - except for archive members, which are identified
+	// by '<archive hash>/<member path>' and record the archive's hash in the
+	// 'archive' property.
 	Hash string `json:"content_hash"`
 
 	// MD is metadata associated with the object
 	MD MetaDataV2 `json:"meta"`
+
+	// History lists previous versions of the object's metadata, oldest first
+	History []Revision `json:"history,omitempty"`
+
+	// Indexed is when the object's content was last indexed. It is kept when
+	// an object with it set is stored again, so that metadata-only updates do
+	// not change it.
+	Indexed time.Time `json:"indexed"`
+}
+
+// Revision is a previous version of an object's metadata
+type Revision struct {
+	// Replaced is when this version was superseded by a reindex
+	Replaced time.Time `json:"replaced"`
+
+	// MD is the metadata as it was before being replaced
+	MD MetaDataV2 `json:"meta"`
 }
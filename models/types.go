@@ -1,9 +1,200 @@
 package models
 
+import (
+	"math"
+	"strings"
+)
+
 // MetaDataV2 is a piece of meta data from a given object after being lensed
 type MetaDataV2 struct {
 	DisplayName string   `json:"display_name"`
 	MimeType    string   `json:"mime_type"`
 	Category    string   `json:"category"`
 	Tags        []string `json:"tags"`
+
+	// Classification is the label assigned by image classification, which is
+	// also included in Tags
+	Classification string `json:"classification,omitempty"`
+
+	// Pages is the number of pages in multi-page documents, such as TIFF images
+	Pages int `json:"pages,omitempty"`
+	// Truncated indicates that only part of the object's content was indexed,
+	// for example because it exceeds a configured page limit
+	Truncated bool `json:"truncated,omitempty"`
+	// Sampled indicates that only a leading sample of the object's text was
+	// indexed, because it exceeds the configured sample size
+	Sampled bool `json:"sampled,omitempty"`
+
+	// Properties are arbitrary user-provided key-value pairs
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// Location is where the object was made, ie from the GPS data of a photo
+	Location *Location `json:"location,omitempty"`
+
+	// Thumbnail is a reference to a preview of the object - either the hash of
+	// the preview on IPFS, or a base64-encoded data URI
+	Thumbnail string `json:"thumbnail,omitempty"`
+
+	// ExtractedText is the hash of the text extracted from the object on IPFS,
+	// if Lens is configured to store it
+	ExtractedText string `json:"extracted_text,omitempty"`
+
+	// Stale indicates that the object could not be retrieved during the last
+	// reachability check
+	Stale bool `json:"stale,omitempty"`
+
+	// Deleted indicates that the object was soft-deleted - deleted objects are
+	// excluded from search, and retained until they are restored or purged
+	Deleted bool `json:"deleted,omitempty"`
+	// DeletedAt is when the object was soft-deleted, in RFC3339 format
+	DeletedAt string `json:"deleted_at,omitempty"`
+
+	// Provenance records how the object was analyzed
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance denotes the analyzers and settings used to produce an object's
+// metadata. It is populated by Lens, and cannot be set by clients.
+type Provenance struct {
+	// LensVersion is the version of Lens that indexed the object
+	LensVersion string `json:"lens_version,omitempty"`
+	// Method is the extraction method used, ie 'text', 'log', 'pdf', 'image',
+	// 'media', or 'archive'
+	Method string `json:"method,omitempty"`
+	// ImageModel is the name of the image classification model used
+	ImageModel string `json:"image_model,omitempty"`
+	// SummaryRatio is the ratio passed to the summarizer, if one was used
+	SummaryRatio float64 `json:"summary_ratio,omitempty"`
+	// SummaryTruncated indicates that only a leading portion of the object's
+	// text was summarized, because it exceeds the configured summary input
+	// limit - all of the text is still indexed
+	SummaryTruncated bool `json:"summary_truncated,omitempty"`
+	// SummaryFallback indicates that keywords were produced by the fallback
+	// summarizer, because the primary summarizer found none
+	SummaryFallback bool `json:"summary_fallback,omitempty"`
+	// ShortContent indicates that the object's text was too short to
+	// summarize, so its words were used as keywords directly
+	ShortContent bool `json:"short_content,omitempty"`
+	// TextMode is the mode content was indexed with - either TextModeRaw, or
+	// empty for standard analysis
+	TextMode string `json:"text_mode,omitempty"`
+	// Source is the address of the IPFS node the object's content was
+	// retrieved through, if recorded
+	Source string `json:"source,omitempty"`
+}
+
+// TextModeRaw indexes content verbatim, without stop word removal
+const TextModeRaw = "raw"
+
+// Location is a geographic position, in decimal degrees
+type Location struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+}
+
+// earthRadius is the mean radius of the earth, in kilometres
+const earthRadius = 6371.0088
+
+// Valid checks that the location's coordinates are in range
+func (l Location) Valid() bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 &&
+		l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// Distance returns the great-circle distance to the given location, in
+// kilometres
+func (l Location) Distance(to Location) float64 {
+	var rad = func(deg float64) float64 { return deg * math.Pi / 180 }
+	var dLat, dLon = rad(to.Latitude - l.Latitude), rad(to.Longitude - l.Longitude)
+	var h = math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(l.Latitude))*math.Cos(rad(to.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// MetaDataPatch denotes changes to apply to existing metadata. Empty fields
+// are left untouched.
+type MetaDataPatch struct {
+	// DisplayName replaces the existing display name
+	DisplayName string
+	// Category overrides the detected category
+	Category string
+	// Tags are added to the existing set of tags
+	Tags []string
+	// RemoveTags are removed from the existing set of tags, ignoring case.
+	// Removals are applied after additions.
+	RemoveTags []string
+	// Properties are merged into existing properties
+	Properties map[string]string
+}
+
+// Apply merges the patch into the given metadata, and reports whether the
+// metadata was changed
+func (p *MetaDataPatch) Apply(md *MetaDataV2) (changed bool) {
+	if p.DisplayName != "" && p.DisplayName != md.DisplayName {
+		md.DisplayName = p.DisplayName
+		changed = true
+	}
+	if p.Category != "" && p.Category != md.Category {
+		md.Category = p.Category
+		changed = true
+	}
+	// tags are copied on write, so md is only modified if they change
+	var tags = md.Tags
+	for _, t := range p.Tags {
+		var exists bool
+		for _, existing := range tags {
+			if existing == t {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tags = append(tags[:len(tags):len(tags)], t)
+		}
+	}
+	if len(p.RemoveTags) > 0 {
+		var kept = make([]string, 0, len(tags))
+		for _, existing := range tags {
+			var removed bool
+			for _, t := range p.RemoveTags {
+				if strings.EqualFold(existing, t) {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				kept = append(kept, existing)
+			}
+		}
+		tags = kept
+	}
+	if !equalTags(tags, md.Tags) {
+		md.Tags = tags
+		changed = true
+	}
+	if len(p.Properties) > 0 {
+		if md.Properties == nil {
+			md.Properties = make(map[string]string, len(p.Properties))
+		}
+		for k, v := range p.Properties {
+			if existing, ok := md.Properties[k]; !ok || existing != v {
+				md.Properties[k] = v
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// equalTags checks if a and b contain the same tags in the same order
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMetaDataPatch_Apply(t *testing.T) {
+	var md = func() MetaDataV2 {
+		return MetaDataV2{
+			DisplayName: "cat.jpg",
+			Category:    "image",
+			Tags:        []string{"cat", "Fluffy"},
+			Properties:  map[string]string{"colour": "orange"},
+		}
+	}
+	tests := []struct {
+		name        string
+		patch       MetaDataPatch
+		wantTags    []string
+		wantChanged bool
+	}{
+		{"empty", MetaDataPatch{}, []string{"cat", "Fluffy"}, false},
+		{"same values", MetaDataPatch{
+			DisplayName: "cat.jpg",
+			Category:    "image",
+			Tags:        []string{"cat"},
+			Properties:  map[string]string{"colour": "orange"},
+		}, []string{"cat", "Fluffy"}, false},
+		{"remove missing tag", MetaDataPatch{RemoveTags: []string{"dog"}},
+			[]string{"cat", "Fluffy"}, false},
+		{"add tag", MetaDataPatch{Tags: []string{"orange"}},
+			[]string{"cat", "Fluffy", "orange"}, true},
+		{"remove tag ignoring case", MetaDataPatch{RemoveTags: []string{"fluffy"}},
+			[]string{"cat"}, true},
+		{"removals after additions", MetaDataPatch{
+			Tags:       []string{"orange"},
+			RemoveTags: []string{"orange"},
+		}, []string{"cat", "Fluffy"}, false},
+		{"property", MetaDataPatch{Properties: map[string]string{"colour": "black"}},
+			[]string{"cat", "Fluffy"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got = md()
+			if changed := tt.patch.Apply(&got); changed != tt.wantChanged {
+				t.Errorf("MetaDataPatch.Apply() = %v, want %v", changed, tt.wantChanged)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.wantTags) {
+				t.Errorf("MetaDataPatch.Apply() tags = %v, want %v", got.Tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestLocation_Distance(t *testing.T) {
+	var vancouver = Location{Latitude: 49.2827, Longitude: -123.1207}
+	tests := []struct {
+		name string
+		to   Location
+		want float64 // approximate distance in km
+	}{
+		{"same", vancouver, 0},
+		{"seattle", Location{Latitude: 47.6062, Longitude: -122.3321}, 195},
+		{"london", Location{Latitude: 51.5074, Longitude: -0.1278}, 7574},
+		{"antipode", Location{Latitude: -49.2827, Longitude: 56.8793}, 20015},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vancouver.Distance(tt.to); math.Abs(got-tt.want) > tt.want*0.01+0.001 {
+				t.Errorf("Location.Distance() = %v, want ~%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocation_Valid(t *testing.T) {
+	tests := []struct {
+		name string
+		l    Location
+		want bool
+	}{
+		{"origin", Location{}, true},
+		{"bounds", Location{Latitude: -90, Longitude: 180}, true},
+		{"latitude", Location{Latitude: 91}, false},
+		{"longitude", Location{Longitude: -181}, false},
+		{"nan", Location{Latitude: math.NaN()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.Valid(); got != tt.want {
+				t.Errorf("Location.Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,7 +15,18 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-func options(certpath, keypath, token string, logger *zap.SugaredLogger) ([]grpc.ServerOption, error) {
+// Limits declares message size and rate limits for the gRPC server. Zero
+// message sizes use gRPC defaults.
+type Limits struct {
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+
+	// IndexRate and SearchRate limit index and search requests per client
+	IndexRate  RateLimit
+	SearchRate RateLimit
+}
+
+func options(certpath, keypath, token string, limits Limits, logger *zap.SugaredLogger) ([]grpc.ServerOption, error) {
 	if token == "" || len(token) < 5 {
 		return nil, fmt.Errorf("token '%s' is too short for safe use", token)
 	}
@@ -40,13 +51,22 @@ func options(certpath, keypath, token string, logger *zap.SugaredLogger) ([]grpc
 		grpc_middleware.WithUnaryServerChain(
 			unaryIntercept,
 			grpc_ctxtags.UnaryServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
-			grpc_zap.UnaryServerInterceptor(grpcLogger, zapOpts...)),
+			grpc_zap.UnaryServerInterceptor(grpcLogger, zapOpts...),
+			newRateLimitInterceptor(limits.IndexRate, limits.SearchRate)),
 		grpc_middleware.WithStreamServerChain(
 			streamInterceptor,
 			grpc_ctxtags.StreamServerInterceptor(grpc_ctxtags.WithFieldExtractor(grpc_ctxtags.CodeGenRequestFieldExtractor)),
 			grpc_zap.StreamServerInterceptor(grpcLogger, zapOpts...)),
 	}
 
+	// set up message size limits
+	if limits.MaxRecvMsgSize > 0 {
+		serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(limits.MaxRecvMsgSize))
+	}
+	if limits.MaxSendMsgSize > 0 {
+		serverOpts = append(serverOpts, grpc.MaxSendMsgSize(limits.MaxSendMsgSize))
+	}
+
 	// set up tls configuration
 	if certpath != "" {
 		logger.Infow("setting up TLS",
@@ -13,6 +13,7 @@ func Test_options(t *testing.T) {
 		certpath string
 		keypath  string
 		token    string
+		limits   Limits
 		logger   *zap.SugaredLogger
 	}
 	tests := []struct {
@@ -22,22 +23,24 @@ func Test_options(t *testing.T) {
 		wantErr     bool
 	}{
 		{"token too short",
-			args{"", "", "", l}, 0, true},
+			args{"", "", "", Limits{}, l}, 0, true},
 		{"no logger provided",
-			args{"", "", "asdfasdf", nil}, 0, true},
+			args{"", "", "asdfasdf", Limits{}, nil}, 0, true},
 		{"invalid tls",
-			args{"../README.md", "", "asdfasdf", l}, 0, true},
+			args{"../README.md", "", "asdfasdf", Limits{}, l}, 0, true},
 		{"ok: no tls",
-			args{"", "", "asdfasdf", l}, 2, false},
+			args{"", "", "asdfasdf", Limits{}, l}, 2, false},
+		{"ok: with limits",
+			args{"", "", "asdfasdf", Limits{MaxRecvMsgSize: 1024, MaxSendMsgSize: 2048}, l}, 4, false},
 		// disabled for now
 		/*
 			{"ok: with tls",
-				args{"../test/certs/crt", "../test/certs/key", "asdfasdf", l}, 3, false},
+				args{"../test/certs/crt", "../test/certs/key", "asdfasdf", Limits{}, l}, 3, false},
 		*/
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := options(tt.args.certpath, tt.args.keypath, tt.args.token, tt.args.logger)
+			got, err := options(tt.args.certpath, tt.args.keypath, tt.args.token, tt.args.limits, tt.args.logger)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("options() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"go.uber.org/zap"
+)
+
+// Pprof returns a job that serves runtime profiles on the given address under
+// '/debug/pprof/', and variables published with expvar under '/debug/vars'.
+// The profiles expose internals of the running process, so the address should
+// not be publicly reachable.
+func Pprof(addr string, l *zap.SugaredLogger) Job {
+	return func(ctx context.Context) {
+		var mux = http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/vars", expvar.Handler())
+		var srv = &http.Server{Addr: addr, Handler: mux}
+
+		go func() {
+			<-ctx.Done()
+			srv.Close()
+		}()
+
+		l.Infow("serving runtime profiles", "address", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			l.Errorw("failed to serve runtime profiles", "error", err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPprof(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan struct{})
+	go func() {
+		Pprof("127.0.0.1:6061", zaptest.NewLogger(t).Sugar())(ctx)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, path := range []string{"/debug/pprof/goroutine?debug=1", "/debug/vars"} {
+		resp, err := http.Get("http://127.0.0.1:6061" + path)
+		if err != nil {
+			t.Errorf("failed to retrieve %s: %v", path, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("got status %d for %s, want %d", resp.StatusCode, path, http.StatusOK)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("job did not stop after context was cancelled")
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimit declares a per-client token bucket rate limit
+type RateLimit struct {
+	// Rate is the number of requests per second each client may make - leave at
+	// 0 for no limit
+	Rate float64
+	// Burst is the number of requests a client may make at once - defaults to 1
+	Burst int
+}
+
+// pruneInterval is how often idle clients are forgotten
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter tracks a token bucket per client. A nil rateLimiter allows all
+// requests.
+type rateLimiter struct {
+	limit RateLimit
+	now   func() time.Time
+
+	mux       sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// newRateLimiter returns nil if limit.Rate is not positive
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &rateLimiter{
+		limit:     limit,
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// allow reports whether the given client may make another request
+func (r *rateLimiter) allow(client string) bool {
+	if r == nil {
+		return true
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var now = r.now()
+	var burst = float64(r.limit.Burst)
+	if now.Sub(r.lastPrune) > pruneInterval {
+		for c, b := range r.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*r.limit.Rate >= burst {
+				delete(r.buckets, c)
+			}
+		}
+		r.lastPrune = now
+	}
+
+	var b, ok = r.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		r.buckets[client] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * r.limit.Rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// clientID identifies the client of a request by its address, excluding the
+// port. All clients share the same authentication token, so it cannot be used
+// to tell clients apart.
+func clientID(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	var addr = p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// newRateLimitInterceptor rejects index and search requests from clients that
+// exceed the configured limits with codes.ResourceExhausted
+func newRateLimitInterceptor(index, search RateLimit) grpc.UnaryServerInterceptor {
+	var limiters = map[string]*rateLimiter{
+		"Index":  newRateLimiter(index),
+		"Search": newRateLimiter(search),
+	}
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var method = info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		if limiter := limiters[method]; !limiter.allow(clientID(ctx)) {
+			return nil, status.Errorf(codes.ResourceExhausted,
+				"rate limit for %s requests exceeded", strings.ToLower(method))
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func Test_rateLimiter(t *testing.T) {
+	if r := newRateLimiter(RateLimit{}); r != nil || !r.allow("a") {
+		t.Error("expected disabled rate limiter to allow requests")
+	}
+
+	var now = time.Now()
+	var r = newRateLimiter(RateLimit{Rate: 2, Burst: 2})
+	r.now = func() time.Time { return now }
+	var steps = []struct {
+		name    string
+		elapsed time.Duration
+		client  string
+		want    bool
+	}{
+		{"first in burst", 0, "a", true},
+		{"second in burst", 0, "a", true},
+		{"burst exhausted", 0, "a", false},
+		{"other client", 0, "b", true},
+		{"not yet refilled", 100 * time.Millisecond, "a", false},
+		{"refilled", 500 * time.Millisecond, "a", true},
+		{"refill does not exceed burst", time.Hour, "a", true},
+		{"second after refill", 0, "a", true},
+		{"exhausted after refill", 0, "a", false},
+	}
+	for _, s := range steps {
+		now = now.Add(s.elapsed)
+		if got := r.allow(s.client); got != s.want {
+			t.Errorf("%s: allow() = %v, want %v", s.name, got, s.want)
+		}
+	}
+	if _, ok := r.buckets["b"]; ok {
+		t.Error("expected idle client to be pruned")
+	}
+}
+
+func Test_newRateLimitInterceptor(t *testing.T) {
+	var intercept = newRateLimitInterceptor(RateLimit{Rate: 1}, RateLimit{})
+	var handler = func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	var call = func(method, addr string) error {
+		var ctx = peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1234},
+		})
+		_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	if err := call("/lensv2.LensV2/Index", "10.0.0.1"); err != nil {
+		t.Errorf("first index request error = %v", err)
+	}
+	if err := call("/lensv2.LensV2/Index", "10.0.0.1"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second index request error = %v, want ResourceExhausted", err)
+	}
+	if err := call("/lensv2.LensV2/Index", "10.0.0.2"); err != nil {
+		t.Errorf("index request from other client error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := call("/lensv2.LensV2/Search", "10.0.0.1"); err != nil {
+			t.Errorf("unlimited search request error = %v", err)
+		}
+	}
+}
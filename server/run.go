@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"net"
+	"sync"
 
 	"github.com/RTradeLtd/config/v2"
 	"github.com/RTradeLtd/grpc/lensv2"
@@ -9,13 +11,25 @@ import (
 	"google.golang.org/grpc"
 )
 
-// RunV2 spins up the V2 Lens gRPC server
-func RunV2(stop <-chan bool, l *zap.SugaredLogger, srv lensv2.LensV2Server, cfg config.Lens) error {
+// Job denotes a background process to run alongside the server. Jobs should
+// return once the given context is cancelled.
+type Job func(ctx context.Context)
+
+// RunV2 spins up the V2 Lens gRPC server, as well as the given background jobs
+func RunV2(
+	stop <-chan bool,
+	l *zap.SugaredLogger,
+	srv lensv2.LensV2Server,
+	cfg config.Lens,
+	limits Limits,
+	jobs ...Job,
+) error {
 	// instantiate server settings
 	serverOpts, err := options(
 		cfg.TLS.CertPath,
 		cfg.TLS.KeyFile,
 		cfg.AuthKey,
+		limits,
 		l)
 	if err != nil {
 		return err
@@ -32,12 +46,22 @@ func RunV2(stop <-chan bool, l *zap.SugaredLogger, srv lensv2.LensV2Server, cfg
 	gServer := grpc.NewServer(serverOpts...)
 	lensv2.RegisterLensV2Server(gServer, srv)
 
+	// start background jobs
+	var ctx, cancel = context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j Job) { j(ctx); wg.Done() }(j)
+	}
+	defer func() { cancel(); wg.Wait() }()
+
 	// interrupt server gracefully if context is cancelled
 	go func() {
 		for {
 			select {
 			case <-stop:
 				l.Info("shutting down server")
+				cancel()
 				gServer.GracefulStop()
 				return
 			}
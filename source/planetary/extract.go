@@ -1,6 +1,12 @@
 package planetary
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+
 	"github.com/RTradeLtd/rtfs/v2"
 )
 
@@ -25,3 +31,22 @@ func (e *Extractor) ExtractObject(contentHash string, out interface{}) error {
 func (e *Extractor) ExtractContents(contentHash string) ([]byte, error) {
 	return e.im.Cat(contentHash)
 }
+
+// PeekContents returns at most the first n bytes of the contents of the ipld
+// object, without retrieving the rest of it
+func (e *Extractor) PeekContents(ctx context.Context, contentHash string, n int) ([]byte, error) {
+	// rtfs.Manager has no ranged read, so the API is called directly
+	resp, err := e.im.CustomRequest(ctx, e.im.NodeAddress(), "cat",
+		map[string]string{"length": strconv.Itoa(n)}, contentHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("no response from IPFS")
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Output, int64(n)))
+}
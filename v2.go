@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -14,11 +16,21 @@ import (
 
 	"github.com/RTradeLtd/Lens/v2/analyzer/images"
 	"github.com/RTradeLtd/Lens/v2/analyzer/ocr"
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
 	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/models"
 	"github.com/RTradeLtd/Lens/v2/source/planetary"
 )
 
 // V2 is the new Lens API, and implements the LensV2 gRPC interface directly.
+//
+// V2 also provides methods that the LensV2 service definition does not have
+// RPCs for yet, ie Count, IndexAsync or Purge, which are only available to
+// programs that embed V2 and are not gated by the token authentication of the
+// gRPC server.
+//
+// TODO: expose these methods as RPCs once the LensV2 service definition
+// supports them
 type V2 struct {
 	se   engine.Searcher
 	ipfs rtfs.Manager
@@ -27,6 +39,59 @@ type V2 struct {
 	oc *ocr.Analyzer
 	px *planetary.Extractor
 	tf images.TensorflowAnalyzer
+	sm text.Summarizer
+
+	// smFallback is used when sm finds no keywords
+	smFallback text.Summarizer
+
+	summaryRatio     float64
+	summaryRatios    map[string]float64
+	minSummaryLength int
+	maxSummaryInput  int
+	sampleSize       int
+
+	// version is recorded in the provenance of indexed objects
+	version string
+	// reuseAnalysis skips analysis when reindexing objects already analyzed by
+	// this version of Lens
+	reuseAnalysis bool
+
+	// Request management
+	indexLimit   *limiter
+	indexTimeout time.Duration
+	jobs         *jobQueue
+	excludeStale bool
+	mergeTagCase bool
+	nameTags     bool
+	detectLogs   bool
+	synonyms     bool
+	rawText      []string
+	searchOrder  engine.Order
+	pdfImages    int
+	thumbnails   ThumbnailOpts
+	archives     ArchiveOpts
+	links        LinkOpts
+	urls         URLOpts
+	pinContent   bool
+	keywords     KeywordLimit
+	filter       ContentFilter
+	categories   map[string]string
+
+	categoryRules  []CategoryRule
+	storeExtracted bool
+	hyphenation    *text.Hyphenation
+	values         text.Values
+	emphasis       text.Emphasis
+	minImageSize   ImageSizeOpts
+	recordSource   bool
+	policies       map[string]AnalysisPolicy
+	softDelete     bool
+	geotags        bool
+
+	stats       statsCache
+	searchCache *searchCache
+	rejections  *rejectionCounter
+	maintenance uint32 // accessed atomically - see SetMaintenance
 
 	l *zap.SugaredLogger
 }
@@ -34,10 +99,192 @@ type V2 struct {
 // V2Options denotes options for the V2 Lens API
 type V2Options struct {
 	TesseractConfigPath string
+	OCR                 ocr.Options
+
+	// Version is the Lens version recorded in the provenance of indexed objects
+	Version string
+
+	// Summarizer, if set, extracts keywords from the text of documents, which
+	// are added to their tags
+	Summarizer text.Summarizer
+	// FallbackSummarizer, if set, is used for documents the Summarizer finds
+	// no keywords in
+	FallbackSummarizer text.Summarizer
+	// SummaryRatio is passed to the Summarizer - defaults to text.DefaultRatio
+	SummaryRatio float64
+	// SummaryRatios overrides SummaryRatio for specific content. Keys may be
+	// full mime types (ie 'application/pdf'), top-level types (ie 'text'), or
+	// categories, and ratios must be in (0,1].
+	SummaryRatios map[string]float64
+	// MinSummaryLength is the minimum length in bytes of text to summarize -
+	// the words of shorter text are added to tags directly
+	MinSummaryLength int
+	// MaxSummaryInput is the maximum number of bytes of each object's text to
+	// pass to the summarizer, which bounds the time spent summarizing large
+	// documents. Unlike SampleSize, all text is still indexed. Leave at 0 to
+	// summarize all text.
+	MaxSummaryInput int
+	// SampleSize is the maximum number of bytes of text to index from each
+	// text document or PDF - objects with more text are indexed from a leading
+	// sample and flagged as sampled. Leave at 0 to index all text.
+	SampleSize int
+
+	// MaxIndexInFlight limits the number of index requests that may be
+	// processed at once - leave at 0 for no limit
+	MaxIndexInFlight int
+	// MaxIndexQueued limits the number of index requests that may wait for
+	// processing when MaxIndexInFlight is reached - further requests are
+	// rejected with codes.ResourceExhausted
+	MaxIndexQueued int
+
+	// IndexTimeout bounds the total time spent processing an index request,
+	// excluding time spent waiting for a free slot - leave at 0 for no limit
+	IndexTimeout time.Duration
+
+	// AsyncIndex configures background workers for IndexAsync
+	AsyncIndex AsyncOpts
+
+	// ExcludeStale omits objects flagged as unreachable from search results by
+	// default. Individual searches may override it with the
+	// 'lens-reachable-only' request metadata key.
+	ExcludeStale bool
+
+	// SoftDelete moves removed objects to the trash instead of deleting them,
+	// so that they can be restored until they are purged - see Purge. Objects
+	// are deleted permanently by default.
+	SoftDelete bool
+
+	// MergeTagCase merges tags of an object that differ only in case, keeping
+	// the most common surface form. Tags are always matched case-insensitively.
+	MergeTagCase bool
+
+	// ReuseAnalysis skips retrieval and analysis when reindexing an object that
+	// was analyzed by the same version of Lens, since the content behind a
+	// hash cannot change - only the index time and request metadata are
+	// updated. Leave disabled to fully analyze objects on every reindex, for
+	// example after changing analysis settings.
+	ReuseAnalysis bool
+
+	// NameTags adds words from the display names of indexed objects, and the
+	// paths of archive members, to their tags
+	NameTags bool
+
+	// ExpandSynonyms matches synonyms of required words in searches, using the
+	// dictionary configured in Engine.Synonyms
+	ExpandSynonyms bool
+
+	// RawTextCategories lists categories of content to index in raw text mode,
+	// which keeps stop words so that phrases such as "to be or not to be" can
+	// be matched exactly. Raw text mode requires an index created by a version
+	// of Lens that supports it.
+	RawTextCategories []string
+
+	// AnalysisPolicies configures analysis per category, ie verbatim keywords
+	// for source code. Policies apply to sub-categories of their category.
+	AnalysisPolicies map[string]AnalysisPolicy
+
+	// KeywordLimit bounds the number of keywords in searches
+	KeywordLimit KeywordLimit
+
+	// SearchOrder is the default order of search results - results are sorted
+	// by relevance if unset
+	SearchOrder engine.Order
+
+	// SearchCache configures caching of search results - disabled by default
+	SearchCache SearchCacheOpts
+
+	// PDFImages is the maximum number of images embedded in each PDF to
+	// classify and run through OCR - leave at 0 to disable
+	PDFImages int
+
+	// Thumbnails configures preview generation for images
+	Thumbnails ThumbnailOpts
+
+	// MinImageSize configures the minimum dimensions of images to classify,
+	// so that icons and tracking pixels do not pollute the index with labels
+	MinImageSize ImageSizeOpts
+
+	// RecordSource records the address of the IPFS node content was
+	// retrieved through in the provenance of indexed objects
+	RecordSource bool
+
+	// Geotags records the locations of objects that have one, from the GPS
+	// metadata of JPEG and TIFF images, and the geotags of HTML documents, so
+	// that they can be found with SearchNearby. Locations are not recorded by
+	// default, since photos often reveal where their owners live.
+	Geotags bool
+
+	// ContentFilter restricts what content may be indexed - all content is
+	// allowed by default
+	ContentFilter ContentFilter
+
+	// Archives configures extraction of zip and tar archive members
+	Archives ArchiveOpts
+
+	// Links configures keyword extraction from hyperlinks in documents
+	Links LinkOpts
+
+	// URLs configures indexing of content fetched from URLs
+	URLs URLOpts
+
+	// PinContent pins the content of indexed objects on the IPFS node, so that
+	// it is not garbage collected, and unpins it once no indexed object
+	// references it. Lens does not track who pinned content, so removing an
+	// object also unpins content that was pinned by other means.
+	PinContent bool
+
+	// StoreExtracted adds the text extracted from each object to IPFS, and
+	// records its hash in the object's metadata, so that the extracted text
+	// can be retrieved and shared without extracting it again
+	StoreExtracted bool
+
+	// CategoryOverrides maps detected content types to the category to assign
+	// to them, in place of the built-in categories. Keys may be full mime types
+	// (ie 'application/pdf') or top-level types (ie 'image').
+	CategoryOverrides map[string]string
+	// CategoryRules assign sub-categories, such as 'document/legal', to objects
+	// based on their tags. Searches for a category also match its
+	// sub-categories.
+	CategoryRules []CategoryRule
+
+	// DetectLogs recognizes syslog, JSON lines, and timestamped plain-text logs,
+	// and indexes their messages, host names, and service names instead of
+	// their full text, which is dominated by timestamps and IDs
+	DetectLogs bool
+
+	// Hyphenation normalizes hyphenated words in indexed text and queries, ie
+	// rejoining words hyphenated across line breaks in extracted PDF text -
+	// see text.HyphenationFor. Leave nil to index text as extracted.
+	Hyphenation *text.Hyphenation
+
+	// Values retains numbers and dates in indexed text as normalized keywords,
+	// and normalizes them in queries to match. Both are discarded as noise by
+	// default.
+	Values text.Values
+
+	// Emphasis weighs words in headings and bold or italic text of HTML and
+	// Markdown documents higher when summarizing them. Words are unweighted by
+	// default.
+	Emphasis text.Emphasis
+
+	// CountRejections keeps count of content rejected for indexing by mime
+	// type, which is reported by Rejections and logged with each rejection
+	CountRejections bool
 
 	Engine engine.Opts
 }
 
+// ocrOptions returns the OCR configuration, bounding the text extracted from
+// each PDF to SampleSize unless configured otherwise - text beyond it would be
+// discarded, so there is no need to hold it in memory
+func (opts V2Options) ocrOptions() ocr.Options {
+	var o = opts.OCR
+	if o.MaxPDFText == 0 && opts.SampleSize > 0 {
+		o.MaxPDFText = opts.SampleSize + utf8.UTFMax
+	}
+	return o
+}
+
 // NewV2 instantiates a new V2 API
 func NewV2(
 	opts V2Options,
@@ -48,6 +295,17 @@ func NewV2(
 	if logger == nil {
 		logger = zap.NewNop().Sugar()
 	}
+	if err := opts.SearchOrder.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid search order: %s", err.Error())
+	}
+	for key, r := range opts.SummaryRatios {
+		if !validRatio(r) {
+			return nil, fmt.Errorf("invalid summary ratio %v for '%s': must be in (0,1]", r, key)
+		}
+	}
+	if err := validatePolicies(opts.AnalysisPolicies); err != nil {
+		return nil, err
+	}
 
 	// create new engine
 	se, err := engine.New(logger.Named("engine"), opts.Engine)
@@ -56,15 +314,12 @@ func NewV2(
 	}
 	go se.Run()
 
-	return &V2{
-		se:   se,
-		ipfs: ipfs,
-
-		tf: ia,
-		px: planetary.NewPlanetaryExtractor(ipfs),
-		oc: ocr.NewAnalyzer(opts.TesseractConfigPath, logger.Named("ocr")),
-		l:  logger.Named("service.v2"),
-	}, nil
+	v, err := newV2(opts, ipfs, ia, se, logger)
+	if err != nil {
+		se.Close()
+		return nil, err
+	}
+	return v, nil
 }
 
 // NewV2WithEngine instantiates a Lens V2 service with the given engine
@@ -74,24 +329,90 @@ func NewV2WithEngine(
 	ia images.TensorflowAnalyzer,
 	se engine.Searcher,
 	logger *zap.SugaredLogger,
-) *V2 {
+) (*V2, error) {
 	if logger == nil {
 		logger = zap.NewNop().Sugar()
 	}
+	return newV2(opts, ipfs, ia, se, logger)
+}
 
-	return &V2{
+// newV2 sets up the service around the given engine
+func newV2(
+	opts V2Options,
+	ipfs rtfs.Manager,
+	ia images.TensorflowAnalyzer,
+	se engine.Searcher,
+	logger *zap.SugaredLogger,
+) (*V2, error) {
+	var v = &V2{
 		se:   se,
 		ipfs: ipfs,
 
 		tf: ia,
 		px: planetary.NewPlanetaryExtractor(ipfs),
-		oc: ocr.NewAnalyzer(opts.TesseractConfigPath, logger.Named("ocr")),
-		l:  logger.Named("service.v2"),
+		oc: ocr.NewAnalyzer(opts.TesseractConfigPath, opts.ocrOptions(), logger.Named("ocr")),
+		sm: opts.Summarizer,
+
+		smFallback: opts.FallbackSummarizer,
+
+		summaryRatio:     opts.SummaryRatio,
+		summaryRatios:    opts.SummaryRatios,
+		minSummaryLength: opts.MinSummaryLength,
+		maxSummaryInput:  opts.MaxSummaryInput,
+		sampleSize:       opts.SampleSize,
+		version:          opts.Version,
+
+		reuseAnalysis: opts.ReuseAnalysis,
+
+		indexLimit:   newLimiter(opts.MaxIndexInFlight, opts.MaxIndexQueued),
+		indexTimeout: opts.IndexTimeout,
+		excludeStale: opts.ExcludeStale,
+		mergeTagCase: opts.MergeTagCase,
+		nameTags:     opts.NameTags,
+		detectLogs:   opts.DetectLogs,
+		synonyms:     opts.ExpandSynonyms,
+		rawText:      opts.RawTextCategories,
+		searchOrder:  opts.SearchOrder,
+		pdfImages:    opts.PDFImages,
+		thumbnails:   opts.Thumbnails,
+		archives:     opts.Archives,
+		links:        opts.Links,
+		urls:         opts.URLs,
+		pinContent:   opts.PinContent,
+		keywords:     opts.KeywordLimit,
+		filter:       opts.ContentFilter,
+		categories:   opts.CategoryOverrides,
+
+		categoryRules:  opts.CategoryRules,
+		storeExtracted: opts.StoreExtracted,
+		hyphenation:    opts.Hyphenation,
+		values:         opts.Values,
+		emphasis:       opts.Emphasis,
+		minImageSize:   opts.MinImageSize,
+		recordSource:   opts.RecordSource,
+		policies:       opts.AnalysisPolicies,
+		softDelete:     opts.SoftDelete,
+		geotags:        opts.Geotags,
+		rejections:     newRejectionCounter(opts.CountRejections),
+		searchCache:    newSearchCache(opts.SearchCache),
+
+		l: logger.Named("service.v2"),
+	}
+	var err error
+	if v.jobs, err = newJobQueue(opts.AsyncIndex, v.Index, v.discard, v.l); err != nil {
+		return nil, err
 	}
+	return v, nil
 }
 
 // Close releases Lens resources
-func (v *V2) Close() { v.se.Close() }
+func (v *V2) Close() {
+	v.jobs.close()
+	v.se.Close()
+}
+
+// IndexQueueDepth reports the number of index requests waiting to be processed
+func (v *V2) IndexQueueDepth() int { return v.indexLimit.depth() }
 
 // Index analyzes and stores the given object
 func (v *V2) Index(ctx context.Context, req *lensv2.IndexReq) (*lensv2.IndexResp, error) {
@@ -103,81 +424,270 @@ func (v *V2) Index(ctx context.Context, req *lensv2.IndexReq) (*lensv2.IndexResp
 		return nil, status.Errorf(codes.InvalidArgument,
 			"invalid data type '%s' provided", req.GetType())
 	}
+	if err := validateIndexReq(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	return v.index(ctx, req, nil, nil, nil, l)
+}
+
+// index analyzes and stores the object described by req. If contents is nil,
+// the object is retrieved from IPFS. The given properties are added to the
+// object's metadata, and progress, if set, receives updates as the object is
+// analyzed. The request metadata of ctx may select what happens if the object
+// is already indexed - see ifIndexedHeader.
+func (v *V2) index(
+	ctx context.Context,
+	req *lensv2.IndexReq,
+	contents []byte,
+	properties map[string]string,
+	progress progressFunc,
+	l *zap.SugaredLogger,
+) (*lensv2.IndexResp, error) {
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+
+	// apply the requested behaviour for objects that are already indexed
+	policy, err := ifIndexed(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !req.GetOptions().GetReindex() && v.se.IsIndexed(req.GetHash()) && v.deleted(req.GetHash()) {
+		// deleted objects are restored by indexing them again
+		policy = ifIndexedReindex
+	}
+	if !req.GetOptions().GetReindex() && policy != ifIndexedError && v.se.IsIndexed(req.GetHash()) {
+		if policy == ifIndexedSkip {
+			if resp, ok, err := v.indexed(req.GetHash()); err != nil || ok {
+				return resp, err
+			}
+		} else {
+			l.Infow("reindexing object that is already indexed")
+			req = &lensv2.IndexReq{
+				Type:        req.GetType(),
+				Hash:        req.GetHash(),
+				DisplayName: req.GetDisplayName(),
+				Tags:        req.GetTags(),
+				Options:     &lensv2.IndexReq_Options{Reindex: true},
+			}
+		}
+	}
+
+	// wait for a free slot
+	release, err := v.indexLimit.acquire(ctx)
+	if err != nil {
+		l.Warnw("index request rejected", "error", err,
+			"queue.depth", v.indexLimit.depth(),
+			"queue.in_flight", v.indexLimit.inFlight())
+		if err == errQueueFull {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+
+	// bound the time spent on this request once it is being processed - stages
+	// that overrun keep the slot until they return, so that abandoned work
+	// still counts towards the limit
+	b, cancel := newBudget(ctx, v.indexTimeout)
+	defer func() {
+		cancel()
+		b.after(release)
+	}()
 
 	var hash = req.GetHash()
 	var reindex = req.GetOptions().GetReindex()
-	content, md, err := v.magnify(hash, magnifyOpts{
-		DisplayName: req.GetDisplayName(),
-		Tags:        req.GetTags(),
-		Reindex:     reindex,
+	var content string
+	var md *models.MetaDataV2
+	err = b.run("magnify", func() (err error) {
+		content, md, err = v.magnify(hash, magnifyOpts{
+			DisplayName: req.GetDisplayName(),
+			Tags:        req.GetTags(),
+			Reindex:     reindex,
+			ModelHint:   modelHint(hash),
+			Budget:      b,
+			Contents:    contents,
+			Progress:    progress,
+		})
+		return err
 	})
 	if err != nil {
 		l.Errorw("failed to magnify document", "error", err)
+		if be, ok := err.(*budgetError); ok {
+			return nil, budgetStatus(be)
+		}
 		if strings.Contains(err.Error(), "failed to find content") {
 			return nil, status.Error(codes.NotFound, err.Error())
 		}
 		return nil, status.Errorf(codes.FailedPrecondition,
 			"failed to perform magnification for '%s': %s", hash, err.Error())
 	}
+	for k, val := range properties {
+		if md.Properties == nil {
+			md.Properties = make(map[string]string, len(properties))
+		}
+		md.Properties[k] = val
+	}
 
-	if err = v.store(hash, content, md, reindex); err != nil {
+	if err = b.run("store", func() error {
+		return v.store(b, hash, content, md, reindex)
+	}); err != nil {
 		l.Errorw("failed to store document", "error", err)
+		if be, ok := err.(*budgetError); ok {
+			return nil, budgetStatus(be)
+		}
 		return nil, status.Errorf(codes.Internal,
 			"failed to store requested document: %s", err.Error())
 	}
 
 	l.Info("document indexed")
+	if v.pinContent {
+		v.pin(hash, l)
+	}
 
-	return &lensv2.IndexResp{
-		Doc: &lensv2.Document{
-			Hash:        hash,
-			DisplayName: md.DisplayName,
-			MimeType:    md.MimeType,
-			Category:    md.Category,
-			Tags:        md.Tags,
-		},
-	}, nil
+	return newIndexResp(hash, md), nil
+}
+
+// IndexIfModified indexes the object described by req, unless it is already
+// indexed and req does not request a reindex. Content behind a hash cannot
+// change, so an indexed hash is never modified - in that case the indexed
+// object is returned without any processing, and modified is false.
+func (v *V2) IndexIfModified(ctx context.Context, req *lensv2.IndexReq) (resp *lensv2.IndexResp, modified bool, err error) {
+	if req.GetHash() != "" && !req.GetOptions().GetReindex() && v.se.IsIndexed(req.GetHash()) {
+		if resp, ok, err := v.indexed(req.GetHash()); err != nil || ok {
+			return resp, false, err
+		}
+	}
+	resp, err = v.Index(ctx, req)
+	return resp, err == nil, err
 }
 
-// Search executes a query against the Lens index
+// indexed returns the indexed object with the given hash, and false if it has
+// been removed since it was checked, so that it can be indexed again
+func (v *V2) indexed(hash string) (*lensv2.IndexResp, bool, error) {
+	doc, err := v.se.Get(hash)
+	if err == nil {
+		return newIndexResp(hash, &doc.Object.MD), true, nil
+	}
+	if err != engine.ErrNotFound {
+		return nil, false, getStatus(err)
+	}
+	return nil, false, nil
+}
+
+// Search executes a query against the Lens index, with results sorted in the
+// configured default order. Each result includes the complete set of tags of
+// the object, not only those that matched, so clients can compute their own
+// similarity measures to re-rank results.
 func (v *V2) Search(ctx context.Context, req *lensv2.SearchReq) (*lensv2.SearchResp, error) {
-	var (
-		err     error
-		results []engine.Result
-		opts    = req.GetOptions()
-	)
+	return v.SearchSorted(ctx, req, v.searchOrder)
+}
 
-	if req.GetQuery() == "" &&
-		len(opts.GetCategories()) < 1 &&
-		len(opts.GetHashes()) < 1 &&
-		len(opts.GetMimeTypes()) < 1 &&
-		len(opts.GetRequired()) < 1 &&
-		len(opts.GetTags()) < 1 {
+// SearchSorted executes a query against the Lens index, with results sorted in
+// the given order
+func (v *V2) SearchSorted(ctx context.Context, req *lensv2.SearchReq, order engine.Order) (*lensv2.SearchResp, error) {
+	query, err := v.newQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := order.Validate(); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument,
-			"no search parameters provided")
-	}
-
-	if opts == nil {
-		results, err = v.se.Search(ctx, engine.Query{Text: req.GetQuery()})
-	} else {
-		results, err = v.se.Search(ctx, engine.Query{
-			Text:       req.GetQuery(),
-			Required:   opts.GetRequired(),
-			Tags:       opts.GetTags(),
-			Categories: opts.GetCategories(),
-			MimeTypes:  opts.GetMimeTypes(),
-			Hashes:     opts.GetHashes(),
-		})
+			"invalid request: %s", err.Error())
 	}
+	query.Order = order
+
+	results, err := v.search(ctx, req, query)
 	if err != nil {
-		v.l.Errorw("error occured on query execution",
-			"error", err, "query", req)
-		return nil, status.Errorf(codes.Internal,
-			"error occured on query execution: %s", err.Error())
+		return nil, err
+	}
+	return newSearchResp(results), nil
+}
+
+// SearchPage executes a query against the Lens index like SearchSorted, and
+// returns at most size results that sort after the given cursor, along with
+// the cursor of the next page - empty once there are no more results. An
+// empty cursor returns the first page. Pages are resumed from the sort key of
+// the last result returned rather than an offset, so that results do not
+// shift between pages as the index changes - see engine.Cursor for the
+// ordering this relies on.
+func (v *V2) SearchPage(
+	ctx context.Context,
+	req *lensv2.SearchReq,
+	order engine.Order,
+	cursor string,
+	size int,
+) (*lensv2.SearchResp, string, error) {
+	if size < 1 {
+		return nil, "", status.Error(codes.InvalidArgument,
+			"invalid request: page size must be positive")
+	}
+	var after *engine.Cursor
+	if cursor != "" {
+		var err error
+		if after, err = engine.ParseCursor(cursor); err != nil {
+			return nil, "", status.Errorf(codes.InvalidArgument,
+				"invalid request: %s", err.Error())
+		}
+	}
+	query, err := v.newQuery(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := order.Validate(); err != nil {
+		return nil, "", status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	query.Order = order
+	if after != nil {
+		if err := after.Validate(order); err != nil {
+			return nil, "", status.Errorf(codes.InvalidArgument,
+				"invalid request: %s", err.Error())
+		}
+		query.After = after
+	}
+
+	results, err := v.search(ctx, req, query)
+	if err != nil {
+		return nil, "", err
+	}
+	var next string
+	if len(results) > size {
+		results = results[:size]
+		next = engine.NewCursor(results[size-1], order).String()
+	}
+	return newSearchResp(results), next, nil
+}
+
+// search executes the given query, using cached results if available
+func (v *V2) search(ctx context.Context, req *lensv2.SearchReq, query engine.Query) ([]engine.Result, error) {
+	var (
+		results []engine.Result
+		cached  bool
+		err     error
+	)
+	key, cacheable := v.searchCache.key(query)
+	if cacheable {
+		results, cached = v.searchCache.get(key)
+	}
+	if !cached {
+		if results, err = v.se.Search(ctx, query); err != nil {
+			v.l.Errorw("error occured on query execution",
+				"error", err, "query", req)
+			return nil, status.Errorf(codes.Internal,
+				"error occured on query execution: %s", err.Error())
+		}
+		if cacheable {
+			v.searchCache.put(key, results)
+		}
 	}
 
 	v.l.Debugw("query completed",
-		"query", req, "results", len(results))
+		"query", req, "results", len(results), "cached", cached)
+	return results, nil
+}
+
+// newSearchResp formats the given search results
+func newSearchResp(results []engine.Result) *lensv2.SearchResp {
 	return &lensv2.SearchResp{
 		Results: func() []*lensv2.SearchResp_Result {
 			var formatted = make([]*lensv2.SearchResp_Result, len(results))
@@ -196,20 +706,337 @@ func (v *V2) Search(ctx context.Context, req *lensv2.SearchReq) (*lensv2.SearchR
 			}
 			return formatted
 		}(),
-	}, nil
+	}
 }
 
-// Remove unindexes and deletes the requested object
+// Count returns the number of objects matching a query without retrieving them
+func (v *V2) Count(ctx context.Context, req *lensv2.SearchReq) (uint64, error) {
+	query, err := v.newQuery(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := v.se.Count(ctx, query)
+	if err != nil {
+		v.l.Errorw("error occured on query execution",
+			"error", err, "query", req)
+		return 0, status.Errorf(codes.Internal,
+			"error occured on query execution: %s", err.Error())
+	}
+
+	v.l.Debugw("count completed",
+		"query", req, "results", count)
+	return count, nil
+}
+
+// Facet returns the number of objects matching a query, grouped by category
+// and mime type. All matches are scanned, so this should only be requested
+// when facets are to be displayed.
+func (v *V2) Facet(ctx context.Context, req *lensv2.SearchReq) (*engine.Facets, error) {
+	query, err := v.newQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	facets, err := v.se.Facet(ctx, query)
+	if err != nil {
+		v.l.Errorw("error occured on query execution",
+			"error", err, "query", req)
+		return nil, status.Errorf(codes.Internal,
+			"error occured on query execution: %s", err.Error())
+	}
+
+	v.l.Debugw("facet completed",
+		"query", req, "results", facets.Total)
+	return facets, nil
+}
+
+// IndexHistogram returns the number of objects last indexed within each span
+// of the given width between from and to. Spans without any objects are
+// included with a count of zero.
+func (v *V2) IndexHistogram(ctx context.Context, from, to time.Time, width time.Duration) ([]engine.Bucket, error) {
+	if width <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"bucket width must be positive")
+	}
+	if !to.After(from) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"end of histogram must be after its start")
+	}
+	if n := (to.Sub(from) + width - 1) / width; n > engine.MaxHistogramBuckets {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"histogram would have %d buckets, more than the maximum of %d",
+			n, engine.MaxHistogramBuckets)
+	}
+
+	buckets, err := v.se.Histogram(ctx, from, to, width)
+	if err != nil {
+		v.l.Errorw("failed to compute index histogram",
+			"error", err, "from", from, "to", to)
+		return nil, status.Errorf(codes.Internal,
+			"failed to compute index histogram: %s", err.Error())
+	}
+	return buckets, nil
+}
+
+// Suggest looks up corrections for query terms that do not appear in the index,
+// for use as a "did you mean" prompt when a search yields no results
+func (v *V2) Suggest(ctx context.Context, req *lensv2.SearchReq) ([]engine.Suggestion, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no query provided")
+	}
+	if err := validateSearchReq(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+
+	suggestions, err := v.se.Suggest(req.GetQuery())
+	if err != nil {
+		v.l.Errorw("error occured on suggestion lookup",
+			"error", err, "query", req)
+		return nil, status.Errorf(codes.Internal,
+			"error occured on suggestion lookup: %s", err.Error())
+	}
+	return suggestions, nil
+}
+
+// Remove unindexes and deletes the requested object. If soft deletes are
+// enabled, the object is moved to the trash instead - see V2Options.SoftDelete.
 func (v *V2) Remove(ctx context.Context, req *lensv2.RemoveReq) (*lensv2.RemoveResp, error) {
 	if req.GetHash() == "" {
 		return nil, status.Errorf(codes.InvalidArgument,
 			"no hash to remove was provided")
 	}
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+	if v.softDelete {
+		if err := v.trash(ctx, req.GetHash(), v.l.With("hash", req.GetHash())); err != nil {
+			return nil, err
+		}
+		return &lensv2.RemoveResp{}, nil
+	}
 
 	if err := v.remove(req.GetHash()); err != nil {
 		return nil, status.Errorf(codes.NotFound,
 			"failed to remove requested hash: %s", err.Error())
 	}
+	if v.pinContent {
+		v.unpin(ctx, v.l.With("hash", req.GetHash()), req.GetHash())
+	}
 
 	return &lensv2.RemoveResp{}, nil
 }
+
+// removeAll removes the given objects like Remove, and returns the number of
+// objects removed
+func (v *V2) removeAll(ctx context.Context, hashes []string) (int, error) {
+	if v.softDelete {
+		return v.trashAll(ctx, hashes)
+	}
+	return v.purgeAll(ctx, hashes)
+}
+
+// purgeAll permanently removes the given objects, and returns the number of
+// objects removed. Content is unpinned once nothing references it anymore.
+func (v *V2) purgeAll(ctx context.Context, hashes []string) (int, error) {
+	var removed int
+	var err error
+	for _, h := range hashes {
+		if err = v.se.Remove(h); err != nil {
+			err = fmt.Errorf("failed to remove document '%s': %s", h, err.Error())
+			break
+		}
+		removed++
+	}
+	if v.pinContent && removed > 0 {
+		v.unpin(ctx, v.l, hashes[:removed]...)
+	}
+	return removed, err
+}
+
+// GetObject retrieves an indexed object's metadata and its history of previous
+// metadata revisions
+func (v *V2) GetObject(hash string) (*models.ObjectV2, error) {
+	if hash == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no hash to retrieve was provided")
+	}
+	doc, err := v.se.Get(hash)
+	if err != nil {
+		return nil, getStatus(err)
+	}
+	return doc.Object, nil
+}
+
+// ObjectResult is the outcome of retrieving a single object with GetObjects
+type ObjectResult struct {
+	Hash   string
+	Object *models.ObjectV2
+	// Err is a gRPC status error if the object could not be retrieved, ie
+	// NotFound for hashes that are not indexed
+	Err error
+}
+
+// GetObjects retrieves the metadata of multiple indexed objects at once, ie a
+// page of search results. Results are in the same order as the given hashes,
+// and objects that cannot be retrieved are reported in their result rather
+// than failing the entire request.
+func (v *V2) GetObjects(hashes []string) ([]ObjectResult, error) {
+	if len(hashes) < 1 {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no hashes to retrieve were provided")
+	}
+	var results = make([]ObjectResult, len(hashes))
+	for i, hash := range hashes {
+		results[i].Hash = hash
+		results[i].Object, results[i].Err = v.GetObject(hash)
+	}
+	return results, nil
+}
+
+// ObjectsForHash returns the hashes of all indexed objects that reference the
+// given content hash - the object itself, if it is indexed, and any archive
+// members indexed from it. An empty list is returned for unknown hashes.
+func (v *V2) ObjectsForHash(ctx context.Context, hash string) ([]string, error) {
+	if hash == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no hash to look up was provided")
+	}
+	var objects = make([]string, 0)
+	if v.se.IsIndexed(hash) {
+		objects = append(objects, hash)
+	}
+	members, err := v.se.ListPrefix(ctx, hash+"/")
+	if err != nil {
+		v.l.Errorw("failed to find archive members",
+			"error", err, "hash", hash)
+		return nil, status.Errorf(codes.Internal,
+			"failed to find objects for hash: %s", err.Error())
+	}
+	return append(objects, members...), nil
+}
+
+// UpdateMetadata applies the given patch to an indexed object's metadata
+// without retrieving or analyzing its content again, ie to add or remove tags.
+// The object is only rewritten if the patch changes its metadata.
+func (v *V2) UpdateMetadata(hash string, patch models.MetaDataPatch) (*models.MetaDataV2, error) {
+	if hash == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no hash to update was provided")
+	}
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+	var l = v.l.With("hash", hash)
+
+	doc, err := v.se.Get(hash)
+	if err != nil {
+		return nil, getStatus(err)
+	}
+	if !patch.Apply(&doc.Object.MD) {
+		l.Debugw("document metadata unchanged", "patch", patch)
+		return &doc.Object.MD, nil
+	}
+	if v.mergeTagCase {
+		doc.Object.MD.Tags = mergeTags(doc.Object.MD.Tags)
+	}
+
+	doc.Reindex = true
+	if err := v.se.Index(*doc); err != nil {
+		l.Errorw("failed to store updated document", "error", err)
+		return nil, status.Errorf(codes.Internal,
+			"failed to store updated document: %s", err.Error())
+	}
+
+	l.Infow("document metadata updated", "patch", patch)
+	return &doc.Object.MD, nil
+}
+
+// minRemovePrefixLength guards against accidentally removing most of the index
+// with a short hash prefix, since most hashes share the same leading characters
+const minRemovePrefixLength = 6
+
+// RemoveByKeyword unindexes every object with a tag equal to the given keyword,
+// ignoring case, and returns the number of objects removed. If soft deletes are enabled, the
+// objects are moved to the trash instead.
+func (v *V2) RemoveByKeyword(ctx context.Context, keyword string) (int, error) {
+	if strings.TrimSpace(keyword) == "" {
+		return 0, status.Errorf(codes.InvalidArgument,
+			"no keyword provided")
+	}
+	if err := v.writable(); err != nil {
+		return 0, err
+	}
+	var removed int
+	hashes, err := v.tagged(ctx, keyword)
+	if err == nil {
+		removed, err = v.removeAll(ctx, hashes)
+	}
+	if err != nil {
+		v.l.Errorw("failed to remove objects by keyword",
+			"error", err, "keyword", keyword, "removed", removed)
+		return removed, status.Errorf(codes.Internal,
+			"removed %d objects before failure: %s", removed, err.Error())
+	}
+	v.l.Infow("objects removed by keyword",
+		"keyword", keyword, "removed", removed)
+	return removed, nil
+}
+
+// tagged returns the objects with a tag equal to the given keyword, ignoring
+// case. Tag queries match any word of a tag, so that "machine learning" also
+// matches objects tagged "machine", and "spam" also matches objects tagged
+// "spam-bot" - matches are checked against their stored tags instead.
+func (v *V2) tagged(ctx context.Context, keyword string) ([]string, error) {
+	candidates, err := v.se.ListMatching(ctx, engine.Query{Tags: []string{keyword}})
+	if err != nil {
+		return nil, err
+	}
+	keyword = strings.TrimSpace(keyword)
+	var hashes = make([]string, 0, len(candidates))
+	for _, hash := range candidates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		doc, err := v.se.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve document '%s': %s", hash, err.Error())
+		}
+		for _, t := range doc.Object.MD.Tags {
+			if strings.EqualFold(strings.TrimSpace(t), keyword) {
+				hashes = append(hashes, hash)
+				break
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// RemoveByPrefix unindexes every object with a hash beginning with the given
+// prefix, and returns the number of objects removed. If soft deletes are
+// enabled, the objects are moved to the trash instead.
+func (v *V2) RemoveByPrefix(ctx context.Context, prefix string) (int, error) {
+	if len(prefix) < minRemovePrefixLength {
+		return 0, status.Errorf(codes.InvalidArgument,
+			"prefix must be at least %d characters", minRemovePrefixLength)
+	}
+	if err := v.writable(); err != nil {
+		return 0, err
+	}
+	var removed int
+	hashes, err := v.se.ListPrefix(ctx, prefix)
+	if err == nil {
+		removed, err = v.removeAll(ctx, hashes)
+	}
+	if err != nil {
+		v.l.Errorw("failed to remove objects by prefix",
+			"error", err, "prefix", prefix, "removed", removed)
+		return removed, status.Errorf(codes.Internal,
+			"removed %d objects before failure: %s", removed, err.Error())
+	}
+	v.l.Infow("objects removed by prefix",
+		"prefix", prefix, "removed", removed)
+	return removed, nil
+}
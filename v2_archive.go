@@ -0,0 +1,203 @@
+package lens
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// Supported archive formats
+const (
+	archiveZip   = "application/zip"
+	archiveTar   = "application/x-tar"
+	archiveTarGz = "application/gzip"
+)
+
+// ArchiveOpts configures indexing of archive members
+type ArchiveOpts struct {
+	// MaxEntries is the maximum number of members to extract from an archive -
+	// leave at 0 to disable archive extraction
+	MaxEntries int
+	// MaxSize is the maximum total size in bytes of members extracted from an
+	// archive
+	MaxSize int64
+}
+
+type archiveEntry struct {
+	name     string
+	contents []byte
+}
+
+// detectArchive returns the format of the given contents if it is a supported
+// archive and archive extraction is enabled
+func (v *V2) detectArchive(contents []byte) string {
+	if v.archives.MaxEntries < 1 {
+		return ""
+	}
+	if bytes.HasPrefix(contents, []byte("PK\x03\x04")) {
+		return archiveZip
+	}
+	if isTar(contents) {
+		return archiveTar
+	}
+	if bytes.HasPrefix(contents, []byte("\x1f\x8b")) {
+		gz, err := gzip.NewReader(bytes.NewReader(contents))
+		if err != nil {
+			return ""
+		}
+		var header = make([]byte, 512)
+		n, _ := io.ReadFull(gz, header)
+		if isTar(header[:n]) {
+			return archiveTarGz
+		}
+	}
+	return ""
+}
+
+// isTar checks for the ustar magic in the first tar header
+func isTar(contents []byte) bool {
+	return len(contents) >= 262 && string(contents[257:262]) == "ustar"
+}
+
+// readArchive extracts regular files from the given archive, up to the
+// configured limits. If the limits are reached, the members extracted so far
+// are returned, and truncated is true.
+func readArchive(format string, contents []byte, opts ArchiveOpts) (entries []archiveEntry, truncated bool, err error) {
+	var remaining = opts.MaxSize
+	var extract = func(name string, size int64, r io.Reader) bool {
+		if len(entries) >= opts.MaxEntries || size > remaining {
+			return false
+		}
+		// don't trust reported sizes
+		b, err := ioutil.ReadAll(io.LimitReader(r, remaining+1))
+		if err != nil || int64(len(b)) > remaining {
+			return false
+		}
+		remaining -= int64(len(b))
+		entries = append(entries, archiveEntry{name, b})
+		return true
+	}
+
+	switch format {
+	case archiveZip:
+		zr, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+		if err != nil {
+			return nil, false, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return entries, false, fmt.Errorf("failed to open '%s': %s", f.Name, err.Error())
+			}
+			var ok = extract(f.Name, int64(f.UncompressedSize64), rc)
+			rc.Close()
+			if !ok {
+				return entries, true, nil
+			}
+		}
+	case archiveTar, archiveTarGz:
+		var r io.Reader = bytes.NewReader(contents)
+		if format == archiveTarGz {
+			if r, err = gzip.NewReader(r); err != nil {
+				return nil, false, err
+			}
+		}
+		var tr = tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return entries, false, err
+			}
+			if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+				continue
+			}
+			if !extract(h.Name, h.Size, tr) {
+				return entries, true, nil
+			}
+		}
+	default:
+		return nil, false, fmt.Errorf("unsupported archive format '%s'", format)
+	}
+	return entries, false, nil
+}
+
+// magnifyArchive indexes each supported member of the given archive as its own
+// object, identified by '<hash>/<member path>', and returns the aggregated
+// contents and tags of all indexed members. Members that cannot be analyzed
+// are skipped. Members indexed before the budget is exceeded remain indexed.
+func (v *V2) magnifyArchive(
+	hash, format string,
+	contents []byte,
+	reindex bool,
+	b *budget,
+	l *zap.SugaredLogger,
+) (content string, tags []string, err error) {
+	entries, truncated, err := readArchive(format, contents, v.archives)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read archive: %s", err.Error())
+	}
+	if truncated {
+		l.Warnw("archive exceeds extraction limits - remaining members are skipped",
+			"archive.max_entries", v.archives.MaxEntries,
+			"archive.max_size", v.archives.MaxSize)
+	}
+
+	var texts = make([]string, 0, len(entries))
+	var skipped int
+	for _, e := range entries {
+		if err := b.enter("archive"); err != nil {
+			return "", nil, err
+		}
+		var id = hash + "/" + strings.TrimPrefix(e.name, "/")
+		var ml = l.With("archive.member", e.name)
+		a, err := v.analyze(id, e.contents, modelHint(id), ml)
+		if err != nil {
+			ml.Debugw("skipping archive member", "error", err)
+			skipped++
+			continue
+		}
+		if v.nameTags {
+			a.keywords = appendUnique(a.keywords, wordTags(e.name)...)
+		}
+		if err := v.store(b, id, a.content, &models.MetaDataV2{
+			DisplayName: path.Base(e.name),
+			MimeType:    a.contentType,
+			Category:    v.category(a.mimeType, a.category),
+			Tags:        a.keywords,
+			Thumbnail:   a.thumbnail,
+			Properties:  map[string]string{"archive": hash},
+
+			Classification: a.classification,
+			Pages:          a.pages,
+			Truncated:      a.truncated,
+			Provenance:     &a.provenance,
+		}, reindex); err != nil {
+			ml.Warnw("failed to store archive member", "error", err)
+			skipped++
+			continue
+		}
+		texts = append(texts, a.content)
+		tags = appendUnique(tags, a.keywords...)
+	}
+	l.Infow("archive members indexed",
+		"indexed", len(texts),
+		"skipped", skipped)
+
+	return strings.Join(texts, "\n"), tags, nil
+}
@@ -0,0 +1,174 @@
+package lens
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+type testMember struct{ name, body string }
+
+func newZip(t *testing.T, members ...testMember) []byte {
+	var buf bytes.Buffer
+	var w = zip.NewWriter(&buf)
+	for _, m := range members {
+		f, err := w.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(m.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTar(t *testing.T, gz bool, members ...testMember) []byte {
+	var buf bytes.Buffer
+	var gw = gzip.NewWriter(&buf)
+	var w *tar.Writer
+	if gz {
+		w = tar.NewWriter(gw)
+	} else {
+		w = tar.NewWriter(&buf)
+	}
+	for _, m := range members {
+		if err := w.WriteHeader(&tar.Header{
+			Name:     m.name,
+			Mode:     0600,
+			Size:     int64(len(m.body)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(m.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz {
+		gw.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestV2_detectArchive(t *testing.T) {
+	var members = []testMember{{"a.txt", "hello world"}}
+	tests := []struct {
+		name     string
+		opts     ArchiveOpts
+		contents []byte
+		want     string
+	}{
+		{"disabled", ArchiveOpts{}, newZip(t, members...), ""},
+		{"text", ArchiveOpts{MaxEntries: 1}, []byte("hello world"), ""},
+		{"zip", ArchiveOpts{MaxEntries: 1}, newZip(t, members...), archiveZip},
+		{"tar", ArchiveOpts{MaxEntries: 1}, newTar(t, false, members...), archiveTar},
+		{"tar.gz", ArchiveOpts{MaxEntries: 1}, newTar(t, true, members...), archiveTarGz},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = &V2{archives: tt.opts}
+			if got := v.detectArchive(tt.contents); got != tt.want {
+				t.Errorf("V2.detectArchive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readArchive(t *testing.T) {
+	var members = []testMember{
+		{"a.txt", "hello"},
+		{"docs/b.txt", "world"},
+		{"c.txt", "bomb"},
+	}
+	tests := []struct {
+		name          string
+		opts          ArchiveOpts
+		wantNames     []string
+		wantTruncated bool
+	}{
+		{"all", ArchiveOpts{MaxEntries: 10, MaxSize: 100},
+			[]string{"a.txt", "docs/b.txt", "c.txt"}, false},
+		{"entry limit", ArchiveOpts{MaxEntries: 2, MaxSize: 100},
+			[]string{"a.txt", "docs/b.txt"}, true},
+		{"size limit", ArchiveOpts{MaxEntries: 10, MaxSize: 12},
+			[]string{"a.txt", "docs/b.txt"}, true},
+	}
+	for _, tt := range tests {
+		for format, contents := range map[string][]byte{
+			archiveZip:   newZip(t, members...),
+			archiveTar:   newTar(t, false, members...),
+			archiveTarGz: newTar(t, true, members...),
+		} {
+			t.Run(tt.name+" "+format, func(t *testing.T) {
+				entries, truncated, err := readArchive(format, contents, tt.opts)
+				if err != nil {
+					t.Errorf("readArchive() error = %v", err)
+					return
+				}
+				var names []string
+				for _, e := range entries {
+					names = append(names, e.name)
+				}
+				if !reflect.DeepEqual(names, tt.wantNames) {
+					t.Errorf("readArchive() = %v, want %v", names, tt.wantNames)
+				}
+				if truncated != tt.wantTruncated {
+					t.Errorf("readArchive() truncated = %v, want %v", truncated, tt.wantTruncated)
+				}
+			})
+		}
+	}
+}
+
+func TestV2_magnify_archive(t *testing.T) {
+	pdf, err := ioutil.ReadFile("test/assets/text.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ipfs = &mocks.FakeRTFSManager{}
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{
+		Archives: ArchiveOpts{MaxEntries: 10, MaxSize: 1 << 20},
+	}, ipfs, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	ipfs.CatReturns(newZip(t,
+		testMember{"notes/hello.txt", "hello world"},
+		testMember{"doc.pdf", string(pdf)},
+		testMember{"binary.bin", "\x00\x01\x02\x03"},
+	), nil)
+
+	content, md, err := v.magnify("asdf", magnifyOpts{})
+	if err != nil {
+		t.Errorf("V2.magnify() error = %v", err)
+		return
+	}
+	if md.Category != models.MimeTypeArchive || md.MimeType != archiveZip {
+		t.Errorf("V2.magnify() = %+v, want archive", md)
+	}
+	if !bytes.Contains([]byte(content), []byte("hello world")) {
+		t.Errorf("V2.magnify() content = %s, want member contents", content)
+	}
+
+	// binary member should be skipped
+	if se.IndexCallCount() != 2 {
+		t.Errorf("wanted 2 members indexed, got %d", se.IndexCallCount())
+		return
+	}
+	var doc = se.IndexArgsForCall(0)
+	if doc.Object.Hash != "asdf/notes/hello.txt" ||
+		doc.Object.MD.DisplayName != "hello.txt" ||
+		doc.Object.MD.Properties["archive"] != "asdf" {
+		t.Errorf("unexpected archive member %+v", doc.Object)
+	}
+}
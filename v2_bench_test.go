@@ -0,0 +1,89 @@
+package lens
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+// benchFixtures maps content categories to fixture files
+var benchFixtures = []struct {
+	category string
+	path     string
+}{
+	{"document", "README.md"},
+	{"pdf", "test/assets/text.pdf"},
+	{"pdf_scanned", "test/assets/scan.pdf"},
+	{"image", "test/assets/image.jpg"},
+}
+
+// BenchmarkV2_Index measures the time taken to analyze and store each category
+// of content. IPFS, image classification, and the search engine are mocked, so
+// results reflect extraction and OCR costs.
+func BenchmarkV2_Index(b *testing.B) {
+	for _, f := range benchFixtures {
+		contents, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(f.category, func(b *testing.B) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var tensor = &mocks.FakeTensorflowAnalyzer{}
+			var v = newTestV2(b, V2Options{},
+				ipfs, tensor, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+			ipfs.CatReturns(contents, nil)
+			tensor.AnalyzeReturns("test", nil)
+
+			var req = &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"}
+			b.SetBytes(int64(len(contents)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := v.Index(context.Background(), req); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkV2_analyze_summaryInput measures summarization of increasingly large
+// documents, with and without MaxSummaryInput - limited runs should take
+// roughly constant time regardless of document size. A word frequency count
+// stands in for a real summarizer, whose cost also grows with its input.
+func BenchmarkV2_analyze_summaryInput(b *testing.B) {
+	var frequencies = text.SummarizerFunc(func(s string, ratio float64) []string {
+		var counts = make(map[string]int)
+		for _, w := range strings.Fields(s) {
+			counts[strings.ToLower(w)]++
+		}
+		return nil
+	})
+	var sentence = "Lens indexes content on the Interplanetary File System. "
+	for _, size := range []int{64 << 10, 1 << 20, 8 << 20} {
+		var contents = []byte(strings.Repeat(sentence, size/len(sentence)))
+		for _, max := range []int{0, 64 << 10} {
+			b.Run(fmt.Sprintf("size=%d/max=%d", size, max), func(b *testing.B) {
+				var v = newTestV2(b, V2Options{
+					Summarizer:      frequencies,
+					MaxSummaryInput: max,
+				}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{},
+					&mocks.FakeSearcher{}, zap.NewNop().Sugar())
+				var l = zap.NewNop().Sugar()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := v.analyze("asdf", contents, "", l); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package lens
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// budget bounds the total duration of an operation, and tracks the stage the
+// operation is in so that overruns can be attributed. A nil budget does not
+// impose any limit.
+type budget struct {
+	ctx   context.Context
+	stage atomic.Value // string
+
+	// running tracks functions started by run that may outlive it
+	running sync.WaitGroup
+	pending int32
+}
+
+// newBudget returns a nil budget if timeout is not positive and ctx can never
+// be cancelled. Otherwise, the budget ends when either the timeout elapses or
+// ctx is cancelled.
+func newBudget(ctx context.Context, timeout time.Duration) (*budget, context.CancelFunc) {
+	if timeout <= 0 {
+		if ctx.Done() == nil {
+			return nil, func() {}
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		return &budget{ctx: ctx}, cancel
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return &budget{ctx: ctx}, cancel
+}
+
+// budgetError indicates that an operation ran out of time
+type budgetError struct {
+	stage string
+	cause error
+}
+
+func (e *budgetError) Error() string {
+	if e.cause == context.Canceled {
+		return fmt.Sprintf("operation cancelled during stage '%s'", e.stage)
+	}
+	return fmt.Sprintf("operation exceeded its time budget during stage '%s': %s",
+		e.stage, e.cause.Error())
+}
+
+// enter records that the operation has reached the given stage, and returns an
+// error if the budget has already been exceeded
+func (b *budget) enter(stage string) error {
+	if b == nil {
+		return nil
+	}
+	b.stage.Store(stage)
+	return b.check()
+}
+
+// check returns an error if the budget has been exceeded, without changing the
+// current stage
+func (b *budget) check() error {
+	if b == nil || b.ctx.Err() == nil {
+		return nil
+	}
+	return b.exceeded()
+}
+
+// run executes fn as the given stage, returning early if the budget is
+// exceeded before fn completes. fn may continue to run in the background after
+// run returns, so it should check the budget before doing anything that cannot
+// be undone - use after to wait for it.
+func (b *budget) run(stage string, fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+	if err := b.enter(stage); err != nil {
+		return err
+	}
+	var done = make(chan error, 1)
+	atomic.AddInt32(&b.pending, 1)
+	b.running.Add(1)
+	go func() {
+		defer b.running.Done()
+		defer atomic.AddInt32(&b.pending, -1)
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-b.ctx.Done():
+		return b.exceeded()
+	}
+}
+
+// after calls fn once every function started by run has returned - immediately
+// if none are still running, or in the background otherwise. It must not be
+// called while run may still be called.
+func (b *budget) after(fn func()) {
+	if b == nil || atomic.LoadInt32(&b.pending) == 0 {
+		fn()
+		return
+	}
+	go func() {
+		b.running.Wait()
+		fn()
+	}()
+}
+
+// context returns the context the budget ends with
+func (b *budget) context() context.Context {
+	if b == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+func (b *budget) exceeded() error {
+	stage, _ := b.stage.Load().(string)
+	return &budgetError{stage: stage, cause: b.ctx.Err()}
+}
+
+// budgetStatus converts a budget error into a gRPC status
+func budgetStatus(err *budgetError) error {
+	if err.cause == context.Canceled {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.DeadlineExceeded, err.Error())
+}
@@ -0,0 +1,142 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func Test_budget(t *testing.T) {
+	t.Run("nil budget", func(t *testing.T) {
+		b, cancel := newBudget(context.Background(), 0)
+		defer cancel()
+		if b != nil {
+			t.Error("expected nil budget")
+		}
+		if err := b.enter("stage"); err != nil {
+			t.Errorf("enter() error = %v", err)
+		}
+		var want = errors.New("oh no")
+		if err := b.run("stage", func() error { return want }); err != want {
+			t.Errorf("run() error = %v, want %v", err, want)
+		}
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		b, cancel := newBudget(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := b.run("fast", func() error { return nil }); err != nil {
+			t.Errorf("run() error = %v", err)
+		}
+		var err = b.run("slow", func() error {
+			b.enter("slower")
+			time.Sleep(200 * time.Millisecond)
+			return nil
+		})
+		be, ok := err.(*budgetError)
+		if !ok || be.stage != "slower" || be.cause != context.DeadlineExceeded {
+			t.Errorf("run() error = %v, want budget exceeded in stage 'slower'", err)
+		}
+		if err := b.enter("next"); err == nil {
+			t.Error("enter() expected error after budget exceeded")
+		}
+		if status.Code(budgetStatus(be)) != codes.DeadlineExceeded {
+			t.Errorf("budgetStatus() = %v, want DeadlineExceeded", budgetStatus(be))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancelCtx := context.WithCancel(context.Background())
+		b, cancel := newBudget(ctx, time.Minute)
+		defer cancel()
+		cancelCtx()
+		be, ok := b.enter("stage").(*budgetError)
+		if !ok || status.Code(budgetStatus(be)) != codes.Canceled {
+			t.Errorf("enter() = %v, want cancellation", be)
+		}
+
+		// cancellation applies without a timeout as well
+		b, cancel = newBudget(ctx, 0)
+		defer cancel()
+		if err := b.enter("stage"); err == nil {
+			t.Error("enter() expected error after cancellation without timeout")
+		}
+	})
+
+	t.Run("after", func(t *testing.T) {
+		b, cancel := newBudget(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		var called = make(chan struct{})
+		b.after(func() { close(called) })
+		select {
+		case <-called:
+		default:
+			t.Error("after() did not call fn immediately with nothing running")
+		}
+
+		var finished int32
+		b.run("slow", func() error {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt32(&finished, 1)
+			return nil
+		})
+		called = make(chan struct{})
+		b.after(func() {
+			if atomic.LoadInt32(&finished) == 0 {
+				t.Error("after() called fn before run() stage returned")
+			}
+			close(called)
+		})
+		select {
+		case <-called:
+		case <-time.After(time.Second):
+			t.Error("after() did not call fn once run() stage returned")
+		}
+	})
+}
+
+func TestV2_Index_timeout(t *testing.T) {
+	var ipfs = &mocks.FakeRTFSManager{}
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{
+		IndexTimeout:     50 * time.Millisecond,
+		MaxIndexInFlight: 1,
+	}, ipfs, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	ipfs.CatStub = func(string) ([]byte, error) {
+		time.Sleep(200 * time.Millisecond)
+		return []byte("hello world"), nil
+	}
+
+	_, err := v.Index(context.Background(), &lensv2.IndexReq{
+		Type: lensv2.IndexReq_IPLD,
+		Hash: "asdf",
+	})
+	if s := status.Convert(err); s.Code() != codes.DeadlineExceeded ||
+		!strings.Contains(s.Message(), "'retrieve'") {
+		t.Errorf("V2.Index() error = %v, want deadline exceeded during retrieve", err)
+	}
+
+	// abandoned request should keep its slot until it returns
+	if n := v.indexLimit.inFlight(); n != 1 {
+		t.Errorf("V2.Index() released slot while still running, in flight = %d", n)
+	}
+
+	// abandoned request should not be stored
+	time.Sleep(300 * time.Millisecond)
+	if se.IndexCallCount() > 0 {
+		t.Error("V2.Index() stored document after exceeding budget")
+	}
+	if n := v.indexLimit.inFlight(); n != 0 {
+		t.Errorf("V2.Index() did not release slot, in flight = %d", n)
+	}
+}
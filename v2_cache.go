@@ -0,0 +1,149 @@
+package lens
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+)
+
+// defaultSearchCacheTTL is how long search results are cached for if no TTL is
+// configured
+const defaultSearchCacheTTL = 10 * time.Second
+
+// SearchCacheOpts configures caching of search results. Index writes are only
+// visible to searches once the engine's queue is flushed, so cached results
+// are not invalidated on writes - they expire after TTL instead, and may be
+// stale for up to TTL.
+type SearchCacheOpts struct {
+	// Size is the maximum number of distinct searches to cache results for -
+	// leave at 0 to disable caching
+	Size int
+	// TTL is how long results are cached for - defaults to 10 seconds
+	TTL time.Duration
+}
+
+// SearchCacheStats reports the effectiveness of the search cache
+type SearchCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+// HitRate returns the proportion of searches served from the cache
+func (s SearchCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// searchCache is a least-recently-used cache of search results, keyed by
+// query. A nil cache does not cache anything.
+type searchCache struct {
+	mux     sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List // most recently used first
+
+	hits   uint64
+	misses uint64
+}
+
+type searchCacheEntry struct {
+	key     string
+	results []engine.Result
+	expires time.Time
+}
+
+// newSearchCache returns nil if caching is disabled
+func newSearchCache(opts SearchCacheOpts) *searchCache {
+	if opts.Size < 1 {
+		return nil
+	}
+	var ttl = opts.TTL
+	if ttl <= 0 {
+		ttl = defaultSearchCacheTTL
+	}
+	return &searchCache{
+		size:    opts.Size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, opts.Size),
+		lru:     list.New(),
+	}
+}
+
+// key normalizes a query into a cache key. Queries that cannot be normalized
+// are not cached, and nothing is cached by a nil cache.
+func (c *searchCache) key(q engine.Query) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	b, err := json.Marshal(q)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// get returns cached results for the given key, if they have not expired.
+// Results must not be modified.
+func (c *searchCache) get(key string) ([]engine.Result, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if el, ok := c.entries[key]; ok {
+		var entry = el.Value.(*searchCacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			c.hits++
+			return entry.results, true
+		}
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, false
+}
+
+// put caches results for the given key, evicting the least recently used
+// entry if the cache is full
+func (c *searchCache) put(key string, results []engine.Result) {
+	if c == nil {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var entry = &searchCacheEntry{key, results, time.Now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.size {
+		var oldest = c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry).key)
+	}
+}
+
+func (c *searchCache) stats() SearchCacheStats {
+	if c == nil {
+		return SearchCacheStats{}
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return SearchCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.lru.Len()}
+}
+
+// SearchCacheStats reports search cache hits and misses since startup, if
+// V2Options.SearchCache is configured
+func (v *V2) SearchCacheStats() SearchCacheStats {
+	return v.searchCache.stats()
+}
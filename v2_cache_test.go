@@ -0,0 +1,86 @@
+package lens
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func Test_searchCache(t *testing.T) {
+	var c = newSearchCache(SearchCacheOpts{Size: 2, TTL: 50 * time.Millisecond})
+	var results = []engine.Result{{Hash: "asdf"}}
+
+	c.put("a", results)
+	c.put("b", results)
+	if _, ok := c.get("a"); !ok {
+		t.Error("searchCache.get(a) missed")
+	}
+	// b is now the least recently used entry
+	c.put("c", results)
+	if _, ok := c.get("b"); ok {
+		t.Error("searchCache.get(b) hit evicted entry")
+	}
+	if got, ok := c.get("c"); !ok || len(got) != 1 || got[0].Hash != "asdf" {
+		t.Errorf("searchCache.get(c) = (%v, %v)", got, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := c.get("a"); ok {
+		t.Error("searchCache.get(a) hit expired entry")
+	}
+
+	var stats = c.stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Errorf("searchCache.stats() = %+v", stats)
+	}
+	if stats.HitRate() != 0.5 {
+		t.Errorf("SearchCacheStats.HitRate() = %v, want 0.5", stats.HitRate())
+	}
+
+	// a nil cache never hits
+	var disabled *searchCache
+	disabled.put("a", results)
+	if _, ok := disabled.get("a"); ok {
+		t.Error("nil searchCache.get() hit")
+	}
+}
+
+func TestV2_Search_cache(t *testing.T) {
+	tests := []struct {
+		name         string
+		cache        SearchCacheOpts
+		wantSearches int
+	}{
+		{"disabled", SearchCacheOpts{}, 3},
+		{"enabled", SearchCacheOpts{Size: 10, TTL: time.Minute}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{SearchCache: tt.cache},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			se.SearchReturns([]engine.Result{{Hash: "asdf"}}, nil)
+
+			for _, query := range []string{"cats", "cats", "dogs"} {
+				resp, err := v.Search(context.Background(), &lensv2.SearchReq{Query: query})
+				if err != nil {
+					t.Errorf("V2.Search() error = %v", err)
+					return
+				}
+				if len(resp.GetResults()) != 1 || resp.GetResults()[0].GetDoc().GetHash() != "asdf" {
+					t.Errorf("V2.Search() = %v", resp.GetResults())
+				}
+			}
+			if se.SearchCallCount() != tt.wantSearches {
+				t.Errorf("V2.Search() executed %d searches, want %d",
+					se.SearchCallCount(), tt.wantSearches)
+			}
+		})
+	}
+}
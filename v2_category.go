@@ -0,0 +1,48 @@
+package lens
+
+import (
+	"strings"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// CategoryRule assigns a sub-category to objects of a category based on their
+// tags, which include keywords extracted from their content and tags provided
+// by clients
+type CategoryRule struct {
+	// Category is the category the rule applies to, ie 'document'
+	Category string
+	// Keywords are matched against tags case-insensitively - the rule applies
+	// if any keyword matches
+	Keywords []string
+	// SubCategory is appended to the category, ie 'legal/contract' to assign
+	// 'document/legal/contract'
+	SubCategory string
+}
+
+// matches checks if the rule applies to the given metadata
+func (r CategoryRule) matches(md *models.MetaDataV2) bool {
+	if !strings.EqualFold(strings.Trim(r.Category, models.CategorySeparator), md.Category) {
+		return false
+	}
+	for _, k := range r.Keywords {
+		for _, t := range md.Tags {
+			if strings.EqualFold(k, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classify refines the category of the given metadata using the configured
+// rules, in order. Each rule is applied at most once, and rules may refine
+// sub-categories assigned by earlier rules.
+func (v *V2) classify(md *models.MetaDataV2) {
+	for _, r := range v.categoryRules {
+		var sub = strings.Trim(r.SubCategory, models.CategorySeparator)
+		if sub != "" && r.matches(md) {
+			md.Category += models.CategorySeparator + sub
+		}
+	}
+}
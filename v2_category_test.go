@@ -0,0 +1,41 @@
+package lens
+
+import (
+	"testing"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_classify(t *testing.T) {
+	var v = newTestV2(t, V2Options{
+		CategoryRules: []CategoryRule{
+			{Category: "document", Keywords: []string{"contract", "lawsuit"}, SubCategory: "legal"},
+			{Category: "document/legal", Keywords: []string{"nda"}, SubCategory: "/contract/"},
+			{Category: "image", Keywords: []string{"contract"}, SubCategory: "scan"},
+			{Category: "document", Keywords: []string{"invoice"}, SubCategory: ""},
+		},
+	}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	tests := []struct {
+		name     string
+		category string
+		tags     []string
+		want     string
+	}{
+		{"no match", "document", []string{"ipfs"}, "document"},
+		{"match", "document", []string{"ipfs", "Lawsuit"}, "document/legal"},
+		{"chained", "document", []string{"contract", "NDA"}, "document/legal/contract"},
+		{"other category", "pdf", []string{"contract"}, "pdf"},
+		{"already refined", "document/legal", []string{"lawsuit"}, "document/legal"},
+		{"empty sub-category ignored", "document", []string{"invoice"}, "document"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var md = &models.MetaDataV2{Category: tt.category, Tags: tt.tags}
+			v.classify(md)
+			if md.Category != tt.want {
+				t.Errorf("V2.classify() = %v, want %v", md.Category, tt.want)
+			}
+		})
+	}
+}
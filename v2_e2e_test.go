@@ -0,0 +1,260 @@
+package lens
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/engine/queue"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+// testServer is an end-to-end test harness that serves a Lens V2 service with
+// a real search engine over gRPC. Objects are retrieved from a fake IPFS node
+// holding fixture content. Authentication and other server options are
+// covered by the server package.
+type testServer struct {
+	client lensv2.LensV2Client
+	ipfs   *mocks.FakeRTFSManager
+
+	// v is the served service, for functionality not exposed over gRPC
+	v *V2
+
+	close func()
+}
+
+// newTestServer starts a test server on a random local port, with an engine
+// in a temporary directory, serving the given objects keyed by hash. Callers
+// must close the server when done.
+func newTestServer(t *testing.T, opts V2Options, objects map[string]string) *testServer {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "lens-e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ipfs = &mocks.FakeRTFSManager{}
+	ipfs.CatStub = func(hash string) ([]byte, error) {
+		content, ok := objects[hash]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(content), nil
+	}
+
+	var l = zaptest.NewLogger(t).Sugar()
+	opts.Engine = engine.Opts{
+		StorePath: dir,
+		Queue:     queue.Options{Rate: 100 * time.Millisecond, BatchSize: 1},
+	}
+	v, err := NewV2(opts, ipfs, &mocks.FakeTensorflowAnalyzer{}, l)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		v.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	var srv = grpc.NewServer()
+	lensv2.RegisterLensV2Server(srv, v)
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		srv.Stop()
+		v.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return &testServer{
+		client: lensv2.NewLensV2Client(conn),
+		ipfs:   ipfs,
+		v:      v,
+		close: func() {
+			conn.Close()
+			srv.Stop()
+			v.Close()
+			os.RemoveAll(dir)
+		},
+	}
+}
+
+// search polls the server until the given search returns results, since
+// indexed objects only become searchable once the engine's queue is flushed
+func (s *testServer) search(t *testing.T, req *lensv2.SearchReq) *lensv2.SearchResp {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		resp, err := s.client.Search(context.Background(), req)
+		if err == nil && len(resp.GetResults()) > 0 {
+			return resp
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Search(%v) = (%v, %v), want results", req, resp, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestV2_e2e_indexAndSearch(t *testing.T) {
+	var s = newTestServer(t, V2Options{}, map[string]string{
+		"QmIPFS": "the interplanetary file system is a peer-to-peer distributed file system",
+		"QmCats": "cats are small carnivorous mammals kept as pets",
+	})
+	defer s.close()
+
+	for _, req := range []*lensv2.IndexReq{
+		{Type: lensv2.IndexReq_IPLD, Hash: "QmIPFS", DisplayName: "ipfs.txt", Tags: []string{"ipfs"}},
+		{Type: lensv2.IndexReq_IPLD, Hash: "QmCats", DisplayName: "cats.txt"},
+	} {
+		resp, err := s.client.Index(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Index(%s) error = %v", req.GetHash(), err)
+		}
+		if doc := resp.GetDoc(); doc.GetHash() != req.GetHash() || doc.GetCategory() != "document" {
+			t.Errorf("Index(%s) = %+v, want indexed document", req.GetHash(), doc)
+		}
+	}
+	if _, err := s.client.Index(context.Background(), &lensv2.IndexReq{
+		Type: lensv2.IndexReq_IPLD,
+		Hash: "QmMissing",
+	}); err == nil {
+		t.Error("Index() of missing object succeeded")
+	}
+
+	tests := []struct {
+		name string
+		req  *lensv2.SearchReq
+		want string
+	}{
+		{"query", &lensv2.SearchReq{Query: "interplanetary"}, "QmIPFS"},
+		{"required", &lensv2.SearchReq{Query: "mammals",
+			Options: &lensv2.SearchReq_Options{Required: []string{"cats"}}}, "QmCats"},
+		{"tags", &lensv2.SearchReq{
+			Options: &lensv2.SearchReq_Options{Tags: []string{"ipfs"}}}, "QmIPFS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results = s.search(t, tt.req).GetResults()
+			if len(results) != 1 {
+				t.Fatalf("Search() returned %d results, want 1", len(results))
+			}
+			if doc := results[0].GetDoc(); doc.GetHash() != tt.want || doc.GetMimeType() == "" {
+				t.Errorf("Search() = %+v, want %s", doc, tt.want)
+			}
+		})
+	}
+
+	// removed objects are no longer found
+	if _, err := s.client.Remove(context.Background(), &lensv2.RemoveReq{Hash: "QmCats"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		resp, err := s.client.Search(context.Background(), &lensv2.SearchReq{Query: "mammals"})
+		if err != nil || len(resp.GetResults()) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Search() after Remove() = %v, want no results", resp.GetResults())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// indexedAfterFlush waits for the given object to be flushed with the given
+// index state
+func (s *testServer) indexedAfterFlush(t *testing.T, hash string, want bool) {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+	for s.v.se.IsIndexed(hash) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("IsIndexed(%s) = %v, want %v", hash, !want, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// keywordObjects are tagged with keywords that share words with each other
+var keywordObjects = map[string]string{
+	"QmML":       "neural networks are trained on data",
+	"QmMachine":  "lathes and mills shape metal",
+	"QmLearning": "students attend lectures",
+	"QmSpam":     "buy cheap watches now",
+	"QmSpamBot":  "automated accounts post links",
+}
+
+// indexKeywordObjects indexes keywordObjects with their tags
+func (s *testServer) indexKeywordObjects(t *testing.T) {
+	t.Helper()
+	for hash, tag := range map[string]string{
+		"QmML":       "machine learning",
+		"QmMachine":  "machine",
+		"QmLearning": "learning",
+		"QmSpam":     "Spam",
+		"QmSpamBot":  "spam-bot",
+	} {
+		if _, err := s.client.Index(context.Background(), &lensv2.IndexReq{
+			Type: lensv2.IndexReq_IPLD,
+			Hash: hash,
+			Tags: []string{tag},
+		}); err != nil {
+			t.Fatalf("Index(%s) error = %v", hash, err)
+		}
+		s.indexedAfterFlush(t, hash, true)
+	}
+}
+
+func TestV2_e2e_RemoveByKeyword(t *testing.T) {
+	var s = newTestServer(t, V2Options{}, keywordObjects)
+	defer s.close()
+	s.indexKeywordObjects(t)
+
+	// only whole tags are matched, ignoring case
+	for keyword, want := range map[string]string{
+		"machine learning": "QmML",
+		"spam":             "QmSpam",
+	} {
+		removed, err := s.v.RemoveByKeyword(context.Background(), keyword)
+		if err != nil || removed != 1 {
+			t.Errorf("RemoveByKeyword(%s) = (%d, %v), want 1 removed", keyword, removed, err)
+		}
+		s.indexedAfterFlush(t, want, false)
+	}
+	for _, hash := range []string{"QmMachine", "QmLearning", "QmSpamBot"} {
+		s.indexedAfterFlush(t, hash, true)
+	}
+}
+
+func TestV2_e2e_RepairKeyword(t *testing.T) {
+	var s = newTestServer(t, V2Options{}, keywordObjects)
+	defer s.close()
+	s.indexKeywordObjects(t)
+
+	// every object is unreachable, but only whole tags are matched
+	s.ipfs.StatReturns(nil, &shell.Error{Message: "merkledag: not found"})
+	report, err := s.v.InspectKeyword(context.Background(), "machine")
+	if err != nil || !reflect.DeepEqual(report.Unreachable, []string{"QmMachine"}) {
+		t.Fatalf("InspectKeyword() = (%+v, %v), want only QmMachine", report, err)
+	}
+	if removed, err := s.v.RepairKeyword(context.Background(), "spam"); err != nil || removed != 1 {
+		t.Errorf("RepairKeyword() = (%d, %v), want 1 removed", removed, err)
+	}
+	s.indexedAfterFlush(t, "QmSpam", false)
+	for _, hash := range []string{"QmML", "QmMachine", "QmLearning", "QmSpamBot"} {
+		s.indexedAfterFlush(t, hash, true)
+	}
+}
@@ -0,0 +1,72 @@
+package lens
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+)
+
+// ExportFormat denotes a serialization of search results for offline use
+type ExportFormat string
+
+const (
+	// ExportCSV writes search results as CSV with a header row
+	ExportCSV ExportFormat = "csv"
+	// ExportJSON writes search results as a JSON array
+	ExportJSON ExportFormat = "json"
+)
+
+// exportedResult is the JSON representation of a search result
+type exportedResult struct {
+	Hash     string   `json:"hash"`
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	MimeType string   `json:"mime_type"`
+	Score    float32  `json:"score"`
+	Tags     []string `json:"tags"`
+}
+
+// ExportResults writes the results of a search in the given format.
+//
+// TODO: serve through an HTTP gateway, with the format selected by the Accept
+// header or a 'format' parameter, once Lens has one
+func ExportResults(w io.Writer, format ExportFormat, resp *lensv2.SearchResp) error {
+	var results = resp.GetResults()
+	switch format {
+	case ExportCSV:
+		var cw = csv.NewWriter(w)
+		cw.Write([]string{"hash", "name", "category", "mime_type", "score"})
+		for _, r := range results {
+			var doc = r.GetDoc()
+			cw.Write([]string{
+				doc.GetHash(),
+				doc.GetDisplayName(),
+				doc.GetCategory(),
+				doc.GetMimeType(),
+				strconv.FormatFloat(float64(r.GetScore()), 'f', -1, 32),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case ExportJSON:
+		var exported = make([]exportedResult, len(results))
+		for i, r := range results {
+			var doc = r.GetDoc()
+			exported[i] = exportedResult{
+				Hash:     doc.GetHash(),
+				Name:     doc.GetDisplayName(),
+				Category: doc.GetCategory(),
+				MimeType: doc.GetMimeType(),
+				Score:    r.GetScore(),
+				Tags:     append([]string{}, doc.GetTags()...),
+			}
+		}
+		return json.NewEncoder(w).Encode(exported)
+	default:
+		return fmt.Errorf("unsupported export format '%s'", format)
+	}
+}
@@ -0,0 +1,52 @@
+package lens
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+)
+
+func TestExportResults(t *testing.T) {
+	var resp = &lensv2.SearchResp{Results: []*lensv2.SearchResp_Result{
+		{Score: 1.5, Doc: &lensv2.Document{
+			Hash:        "asdf",
+			DisplayName: `report, "final"`,
+			Category:    "document",
+			MimeType:    "application/pdf",
+			Tags:        []string{"quarterly"},
+		}},
+		{Score: 0.25, Doc: &lensv2.Document{
+			Hash:     "qwer",
+			Category: "image",
+			MimeType: "image/png",
+		}},
+	}}
+	tests := []struct {
+		name    string
+		format  ExportFormat
+		want    string
+		wantErr bool
+	}{
+		{"csv", ExportCSV, "hash,name,category,mime_type,score\n" +
+			"asdf,\"report, \"\"final\"\"\",document,application/pdf,1.5\n" +
+			"qwer,,image,image/png,0.25\n", false},
+		{"json", ExportJSON, `[{"hash":"asdf","name":"report, \"final\"","category":"document",` +
+			`"mime_type":"application/pdf","score":1.5,"tags":["quarterly"]},` +
+			`{"hash":"qwer","name":"","category":"image","mime_type":"image/png","score":0.25,"tags":[]}]` +
+			"\n", false},
+		{"unsupported", ExportFormat("xml"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ExportResults(&buf, tt.format, resp); (err != nil) != tt.wantErr {
+				t.Errorf("ExportResults() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("ExportResults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package lens
+
+import (
+	"strings"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// extractedDocument denotes the fields each format analyzer extracts from an
+// object, independent of its format. Keywords are derived from it in one
+// place, by weighKeywords, so that they are weighted the same way for all
+// formats.
+type extractedDocument struct {
+	title   string
+	author  string
+	content string      // body text
+	links   []text.Link // only extracted if link keywords are enabled
+	tags    []string    // keywords provided by the format, ie image classes
+
+	// emphasis weighs words emphasized in the body, keyed by lowercase word
+	emphasis map[string]float64
+
+	// location is the geotag of the object, if geotagging is enabled
+	location *models.Location
+}
+
+// weighKeywords returns the keywords of the given document in order of
+// weight - its title, author, and format tags first, followed by keywords
+// from its links and the given keywords extracted from its body
+func (v *V2) weighKeywords(d *extractedDocument, body []string) []string {
+	var keywords []string
+	for _, f := range []string{d.title, d.author} {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			keywords = appendUnique(keywords, f)
+		}
+	}
+	keywords = appendUnique(keywords, d.tags...)
+	keywords = appendUnique(keywords, v.linkTags(d.links)...)
+	return appendUnique(keywords, body...)
+}
@@ -0,0 +1,176 @@
+package lens
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/ocr"
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_weighKeywords(t *testing.T) {
+	var v = newTestV2(t, V2Options{Links: LinkOpts{Domains: true}},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	tests := []struct {
+		name string
+		doc  extractedDocument
+		body []string
+		want []string
+	}{
+		{"empty", extractedDocument{}, nil, nil},
+		{"body only", extractedDocument{}, []string{"web"}, []string{"web"}},
+		{"weighted", extractedDocument{
+			title:  " Lens Guide ",
+			author: "Jane Doe",
+			links:  []text.Link{{Text: "IPFS", Target: "https://ipfs.io"}},
+			tags:   []string{"guide"},
+		}, []string{"search", "guide"}, []string{"lens guide", "jane doe", "guide", "ipfs.io", "search"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.weighKeywords(&tt.doc, tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("V2.weighKeywords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_fields(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantTitle  string
+		wantAuthor string
+		wantTags   []string
+	}{
+		{"html", `<html><head><title>Lens Guide</title><meta name="author" content="Jane Doe"></head>` +
+			`<body>search <a href="https://ipfs.io">ipfs</a></body></html>`,
+			"Lens Guide", "Jane Doe", []string{"lens guide", "jane doe", "ipfs.io", "search"}},
+		{"markdown", "# Lens Guide\n\nsearch [ipfs](https://ipfs.io)",
+			"Lens Guide", "", []string{"lens guide", "ipfs.io", "search"}},
+		{"plain", "search ipfs", "", "", []string{"search"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{
+				Links: LinkOpts{Domains: true},
+				Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+					return []string{"search"}
+				}),
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(tt.content), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if a.title != tt.wantTitle || a.author != tt.wantAuthor {
+				t.Errorf("V2.analyze() title = %q, author = %q, want %q, %q",
+					a.title, a.author, tt.wantTitle, tt.wantAuthor)
+			}
+			if !reflect.DeepEqual(a.keywords, tt.wantTags) {
+				t.Errorf("V2.analyze() keywords = %v, want %v", a.keywords, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_outline(t *testing.T) {
+	pdf, err := ioutil.ReadFile("test/assets/outline.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		maxOutline int
+		want       []string
+	}{
+		{"disabled", 0, []string{"lens"}},
+		{"limited", 2, []string{"overview", "getting started", "lens"}},
+		{"nested", 10, []string{"overview", "getting started", "installation", "search syntax", "lens"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{
+				OCR: ocr.Options{MaxPDFOutline: tt.maxOutline},
+				Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+					return []string{"lens"}
+				}),
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", pdf, "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.keywords, tt.want) {
+				t.Errorf("V2.analyze() keywords = %v, want %v", a.keywords, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_emphasis(t *testing.T) {
+	tests := []struct {
+		name     string
+		emphasis text.Emphasis
+		content  string
+		want     []string
+	}{
+		{"disabled", text.Emphasis{}, "the distributed web runs on **IPFS**", []string{"web", "ipfs"}},
+		{"markdown", text.Emphasis{Strong: 2}, "the distributed web runs on **IPFS**", []string{"ipfs", "web"}},
+		{"html", text.Emphasis{Strong: 2}, "<html><body><p>the distributed web runs on <b>IPFS</b></p></body></html>",
+			[]string{"ipfs", "web"}},
+		{"no emphasis", text.Emphasis{Strong: 2}, "the distributed web runs on IPFS", []string{"web", "ipfs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{
+				Emphasis: tt.emphasis,
+				Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+					return []string{"web", "ipfs"}
+				}),
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(tt.content), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.keywords, tt.want) {
+				t.Errorf("V2.analyze() keywords = %v, want %v", a.keywords, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_geotags(t *testing.T) {
+	const page = `<html><head><meta name="geo.position" content="49.2827;-123.1207"></head>` +
+		`<body>search</body></html>`
+	tests := []struct {
+		name    string
+		geotags bool
+		content string
+		want    *models.Location
+	}{
+		{"disabled", false, page, nil},
+		{"html", true, page, &models.Location{Latitude: 49.2827, Longitude: -123.1207}},
+		{"no geotag", true, "<html><body>search</body></html>", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{Geotags: tt.geotags},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(tt.content), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.location, tt.want) {
+				t.Errorf("V2.analyze() location = %v, want %v", a.location, tt.want)
+			}
+		})
+	}
+}
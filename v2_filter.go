@@ -0,0 +1,105 @@
+package lens
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// ContentFilter restricts what content may be indexed. Entries may be
+// categories (ie 'image'), which include their sub-categories, or mime type
+// prefixes (ie 'image/' or 'application/pdf'). Denied entries take precedence over allowed entries, and
+// if no entries are allowed, all content not denied is allowed.
+//
+// If any entries are configured, the content type of IPFS objects is detected
+// from their first bytes where possible, so that disallowed objects are
+// rejected without being retrieved in full.
+type ContentFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// check returns an error if content of the given mime type and category may
+// not be indexed
+func (f ContentFilter) check(mimeType, category string) error {
+	var matches = func(entries []string) bool {
+		for _, e := range entries {
+			if e = strings.TrimSpace(e); e == "" {
+				continue
+			}
+			if models.InCategory(category, e) || strings.HasPrefix(mimeType, e) {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(f.Deny) || (len(f.Allow) > 0 && !matches(f.Allow)) {
+		return fmt.Errorf("content type '%s' (category '%s') is not allowed for indexing",
+			mimeType, category)
+	}
+	return nil
+}
+
+// peekSize is the number of bytes content types are detected from
+const peekSize = 512
+
+// precheck retrieves only the first bytes of the given object to reject it if
+// its content type is not allowed, before its contents are retrieved in full.
+// Objects whose type cannot be determined from their first bytes, such as
+// archives and media, are left to be checked once retrieved, as are objects
+// that could not be peeked at.
+func (v *V2) precheck(hash string, b *budget, l *zap.SugaredLogger) error {
+	if len(v.filter.Allow) == 0 && len(v.filter.Deny) == 0 {
+		return nil
+	}
+	head, err := v.px.PeekContents(b.context(), hash, peekSize)
+	if err != nil {
+		l.Debugw("failed to peek at content type", "error", err)
+		return nil
+	}
+	if isCompressed(head) {
+		return nil
+	}
+	var mimeType = strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+	if mimeType == "application/octet-stream" {
+		return nil
+	}
+	if err := v.filter.check(mimeType, v.category(mimeType, detectCategory(mimeType))); err != nil {
+		return v.reject(mimeType, err, l)
+	}
+	return nil
+}
+
+// isCompressed checks if the given contents begin with a gzip or zip header,
+// which may hold archives that cannot be detected from their first bytes
+func isCompressed(head []byte) bool {
+	return strings.HasPrefix(string(head), "\x1f\x8b") ||
+		strings.HasPrefix(string(head), "PK\x03\x04")
+}
+
+// detectCategory returns the built-in category for the given mime type
+func detectCategory(mimeType string) models.MimeType {
+	switch {
+	case mimeType == "application/pdf":
+		return models.MimeTypePDF
+	case strings.HasPrefix(mimeType, "text/"):
+		return models.MimeTypeDocument
+	case strings.HasPrefix(mimeType, "image/"):
+		return models.MimeTypeImage
+	case isMedia(mimeType):
+		return models.MimeTypeMedia
+	default:
+		return models.MimeTypeUnknown
+	}
+}
+
+// isMedia checks if the given mime type denotes audio or video
+func isMedia(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "audio/") ||
+		strings.HasPrefix(mimeType, "video/") ||
+		mimeType == "application/ogg"
+}
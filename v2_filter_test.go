@@ -0,0 +1,141 @@
+package lens
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestContentFilter_check(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   ContentFilter
+		mimeType string
+		category string
+		wantErr  bool
+	}{
+		{"allow all by default", ContentFilter{}, "image/png", "image", false},
+		{"deny category", ContentFilter{Deny: []string{"image"}}, "image/png", "image", true},
+		{"deny mime prefix", ContentFilter{Deny: []string{"text/html"}}, "text/html", "document", true},
+		{"deny other", ContentFilter{Deny: []string{"image"}}, "text/plain", "document", false},
+		{"deny parent category", ContentFilter{Deny: []string{"image"}}, "image/png", "image/photo", true},
+		{"deny sub-category", ContentFilter{Deny: []string{"image/photo"}}, "image/png", "image", false},
+		{"allow category", ContentFilter{Allow: []string{"document", "pdf"}}, "text/plain", "document", false},
+		{"allow mime prefix", ContentFilter{Allow: []string{"application/"}}, "application/pdf", "pdf", false},
+		{"not allowed", ContentFilter{Allow: []string{"document"}}, "image/png", "image", true},
+		{"deny takes precedence", ContentFilter{
+			Allow: []string{"document"},
+			Deny:  []string{"text/html"},
+		}, "text/html", "document", true},
+		{"blank entries ignored", ContentFilter{Deny: []string{" "}}, "image/png", "image", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.check(tt.mimeType, tt.category); (err != nil) != tt.wantErr {
+				t.Errorf("ContentFilter.check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_detectCategory(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     models.MimeType
+	}{
+		{"application/pdf", models.MimeTypePDF},
+		{"text/plain", models.MimeTypeDocument},
+		{"image/jpeg", models.MimeTypeImage},
+		{"audio/mpeg", models.MimeTypeMedia},
+		{"application/ogg", models.MimeTypeMedia},
+		{"application/octet-stream", models.MimeTypeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			if got := detectCategory(tt.mimeType); got != tt.want {
+				t.Errorf("detectCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_filtered(t *testing.T) {
+	image, err := ioutil.ReadFile("test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tensor = &mocks.FakeTensorflowAnalyzer{}
+	var v = newTestV2(t, V2Options{
+		ContentFilter: ContentFilter{Deny: []string{"image"}},
+	}, &mocks.FakeRTFSManager{}, tensor, &mocks.FakeSearcher{}, nil)
+
+	if _, err := v.analyze("asdf", image, "", zap.NewNop().Sugar()); err == nil {
+		t.Error("V2.analyze() expected error for denied content")
+	}
+	if tensor.AnalyzeCallCount() > 0 {
+		t.Error("V2.analyze() should not classify denied content")
+	}
+	if _, err := v.analyze("asdf", []byte("hello world"), "", zap.NewNop().Sugar()); err != nil {
+		t.Errorf("V2.analyze() error = %v", err)
+	}
+}
+
+func TestV2_Index_precheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        ContentFilter
+		head          []byte
+		peekErr       error
+		wantPeek      bool
+		wantRetrieved bool
+	}{
+		{"no filter", ContentFilter{}, []byte("%PDF-1.4"), nil, false, true},
+		{"denied", ContentFilter{Deny: []string{"pdf"}}, []byte("%PDF-1.4"), nil, true, false},
+		{"not allowed", ContentFilter{Allow: []string{"document"}}, []byte("%PDF-1.4"), nil, true, false},
+		{"allowed", ContentFilter{Allow: []string{"document"}}, []byte("hello world"), nil, true, true},
+		{"undetermined", ContentFilter{Deny: []string{"pdf"}}, []byte{0x00, 0x01}, nil, true, true},
+		{"compressed", ContentFilter{Deny: []string{"application/"}}, []byte("PK\x03\x04"), nil, true, true},
+		{"peek failed", ContentFilter{Deny: []string{"pdf"}}, nil, errors.New("oh no"), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var v = newTestV2(t, V2Options{ContentFilter: tt.filter}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+			ipfs.CustomRequestReturns(&shell.Response{
+				Output: ioutil.NopCloser(bytes.NewReader(tt.head)),
+			}, tt.peekErr)
+			ipfs.CatReturns([]byte("hello world"), nil)
+
+			_, err := v.Index(context.Background(), &lensv2.IndexReq{
+				Type: lensv2.IndexReq_IPLD,
+				Hash: "asdf",
+			})
+			if peeked := ipfs.CustomRequestCallCount() > 0; peeked != tt.wantPeek {
+				t.Errorf("V2.Index() peeked = %v, want %v", peeked, tt.wantPeek)
+			}
+			if retrieved := ipfs.CatCallCount() > 0; retrieved != tt.wantRetrieved {
+				t.Errorf("V2.Index() retrieved = %v, want %v", retrieved, tt.wantRetrieved)
+			}
+			if !tt.wantRetrieved && status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("V2.Index() error = %v, want rejection", err)
+			}
+			if tt.wantPeek {
+				if _, _, command, opts, _ := ipfs.CustomRequestArgsForCall(0); command != "cat" || opts["length"] != "512" {
+					t.Errorf("V2.Index() peeked with %s %v, want cat of 512 bytes", command, opts)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package lens
+
+import (
+	"context"
+	"sort"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// SearchNearby returns geotagged objects located within radiusKm kilometres
+// of the given latitude and longitude, nearest first. The search may be
+// narrowed by the query and options of req, which may be nil to match every
+// nearby object. Objects without a location are never returned - see
+// V2Options.Geotags.
+func (v *V2) SearchNearby(
+	ctx context.Context,
+	req *lensv2.SearchReq,
+	lat, lon, radiusKm float64,
+) (*lensv2.SearchResp, error) {
+	var center = models.Location{Latitude: lat, Longitude: lon}
+	if !center.Valid() {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid request: location %v, %v is out of range", lat, lon)
+	}
+	if !(radiusKm > 0) {
+		return nil, status.Error(codes.InvalidArgument,
+			"invalid request: radius must be positive")
+	}
+	query, err := v.filterQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	query.Near = &engine.Area{Center: center, Radius: radiusKm}
+
+	results, err := v.search(ctx, req, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// results may be cached, so sort a copy
+	var nearest = make([]engine.Result, 0, len(results))
+	for _, r := range results {
+		if r.MD.Location != nil {
+			nearest = append(nearest, r)
+		}
+	}
+	sort.SliceStable(nearest, func(i, j int) bool {
+		return center.Distance(*nearest[i].MD.Location) < center.Distance(*nearest[j].MD.Location)
+	})
+	return newSearchResp(nearest), nil
+}
@@ -0,0 +1,77 @@
+package lens
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_SearchNearby(t *testing.T) {
+	var at = func(hash string, lat, lon float64) engine.Result {
+		return engine.Result{Hash: hash, MD: models.MetaDataV2{
+			Location: &models.Location{Latitude: lat, Longitude: lon}}}
+	}
+	var results = []engine.Result{
+		at("seattle", 47.6062, -122.3321),
+		{Hash: "nowhere"},
+		at("vancouver", 49.2827, -123.1207),
+		at("victoria", 48.4284, -123.3656),
+	}
+	tests := []struct {
+		name        string
+		req         *lensv2.SearchReq
+		lat, lon    float64
+		radius      float64
+		want        []string
+		wantErrCode codes.Code
+	}{
+		{"nearest first", nil, 49.2827, -123.1207, 250, []string{"vancouver", "victoria", "seattle"}, 0},
+		{"with keywords", &lensv2.SearchReq{Query: "harbour"}, 47.6062, -122.3321, 250,
+			[]string{"seattle", "victoria", "vancouver"}, 0},
+		{"bad latitude", nil, 91, 0, 250, nil, codes.InvalidArgument},
+		{"bad longitude", nil, 0, -181, 250, nil, codes.InvalidArgument},
+		{"bad radius", nil, 0, 0, 0, nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			se.SearchReturns(results, nil)
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+
+			got, err := v.SearchNearby(context.Background(), tt.req, tt.lat, tt.lon, tt.radius)
+			if status.Code(err) != tt.wantErrCode {
+				t.Fatalf("V2.SearchNearby() error = %v, want code %s", err, tt.wantErrCode)
+			}
+			if err != nil {
+				return
+			}
+			var hashes = make([]string, len(got.GetResults()))
+			for i, r := range got.GetResults() {
+				hashes[i] = r.GetDoc().GetHash()
+			}
+			if !reflect.DeepEqual(hashes, tt.want) {
+				t.Errorf("V2.SearchNearby() = %v, want %v", hashes, tt.want)
+			}
+
+			_, q := se.SearchArgsForCall(0)
+			var wantArea = &engine.Area{
+				Center: models.Location{Latitude: tt.lat, Longitude: tt.lon},
+				Radius: tt.radius,
+			}
+			if !reflect.DeepEqual(q.Near, wantArea) || q.Text != tt.req.GetQuery() {
+				t.Errorf("V2.SearchNearby() query = %+v, want area %+v and query %q",
+					q, wantArea, tt.req.GetQuery())
+			}
+		})
+	}
+}
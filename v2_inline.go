@@ -0,0 +1,73 @@
+package lens
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// inlineHashPrefix identifies objects indexed from inline content that was not
+// added to IPFS
+const inlineHashPrefix = "sha256:"
+
+// IndexBytesOpts configures IndexBytes
+type IndexBytesOpts struct {
+	DisplayName string
+	Tags        []string
+	Reindex     bool
+
+	// AddToIPFS adds the content to IPFS, and indexes it under the resulting
+	// hash. Otherwise, the content is indexed under its SHA-256 checksum in the
+	// form 'sha256:<hex>', and cannot be retrieved from Lens later.
+	AddToIPFS bool
+}
+
+// IndexBytes analyzes and stores the given content, which is provided directly
+// rather than retrieved from IPFS. The returned document hash identifies the
+// indexed object.
+func (v *V2) IndexBytes(ctx context.Context, content []byte, opts IndexBytesOpts) (*lensv2.IndexResp, error) {
+	if len(content) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no content provided")
+	}
+	if len(content) > maxInlineContentLength {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"content exceeds maximum length of %d", maxInlineContentLength)
+	}
+	// check before adding content to IPFS, which cannot be undone
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+
+	var hash string
+	if opts.AddToIPFS {
+		var err error
+		if hash, err = v.ipfs.Add(bytes.NewReader(content)); err != nil {
+			return nil, status.Errorf(codes.Unavailable,
+				"failed to add content to IPFS: %s", err.Error())
+		}
+	} else {
+		var sum = sha256.Sum256(content)
+		hash = inlineHashPrefix + hex.EncodeToString(sum[:])
+	}
+
+	var req = &lensv2.IndexReq{
+		Type:        lensv2.IndexReq_IPLD,
+		Hash:        hash,
+		DisplayName: opts.DisplayName,
+		Tags:        opts.Tags,
+		Options:     &lensv2.IndexReq_Options{Reindex: opts.Reindex},
+	}
+	if err := validateIndexReq(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	return v.index(ctx, req, content, nil, nil, v.l.With(
+		"hash", hash,
+		"display_name", opts.DisplayName,
+		"size", len(content)))
+}
@@ -0,0 +1,67 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func TestV2_IndexBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		opts        IndexBytesOpts
+		addErr      error
+		wantHash    string
+		wantErrCode codes.Code
+	}{
+		{"no content", nil, IndexBytesOpts{}, nil, "", codes.InvalidArgument},
+		{"too large", make([]byte, maxInlineContentLength+1), IndexBytesOpts{},
+			nil, "", codes.InvalidArgument},
+		{"ipfs unavailable", []byte("hello world"), IndexBytesOpts{AddToIPFS: true},
+			errors.New("oh no"), "", codes.Unavailable},
+		{"ok: analysis only", []byte("hello world"), IndexBytesOpts{DisplayName: "hello.txt"}, nil,
+			"sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", 0},
+		{"ok: add to ipfs", []byte("hello world"), IndexBytesOpts{AddToIPFS: true},
+			nil, "QmHello", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			ipfs.AddReturns("QmHello", tt.addErr)
+
+			got, err := v.IndexBytes(context.Background(), tt.content, tt.opts)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.IndexBytes() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.IndexBytes() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+				return
+			}
+			if got.GetDoc().GetHash() != tt.wantHash {
+				t.Errorf("V2.IndexBytes() hash = %s, want %s", got.GetDoc().GetHash(), tt.wantHash)
+			}
+			if ipfs.CatCallCount() > 0 {
+				t.Error("V2.IndexBytes() retrieved content from IPFS")
+			}
+			var doc = se.IndexArgsForCall(0)
+			if doc.Object.Hash != tt.wantHash || !strings.Contains(doc.Content, "hello world") {
+				t.Errorf("V2.IndexBytes() stored %+v", doc)
+			}
+		})
+	}
+}
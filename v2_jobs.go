@@ -0,0 +1,470 @@
+package lens
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultRetainedJobs is the number of finished jobs kept for status queries
+// if AsyncOpts.Retain is not set
+const defaultRetainedJobs = 1000
+
+// AsyncOpts configures asynchronous indexing
+type AsyncOpts struct {
+	// Workers is the number of background index workers - leave at 0 to
+	// disable asynchronous indexing
+	Workers int
+	// QueueSize is the number of jobs that may wait for a worker - further
+	// jobs are rejected with codes.ResourceExhausted
+	QueueSize int
+	// Retain is the number of finished jobs to keep for status queries
+	Retain int
+	// StorePath is the path of the database job records are persisted in, so
+	// that jobs can be queried across restarts - leave empty to only track
+	// jobs in memory
+	StorePath string
+}
+
+// JobStatus denotes the state of an asynchronous index job
+type JobStatus string
+
+const (
+	// JobPending indicates the job is queued or being processed
+	JobPending JobStatus = "pending"
+	// JobDone indicates the job completed successfully
+	JobDone JobStatus = "done"
+	// JobFailed indicates the job could not be completed
+	JobFailed JobStatus = "failed"
+	// JobCancelled indicates the job was cancelled before it completed
+	JobCancelled JobStatus = "cancelled"
+)
+
+var (
+	errJobNotFound  = errors.New("job not found")
+	errJobFinished  = errors.New("job has already finished")
+	errJobCancelled = errors.New("job has been cancelled")
+)
+
+// IndexJob denotes the state of an asynchronous index request
+type IndexJob struct {
+	ID      string
+	Status  JobStatus
+	Created time.Time
+	Updated time.Time
+
+	// Result is set once the job is done
+	Result *lensv2.IndexResp
+	// Error is set if the job failed
+	Error string
+}
+
+type indexFunc func(ctx context.Context, req *lensv2.IndexReq) (*lensv2.IndexResp, error)
+
+// discardFunc undoes the given writes made by a cancelled index request
+type discardFunc func(req *lensv2.IndexReq, written []string)
+
+// jobWrites records the documents created by an index job, so that they can be
+// discarded if the job is cancelled
+type jobWrites struct {
+	mux     sync.Mutex
+	written []string
+	closed  bool
+}
+
+type jobWritesKey struct{}
+
+// jobWritesFrom returns the write log of the job the given context belongs to,
+// or nil if it does not belong to a job
+func jobWritesFrom(ctx context.Context) *jobWrites {
+	w, _ := ctx.Value(jobWritesKey{}).(*jobWrites)
+	return w
+}
+
+// write performs the given write of hash, and records hash if created is set.
+// Once the log is closed, writes are rejected. A nil log performs writes
+// without recording them.
+func (w *jobWrites) write(hash string, created bool, fn func() error) error {
+	if w == nil {
+		return fn()
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.closed {
+		return errJobCancelled
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if created {
+		w.written = append(w.written, hash)
+	}
+	return nil
+}
+
+// close waits for writes in progress, rejects any further writes, and returns
+// the documents created so far
+func (w *jobWrites) close() []string {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.closed = true
+	return w.written
+}
+
+type queuedJob struct {
+	id  string
+	ctx context.Context
+	req *lensv2.IndexReq
+}
+
+// jobQueue runs index requests on a bounded pool of background workers and
+// tracks their status. A nil jobQueue does not accept any jobs.
+type jobQueue struct {
+	queue chan queuedJob
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	store *jobStore
+	l     *zap.SugaredLogger
+
+	mux      sync.RWMutex
+	jobs     map[string]*IndexJob
+	cancels  map[string]context.CancelFunc // unfinished jobs only
+	finished []string                      // oldest first
+	retain   int
+	capacity int // maximum number of unfinished jobs
+}
+
+// newJobQueue returns nil if opts.Workers is not positive. If discard is not
+// nil, it is called for each job that is cancelled while being processed. Jobs
+// recorded in opts.StorePath are restored.
+func newJobQueue(
+	opts AsyncOpts,
+	index indexFunc,
+	discard discardFunc,
+	logger *zap.SugaredLogger,
+) (*jobQueue, error) {
+	if opts.Workers < 1 {
+		return nil, nil
+	}
+	if opts.QueueSize < 0 {
+		opts.QueueSize = 0
+	}
+	if opts.Retain < 1 {
+		opts.Retain = defaultRetainedJobs
+	}
+	// jobs being processed are counted separately from the jobs waiting for
+	// a worker, so that submissions never block
+	var capacity = opts.Workers + opts.QueueSize
+	var q = &jobQueue{
+		queue:    make(chan queuedJob, capacity),
+		stop:     make(chan struct{}),
+		jobs:     make(map[string]*IndexJob),
+		cancels:  make(map[string]context.CancelFunc),
+		retain:   opts.Retain,
+		capacity: capacity,
+		l:        logger.Named("jobs"),
+	}
+	var err error
+	if q.store, err = openJobStore(opts.StorePath); err != nil {
+		return nil, fmt.Errorf("failed to open job store: %s", err.Error())
+	}
+	if err := q.restore(); err != nil {
+		q.store.close()
+		return nil, fmt.Errorf("failed to restore jobs: %s", err.Error())
+	}
+	for i := 0; i < opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(index, discard)
+	}
+	return q, nil
+}
+
+// restore loads persisted jobs. Requests are not persisted, so jobs that were
+// still pending when Lens stopped cannot be resumed, and are marked as failed.
+func (q *jobQueue) restore() error {
+	jobs, err := q.store.load()
+	if err != nil {
+		return err
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Updated.Before(jobs[j].Updated) })
+	for i := range jobs {
+		var job = jobs[i]
+		if job.Status == JobPending {
+			job.Status = JobFailed
+			job.Error = "job was interrupted by a restart"
+			job.Updated = time.Now()
+			if err := q.store.put(job); err != nil {
+				return err
+			}
+		}
+		q.jobs[job.ID] = &job
+		q.finished = append(q.finished, job.ID)
+	}
+	if len(jobs) > 0 {
+		q.l.Infow("jobs restored", "jobs", len(jobs))
+	}
+	return q.evict()
+}
+
+// evict forgets the oldest finished jobs if more than the configured number
+// are retained
+func (q *jobQueue) evict() error {
+	if len(q.finished) <= q.retain {
+		return nil
+	}
+	var evicted = q.finished[:len(q.finished)-q.retain]
+	for _, id := range evicted {
+		delete(q.jobs, id)
+	}
+	q.finished = q.finished[len(evicted):]
+	return q.store.remove(evicted...)
+}
+
+func (q *jobQueue) work(index indexFunc, discard discardFunc) {
+	defer q.wg.Done()
+	for {
+		select {
+		case j := <-q.queue:
+			// jobs cancelled while queued are not processed at all
+			if j.ctx.Err() != nil {
+				q.finish(j.id, nil, j.ctx.Err())
+				continue
+			}
+			var writes = &jobWrites{}
+			resp, err := index(context.WithValue(j.ctx, jobWritesKey{}, writes), j.req)
+			if j.ctx.Err() != nil {
+				// stages abandoned on cancellation may still be writing
+				if written := writes.close(); discard != nil && len(written) > 0 {
+					discard(j.req, written)
+				}
+			}
+			q.finish(j.id, resp, err)
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// submit registers a new job for the given request, or returns errQueueFull
+// if no more jobs can be accepted
+func (q *jobQueue) submit(req *lensv2.IndexReq) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+	var now = time.Now()
+	var job = &IndexJob{ID: id, Status: JobPending, Created: now, Updated: now}
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mux.Lock()
+	if len(q.cancels) >= q.capacity {
+		q.mux.Unlock()
+		cancel()
+		return "", errQueueFull
+	}
+	q.jobs[id] = job
+	q.cancels[id] = cancel
+	q.mux.Unlock()
+
+	if err := q.store.put(*job); err != nil {
+		q.mux.Lock()
+		delete(q.jobs, id)
+		delete(q.cancels, id)
+		q.mux.Unlock()
+		cancel()
+		return "", fmt.Errorf("failed to record job: %s", err.Error())
+	}
+	// unfinished jobs never exceed the capacity of the queue
+	q.queue <- queuedJob{id, ctx, req}
+	return id, nil
+}
+
+// cancel marks the given job as cancelled and signals its worker to stop. The
+// job's final state is returned, or errJobFinished if it had already finished.
+func (q *jobQueue) cancel(id string) (IndexJob, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	var job, ok = q.jobs[id]
+	if !ok {
+		return IndexJob{}, errJobNotFound
+	}
+	cancel, ok := q.cancels[id]
+	if !ok || job.Status != JobPending {
+		return *job, errJobFinished
+	}
+	cancel()
+	job.Status = JobCancelled
+	job.Updated = time.Now()
+	if err := q.store.put(*job); err != nil {
+		q.l.Warnw("failed to record cancelled job", "job", id, "error", err)
+	}
+	return *job, nil
+}
+
+// finish records the outcome of a job, unless it was cancelled, and evicts the
+// oldest finished jobs if more than the configured number are retained
+func (q *jobQueue) finish(id string, resp *lensv2.IndexResp, err error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+		delete(q.cancels, id)
+	}
+	var job, ok = q.jobs[id]
+	if !ok {
+		return
+	}
+	switch {
+	case job.Status == JobCancelled:
+		// the outcome of a cancelled job is discarded
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		job.Updated = time.Now()
+	default:
+		job.Status = JobDone
+		job.Result = resp
+		job.Updated = time.Now()
+	}
+	if err := q.store.put(*job); err != nil {
+		q.l.Warnw("failed to record finished job", "job", id, "error", err)
+	}
+	q.finished = append(q.finished, id)
+	if err := q.evict(); err != nil {
+		q.l.Warnw("failed to remove records of evicted jobs", "error", err)
+	}
+}
+
+// get returns a copy of the given job
+func (q *jobQueue) get(id string) (IndexJob, bool) {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	var job, ok = q.jobs[id]
+	if !ok {
+		return IndexJob{}, false
+	}
+	return *job, true
+}
+
+// close stops all workers - pending jobs are not processed
+func (q *jobQueue) close() {
+	if q == nil {
+		return
+	}
+	close(q.stop)
+	q.wg.Wait()
+	if err := q.store.close(); err != nil {
+		q.l.Warnw("failed to close job store", "error", err)
+	}
+}
+
+func newJobID() (string, error) {
+	var b = make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// IndexAsync queues the given object for analysis and storage, and returns a
+// job ID that can be used to check on its progress with IndexStatus.
+func (v *V2) IndexAsync(req *lensv2.IndexReq) (string, error) {
+	if v.jobs == nil {
+		return "", status.Error(codes.FailedPrecondition,
+			"asynchronous indexing is not enabled")
+	}
+	if req.GetType() != lensv2.IndexReq_IPLD {
+		return "", status.Errorf(codes.InvalidArgument,
+			"invalid data type '%s' provided", req.GetType())
+	}
+	if err := validateIndexReq(req); err != nil {
+		return "", status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	if err := v.writable(); err != nil {
+		return "", err
+	}
+
+	id, err := v.jobs.submit(req)
+	if err != nil {
+		if err == errQueueFull {
+			return "", status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return "", status.Errorf(codes.Internal,
+			"failed to create job: %s", err.Error())
+	}
+	v.l.Infow("index job queued", "job", id, "hash", req.GetHash())
+	return id, nil
+}
+
+// IndexStatus reports the state of an asynchronous index job. Jobs are only
+// tracked across restarts if AsyncOpts.StorePath is set, and finished jobs are
+// eventually forgotten.
+func (v *V2) IndexStatus(jobID string) (*IndexJob, error) {
+	if v.jobs == nil {
+		return nil, status.Error(codes.FailedPrecondition,
+			"asynchronous indexing is not enabled")
+	}
+	job, ok := v.jobs.get(jobID)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound,
+			"no job '%s' found", jobID)
+	}
+	return &job, nil
+}
+
+// CancelIndexJob stops an asynchronous index job. Objects created by the job
+// before it was stopped are removed again. Objects that were indexed before the
+// job started are left as they are, so a cancelled reindex may leave an object
+// partially updated. Jobs that have already finished cannot be cancelled.
+func (v *V2) CancelIndexJob(jobID string) (*IndexJob, error) {
+	if v.jobs == nil {
+		return nil, status.Error(codes.FailedPrecondition,
+			"asynchronous indexing is not enabled")
+	}
+	job, err := v.jobs.cancel(jobID)
+	switch err {
+	case nil:
+		v.l.Infow("index job cancelled", "job", jobID)
+		return &job, nil
+	case errJobNotFound:
+		return nil, status.Errorf(codes.NotFound,
+			"no job '%s' found", jobID)
+	default:
+		return &job, status.Errorf(codes.FailedPrecondition,
+			"job '%s' is already %s", jobID, job.Status)
+	}
+}
+
+// discard removes the objects created by a cancelled index request
+func (v *V2) discard(req *lensv2.IndexReq, written []string) {
+	var hash = req.GetHash()
+	var l = v.l.With("hash", hash)
+	var removed int
+	var created bool
+	for _, id := range written {
+		created = created || id == hash
+		// writes may not have been flushed yet, so Remove cannot be used
+		if err := v.se.Unindex(id); err != nil {
+			l.Warnw("failed to remove object of cancelled job",
+				"error", err, "object", id)
+			continue
+		}
+		removed++
+	}
+	if v.pinContent && created {
+		// the object itself was created by the job, so nothing else
+		// references its content
+		v.removePin(context.Background(), hash, l)
+	}
+	l.Infow("cancelled index job discarded", "objects_removed", removed)
+}
@@ -0,0 +1,94 @@
+package lens
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// jobsBucket holds job records, keyed by job ID
+var jobsBucket = []byte("jobs")
+
+// jobStore persists job records so that they survive restarts. A nil jobStore
+// does not persist anything.
+type jobStore struct {
+	db *bolt.DB
+}
+
+// openJobStore opens or creates the job database at the given path, or returns
+// nil if path is empty
+func openJobStore(path string) (*jobStore, error) {
+	if path == "" {
+		return nil, nil
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &jobStore{db: db}, nil
+}
+
+// put creates or replaces the record of the given job
+func (s *jobStore) put(job IndexJob) error {
+	if s == nil {
+		return nil
+	}
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), b)
+	})
+}
+
+// remove deletes the records of the given jobs
+func (s *jobStore) remove(ids ...string) error {
+	if s == nil || len(ids) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var b = tx.Bucket(jobsBucket)
+		for _, id := range ids {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// load returns every recorded job
+func (s *jobStore) load() ([]IndexJob, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var jobs []IndexJob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			var job IndexJob
+			if err := json.Unmarshal(v, &job); err != nil {
+				return fmt.Errorf("invalid record for job '%s': %s", k, err.Error())
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	return jobs, err
+}
+
+func (s *jobStore) close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,349 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func Test_jobQueue(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		var q, err = newJobQueue(AsyncOpts{}, nil, nil, zap.NewNop().Sugar())
+		if q != nil || err != nil {
+			t.Errorf("newJobQueue() = %v, %v, expected nil job queue", q, err)
+		}
+		q.close()
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		var block = make(chan struct{})
+		q, _ := newJobQueue(AsyncOpts{Workers: 1, QueueSize: 1},
+			func(context.Context, *lensv2.IndexReq) (*lensv2.IndexResp, error) {
+				<-block
+				return &lensv2.IndexResp{}, nil
+			}, nil, zap.NewNop().Sugar())
+
+		// first job is picked up by the worker, second waits in queue
+		first, err := q.submit(&lensv2.IndexReq{})
+		if err != nil {
+			t.Errorf("submit() error = %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if _, err = q.submit(&lensv2.IndexReq{}); err != nil {
+			t.Errorf("submit() error = %v", err)
+		}
+		if _, err = q.submit(&lensv2.IndexReq{}); err != errQueueFull {
+			t.Errorf("submit() error = %v, want %v", err, errQueueFull)
+		}
+		if job, _ := q.get(first); job.Status != JobPending {
+			t.Errorf("get() status = %s, want %s", job.Status, JobPending)
+		}
+		close(block)
+		time.Sleep(100 * time.Millisecond)
+		q.close()
+		if job, _ := q.get(first); job.Status != JobDone || job.Result == nil {
+			t.Errorf("get() = %+v, want finished job", job)
+		}
+	})
+
+	t.Run("retain", func(t *testing.T) {
+		q, _ := newJobQueue(AsyncOpts{Workers: 1, QueueSize: 3, Retain: 2},
+			func(context.Context, *lensv2.IndexReq) (*lensv2.IndexResp, error) {
+				return nil, errors.New("oh no")
+			}, nil, zap.NewNop().Sugar())
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := q.submit(&lensv2.IndexReq{})
+			if err != nil {
+				t.Errorf("submit() error = %v", err)
+			}
+			ids = append(ids, id)
+		}
+		time.Sleep(100 * time.Millisecond)
+		q.close()
+		if _, ok := q.get(ids[0]); ok {
+			t.Error("expected oldest job to be evicted")
+		}
+		if job, _ := q.get(ids[2]); job.Status != JobFailed || job.Error != "oh no" {
+			t.Errorf("get() = %+v, want failed job", job)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		var started = make(chan struct{}, 1)
+		var discarded = make(chan []string, 2)
+		var late = make(chan error, 1)
+		var write = func() error { return nil }
+		q, _ := newJobQueue(AsyncOpts{Workers: 1, QueueSize: 2},
+			func(ctx context.Context, req *lensv2.IndexReq) (*lensv2.IndexResp, error) {
+				var w = jobWritesFrom(ctx)
+				w.write(req.GetHash(), true, write)
+				w.write("existing", false, write)
+				started <- struct{}{}
+				<-ctx.Done()
+				// abandoned stages may still attempt to write
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					late <- w.write(req.GetHash()+"/late", true, write)
+				}()
+				return nil, ctx.Err()
+			},
+			func(req *lensv2.IndexReq, written []string) { discarded <- written },
+			zap.NewNop().Sugar())
+
+		// first job is running, second is still queued
+		running, _ := q.submit(&lensv2.IndexReq{Hash: "running"})
+		<-started
+		queued, _ := q.submit(&lensv2.IndexReq{Hash: "queued"})
+
+		if _, err := q.cancel("not_a_job"); err != errJobNotFound {
+			t.Errorf("cancel() error = %v, want %v", err, errJobNotFound)
+		}
+		for _, id := range []string{queued, running} {
+			if job, err := q.cancel(id); err != nil || job.Status != JobCancelled {
+				t.Errorf("cancel() = %+v, %v, want cancelled job", job, err)
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		q.close()
+
+		if job, err := q.cancel(running); err != errJobFinished || job.Status != JobCancelled {
+			t.Errorf("cancel() = %+v, %v, want %v", job, err, errJobFinished)
+		}
+		if written := <-discarded; !reflect.DeepEqual(written, []string{"running"}) {
+			t.Errorf("discarded %v, want %v", written, []string{"running"})
+		}
+		if len(discarded) > 0 {
+			t.Error("expected queued job to be skipped without discarding")
+		}
+		if err := <-late; err != errJobCancelled {
+			t.Errorf("write() after cancellation error = %v, want %v", err, errJobCancelled)
+		}
+	})
+
+	t.Run("persist", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "lens-jobs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		var opts = AsyncOpts{
+			Workers:   1,
+			QueueSize: 2,
+			StorePath: filepath.Join(dir, "jobs.db"),
+		}
+
+		var block = make(chan struct{})
+		q, err := newJobQueue(opts,
+			func(_ context.Context, req *lensv2.IndexReq) (*lensv2.IndexResp, error) {
+				if req.GetHash() == "blocked" {
+					<-block
+				}
+				return &lensv2.IndexResp{Doc: &lensv2.Document{Hash: req.GetHash()}}, nil
+			}, nil, zap.NewNop().Sugar())
+		if err != nil {
+			t.Fatalf("newJobQueue() error = %v", err)
+		}
+		done, _ := q.submit(&lensv2.IndexReq{Hash: "done"})
+		time.Sleep(100 * time.Millisecond)
+		interrupted, _ := q.submit(&lensv2.IndexReq{Hash: "blocked"})
+		time.Sleep(100 * time.Millisecond)
+
+		// jobs survive a restart, but pending jobs cannot be resumed
+		q.store.close()
+		restored, err := newJobQueue(opts, nil, nil, zap.NewNop().Sugar())
+		close(block)
+		close(q.stop)
+		q.wg.Wait()
+		if err != nil {
+			t.Fatalf("newJobQueue() error = %v", err)
+		}
+		defer restored.close()
+		q = restored
+		if job, _ := q.get(done); job.Status != JobDone || job.Result.GetDoc().GetHash() != "done" {
+			t.Errorf("get() = %+v, want restored finished job", job)
+		}
+		if job, _ := q.get(interrupted); job.Status != JobFailed || job.Error == "" {
+			t.Errorf("get() = %+v, want interrupted job to have failed", job)
+		}
+	})
+}
+
+func TestV2_IndexAsync(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        AsyncOpts
+		req         *lensv2.IndexReq
+		wantErrCode codes.Code
+		wantStatus  JobStatus
+	}{
+		{"disabled",
+			AsyncOpts{},
+			&lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"},
+			codes.FailedPrecondition, ""},
+		{"bad type",
+			AsyncOpts{Workers: 1},
+			&lensv2.IndexReq{Type: lensv2.IndexReq_UNKNOWN},
+			codes.InvalidArgument, ""},
+		{"no content for hash found",
+			AsyncOpts{Workers: 1},
+			&lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"},
+			0, JobFailed},
+		{"ok",
+			AsyncOpts{Workers: 1},
+			&lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"},
+			0, JobDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var v = newTestV2(t, V2Options{AsyncIndex: tt.opts},
+				ipfs,
+				&mocks.FakeTensorflowAnalyzer{},
+				&mocks.FakeSearcher{},
+				zap.NewNop().Sugar())
+			defer v.jobs.close()
+			if tt.wantStatus == JobDone {
+				ipfs.CatStub = mocks.StubIpfsCat("README.md")
+			} else {
+				ipfs.CatStub = mocks.StubIpfsCat("")
+			}
+
+			id, err := v.IndexAsync(tt.req)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.IndexAsync() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.IndexAsync() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+				return
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			job, err := v.IndexStatus(id)
+			if err != nil {
+				t.Errorf("V2.IndexStatus() error = %v", err)
+				return
+			}
+			if job.Status != tt.wantStatus {
+				t.Errorf("V2.IndexStatus() status = %s, want %s (%s)",
+					job.Status, tt.wantStatus, job.Error)
+			}
+			if _, err := v.IndexStatus("not_a_job"); status.Code(err) != codes.NotFound {
+				t.Errorf("V2.IndexStatus() error = %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestV2_CancelIndexJob(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{}, &mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	if _, err := v.CancelIndexJob("asdf"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("V2.CancelIndexJob() error = %v, want FailedPrecondition", err)
+	}
+
+	var ipfs = &mocks.FakeRTFSManager{}
+	ipfs.CatStub = mocks.StubIpfsCat("README.md")
+	v = newTestV2(t, V2Options{AsyncIndex: AsyncOpts{Workers: 1}}, ipfs,
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	defer v.jobs.close()
+	id, err := v.IndexAsync(&lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"})
+	if err != nil {
+		t.Errorf("V2.IndexAsync() error = %v", err)
+		return
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := v.CancelIndexJob("not_a_job"); status.Code(err) != codes.NotFound {
+		t.Errorf("V2.CancelIndexJob() error = %v, want NotFound", err)
+	}
+	job, err := v.CancelIndexJob(id)
+	if status.Code(err) != codes.FailedPrecondition || job == nil || job.Status != JobDone {
+		t.Errorf("V2.CancelIndexJob() = %+v, %v, want already done", job, err)
+	}
+}
+
+func TestV2_discard(t *testing.T) {
+	tests := []struct {
+		name      string
+		written   []string
+		wantUnpin bool
+	}{
+		{"new object", []string{"asdf/a.txt", "asdf"}, true},
+		{"new archive members", []string{"asdf/a.txt"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{PinContent: true}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			ipfs.CustomRequestReturns(&shell.Response{
+				Output: ioutil.NopCloser(strings.NewReader("")),
+			}, nil)
+
+			v.discard(&lensv2.IndexReq{Hash: "asdf"}, tt.written)
+			if se.RemoveCallCount() > 0 || se.RemovePrefixCallCount() > 0 {
+				t.Error("V2.discard() removed objects that were not written by the job")
+			}
+			var got []string
+			for i := 0; i < se.UnindexCallCount(); i++ {
+				got = append(got, se.UnindexArgsForCall(i))
+			}
+			if !reflect.DeepEqual(got, tt.written) {
+				t.Errorf("V2.discard() removed %v, want %v", got, tt.written)
+			}
+			if unpinned := ipfs.CustomRequestCallCount() > 0; unpinned != tt.wantUnpin {
+				t.Errorf("V2.discard() unpinned = %v, want %v", unpinned, tt.wantUnpin)
+			}
+		})
+	}
+}
+
+func TestV2_store_jobWrites(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	se.IsIndexedStub = func(hash string) bool { return hash == "existing" }
+	var v = newTestV2(t, V2Options{}, &mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+
+	var writes = &jobWrites{}
+	// job contexts are always cancellable
+	ctx, cancelJob := context.WithCancel(context.WithValue(context.Background(), jobWritesKey{}, writes))
+	defer cancelJob()
+	b, cancel := newBudget(ctx, 0)
+	defer cancel()
+	for _, hash := range []string{"existing", "new"} {
+		if err := v.store(b, hash, "", &models.MetaDataV2{}, true); err != nil {
+			t.Errorf("V2.store() error = %v", err)
+		}
+	}
+	if written := writes.close(); !reflect.DeepEqual(written, []string{"new"}) {
+		t.Errorf("V2.store() recorded %v, want %v", written, []string{"new"})
+	}
+	if err := v.store(b, "later", "", &models.MetaDataV2{}, false); err != errJobCancelled {
+		t.Errorf("V2.store() after discard error = %v, want %v", err, errJobCancelled)
+	}
+	if se.IndexCallCount() != 2 {
+		t.Errorf("V2.store() indexed %d documents, want 2", se.IndexCallCount())
+	}
+}
@@ -0,0 +1,92 @@
+package lens
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// KeywordReport describes the objects tagged with a keyword
+type KeywordReport struct {
+	Keyword string
+	// Hashes lists every indexed object tagged with the keyword, in order
+	Hashes []string
+	// Unreachable lists the objects in Hashes whose content could not be found
+	// on IPFS. Archive members are checked by their archive, and inline
+	// content, which was never on IPFS, is not checked.
+	Unreachable []string
+}
+
+// InspectKeyword reports the objects with a tag equal to the given keyword,
+// ignoring case, and which of them can no longer be found on IPFS, for
+// debugging search results. It fails if IPFS cannot be reached, so that an
+// outage is not mistaken for missing objects.
+func (v *V2) InspectKeyword(ctx context.Context, keyword string) (*KeywordReport, error) {
+	if strings.TrimSpace(keyword) == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no keyword provided")
+	}
+	hashes, err := v.tagged(ctx, keyword)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal,
+			"failed to find objects for keyword: %s", err.Error())
+	}
+
+	var report = &KeywordReport{
+		Keyword:     keyword,
+		Hashes:      hashes,
+		Unreachable: make([]string, 0),
+	}
+	var reachable = make(map[string]bool)
+	for _, h := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, status.Error(codes.Canceled, err.Error())
+		}
+		if strings.HasPrefix(h, inlineHashPrefix) {
+			continue
+		}
+		// archive members are stored as '<archive>/<path>'
+		var root = strings.SplitN(h, "/", 2)[0]
+		ok, checked := reachable[root]
+		if !checked {
+			_, err := v.ipfs.Stat(root)
+			if err != nil && !notFound(err) {
+				return nil, status.Errorf(codes.Unavailable,
+					"failed to reach IPFS: %s", err.Error())
+			}
+			ok = err == nil
+			reachable[root] = ok
+		}
+		if !ok {
+			report.Unreachable = append(report.Unreachable, h)
+		}
+	}
+	return report, nil
+}
+
+// RepairKeyword unindexes the objects tagged with the given keyword that can
+// no longer be found on IPFS, as reported by InspectKeyword, and returns the
+// number of objects removed. Unlike the sweeper, which only flags unreachable
+// objects, this permanently removes them from the index.
+func (v *V2) RepairKeyword(ctx context.Context, keyword string) (int, error) {
+	if err := v.writable(); err != nil {
+		return 0, err
+	}
+	report, err := v.InspectKeyword(ctx, keyword)
+	if err != nil {
+		return 0, err
+	}
+	if removed, err := v.purgeAll(ctx, report.Unreachable); err != nil {
+		v.l.Errorw("failed to remove unreachable objects",
+			"error", err, "keyword", keyword, "removed", removed)
+		return removed, status.Errorf(codes.Internal,
+			"removed %d objects before failure: %s", removed, err.Error())
+	}
+	v.l.Infow("unreachable objects removed by keyword",
+		"keyword", keyword,
+		"checked", len(report.Hashes),
+		"removed", len(report.Unreachable))
+	return len(report.Unreachable), nil
+}
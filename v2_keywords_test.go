@@ -0,0 +1,120 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// taggedStub returns documents tagged 'IPFS', except for the given document,
+// which is tagged with the given tag instead
+func taggedStub(hash, tag string) func(string) (*engine.Document, error) {
+	return func(h string) (*engine.Document, error) {
+		var tags = []string{"IPFS"}
+		if h == hash {
+			tags = []string{tag}
+		}
+		return &engine.Document{Object: &models.ObjectV2{
+			Hash: h, MD: models.MetaDataV2{Tags: tags}}}, nil
+	}
+}
+
+func TestV2_InspectKeyword(t *testing.T) {
+	tests := []struct {
+		name            string
+		keyword         string
+		listErr         error
+		statErr         error
+		wantUnreachable []string
+		wantErrCode     codes.Code
+	}{
+		{"no keyword", " ", nil, nil, nil, codes.InvalidArgument},
+		{"list error", "ipfs", errors.New("oh no"), nil, nil, codes.Internal},
+		{"ipfs unavailable", "ipfs", nil, errors.New("connection refused"), nil, codes.Unavailable},
+		{"ok", "ipfs", nil, nil, []string{"QmB", "QmB/docs/a.txt"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			var hashes = []string{"QmA", "QmB", "QmB/docs/a.txt", inlineHashPrefix + "abcd"}
+			se.ListMatchingReturns(append(hashes, "QmPartial"), tt.listErr)
+			se.GetStub = taggedStub("QmPartial", "ipfs-cluster")
+			ipfs.StatStub = func(hash string) (*shell.ObjectStats, error) {
+				if hash == "QmB" {
+					return nil, &shell.Error{Message: "merkledag: not found"}
+				}
+				return &shell.ObjectStats{}, tt.statErr
+			}
+
+			report, err := v.InspectKeyword(context.Background(), tt.keyword)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.InspectKeyword() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if tt.wantErrCode != 0 {
+				return
+			}
+			if !reflect.DeepEqual(report.Hashes, hashes) ||
+				!reflect.DeepEqual(report.Unreachable, tt.wantUnreachable) {
+				t.Errorf("V2.InspectKeyword() = %+v, want unreachable %v", report, tt.wantUnreachable)
+			}
+			if ipfs.StatCallCount() != 2 {
+				t.Errorf("V2.InspectKeyword() checked %d hashes on IPFS, want 2", ipfs.StatCallCount())
+			}
+		})
+	}
+}
+
+func TestV2_RepairKeyword(t *testing.T) {
+	tests := []struct {
+		name        string
+		removeErr   error
+		want        int
+		wantErrCode codes.Code
+	}{
+		{"ok", nil, 2, 0},
+		{"remove error", errors.New("oh no"), 0, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			se.ListMatchingReturns([]string{"QmA", "QmB", "QmC", "QmPartial"}, nil)
+			se.GetStub = taggedStub("QmPartial", "ipfs-cluster")
+			se.RemoveReturns(tt.removeErr)
+			ipfs.StatStub = func(hash string) (*shell.ObjectStats, error) {
+				if hash == "QmB" {
+					return &shell.ObjectStats{}, nil
+				}
+				return nil, &shell.Error{Message: "merkledag: not found"}
+			}
+
+			got, err := v.RepairKeyword(context.Background(), "ipfs")
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.RepairKeyword() error = %v, want code %s", err, tt.wantErrCode)
+			}
+			if got != tt.want {
+				t.Errorf("V2.RepairKeyword() = %d, want %d", got, tt.want)
+			}
+			if tt.wantErrCode == 0 && (se.RemoveArgsForCall(0) != "QmA" || se.RemoveArgsForCall(1) != "QmC") {
+				t.Errorf("V2.RepairKeyword() removed %s and %s, want QmA and QmC",
+					se.RemoveArgsForCall(0), se.RemoveArgsForCall(1))
+			}
+		})
+	}
+}
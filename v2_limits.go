@@ -0,0 +1,197 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+)
+
+// errQueueFull indicates that no more requests can be accepted
+var errQueueFull = errors.New("too many pending requests")
+
+// limiter bounds the number of operations that may execute at once, and the
+// number of operations that may wait for a free slot. A nil limiter does not
+// impose any limits.
+type limiter struct {
+	slots      chan struct{}
+	waiting    int32
+	maxWaiting int32
+}
+
+// newLimiter returns nil if maxInFlight is not positive
+func newLimiter(maxInFlight, maxQueued int) *limiter {
+	if maxInFlight < 1 {
+		return nil
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &limiter{
+		slots:      make(chan struct{}, maxInFlight),
+		maxWaiting: int32(maxQueued),
+	}
+}
+
+// acquire blocks until a slot is available, the queue is full, or the given
+// context is cancelled. The returned function must be called to release the
+// acquired slot.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// fast path if a slot is free
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+
+	// otherwise wait in queue, if there is space
+	if atomic.AddInt32(&l.waiting, 1) > l.maxWaiting {
+		atomic.AddInt32(&l.waiting, -1)
+		return nil, errQueueFull
+	}
+	defer atomic.AddInt32(&l.waiting, -1)
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *limiter) release() { <-l.slots }
+
+// depth reports the number of operations waiting for a slot
+func (l *limiter) depth() int {
+	if l == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&l.waiting))
+}
+
+// inFlight reports the number of operations currently holding a slot
+func (l *limiter) inFlight() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// Request field limits
+const (
+	maxHashLength        = 128
+	maxDisplayNameLength = 256
+	maxQueryLength       = 1024
+	maxTagLength         = 128
+	maxListLength        = 100
+
+	// maxInlineContentLength bounds content provided directly to IndexBytes
+	maxInlineContentLength = 16 << 20
+)
+
+func validateIndexReq(req *lensv2.IndexReq) error {
+	if req.GetHash() == "" {
+		return errors.New("no hash provided")
+	}
+	if len(req.GetHash()) > maxHashLength {
+		return fmt.Errorf("hash exceeds maximum length of %d", maxHashLength)
+	}
+	if len(req.GetDisplayName()) > maxDisplayNameLength {
+		return fmt.Errorf("display name exceeds maximum length of %d", maxDisplayNameLength)
+	}
+	return validateList("tags", req.GetTags(), maxTagLength)
+}
+
+func validateSearchReq(req *lensv2.SearchReq) error {
+	if len(req.GetQuery()) > maxQueryLength {
+		return fmt.Errorf("query exceeds maximum length of %d", maxQueryLength)
+	}
+	var opts = req.GetOptions()
+	for name, list := range map[string][]string{
+		"required":   opts.GetRequired(),
+		"tags":       opts.GetTags(),
+		"categories": opts.GetCategories(),
+		"mime types": opts.GetMimeTypes(),
+	} {
+		if err := validateList(name, list, maxTagLength); err != nil {
+			return err
+		}
+	}
+	return validateList("hashes", opts.GetHashes(), maxHashLength)
+}
+
+// warningTrailer is the response trailer used to report problems with a
+// request that did not prevent it from succeeding
+const warningTrailer = "lens-warning"
+
+// KeywordLimit bounds the number of keywords - the words of required terms,
+// and tags - in a search, since each keyword adds a term lookup to the query
+type KeywordLimit struct {
+	// Max is the maximum number of keywords - leave at 0 for no limit other
+	// than the maximum number of entries in each list
+	Max int
+	// Truncate drops keywords beyond Max instead of rejecting the search with
+	// codes.InvalidArgument. Truncation is reported in a 'lens-warning'
+	// response trailer.
+	Truncate bool
+}
+
+// apply enforces the limit on the given query. Required terms are kept before
+// tags, in the order they were provided. If keywords were dropped, a warning
+// describing the truncation is returned.
+func (k KeywordLimit) apply(q *engine.Query) (string, error) {
+	if k.Max <= 0 {
+		return "", nil
+	}
+	var count = len(q.Tags)
+	for _, r := range q.Required {
+		count += len(strings.Fields(r))
+	}
+	if count <= k.Max {
+		return "", nil
+	}
+	if !k.Truncate {
+		return "", fmt.Errorf("query has %d keywords - maximum is %d", count, k.Max)
+	}
+
+	var remaining = k.Max
+	var required = make([]string, 0, len(q.Required))
+	for _, r := range q.Required {
+		if n := len(strings.Fields(r)); n <= remaining {
+			required = append(required, r)
+			remaining -= n
+		}
+	}
+	var tags = q.Tags
+	if len(tags) > remaining {
+		tags = tags[:remaining]
+	}
+	if len(required) == 0 && len(tags) == 0 && q.Text == "" &&
+		len(q.Categories) == 0 && len(q.MimeTypes) == 0 {
+		return "", fmt.Errorf("query has %d keywords - maximum is %d", count, k.Max)
+	}
+	q.Required, q.Tags = required, tags
+	return fmt.Sprintf("query truncated from %d to %d keywords",
+		count, k.Max-remaining+len(tags)), nil
+}
+
+func validateList(name string, list []string, maxLength int) error {
+	if len(list) > maxListLength {
+		return fmt.Errorf("too many %s provided - maximum is %d", name, maxListLength)
+	}
+	for _, s := range list {
+		if len(s) > maxLength {
+			return fmt.Errorf("%s entry '%.16s...' exceeds maximum length of %d",
+				name, s, maxLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package lens
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+)
+
+func Test_limiter(t *testing.T) {
+	t.Run("nil limiter", func(t *testing.T) {
+		var l *limiter
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Errorf("acquire() error = %v", err)
+			return
+		}
+		release()
+		if l.depth() != 0 || l.inFlight() != 0 {
+			t.Error("expected empty nil limiter")
+		}
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		var l = newLimiter(1, 1)
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Errorf("acquire() error = %v", err)
+			return
+		}
+
+		// second request should wait in queue
+		var acquired = make(chan error)
+		go func() {
+			r, err := l.acquire(context.Background())
+			if err == nil {
+				r()
+			}
+			acquired <- err
+		}()
+		time.Sleep(100 * time.Millisecond)
+		if l.depth() != 1 {
+			t.Errorf("depth() = %d, want 1", l.depth())
+		}
+
+		// third request should be rejected
+		if _, err := l.acquire(context.Background()); err != errQueueFull {
+			t.Errorf("acquire() error = %v, want %v", err, errQueueFull)
+		}
+
+		// releasing should let queued request through
+		release()
+		if err := <-acquired; err != nil {
+			t.Errorf("queued acquire() error = %v", err)
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		var l = newLimiter(1, 1)
+		release, _ := l.acquire(context.Background())
+		defer release()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := l.acquire(ctx); err == nil {
+			t.Error("expected error on cancelled context")
+		}
+		if l.depth() != 0 {
+			t.Errorf("depth() = %d, want 0", l.depth())
+		}
+	})
+}
+
+func TestKeywordLimit_apply(t *testing.T) {
+	var query = engine.Query{
+		Required: []string{"bitcoin", "interplanetary file system", "ipfs"},
+		Tags:     []string{"crypto", "storage"},
+	}
+	tests := []struct {
+		name         string
+		limit        KeywordLimit
+		query        engine.Query
+		wantRequired []string
+		wantTags     []string
+		wantWarning  bool
+		wantErr      bool
+	}{
+		{"no limit", KeywordLimit{}, query,
+			query.Required, query.Tags, false, false},
+		{"at limit", KeywordLimit{Max: 7}, query,
+			query.Required, query.Tags, false, false},
+		{"above limit", KeywordLimit{Max: 6}, query,
+			nil, nil, false, true},
+		{"truncate tags", KeywordLimit{Max: 6, Truncate: true}, query,
+			query.Required, []string{"crypto"}, true, false},
+		{"truncate required", KeywordLimit{Max: 3, Truncate: true}, query,
+			[]string{"bitcoin", "ipfs"}, []string{"crypto"}, true, false},
+		{"nothing left", KeywordLimit{Max: 1, Truncate: true},
+			engine.Query{Required: []string{"interplanetary file system"}},
+			nil, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q = tt.query
+			warning, err := tt.limit.apply(&q)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KeywordLimit.apply() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("KeywordLimit.apply() warning = %q, wantWarning %v", warning, tt.wantWarning)
+			}
+			if !reflect.DeepEqual(q.Required, tt.wantRequired) || !reflect.DeepEqual(q.Tags, tt.wantTags) {
+				t.Errorf("KeywordLimit.apply() = (%v, %v), want (%v, %v)",
+					q.Required, q.Tags, tt.wantRequired, tt.wantTags)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package lens
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maintenanceHeader is the response header Stats reports maintenance mode in,
+// as 'true' or 'false'
+const maintenanceHeader = "lens-maintenance"
+
+// SetMaintenance enables or disables maintenance mode. In maintenance mode,
+// requests that modify the index - indexing, removals, and metadata updates -
+// are rejected with codes.FailedPrecondition, and background sweeps are
+// paused, while searches and retrievals are served as usual. Writes accepted
+// before maintenance mode was enabled may still be queued by the engine, so
+// wait for its queue to flush before backing up the datastore.
+func (v *V2) SetMaintenance(enabled bool) {
+	var flag uint32
+	if enabled {
+		flag = 1
+	}
+	if atomic.SwapUint32(&v.maintenance, flag) != flag {
+		v.l.Infow("maintenance mode changed", "maintenance", enabled)
+	}
+}
+
+// Maintenance reports whether maintenance mode is enabled - see SetMaintenance
+func (v *V2) Maintenance() bool { return atomic.LoadUint32(&v.maintenance) == 1 }
+
+// writable returns an error if the index may not be modified
+func (v *V2) writable() error {
+	if v.Maintenance() {
+		return status.Error(codes.FailedPrecondition,
+			"lens is in maintenance mode - the index is read-only")
+	}
+	return nil
+}
+
+// reportMaintenance sets the maintenance header on the response to the gRPC
+// request of the given context
+func (v *V2) reportMaintenance(ctx context.Context) {
+	// fails outside of gRPC requests, where there is no one to report to
+	grpc.SetHeader(ctx, metadata.Pairs(maintenanceHeader, strconv.FormatBool(v.Maintenance())))
+}
@@ -0,0 +1,89 @@
+package lens
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_SetMaintenance(t *testing.T) {
+	var ctx = context.Background()
+	var writes = []struct {
+		name string
+		fn   func(v *V2) error
+	}{
+		{"index", func(v *V2) error {
+			_, err := v.Index(ctx, &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"})
+			return err
+		}},
+		{"remove", func(v *V2) error {
+			_, err := v.Remove(ctx, &lensv2.RemoveReq{Hash: "asdf"})
+			return err
+		}},
+		{"update metadata", func(v *V2) error {
+			_, err := v.UpdateMetadata("asdf", models.MetaDataPatch{DisplayName: "report"})
+			return err
+		}},
+		{"remove by keyword", func(v *V2) error {
+			_, err := v.RemoveByKeyword(ctx, "draft")
+			return err
+		}},
+		{"remove by prefix", func(v *V2) error {
+			_, err := v.RemoveByPrefix(ctx, "QmAsdf")
+			return err
+		}},
+		{"index bytes", func(v *V2) error {
+			_, err := v.IndexBytes(ctx, []byte("hello world"), IndexBytesOpts{AddToIPFS: true})
+			return err
+		}},
+	}
+	for _, tt := range writes {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			se.GetReturns(&engine.Document{Object: &models.ObjectV2{Hash: "asdf"}}, nil)
+			var ipfs = &mocks.FakeRTFSManager{}
+			ipfs.CatReturns([]byte("hello world"), nil)
+			var v = newTestV2(t, V2Options{}, ipfs, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+
+			v.SetMaintenance(true)
+			if !v.Maintenance() {
+				t.Fatal("V2.Maintenance() = false, want true")
+			}
+			if err := tt.fn(v); status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("write in maintenance mode error = %v, want FailedPrecondition", err)
+			}
+			if se.IndexCallCount() > 0 || se.RemoveCallCount() > 0 ||
+				se.RemoveMatchingCallCount() > 0 || se.RemovePrefixCallCount() > 0 ||
+				ipfs.AddCallCount() > 0 {
+				t.Error("index modified in maintenance mode")
+			}
+
+			v.SetMaintenance(false)
+			if err := tt.fn(v); status.Code(err) == codes.FailedPrecondition {
+				t.Errorf("write after maintenance mode error = %v", err)
+			}
+		})
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		var se = &mocks.FakeSearcher{}
+		se.GetReturns(&engine.Document{Object: &models.ObjectV2{Hash: "asdf"}}, nil)
+		var v = newTestV2(t, V2Options{}, &mocks.FakeRTFSManager{},
+			&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+		v.SetMaintenance(true)
+		if _, err := v.Search(ctx, &lensv2.SearchReq{Query: "hello"}); err != nil {
+			t.Errorf("V2.Search() in maintenance mode error = %v", err)
+		}
+		if _, err := v.GetObject("asdf"); err != nil {
+			t.Errorf("V2.GetObject() in maintenance mode error = %v", err)
+		}
+	})
+}
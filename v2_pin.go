@@ -0,0 +1,83 @@
+package lens
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// pin pins the given content hash on the IPFS node, so that indexed content
+// is not garbage collected. Failures are only logged, since the content has
+// already been indexed.
+func (v *V2) pin(hash string, l *zap.SugaredLogger) {
+	if err := v.ipfs.Pin(hash); err != nil {
+		l.Warnw("failed to pin indexed content", "error", err)
+		return
+	}
+	l.Debug("indexed content pinned")
+}
+
+// unpin unpins the content behind the given removed objects, unless other
+// indexed objects - the archive an object was extracted from, or other members
+// of the same archive - still reference it
+func (v *V2) unpin(ctx context.Context, l *zap.SugaredLogger, removed ...string) {
+	var gone = make(map[string]bool, len(removed))
+	var seen = make(map[string]bool, len(removed))
+	var hashes = make([]string, 0, len(removed))
+	for _, r := range removed {
+		gone[r] = true
+		var hash = r
+		if i := strings.Index(r, "/"); i > 0 {
+			hash = r[:i]
+		}
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	for _, hash := range hashes {
+		var hl = l.With("pin", hash)
+		if v.referenced(ctx, hash, gone, hl) {
+			hl.Debug("content still referenced - keeping pin")
+			continue
+		}
+		v.removePin(ctx, hash, hl)
+	}
+}
+
+// referenced checks if any indexed object other than the removed ones
+// references the given content hash. Failures to check count as references.
+func (v *V2) referenced(ctx context.Context, hash string, removed map[string]bool, l *zap.SugaredLogger) bool {
+	// removals are queued, so removed objects are still listed
+	if !removed[hash] && v.se.IsIndexed(hash) {
+		return true
+	}
+	members, err := v.se.ListPrefix(ctx, hash+"/")
+	if err != nil {
+		l.Warnw("failed to check references to content", "error", err)
+		return true
+	}
+	for _, m := range members {
+		if !removed[m] {
+			return true
+		}
+	}
+	return false
+}
+
+// removePin unpins the given content hash on the IPFS node
+func (v *V2) removePin(ctx context.Context, hash string, l *zap.SugaredLogger) {
+	// rtfs.Manager has no unpin method, so the API is called directly
+	resp, err := v.ipfs.CustomRequest(ctx, v.ipfs.NodeAddress(), "pin/rm", nil, hash)
+	if err != nil {
+		l.Warnw("failed to unpin removed content", "error", err)
+		return
+	}
+	defer resp.Close()
+	if resp.Error != nil {
+		l.Warnw("failed to unpin removed content", "error", resp.Error)
+		return
+	}
+	l.Debug("removed content unpinned")
+}
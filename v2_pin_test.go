@@ -0,0 +1,151 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_Index_pin(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     bool
+		pinErr  error
+		wantPin bool
+	}{
+		{"disabled", false, nil, false},
+		{"enabled", true, nil, true},
+		{"pin failure does not fail indexing", true, errors.New("oh no"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var v = newTestV2(t, V2Options{PinContent: tt.pin}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+			ipfs.CatReturns([]byte("hello world"), nil)
+			ipfs.PinReturns(tt.pinErr)
+
+			if _, err := v.Index(context.Background(), &lensv2.IndexReq{
+				Type: lensv2.IndexReq_IPLD,
+				Hash: "asdf",
+			}); err != nil {
+				t.Errorf("V2.Index() error = %v", err)
+				return
+			}
+			if pinned := ipfs.PinCallCount() > 0; pinned != tt.wantPin {
+				t.Errorf("V2.Index() pinned = %v, want %v", pinned, tt.wantPin)
+				return
+			}
+			if tt.wantPin && ipfs.PinArgsForCall(0) != "asdf" {
+				t.Errorf("V2.Index() pinned '%s', want '%s'", ipfs.PinArgsForCall(0), "asdf")
+			}
+		})
+	}
+}
+
+func TestV2_Remove_unpin(t *testing.T) {
+	tests := []struct {
+		name      string
+		pin       bool
+		hash      string
+		indexed   bool     // whether the archive itself is still indexed
+		members   []string // indexed archive members
+		wantUnpin string
+	}{
+		{"disabled", false, "asdf", true, nil, ""},
+		{"object", true, "asdf", true, nil, "asdf"},
+		{"archive with members", true, "asdf", true, []string{"asdf/a.txt"}, ""},
+		{"member of indexed archive", true, "asdf/a.txt", true, []string{"asdf/a.txt"}, ""},
+		{"other members remain", true, "asdf/a.txt", false,
+			[]string{"asdf/a.txt", "asdf/b.txt"}, ""},
+		{"last member", true, "asdf/a.txt", false, []string{"asdf/a.txt"}, "asdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{PinContent: tt.pin}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			se.IsIndexedStub = func(hash string) bool {
+				// the removed object remains listed until removal is flushed
+				return hash == tt.hash || tt.indexed
+			}
+			se.ListPrefixReturns(tt.members, nil)
+			ipfs.CustomRequestReturns(&shell.Response{
+				Output: ioutil.NopCloser(strings.NewReader("")),
+			}, nil)
+
+			if _, err := v.Remove(context.Background(), &lensv2.RemoveReq{Hash: tt.hash}); err != nil {
+				t.Errorf("V2.Remove() error = %v", err)
+				return
+			}
+			if tt.wantUnpin == "" {
+				if ipfs.CustomRequestCallCount() > 0 {
+					t.Error("V2.Remove() unexpectedly unpinned content")
+				}
+				return
+			}
+			if ipfs.CustomRequestCallCount() != 1 {
+				t.Errorf("V2.Remove() unpinned %d times, want 1", ipfs.CustomRequestCallCount())
+				return
+			}
+			_, _, command, _, args := ipfs.CustomRequestArgsForCall(0)
+			if command != "pin/rm" || len(args) != 1 || args[0] != tt.wantUnpin {
+				t.Errorf("V2.Remove() requested '%s %v', want 'pin/rm [%s]'",
+					command, args, tt.wantUnpin)
+			}
+		})
+	}
+}
+
+func TestV2_bulkRemove_unpin(t *testing.T) {
+	var indexed = map[string][]string{
+		// archive whose members are all removed along with it
+		"QmArchive": {"QmArchive/a.txt", "QmArchive/b.txt"},
+		// archive that keeps a member tagged differently
+		"QmOther": {"QmOther/a.txt", "QmOther/c.txt"},
+	}
+	var ipfs = &mocks.FakeRTFSManager{}
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{PinContent: true}, ipfs,
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	// removed objects remain listed until removal is flushed
+	se.IsIndexedReturns(true)
+	se.ListPrefixStub = func(_ context.Context, prefix string) ([]string, error) {
+		return indexed[strings.TrimSuffix(prefix, "/")], nil
+	}
+	se.ListMatchingReturns([]string{
+		"QmArchive", "QmArchive/a.txt", "QmArchive/b.txt", "QmOther/a.txt",
+	}, nil)
+	se.GetStub = func(hash string) (*engine.Document, error) {
+		return &engine.Document{Object: &models.ObjectV2{
+			Hash: hash, MD: models.MetaDataV2{Tags: []string{"spam"}}}}, nil
+	}
+	ipfs.CustomRequestReturns(&shell.Response{
+		Output: ioutil.NopCloser(strings.NewReader("")),
+	}, nil)
+
+	if removed, err := v.RemoveByKeyword(context.Background(), "spam"); err != nil || removed != 4 {
+		t.Errorf("V2.RemoveByKeyword() = (%d, %v), want 4 removed", removed, err)
+		return
+	}
+	if ipfs.CustomRequestCallCount() != 1 {
+		t.Errorf("V2.RemoveByKeyword() unpinned %d times, want 1", ipfs.CustomRequestCallCount())
+		return
+	}
+	_, _, command, _, args := ipfs.CustomRequestArgsForCall(0)
+	if command != "pin/rm" || len(args) != 1 || args[0] != "QmArchive" {
+		t.Errorf("V2.RemoveByKeyword() requested '%s %v', want 'pin/rm [QmArchive]'",
+			command, args)
+	}
+}
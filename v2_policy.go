@@ -0,0 +1,54 @@
+package lens
+
+import (
+	"fmt"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// AnalysisPolicy configures how content of a category is analyzed, to tune
+// analysis per format in one place. Unset fields keep the global behaviour.
+type AnalysisPolicy struct {
+	// SummaryRatio overrides the summarization ratio, including ratios
+	// configured in V2Options.SummaryRatios - must be in (0,1] if set
+	SummaryRatio float64
+	// Verbatim skips summarization, and keeps the words of the text as
+	// keywords directly, ie for source code
+	Verbatim bool
+	// RawText indexes content in raw text mode, which keeps stop words - see
+	// V2Options.RawTextCategories
+	RawText bool
+	// MaxKeywords bounds the number of keywords extracted from the text of
+	// each object - leave at 0 for no limit
+	MaxKeywords int
+}
+
+// validatePolicies checks the given analysis policies for invalid settings
+func validatePolicies(policies map[string]AnalysisPolicy) error {
+	for category, p := range policies {
+		if p.SummaryRatio != 0 && !validRatio(p.SummaryRatio) {
+			return fmt.Errorf("invalid summary ratio %v for category '%s': must be in (0,1]",
+				p.SummaryRatio, category)
+		}
+		if p.MaxKeywords < 0 {
+			return fmt.Errorf("invalid maximum keywords %d for category '%s'",
+				p.MaxKeywords, category)
+		}
+	}
+	return nil
+}
+
+// policy returns the analysis policy of the given category. Policies of
+// sub-categories take precedence over those of their parents. Categories
+// without a policy get the zero policy.
+func (v *V2) policy(category string) AnalysisPolicy {
+	var policy AnalysisPolicy
+	var depth int
+	for c, p := range v.policies {
+		var paths = models.CategoryPaths(c)
+		if len(paths) > depth && models.InCategory(category, c) {
+			policy, depth = p, len(paths)
+		}
+	}
+	return policy
+}
@@ -0,0 +1,98 @@
+package lens
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func Test_validatePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies map[string]AnalysisPolicy
+		wantErr  bool
+	}{
+		{"none", nil, false},
+		{"valid", map[string]AnalysisPolicy{"document": {SummaryRatio: 0.5, MaxKeywords: 10}}, false},
+		{"invalid ratio", map[string]AnalysisPolicy{"document": {SummaryRatio: 2}}, true},
+		{"invalid max keywords", map[string]AnalysisPolicy{"document": {MaxKeywords: -1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePolicies(tt.policies); (err != nil) != tt.wantErr {
+				t.Errorf("validatePolicies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestV2_policy(t *testing.T) {
+	var v = newTestV2(t, V2Options{AnalysisPolicies: map[string]AnalysisPolicy{
+		"document":      {SummaryRatio: 0.5},
+		"document/code": {Verbatim: true},
+	}}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	tests := []struct {
+		category string
+		want     AnalysisPolicy
+	}{
+		{"image", AnalysisPolicy{}},
+		{"document", AnalysisPolicy{SummaryRatio: 0.5}},
+		{"Document/Legal", AnalysisPolicy{SummaryRatio: 0.5}},
+		{"document/code/go", AnalysisPolicy{Verbatim: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := v.policy(tt.category); got != tt.want {
+				t.Errorf("V2.policy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_policy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       AnalysisPolicy
+		wantKeywords []string
+		wantRatio    float64
+	}{
+		{"default", AnalysisPolicy{}, []string{"summary"}, text.DefaultRatio},
+		{"ratio", AnalysisPolicy{SummaryRatio: 0.5}, []string{"summary"}, 0.5},
+		{"verbatim", AnalysisPolicy{Verbatim: true}, []string{"func", "main", "println", "hello"}, 0},
+		{"max keywords", AnalysisPolicy{Verbatim: true, MaxKeywords: 2}, []string{"func", "main"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ratio float64
+			var v = newTestV2(t, V2Options{
+				AnalysisPolicies: map[string]AnalysisPolicy{"document": tt.policy},
+				Summarizer: text.SummarizerFunc(func(s string, r float64) []string {
+					ratio = r
+					return []string{"summary"}
+				}),
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(`func main() { println("hello") }`), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.keywords, tt.wantKeywords) || ratio != tt.wantRatio {
+				t.Errorf("V2.analyze() = (%v, ratio %v), want (%v, ratio %v)",
+					a.keywords, ratio, tt.wantKeywords, tt.wantRatio)
+			}
+		})
+	}
+}
+
+func TestV2_isRawText_policy(t *testing.T) {
+	var v = newTestV2(t, V2Options{AnalysisPolicies: map[string]AnalysisPolicy{
+		"document/code": {RawText: true},
+	}}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	if !v.isRawText("document/code") || v.isRawText("document") {
+		t.Error("V2.isRawText() does not apply analysis policies")
+	}
+}
@@ -0,0 +1,116 @@
+package lens
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IndexStage denotes a stage of an index request reported by IndexProgress
+type IndexStage string
+
+const (
+	// IndexStageDetected is reported once the object's content type and
+	// category have been detected, before its contents are extracted
+	IndexStageDetected IndexStage = "detected"
+	// IndexStageExtracted is reported once the object's contents have been
+	// extracted, with preliminary keywords from its title, author, and format,
+	// before its text is summarized
+	IndexStageExtracted IndexStage = "extracted"
+	// IndexStageStored is the final update, reported once the object has been
+	// indexed
+	IndexStageStored IndexStage = "stored"
+)
+
+// IndexUpdate reports the progress of an index request
+type IndexUpdate struct {
+	Stage    IndexStage
+	MimeType string
+	Category string
+	// Tags holds the keywords known so far - final keywords are only available
+	// from Result
+	Tags []string
+	// Result is the indexed object, only set for IndexStageStored
+	Result *lensv2.IndexResp
+}
+
+// IndexProgressStream receives the updates of an IndexProgress request. It
+// matches the server stream of a server-streaming gRPC method.
+type IndexProgressStream interface {
+	Context() context.Context
+	Send(*IndexUpdate) error
+}
+
+// progressFunc receives updates on the progress of an index request
+type progressFunc func(*IndexUpdate)
+
+// report passes the given update to p, if set
+func (p progressFunc) report(u *IndexUpdate) {
+	if p != nil {
+		p(u)
+	}
+}
+
+// IndexProgress analyzes and stores the given object like Index, sending
+// updates to stream as each stage completes, so that clients can act on the
+// detected content type and category of large objects before they are
+// indexed. The last update sent on success is IndexStageStored. Objects that
+// are already indexed, or whose previous analysis is reused, may skip earlier
+// stages.
+func (v *V2) IndexProgress(req *lensv2.IndexReq, stream IndexProgressStream) error {
+	var l = v.l.With("request", req)
+	switch req.GetType() {
+	case lensv2.IndexReq_IPLD:
+		break
+	default:
+		return status.Errorf(codes.InvalidArgument,
+			"invalid data type '%s' provided", req.GetType())
+	}
+	if err := validateIndexReq(req); err != nil {
+		return status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+
+	// analysis may continue in the background once a request's budget is
+	// exceeded, so stop sending updates when the request ends
+	var (
+		lock    sync.Mutex
+		done    bool
+		sendErr error
+	)
+	var send = func(u *IndexUpdate) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if !done && sendErr == nil {
+			sendErr = stream.Send(u)
+		}
+		return sendErr
+	}
+	defer func() {
+		lock.Lock()
+		done = true
+		lock.Unlock()
+	}()
+
+	resp, err := v.index(stream.Context(), req, nil, nil,
+		func(u *IndexUpdate) { send(u) }, l)
+	if err != nil {
+		return err
+	}
+	var doc = resp.GetDoc()
+	if err := send(&IndexUpdate{
+		Stage:    IndexStageStored,
+		MimeType: doc.GetMimeType(),
+		Category: doc.GetCategory(),
+		Tags:     doc.GetTags(),
+		Result:   resp,
+	}); err != nil {
+		l.Warnw("failed to send index progress", "error", err)
+		return status.Errorf(codes.Unavailable,
+			"failed to send progress: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+type fakeProgressStream struct {
+	updates []*IndexUpdate
+	err     error
+}
+
+func (s *fakeProgressStream) Context() context.Context { return context.Background() }
+
+func (s *fakeProgressStream) Send(u *IndexUpdate) error {
+	s.updates = append(s.updates, u)
+	return s.err
+}
+
+func TestV2_IndexProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *lensv2.IndexReq
+		sendErr    error
+		wantStages []IndexStage
+		wantCode   codes.Code
+	}{
+		{"invalid request", &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD}, nil,
+			nil, codes.InvalidArgument},
+		{"indexed", &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"}, nil,
+			[]IndexStage{IndexStageDetected, IndexStageExtracted, IndexStageStored}, codes.OK},
+		{"send failed", &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "asdf"}, errors.New("oh no"),
+			[]IndexStage{IndexStageDetected}, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			ipfs.CatReturns([]byte("<html><head><title>Lens Guide</title></head><body>search</body></html>"), nil)
+			var v = newTestV2(t, V2Options{
+				Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+					return []string{"search"}
+				}),
+			}, ipfs, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+			var stream = &fakeProgressStream{err: tt.sendErr}
+
+			err := v.IndexProgress(tt.req, stream)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("V2.IndexProgress() error = %v, want %s", err, tt.wantCode)
+			}
+			var stages []IndexStage
+			for _, u := range stream.updates {
+				stages = append(stages, u.Stage)
+			}
+			if !reflect.DeepEqual(stages, tt.wantStages) {
+				t.Errorf("V2.IndexProgress() stages = %v, want %v", stages, tt.wantStages)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			var detected, extracted, stored = stream.updates[0], stream.updates[1], stream.updates[2]
+			if detected.Category != "document" || detected.Tags != nil {
+				t.Errorf("V2.IndexProgress() detected = %+v, want document without tags", detected)
+			}
+			if !reflect.DeepEqual(extracted.Tags, []string{"lens guide"}) {
+				t.Errorf("V2.IndexProgress() extracted tags = %v, want title", extracted.Tags)
+			}
+			if stored.Result == nil || !reflect.DeepEqual(stored.Tags, []string{"lens guide", "search"}) {
+				t.Errorf("V2.IndexProgress() stored = %+v, want result with final tags", stored)
+			}
+		})
+	}
+}
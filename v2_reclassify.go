@@ -0,0 +1,118 @@
+package lens
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// reclassifyBatchSize is the number of objects to list at a time when
+// reclassifying images
+const reclassifyBatchSize = 100
+
+// ReclassifyReport summarizes the results of ReclassifyImages
+type ReclassifyReport struct {
+	// Checked is the number of images classified
+	Checked int
+	// Updated is the number of images with a new classification
+	Updated int
+	// Skipped is the number of images that could not be retrieved, or that
+	// are below the minimum image size
+	Skipped int
+	// Failed is the number of images that could not be classified or updated
+	Failed int
+}
+
+// ReclassifyImages runs image classification again on every indexed image,
+// for example after the image model has been upgraded. The previous
+// classification tag of each image is replaced, and all other metadata and
+// content is left untouched. Images that cannot be retrieved are skipped.
+//
+// Images are classified like they are when indexed: images below the minimum
+// image size are skipped, and TIFF images are classified by their first page
+// that can be classified. Images indexed before classifications were recorded
+// separately keep their previous classification tag alongside the new one.
+func (v *V2) ReclassifyImages(ctx context.Context) (*ReclassifyReport, error) {
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+	var l = v.l.Named("reclassify")
+	var report = &ReclassifyReport{}
+	for offset := 0; ; offset += reclassifyBatchSize {
+		results, err := v.se.List(ctx, offset, reclassifyBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, r := range results {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if !strings.HasPrefix(r.MD.MimeType, "image/") {
+				continue
+			}
+			var hl = l.With("hash", r.Hash)
+
+			contents, err := v.px.ExtractContents(r.Hash)
+			if err != nil {
+				hl.Debugw("skipping unreachable image", "error", err)
+				report.Skipped++
+				continue
+			}
+			var model = modelHint(r.Hash)
+			keyword, err := v.classifyImage(r.Hash, contents, model, hl)
+			if err != nil {
+				hl.Warnw("failed to classify image", "error", err)
+				report.Failed++
+				continue
+			}
+			if keyword == "" {
+				hl.Debug("skipping image below minimum size")
+				report.Skipped++
+				continue
+			}
+			report.Checked++
+			if keyword == r.MD.Classification {
+				continue
+			}
+
+			// replace previous classification
+			doc, err := v.se.Get(r.Hash)
+			if err != nil {
+				hl.Warnw("failed to retrieve document", "error", err)
+				report.Failed++
+				continue
+			}
+			var md = &doc.Object.MD
+			var tags = make([]string, 0, len(md.Tags))
+			for _, t := range md.Tags {
+				if md.Classification == "" || t != md.Classification {
+					tags = append(tags, t)
+				}
+			}
+			md.Tags = appendUnique(tags, keyword)
+			md.Classification = keyword
+			if md.Provenance == nil {
+				md.Provenance = &models.Provenance{Method: "image"}
+			}
+			md.Provenance.ImageModel = v.tf.Model(model)
+			doc.Reindex = true
+			if err := v.se.Index(*doc); err != nil {
+				hl.Warnw("failed to update document", "error", err)
+				report.Failed++
+				continue
+			}
+			report.Updated++
+		}
+
+		l.Infow("reclassification progress",
+			"listed", offset+len(results),
+			"checked", report.Checked,
+			"updated", report.Updated,
+			"skipped", report.Skipped,
+			"failed", report.Failed)
+		if len(results) < reclassifyBatchSize {
+			return report, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package lens
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_ReclassifyImages(t *testing.T) {
+	var ipfs = &mocks.FakeRTFSManager{}
+	var tensor = &mocks.FakeTensorflowAnalyzer{}
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{MinImageSize: ImageSizeOpts{Width: 2}},
+		ipfs, tensor, se, zap.NewNop().Sugar())
+
+	var pixel = new(bytes.Buffer)
+	if err := png.Encode(pixel, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	// little-endian TIFF with two empty pages
+	var tiff = []byte{
+		'I', 'I', 42, 0, 8, 0, 0, 0,
+		0, 0, 14, 0, 0, 0,
+		0, 0, 0, 0, 0, 0,
+	}
+
+	var docs = map[string]models.MetaDataV2{
+		"doc":         {MimeType: "text/plain", Tags: []string{"notes"}},
+		"changed":     {MimeType: "image/png", Tags: []string{"mine", "dog"}, Classification: "dog"},
+		"unchanged":   {MimeType: "image/png", Tags: []string{"cat"}, Classification: "cat"},
+		"legacy":      {MimeType: "image/jpeg", Tags: []string{"dog"}},
+		"unreachable": {MimeType: "image/png", Tags: []string{"dog"}, Classification: "dog"},
+		"broken":      {MimeType: "image/png"},
+		"pixel":       {MimeType: "image/png", Tags: []string{"dog"}, Classification: "dog"},
+		"scan":        {MimeType: "image/tiff"},
+	}
+	var listed []engine.Result
+	for _, h := range []string{"doc", "changed", "unchanged", "legacy", "unreachable", "broken", "pixel", "scan"} {
+		listed = append(listed, engine.Result{Hash: h, MD: docs[h]})
+	}
+	se.ListReturns(listed, nil)
+	se.GetStub = func(hash string) (*engine.Document, error) {
+		return &engine.Document{Object: &models.ObjectV2{Hash: hash, MD: docs[hash]}}, nil
+	}
+	ipfs.CatStub = func(hash string) ([]byte, error) {
+		if hash == "unreachable" {
+			return nil, errors.New("oh no")
+		}
+		switch hash {
+		case "pixel":
+			return pixel.Bytes(), nil
+		case "scan":
+			return tiff, nil
+		}
+		return []byte(hash), nil
+	}
+	tensor.AnalyzeStub = func(hash string, contents []byte, _ string) (string, error) {
+		switch hash {
+		case "scan":
+			if bytes.Equal(contents, tiff) {
+				return "", errors.New("classified whole tiff")
+			}
+			return "wolf", nil
+		case "broken":
+			return "", errors.New("oh no")
+		case "unchanged":
+			return "cat", nil
+		default:
+			return "wolf", nil
+		}
+	}
+
+	report, err := v.ReclassifyImages(context.Background())
+	if err != nil {
+		t.Errorf("V2.ReclassifyImages() error = %v", err)
+		return
+	}
+	var want = &ReclassifyReport{Checked: 4, Updated: 3, Skipped: 2, Failed: 1}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("V2.ReclassifyImages() = %+v, want %+v", report, want)
+	}
+
+	var updated = make(map[string][]string)
+	for i := 0; i < se.IndexCallCount(); i++ {
+		var doc = se.IndexArgsForCall(i)
+		if !doc.Reindex || doc.Object.MD.Classification != "wolf" {
+			t.Errorf("unexpected update %+v", doc.Object)
+		}
+		updated[doc.Object.Hash] = doc.Object.MD.Tags
+	}
+	var wantTags = map[string][]string{
+		"changed": {"mine", "wolf"},
+		"legacy":  {"dog", "wolf"},
+		"scan":    {"wolf"},
+	}
+	if !reflect.DeepEqual(updated, wantTags) {
+		t.Errorf("V2.ReclassifyImages() updated tags = %v, want %v", updated, wantTags)
+	}
+}
@@ -0,0 +1,67 @@
+package lens
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// rejectionCounter counts content rejected for indexing by mime type. A nil
+// counter does not count anything.
+type rejectionCounter struct {
+	mux    sync.Mutex
+	counts map[string]uint64
+}
+
+// newRejectionCounter returns nil if enabled is false
+func newRejectionCounter(enabled bool) *rejectionCounter {
+	if !enabled {
+		return nil
+	}
+	return &rejectionCounter{counts: make(map[string]uint64)}
+}
+
+// add counts a rejection and returns the number of rejections of the mime type
+// so far
+func (c *rejectionCounter) add(mimeType string) uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.counts[mimeType]++
+	return c.counts[mimeType]
+}
+
+func (c *rejectionCounter) snapshot() map[string]uint64 {
+	var counts = make(map[string]uint64)
+	if c == nil {
+		return counts
+	}
+	c.mux.Lock()
+	for mimeType, n := range c.counts {
+		counts[mimeType] = n
+	}
+	c.mux.Unlock()
+	return counts
+}
+
+// reject records that content of the given mime type was rejected for the
+// given reason, and returns the reason. If rejections are counted, the number
+// of rejections of the mime type so far is logged as well.
+func (v *V2) reject(mimeType string, reason error, l *zap.SugaredLogger) error {
+	var fields = []interface{}{"mime_type", mimeType, "reason", reason.Error()}
+	if n := v.rejections.add(mimeType); n > 0 {
+		fields = append(fields, "rejected", n)
+	}
+	l.Warnw("content rejected for indexing", fields...)
+	return reason
+}
+
+// Rejections reports the number of objects rejected for indexing since startup
+// by detected mime type, ie because they are not supported or not allowed by
+// the content filter, to help prioritize support for new formats. Rejections
+// are only counted if V2Options.CountRejections is set.
+func (v *V2) Rejections() map[string]uint64 {
+	return v.rejections.snapshot()
+}
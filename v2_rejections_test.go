@@ -0,0 +1,51 @@
+package lens
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func TestV2_Rejections(t *testing.T) {
+	var (
+		binary = []byte{0x00, 0x01, 0x02, 0x03}
+		text   = []byte("hello world")
+	)
+	tests := []struct {
+		name     string
+		count    bool
+		contents [][]byte
+		want     map[string]uint64
+	}{
+		{"disabled", false, [][]byte{binary, text}, map[string]uint64{}},
+		{"unsupported and filtered", true, [][]byte{binary, text, binary},
+			map[string]uint64{"application/octet-stream": 2, "text/plain": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var v = newTestV2(t, V2Options{
+				CountRejections: tt.count,
+				ContentFilter:   ContentFilter{Deny: []string{"text/"}},
+			}, ipfs, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+
+			for i, c := range tt.contents {
+				ipfs.CatReturnsOnCall(i, c, nil)
+				if _, err := v.Index(context.Background(), &lensv2.IndexReq{
+					Type: lensv2.IndexReq_IPLD,
+					Hash: "asdf",
+				}); err == nil {
+					t.Errorf("V2.Index() expected content %d to be rejected", i)
+				}
+			}
+			if got := v.Rejections(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("V2.Rejections() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package lens
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+)
+
+// statsTTL is how long index statistics are cached for
+const statsTTL = 30 * time.Second
+
+// statsCache holds the most recently computed index statistics
+type statsCache struct {
+	mux     sync.Mutex
+	stats   *engine.Stats
+	updated time.Time
+}
+
+// Stats reports the number of indexed objects, distinct tags and terms,
+// objects per category, and the size of the index on disk. Statistics are
+// cached briefly, since computing them requires scanning the index. Whether
+// maintenance mode is enabled is reported in a 'lens-maintenance' response
+// header.
+func (v *V2) Stats(ctx context.Context) (*engine.Stats, error) {
+	v.reportMaintenance(ctx)
+	v.stats.mux.Lock()
+	defer v.stats.mux.Unlock()
+	if v.stats.stats != nil && time.Since(v.stats.updated) < statsTTL {
+		return v.stats.stats, nil
+	}
+
+	stats, err := v.se.Stats(ctx)
+	if err != nil {
+		v.l.Errorw("failed to compute index statistics", "error", err)
+		return nil, status.Errorf(codes.Internal,
+			"failed to compute index statistics: %s", err.Error())
+	}
+	v.stats.stats = stats
+	v.stats.updated = time.Now()
+	return stats, nil
+}
@@ -0,0 +1,44 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func TestV2_Stats(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{}, &mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+
+	se.StatsReturns(nil, errors.New("oh no"))
+	if _, err := v.Stats(context.Background()); status.Code(err) != codes.Internal {
+		t.Errorf("V2.Stats() error = %v, want Internal", err)
+	}
+
+	var stats = &engine.Stats{Objects: 2, Categories: map[string]int{"document": 2}}
+	se.StatsReturns(stats, nil)
+	for i := 0; i < 2; i++ {
+		if got, err := v.Stats(context.Background()); err != nil || got != stats {
+			t.Errorf("V2.Stats() = %v, %v", got, err)
+		}
+	}
+	if se.StatsCallCount() != 2 {
+		t.Errorf("wanted cached statistics to be reused, got %d engine calls", se.StatsCallCount())
+	}
+
+	// statistics are recomputed once stale
+	v.stats.updated = time.Now().Add(-statsTTL)
+	v.Stats(context.Background())
+	if se.StatsCallCount() != 3 {
+		t.Errorf("wanted stale statistics to be recomputed, got %d engine calls", se.StatsCallCount())
+	}
+}
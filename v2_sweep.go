@@ -0,0 +1,137 @@
+package lens
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+)
+
+// SweepOpts configures the background reachability sweeper
+type SweepOpts struct {
+	// Interval is the duration between each batch of checks - leave at 0 to
+	// disable sweeping
+	Interval time.Duration
+	// BatchSize is the number of objects to check on each interval
+	BatchSize int
+}
+
+// Sweep periodically checks that indexed objects can still be retrieved from
+// IPFS, and flags objects that IPFS cannot find as stale. Sweeps are retried
+// while IPFS is unavailable. It blocks until the given context is cancelled.
+func (v *V2) Sweep(ctx context.Context, opts SweepOpts) {
+	if opts.Interval <= 0 {
+		return
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 10
+	}
+
+	var l = v.l.Named("sweeper")
+	l.Infow("starting sweeper",
+		"interval", opts.Interval,
+		"batch", opts.BatchSize)
+	var ticker = time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	var offset int
+	for {
+		select {
+		case <-ctx.Done():
+			l.Info("stopping sweeper")
+			return
+		case <-ticker.C:
+			if v.Maintenance() {
+				l.Debug("skipping sweep in maintenance mode")
+				continue
+			}
+			offset = v.sweep(ctx, offset, opts.BatchSize)
+		}
+	}
+}
+
+// notFound checks if the given error returned by IPFS indicates that content
+// could not be found or resolved. Other errors, ie timeouts or refused
+// connections, indicate that IPFS itself is unavailable.
+func notFound(err error) bool {
+	e, ok := err.(*shell.Error)
+	if !ok {
+		return false
+	}
+	var msg = strings.ToLower(e.Message)
+	for _, s := range []string{"not found", "resolve", "invalid"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sweep checks a single batch of objects, and returns the offset of the next
+// batch to check
+func (v *V2) sweep(ctx context.Context, offset, size int) int {
+	var l = v.l.Named("sweeper").With("offset", offset)
+	results, err := v.se.List(ctx, offset, size)
+	if err != nil {
+		l.Warnw("failed to list documents", "error", err)
+		return 0
+	}
+
+	var flagged int
+	var reachable = make(map[string]bool)
+	for _, r := range results {
+		if ctx.Err() != nil {
+			return offset
+		}
+
+		// inline content was never on IPFS
+		if strings.HasPrefix(r.Hash, inlineHashPrefix) {
+			continue
+		}
+
+		// archive members are stored as '<archive>/<path>', and are reachable
+		// if their archive is
+		var root = strings.SplitN(r.Hash, "/", 2)[0]
+		ok, checked := reachable[root]
+		if !checked {
+			_, err := v.ipfs.Stat(root)
+			if err != nil && !notFound(err) {
+				// retry the batch once IPFS is available again, rather than
+				// flagging every object
+				l.Warnw("failed to reach IPFS - aborting sweep",
+					"hash", r.Hash, "error", err)
+				return offset
+			}
+			ok = err == nil
+			reachable[root] = ok
+		}
+
+		// update flag only if reachability has changed
+		var stale = !ok
+		if stale == r.MD.Stale {
+			continue
+		}
+		doc, err := v.se.Get(r.Hash)
+		if err != nil {
+			l.Warnw("failed to retrieve document", "hash", r.Hash, "error", err)
+			continue
+		}
+		doc.Object.MD.Stale = stale
+		doc.Reindex = true
+		if err := v.se.Index(*doc); err != nil {
+			l.Warnw("failed to update document", "hash", r.Hash, "error", err)
+			continue
+		}
+		flagged++
+	}
+	l.Debugw("batch swept",
+		"checked", len(results),
+		"updated", flagged)
+
+	// start over once we reach the end
+	if len(results) < size {
+		return 0
+	}
+	return offset + len(results)
+}
@@ -0,0 +1,138 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	shell "github.com/RTradeLtd/go-ipfs-api"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func TestV2_sweep(t *testing.T) {
+	type args struct {
+		offset int
+		size   int
+	}
+	type returns struct {
+		listed  []engine.Result
+		listErr error
+		statErr error
+	}
+	tests := []struct {
+		name        string
+		args        args
+		returns     returns
+		wantOffset  int
+		wantUpdates int
+		wantStale   bool
+	}{
+		{"list error",
+			args{5, 2},
+			returns{nil, errors.New("oh no"), nil},
+			0, 0, false},
+		{"reachable and unchanged",
+			args{0, 2},
+			returns{[]engine.Result{{Hash: "a"}, {Hash: "b"}}, nil, nil},
+			2, 0, false},
+		{"unreachable",
+			args{0, 2},
+			returns{[]engine.Result{{Hash: "a"}}, nil, &shell.Error{Message: "merkledag: not found"}},
+			0, 1, true},
+		{"unresolvable",
+			args{0, 2},
+			returns{[]engine.Result{{Hash: "a"}}, nil, &shell.Error{Message: "failed to resolve /ipfs/a"}},
+			0, 1, true},
+		{"ipfs unavailable",
+			args{4, 2},
+			returns{[]engine.Result{{Hash: "a"}, {Hash: "b"}}, nil, errors.New("connection refused")},
+			4, 0, false},
+		{"reachable again",
+			args{2, 2},
+			returns{[]engine.Result{
+				{Hash: "a", MD: models.MetaDataV2{Stale: true}},
+				{Hash: "b", MD: models.MetaDataV2{Stale: true}},
+			}, nil, nil},
+			4, 2, false},
+		{"archive members are checked by their archive",
+			args{0, 2},
+			returns{[]engine.Result{{Hash: "a"}, {Hash: "a/docs/b.txt"}}, nil, nil},
+			2, 0, false},
+		{"inline content is not checked",
+			args{0, 2},
+			returns{[]engine.Result{{Hash: inlineHashPrefix + "abcd"}}, nil, errors.New("oh no")},
+			0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var tensor = &mocks.FakeTensorflowAnalyzer{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				ipfs,
+				tensor,
+				se,
+				zap.NewNop().Sugar())
+
+			// set up mocks
+			se.ListReturns(tt.returns.listed, tt.returns.listErr)
+			se.GetStub = func(hash string) (*engine.Document, error) {
+				return &engine.Document{Object: &models.ObjectV2{Hash: hash}}, nil
+			}
+			ipfs.StatStub = func(hash string) (*shell.ObjectStats, error) {
+				if strings.Contains(hash, "/") {
+					return nil, &shell.Error{Message: "invalid path"}
+				}
+				return &shell.ObjectStats{}, tt.returns.statErr
+			}
+
+			// execute tests
+			if got := v.sweep(context.Background(), tt.args.offset, tt.args.size); got != tt.wantOffset {
+				t.Errorf("V2.sweep() = %d, want %d", got, tt.wantOffset)
+			}
+			if se.IndexCallCount() != tt.wantUpdates {
+				t.Errorf("V2.sweep() updated %d documents, want %d",
+					se.IndexCallCount(), tt.wantUpdates)
+			}
+			for i := 0; i < se.IndexCallCount(); i++ {
+				if doc := se.IndexArgsForCall(i); doc.Object.MD.Stale != tt.wantStale {
+					t.Errorf("V2.sweep() set stale = %v, want %v",
+						doc.Object.MD.Stale, tt.wantStale)
+				}
+			}
+		})
+	}
+}
+
+func TestV2_Sweep(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{},
+		&mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{},
+		se,
+		zap.NewNop().Sugar())
+
+	// sweeper should stop on context cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	var done = make(chan bool)
+	go func() {
+		v.Sweep(ctx, SweepOpts{Interval: 10 * time.Millisecond})
+		done <- true
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("V2.Sweep() did not stop after cancellation")
+	}
+	if se.ListCallCount() < 1 {
+		t.Error("V2.Sweep() did not check any documents")
+	}
+}
@@ -3,10 +3,18 @@ package lens
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/RTradeLtd/Lens/v2/analyzer/images"
 	"github.com/RTradeLtd/Lens/v2/engine"
 	"github.com/RTradeLtd/Lens/v2/mocks"
 	"github.com/RTradeLtd/Lens/v2/models"
@@ -27,11 +35,48 @@ func TestNewV2(t *testing.T) {
 		return
 	}
 	service.Close()
-	if service = NewV2WithEngine(V2Options{}, ipfs, ia, &mocks.FakeSearcher{}, nil); service == nil {
-		t.Error("NewV2WithEngine() = nil")
+	if _, err = NewV2(V2Options{
+		SummaryRatios: map[string]float64{"text": 0},
+		Engine:        engine.Opts{StorePath: "tmp"},
+	}, ipfs, ia, nil); err == nil {
+		t.Error("NewV2() expected error for invalid summary ratio")
+	}
+	if service, err = NewV2WithEngine(V2Options{}, ipfs, ia, &mocks.FakeSearcher{}, nil); err != nil {
+		t.Errorf("NewV2WithEngine() error = %v", err)
 		return
 	}
 	service.Close()
+
+	// the job store cannot be created under a regular file
+	file, err := ioutil.TempFile("", "lens-jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	if _, err = NewV2WithEngine(V2Options{
+		AsyncIndex: AsyncOpts{Workers: 1, StorePath: filepath.Join(file.Name(), "jobs.db")},
+	}, ipfs, ia, &mocks.FakeSearcher{}, nil); err == nil {
+		t.Error("NewV2WithEngine() expected error for unusable job store")
+	}
+}
+
+// newTestV2 instantiates a Lens V2 service with the given engine, failing the
+// test if it cannot be set up
+func newTestV2(
+	tb testing.TB,
+	opts V2Options,
+	ipfs *mocks.FakeRTFSManager,
+	ia images.TensorflowAnalyzer,
+	se engine.Searcher,
+	logger *zap.SugaredLogger,
+) *V2 {
+	tb.Helper()
+	v, err := NewV2WithEngine(opts, ipfs, ia, se, logger)
+	if err != nil {
+		tb.Fatalf("NewV2WithEngine() error = %v", err)
+	}
+	return v
 }
 
 func TestV2_Index(t *testing.T) {
@@ -63,6 +108,23 @@ func TestV2_Index(t *testing.T) {
 			returns{"", false, false, false},
 			"",
 			codes.InvalidArgument},
+		{"hash too long",
+			args{&lensv2.IndexReq{
+				Type: lensv2.IndexReq_IPLD,
+				Hash: strings.Repeat("a", maxHashLength+1),
+			}},
+			returns{"", false, false, false},
+			"",
+			codes.InvalidArgument},
+		{"too many tags",
+			args{&lensv2.IndexReq{
+				Type: lensv2.IndexReq_IPLD,
+				Hash: "asdf",
+				Tags: make([]string, maxListLength+1),
+			}},
+			returns{"", false, false, false},
+			"",
+			codes.InvalidArgument},
 		{"no content for hash found",
 			args{&lensv2.IndexReq{
 				Type: lensv2.IndexReq_IPLD,
@@ -107,6 +169,8 @@ func TestV2_Index(t *testing.T) {
 			args{&lensv2.IndexReq{
 				Type: lensv2.IndexReq_IPLD,
 				Hash: "asdf",
+				// takes precedence over the document's title
+				DisplayName: "readme",
 			}},
 			returns{"README.md", false, false, false},
 			models.MimeTypeDocument,
@@ -117,7 +181,7 @@ func TestV2_Index(t *testing.T) {
 			var ipfs = &mocks.FakeRTFSManager{}
 			var tensor = &mocks.FakeTensorflowAnalyzer{}
 			var se = &mocks.FakeSearcher{}
-			var v = NewV2WithEngine(V2Options{},
+			var v = newTestV2(t, V2Options{},
 				ipfs,
 				tensor,
 				se,
@@ -170,6 +234,123 @@ func TestV2_Index(t *testing.T) {
 	}
 }
 
+func TestV2_IndexIfModified(t *testing.T) {
+	var stored = &engine.Document{Object: &models.ObjectV2{
+		Hash: "asdf",
+		MD:   models.MetaDataV2{DisplayName: "hello.txt", Category: "document"},
+	}}
+	tests := []struct {
+		name         string
+		indexed      bool
+		reindex      bool
+		getErr       error
+		wantModified bool
+		wantErrCode  codes.Code
+	}{
+		{"not indexed", false, false, nil, true, 0},
+		{"not modified", true, false, nil, false, 0},
+		{"forced reindex", true, true, nil, true, 0},
+		{"index error", true, false, errors.New("oh no"), false, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			ipfs.CatReturns([]byte("hello world"), nil)
+			se.IsIndexedReturns(tt.indexed)
+			se.GetReturns(stored, tt.getErr)
+
+			resp, modified, err := v.IndexIfModified(context.Background(), &lensv2.IndexReq{
+				Type:    lensv2.IndexReq_IPLD,
+				Hash:    "asdf",
+				Options: &lensv2.IndexReq_Options{Reindex: tt.reindex},
+			})
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.IndexIfModified() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.IndexIfModified() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+				return
+			}
+			if modified != tt.wantModified {
+				t.Errorf("V2.IndexIfModified() modified = %v, want %v", modified, tt.wantModified)
+			}
+			if processed := ipfs.CatCallCount() > 0; processed != tt.wantModified {
+				t.Errorf("V2.IndexIfModified() processed content = %v, want %v", processed, tt.wantModified)
+			}
+			if !modified && resp.GetDoc().GetDisplayName() != "hello.txt" {
+				t.Errorf("V2.IndexIfModified() = %+v, want indexed object", resp.GetDoc())
+			}
+		})
+	}
+}
+
+func TestV2_Index_ifIndexed(t *testing.T) {
+	var stored = &engine.Document{Object: &models.ObjectV2{
+		Hash: "asdf",
+		MD:   models.MetaDataV2{DisplayName: "hello.txt", Category: "document"},
+	}}
+	tests := []struct {
+		name          string
+		header        string
+		indexed       bool
+		wantProcessed bool
+		wantErrCode   codes.Code
+	}{
+		{"not indexed", ifIndexedSkip, false, true, 0},
+		{"default", "", true, false, codes.FailedPrecondition},
+		{"error", ifIndexedError, true, false, codes.FailedPrecondition},
+		{"skip", "Skip", true, false, 0},
+		{"reindex", ifIndexedReindex, true, true, 0},
+		{"invalid", "ignore", true, false, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			ipfs.CatReturns([]byte("hello world"), nil)
+			se.IsIndexedReturns(tt.indexed)
+			se.GetReturns(stored, nil)
+
+			var ctx = context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx,
+					metadata.Pairs(ifIndexedHeader, tt.header))
+			}
+			resp, err := v.Index(ctx, &lensv2.IndexReq{
+				Type: lensv2.IndexReq_IPLD,
+				Hash: "asdf",
+			})
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Index() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if processed := ipfs.CatCallCount() > 0; processed != tt.wantProcessed {
+				t.Errorf("V2.Index() processed content = %v, want %v", processed, tt.wantProcessed)
+			}
+			if tt.wantErrCode != 0 {
+				return
+			}
+			if tt.wantProcessed {
+				if se.IndexCallCount() != 1 || se.IndexArgsForCall(0).Reindex != tt.indexed {
+					t.Errorf("V2.Index() stored %d documents, want one with reindex = %v",
+						se.IndexCallCount(), tt.indexed)
+				}
+			} else if resp.GetDoc().GetDisplayName() != "hello.txt" {
+				t.Errorf("V2.Index() = %+v, want indexed object", resp.GetDoc())
+			}
+		})
+	}
+}
+
 func TestV2_Search(t *testing.T) {
 	type args struct {
 		req *lensv2.SearchReq
@@ -192,6 +373,12 @@ func TestV2_Search(t *testing.T) {
 			args{&lensv2.SearchReq{}},
 			returns{[]engine.Result{}, nil},
 			codes.InvalidArgument},
+		{"query too long",
+			args{&lensv2.SearchReq{
+				Query: strings.Repeat("a", maxQueryLength+1),
+			}},
+			returns{[]engine.Result{}, nil},
+			codes.InvalidArgument},
 		{"search error",
 			args{&lensv2.SearchReq{
 				Query: "cats",
@@ -230,7 +417,7 @@ func TestV2_Search(t *testing.T) {
 			var ipfs = &mocks.FakeRTFSManager{}
 			var tensor = &mocks.FakeTensorflowAnalyzer{}
 			var se = &mocks.FakeSearcher{}
-			var v = NewV2WithEngine(V2Options{},
+			var v = newTestV2(t, V2Options{},
 				ipfs,
 				tensor,
 				se,
@@ -265,6 +452,267 @@ func TestV2_Search(t *testing.T) {
 	}
 }
 
+func TestV2_Search_keywordLimit(t *testing.T) {
+	var req = &lensv2.SearchReq{Options: &lensv2.SearchReq_Options{
+		Required: []string{"bitcoin", "ipfs"},
+		Tags:     []string{"crypto"},
+	}}
+	tests := []struct {
+		name        string
+		limit       KeywordLimit
+		wantTags    int
+		wantErrCode codes.Code
+	}{
+		{"at limit", KeywordLimit{Max: 3}, 1, 0},
+		{"above limit", KeywordLimit{Max: 2}, 0, codes.InvalidArgument},
+		{"above limit, truncated", KeywordLimit{Max: 2, Truncate: true}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{KeywordLimit: tt.limit},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			_, err := v.Search(context.Background(), req)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Search() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if _, q := se.SearchArgsForCall(0); len(q.Required) != 2 || len(q.Tags) != tt.wantTags {
+				t.Errorf("V2.Search() query = (%v, %v)", q.Required, q.Tags)
+			}
+		})
+	}
+}
+
+func TestV2_SearchPage(t *testing.T) {
+	var req = &lensv2.SearchReq{Query: "cats"}
+	var results = []engine.Result{
+		{Hash: "a", Score: 4}, {Hash: "b", Score: 3}, {Hash: "c", Score: 2}, {Hash: "d", Score: 1},
+	}
+	var se = &mocks.FakeSearcher{}
+	se.SearchReturns(results, nil)
+	var v = newTestV2(t, V2Options{}, &mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+	var hashes = func(resp *lensv2.SearchResp) []string {
+		var h []string
+		for _, r := range resp.GetResults() {
+			h = append(h, r.GetDoc().GetHash())
+		}
+		return h
+	}
+
+	// first page
+	resp, next, err := v.SearchPage(context.Background(), req, engine.Order{}, "", 2)
+	if err != nil || next == "" || !reflect.DeepEqual(hashes(resp), []string{"a", "b"}) {
+		t.Fatalf("V2.SearchPage() = (%v, %q, %v), want first page", hashes(resp), next, err)
+	}
+	var cursor = next
+
+	// the cursor is passed on to the engine
+	se.SearchReturns(results[2:], nil)
+	resp, next, err = v.SearchPage(context.Background(), req, engine.Order{}, next, 2)
+	if err != nil || next != "" || !reflect.DeepEqual(hashes(resp), []string{"c", "d"}) {
+		t.Errorf("V2.SearchPage() = (%v, %q, %v), want last page", hashes(resp), next, err)
+	}
+	if _, q := se.SearchArgsForCall(1); q.After == nil || q.After.Hash != "b" {
+		t.Errorf("V2.SearchPage() searched after %+v, want cursor of b", q.After)
+	}
+
+	// invalid requests
+	if _, _, err := v.SearchPage(context.Background(), req, engine.Order{}, "", 0); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("V2.SearchPage() with no page size error = %v, want InvalidArgument", err)
+	}
+	if _, _, err := v.SearchPage(context.Background(), req, engine.Order{}, "!!!", 2); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("V2.SearchPage() with malformed cursor error = %v, want InvalidArgument", err)
+	}
+	if _, _, err := v.SearchPage(context.Background(), req, engine.Order{By: engine.OrderName}, cursor, 2); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("V2.SearchPage() with mismatched cursor error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestV2_Search_reachableOnly(t *testing.T) {
+	var req = &lensv2.SearchReq{Query: "cats"}
+	tests := []struct {
+		name             string
+		excludeStale     bool
+		header           string // blank to omit the header
+		wantExcludeStale bool
+		wantErrCode      codes.Code
+	}{
+		{"default include", false, "", false, 0},
+		{"default exclude", true, "", true, 0},
+		{"override include", true, "false", false, 0},
+		{"override exclude", false, "true", true, 0},
+		{"invalid override", false, "yes please", false, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{ExcludeStale: tt.excludeStale},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			var ctx = context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx,
+					metadata.Pairs(reachableOnlyHeader, tt.header))
+			}
+			_, err := v.Search(ctx, req)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Search() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if _, q := se.SearchArgsForCall(0); q.ExcludeStale != tt.wantExcludeStale {
+				t.Errorf("V2.Search() ExcludeStale = %v, want %v",
+					q.ExcludeStale, tt.wantExcludeStale)
+			}
+		})
+	}
+}
+
+func TestV2_Search_categoryBoosts(t *testing.T) {
+	var req = &lensv2.SearchReq{Query: "cats"}
+	tests := []struct {
+		name        string
+		header      []string
+		want        map[string]float64
+		wantErrCode codes.Code
+	}{
+		{"none", nil, nil, 0},
+		{"boosts", []string{"image:1.5, document/legal:0.5", "video:2"},
+			map[string]float64{"image": 1.5, "document/legal": 0.5, "video": 2}, 0},
+		{"missing boost", []string{"image"}, nil, codes.InvalidArgument},
+		{"invalid boost", []string{"image:lots"}, nil, codes.InvalidArgument},
+		{"negative boost", []string{"image:-1"}, nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			var md = metadata.MD{}
+			for _, h := range tt.header {
+				md.Append(categoryBoostHeader, h)
+			}
+			_, err := v.Search(metadata.NewIncomingContext(context.Background(), md), req)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Search() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if _, q := se.SearchArgsForCall(0); !reflect.DeepEqual(q.CategoryBoosts, tt.want) {
+				t.Errorf("V2.Search() CategoryBoosts = %v, want %v", q.CategoryBoosts, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_Search_properties(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *lensv2.SearchReq
+		header      []string
+		wantProps   map[string]string
+		wantErrCode codes.Code
+	}{
+		{"none", &lensv2.SearchReq{Query: "cats"}, nil, nil, 0},
+		{"pairs", &lensv2.SearchReq{Query: "cats"}, []string{"project=alpha, team = search", "labels=a,b"},
+			map[string]string{"project": "alpha", "team": "search", "labels": "a,b"}, 0},
+		{"properties only", &lensv2.SearchReq{}, []string{"project=alpha"},
+			map[string]string{"project": "alpha"}, 0},
+		{"missing value", &lensv2.SearchReq{Query: "cats"}, []string{"project="}, nil, codes.InvalidArgument},
+		{"missing key", &lensv2.SearchReq{Query: "cats"}, []string{"=alpha"}, nil, codes.InvalidArgument},
+		{"no parameters", &lensv2.SearchReq{}, nil, nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			var md = metadata.MD{}
+			for _, h := range tt.header {
+				md.Append(propertiesHeader, h)
+			}
+			_, err := v.Search(metadata.NewIncomingContext(context.Background(), md), tt.req)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Search() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if _, q := se.SearchArgsForCall(0); !reflect.DeepEqual(q.Properties, tt.wantProps) {
+				t.Errorf("V2.Search() Properties = %v, want %v", q.Properties, tt.wantProps)
+			}
+		})
+	}
+}
+
+func TestV2_Search_prefix(t *testing.T) {
+	var req = &lensv2.SearchReq{Options: &lensv2.SearchReq_Options{Tags: []string{"crypt"}}}
+	tests := []struct {
+		name        string
+		header      []string
+		want        bool
+		wantErrCode codes.Code
+	}{
+		{"default", nil, false, 0},
+		{"enabled", []string{"true"}, true, 0},
+		{"last wins", []string{"true", "false"}, false, 0},
+		{"invalid", []string{"sometimes"}, false, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			var md = metadata.MD{}
+			for _, h := range tt.header {
+				md.Append(prefixHeader, h)
+			}
+			_, err := v.Search(metadata.NewIncomingContext(context.Background(), md), req)
+			if status.Code(err) != tt.wantErrCode {
+				t.Errorf("V2.Search() error = %v, want code %s", err, tt.wantErrCode)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if _, q := se.SearchArgsForCall(0); q.Prefix != tt.want {
+				t.Errorf("V2.Search() Prefix = %v, want %v", q.Prefix, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_SearchSorted(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{SearchOrder: engine.Order{By: engine.OrderName}},
+		&mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{},
+		se,
+		zap.NewNop().Sugar())
+	var req = &lensv2.SearchReq{Query: "cats"}
+
+	if _, err := v.SearchSorted(context.Background(), req,
+		engine.Order{By: "size"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("V2.SearchSorted() error = %v, want InvalidArgument", err)
+	}
+	if _, err := v.Search(context.Background(), req); err != nil {
+		t.Errorf("V2.Search() error = %v", err)
+		return
+	}
+	if _, q := se.SearchArgsForCall(0); q.Order.By != engine.OrderName {
+		t.Errorf("V2.Search() order = %v, want default order", q.Order)
+	}
+}
+
 func TestV2_Remove(t *testing.T) {
 	type args struct {
 		req *lensv2.RemoveReq
@@ -304,7 +752,7 @@ func TestV2_Remove(t *testing.T) {
 			var ipfs = &mocks.FakeRTFSManager{}
 			var tensor = &mocks.FakeTensorflowAnalyzer{}
 			var se = &mocks.FakeSearcher{}
-			var v = NewV2WithEngine(V2Options{},
+			var v = newTestV2(t, V2Options{},
 				ipfs,
 				tensor,
 				se,
@@ -338,3 +786,549 @@ func TestV2_Remove(t *testing.T) {
 		})
 	}
 }
+
+func TestV2_GetObject(t *testing.T) {
+	var obj = &models.ObjectV2{
+		Hash: "asdf",
+		MD:   models.MetaDataV2{Category: "cats"},
+		History: []models.Revision{
+			{MD: models.MetaDataV2{Category: "image"}},
+		},
+	}
+	tests := []struct {
+		name        string
+		hash        string
+		getErr      error
+		wantErrCode codes.Code
+	}{
+		{"no hash", "", nil, codes.InvalidArgument},
+		{"not indexed", "asdf", engine.ErrNotFound, codes.NotFound},
+		{"index error", "asdf", errors.New("oh no"), codes.Internal},
+		{"ok", "asdf", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			if tt.getErr != nil {
+				se.GetReturns(nil, tt.getErr)
+			} else {
+				se.GetReturns(&engine.Document{Object: obj}, nil)
+			}
+
+			got, err := v.GetObject(tt.hash)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.GetObject() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode == 0 {
+				if !reflect.DeepEqual(got, obj) {
+					t.Errorf("V2.GetObject() = %v, want %v", got, obj)
+				}
+			} else if s := status.Convert(err); s.Code() != tt.wantErrCode {
+				t.Errorf("V2.GetObject() err code = %s, want %s",
+					s.Code().String(), tt.wantErrCode.String())
+			}
+		})
+	}
+}
+
+func TestV2_GetObjects(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{},
+		&mocks.FakeRTFSManager{},
+		&mocks.FakeTensorflowAnalyzer{},
+		se,
+		zap.NewNop().Sugar())
+	se.GetStub = func(hash string) (*engine.Document, error) {
+		switch hash {
+		case "missing":
+			return nil, engine.ErrNotFound
+		case "broken":
+			return nil, errors.New("oh no")
+		}
+		return &engine.Document{Object: &models.ObjectV2{Hash: hash}}, nil
+	}
+
+	if _, err := v.GetObjects(nil); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("V2.GetObjects() error = %v, want InvalidArgument", err)
+	}
+
+	got, err := v.GetObjects([]string{"b", "missing", "a", "", "broken"})
+	if err != nil {
+		t.Errorf("V2.GetObjects() error = %v", err)
+		return
+	}
+	var want = []struct {
+		hash    string
+		errCode codes.Code
+	}{
+		{"b", codes.OK},
+		{"missing", codes.NotFound},
+		{"a", codes.OK},
+		{"", codes.InvalidArgument},
+		{"broken", codes.Internal},
+	}
+	if len(got) != len(want) {
+		t.Errorf("V2.GetObjects() returned %d results, want %d", len(got), len(want))
+		return
+	}
+	for i, w := range want {
+		if got[i].Hash != w.hash || status.Code(got[i].Err) != w.errCode {
+			t.Errorf("V2.GetObjects()[%d] = (%s, %v), want (%s, %s)",
+				i, got[i].Hash, got[i].Err, w.hash, w.errCode)
+		}
+		if w.errCode == codes.OK && (got[i].Object == nil || got[i].Object.Hash != w.hash) {
+			t.Errorf("V2.GetObjects()[%d] object = %v, want %s", i, got[i].Object, w.hash)
+		}
+	}
+}
+
+func TestV2_ObjectsForHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		hash        string
+		indexed     bool
+		members     []string
+		listErr     error
+		want        []string
+		wantErrCode codes.Code
+	}{
+		{"no hash", "", false, nil, nil, nil, codes.InvalidArgument},
+		{"list error", "asdf", true, nil, errors.New("oh no"), nil, codes.Internal},
+		{"unknown hash", "asdf", false, nil, nil, []string{}, 0},
+		{"object only", "asdf", true, nil, nil, []string{"asdf"}, 0},
+		{"archive members", "asdf", true, []string{"asdf/a.txt", "asdf/b.txt"}, nil,
+			[]string{"asdf", "asdf/a.txt", "asdf/b.txt"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			se.IsIndexedReturns(tt.indexed)
+			se.ListPrefixReturns(tt.members, tt.listErr)
+
+			got, err := v.ObjectsForHash(context.Background(), tt.hash)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.ObjectsForHash() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.ObjectsForHash() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("V2.ObjectsForHash() = %v, want %v", got, tt.want)
+			}
+			if _, prefix := se.ListPrefixArgsForCall(0); prefix != tt.hash+"/" {
+				t.Errorf("V2.ObjectsForHash() listed prefix %s, want %s", prefix, tt.hash+"/")
+			}
+		})
+	}
+}
+
+func TestV2_UpdateMetadata(t *testing.T) {
+	type args struct {
+		hash  string
+		patch models.MetaDataPatch
+	}
+	type returns struct {
+		getErr   error
+		indexErr error
+	}
+	tests := []struct {
+		name        string
+		args        args
+		returns     returns
+		wantMD      *models.MetaDataV2
+		wantErrCode codes.Code
+	}{
+		{"no hash",
+			args{"", models.MetaDataPatch{}},
+			returns{nil, nil},
+			nil,
+			codes.InvalidArgument},
+		{"not indexed",
+			args{"asdf", models.MetaDataPatch{}},
+			returns{engine.ErrNotFound, nil},
+			nil,
+			codes.NotFound},
+		{"get failure",
+			args{"asdf", models.MetaDataPatch{}},
+			returns{errors.New("oh no"), nil},
+			nil,
+			codes.Internal},
+		{"index failure",
+			args{"asdf", models.MetaDataPatch{Category: "cats"}},
+			returns{nil, errors.New("oh no")},
+			nil,
+			codes.Internal},
+		{"ok: merge patch",
+			args{"asdf", models.MetaDataPatch{
+				Category:   "cats",
+				Tags:       []string{"fluffy", "auto"},
+				Properties: map[string]string{"colour": "orange"},
+			}},
+			returns{nil, nil},
+			&models.MetaDataV2{
+				DisplayName: "my cat",
+				Category:    "cats",
+				Tags:        []string{"auto", "fluffy"},
+				Properties:  map[string]string{"colour": "orange"},
+			},
+			0},
+		{"ok: remove tags",
+			args{"asdf", models.MetaDataPatch{
+				Tags:       []string{"fluffy"},
+				RemoveTags: []string{"AUTO"},
+			}},
+			returns{nil, nil},
+			&models.MetaDataV2{
+				DisplayName: "my cat",
+				Category:    "image",
+				Tags:        []string{"fluffy"},
+			},
+			0},
+		{"ok: unchanged",
+			args{"asdf", models.MetaDataPatch{
+				Category:   "image",
+				Tags:       []string{"auto"},
+				RemoveTags: []string{"fluffy"},
+			}},
+			returns{nil, errors.New("should not be indexed")},
+			&models.MetaDataV2{
+				DisplayName: "my cat",
+				Category:    "image",
+				Tags:        []string{"auto"},
+			},
+			0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var tensor = &mocks.FakeTensorflowAnalyzer{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				ipfs,
+				tensor,
+				se,
+				zap.NewNop().Sugar())
+
+			// set up mocks
+			if tt.returns.getErr != nil {
+				se.GetReturns(nil, tt.returns.getErr)
+			} else {
+				se.GetReturns(&engine.Document{
+					Object: &models.ObjectV2{
+						Hash: tt.args.hash,
+						MD: models.MetaDataV2{
+							DisplayName: "my cat",
+							Category:    "image",
+							Tags:        []string{"auto"},
+						},
+					},
+					Content: "meow",
+				}, nil)
+			}
+			se.IndexReturns(tt.returns.indexErr)
+
+			// execute tests
+			got, err := v.UpdateMetadata(tt.args.hash, tt.args.patch)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.UpdateMetadata() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+
+			if tt.wantErrCode == 0 {
+				if !reflect.DeepEqual(got, tt.wantMD) {
+					t.Errorf("V2.UpdateMetadata() = %v, want %v", got, tt.wantMD)
+				}
+				if tt.returns.indexErr != nil {
+					if se.IndexCallCount() > 0 {
+						t.Error("V2.UpdateMetadata() rewrote unchanged document")
+					}
+				} else if doc := se.IndexArgsForCall(0); doc.Content != "meow" || !doc.Reindex {
+					t.Errorf("V2.UpdateMetadata() stored unexpected document %v", doc)
+				}
+			} else {
+				var s = status.Convert(err)
+				if s.Code() != tt.wantErrCode {
+					t.Errorf("V2.UpdateMetadata() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+			}
+		})
+	}
+}
+
+func TestV2_Count(t *testing.T) {
+	type args struct {
+		req *lensv2.SearchReq
+	}
+	type returns struct {
+		count    uint64
+		countErr error
+	}
+	tests := []struct {
+		name        string
+		args        args
+		returns     returns
+		wantCount   uint64
+		wantErrCode codes.Code
+	}{
+		{"no query, no options",
+			args{&lensv2.SearchReq{}},
+			returns{0, nil},
+			0,
+			codes.InvalidArgument},
+		{"count error",
+			args{&lensv2.SearchReq{Query: "cats"}},
+			returns{0, errors.New("oh no")},
+			0,
+			codes.Internal},
+		{"ok",
+			args{&lensv2.SearchReq{Query: "cats"}},
+			returns{42, nil},
+			42,
+			0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+
+			// set up mocks
+			se.CountReturns(tt.returns.count, tt.returns.countErr)
+
+			// execute tests
+			got, err := v.Count(context.Background(), tt.args.req)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.Count() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.Count() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+			} else if got != tt.wantCount {
+				t.Errorf("V2.Count() = %d, want %d", got, tt.wantCount)
+			}
+			if se.SearchCallCount() > 0 {
+				t.Error("V2.Count() should not execute a full search")
+			}
+		})
+	}
+}
+
+func TestV2_Facet(t *testing.T) {
+	var facets = &engine.Facets{
+		Total:      3,
+		Categories: map[string]int{"document": 2, "image": 1},
+		MimeTypes:  map[string]int{"text/plain": 2, "image/png": 1},
+	}
+	tests := []struct {
+		name        string
+		req         *lensv2.SearchReq
+		facetErr    error
+		wantErrCode codes.Code
+	}{
+		{"no query, no options", &lensv2.SearchReq{}, nil, codes.InvalidArgument},
+		{"facet error", &lensv2.SearchReq{Query: "cats"}, errors.New("oh no"), codes.Internal},
+		{"ok", &lensv2.SearchReq{Query: "cats"}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			if tt.facetErr != nil {
+				se.FacetReturns(nil, tt.facetErr)
+			} else {
+				se.FacetReturns(facets, nil)
+			}
+
+			got, err := v.Facet(context.Background(), tt.req)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.Facet() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.Facet() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+			} else if !reflect.DeepEqual(got, facets) {
+				t.Errorf("V2.Facet() = %v, want %v", got, facets)
+			}
+		})
+	}
+}
+
+func TestV2_IndexHistogram(t *testing.T) {
+	var now = time.Now()
+	tests := []struct {
+		name         string
+		from, to     time.Time
+		width        time.Duration
+		histogramErr error
+		wantErrCode  codes.Code
+	}{
+		{"invalid width", now.Add(-time.Hour), now, 0, nil, codes.InvalidArgument},
+		{"invalid range", now, now.Add(-time.Hour), time.Minute, nil, codes.InvalidArgument},
+		{"too many buckets", now.Add(-time.Hour), now, time.Millisecond, nil, codes.InvalidArgument},
+		{"histogram error", now.Add(-time.Hour), now, time.Minute, errors.New("oh no"), codes.Internal},
+		{"ok", now.Add(-time.Hour), now, time.Minute, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			se.HistogramReturns([]engine.Bucket{{Start: tt.from, Count: 1}}, tt.histogramErr)
+
+			_, err := v.IndexHistogram(context.Background(), tt.from, tt.to, tt.width)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.IndexHistogram() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if s := status.Convert(err); tt.wantErrCode != 0 && s.Code() != tt.wantErrCode {
+				t.Errorf("V2.IndexHistogram() err code = %s, want %s",
+					s.Code().String(), tt.wantErrCode.String())
+			}
+			if tt.wantErrCode == codes.InvalidArgument && se.HistogramCallCount() > 0 {
+				t.Error("V2.IndexHistogram() should not scan the index with invalid arguments")
+			}
+		})
+	}
+}
+
+func TestV2_Suggest(t *testing.T) {
+	type returns struct {
+		suggestions []engine.Suggestion
+		err         error
+	}
+	tests := []struct {
+		name        string
+		req         *lensv2.SearchReq
+		returns     returns
+		wantErrCode codes.Code
+	}{
+		{"no query",
+			&lensv2.SearchReq{},
+			returns{nil, nil},
+			codes.InvalidArgument},
+		{"lookup error",
+			&lensv2.SearchReq{Query: "ctas"},
+			returns{nil, errors.New("oh no")},
+			codes.Internal},
+		{"ok",
+			&lensv2.SearchReq{Query: "ctas"},
+			returns{[]engine.Suggestion{{Term: "ctas", Suggestion: "cats"}}, nil},
+			0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			se.SuggestReturns(tt.returns.suggestions, tt.returns.err)
+
+			got, err := v.Suggest(context.Background(), tt.req)
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.Suggest() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.Suggest() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+			} else if !reflect.DeepEqual(got, tt.returns.suggestions) {
+				t.Errorf("V2.Suggest() = %v, want %v", got, tt.returns.suggestions)
+			}
+		})
+	}
+}
+
+func TestV2_bulkRemove(t *testing.T) {
+	tests := []struct {
+		name        string
+		byPrefix    bool
+		arg         string
+		removeErr   error
+		wantRemoved int
+		wantErrCode codes.Code
+	}{
+		{"no keyword", false, "  ", nil, 0, codes.InvalidArgument},
+		{"keyword failure", false, "spam", errors.New("oh no"), 1, codes.Internal},
+		{"ok: keyword", false, "spam", nil, 2, 0},
+		{"prefix too short", true, "Qm", nil, 0, codes.InvalidArgument},
+		{"prefix failure", true, "QmBadBatch", errors.New("oh no"), 1, codes.Internal},
+		{"ok: prefix", true, "QmBadBatch", nil, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{},
+				&mocks.FakeTensorflowAnalyzer{},
+				se,
+				zap.NewNop().Sugar())
+			se.ListMatchingReturns([]string{"QmBadBatch1", "QmBadBatch2", "QmSpamBot"}, nil)
+			se.ListPrefixReturns([]string{"QmBadBatch1", "QmBadBatch2"}, nil)
+			se.GetStub = func(hash string) (*engine.Document, error) {
+				var tags = []string{"Spam"}
+				if hash == "QmSpamBot" {
+					tags = []string{"spam-bot"}
+				}
+				return &engine.Document{Object: &models.ObjectV2{
+					Hash: hash, MD: models.MetaDataV2{Tags: tags}}}, nil
+			}
+			se.RemoveReturnsOnCall(1, tt.removeErr)
+
+			var got int
+			var err error
+			if tt.byPrefix {
+				got, err = v.RemoveByPrefix(context.Background(), tt.arg)
+			} else {
+				got, err = v.RemoveByKeyword(context.Background(), tt.arg)
+			}
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if s := status.Convert(err); s.Code() != tt.wantErrCode {
+				t.Errorf("err code = %s, want %s",
+					s.Code().String(), tt.wantErrCode.String())
+			}
+			if got != tt.wantRemoved {
+				t.Errorf("removed = %d, want %d", got, tt.wantRemoved)
+			}
+		})
+	}
+}
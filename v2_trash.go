@@ -0,0 +1,210 @@
+package lens
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+// TrashOpts configures the background purge of soft-deleted objects - see
+// V2Options.SoftDelete
+type TrashOpts struct {
+	// Retention is how long deleted objects are kept before they are purged -
+	// leave at 0 to keep them until they are purged with Purge
+	Retention time.Duration
+	// Interval is the duration between purges - defaults to an hour
+	Interval time.Duration
+}
+
+// trash soft-deletes the given object, which excludes it from search but
+// retains it until it is purged. Removing an object that is already in the
+// trash purges it.
+func (v *V2) trash(ctx context.Context, hash string, l *zap.SugaredLogger) error {
+	doc, err := v.se.Get(hash)
+	if err != nil {
+		return getStatus(err)
+	}
+	if doc.Object.MD.Deleted {
+		if err := v.se.Remove(hash); err != nil {
+			return status.Errorf(codes.Internal,
+				"failed to purge requested hash: %s", err.Error())
+		}
+		if v.pinContent {
+			v.unpin(ctx, l, hash)
+		}
+		l.Info("deleted object purged")
+		return nil
+	}
+
+	if err := v.markDeleted(doc); err != nil {
+		l.Errorw("failed to store deleted document", "error", err)
+		return status.Errorf(codes.Internal,
+			"failed to delete requested hash: %s", err.Error())
+	}
+	l.Info("object moved to trash")
+	return nil
+}
+
+// trashAll soft-deletes the given objects, skipping objects that are already in
+// the trash, and returns the number of objects moved to the trash
+func (v *V2) trashAll(ctx context.Context, hashes []string) (int, error) {
+	var trashed int
+	for _, hash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return trashed, err
+		}
+		doc, err := v.se.Get(hash)
+		if err != nil {
+			return trashed, fmt.Errorf("failed to retrieve document '%s': %s", hash, err.Error())
+		}
+		if doc.Object.MD.Deleted {
+			continue
+		}
+		if err := v.markDeleted(doc); err != nil {
+			return trashed, fmt.Errorf("failed to delete document '%s': %s", hash, err.Error())
+		}
+		trashed++
+	}
+	return trashed, nil
+}
+
+// markDeleted stores the given document as soft-deleted
+func (v *V2) markDeleted(doc *engine.Document) error {
+	doc.Object.MD.Deleted = true
+	doc.Object.MD.DeletedAt = time.Now().UTC().Format(time.RFC3339)
+	doc.Reindex = true
+	return v.se.Index(*doc)
+}
+
+// deleted checks if the given object is in the trash
+func (v *V2) deleted(hash string) bool {
+	doc, err := v.se.Get(hash)
+	return err == nil && doc != nil && doc.Object != nil && doc.Object.MD.Deleted
+}
+
+// Restore moves a soft-deleted object out of the trash, so that it appears in
+// search results again. Indexing a deleted object also restores it.
+func (v *V2) Restore(hash string) (*models.MetaDataV2, error) {
+	if hash == "" {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"no hash to restore was provided")
+	}
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+	var l = v.l.With("hash", hash)
+
+	doc, err := v.se.Get(hash)
+	if err != nil {
+		return nil, getStatus(err)
+	}
+	if !doc.Object.MD.Deleted {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"object '%s' is not deleted", hash)
+	}
+	doc.Object.MD.Deleted = false
+	doc.Object.MD.DeletedAt = ""
+	doc.Reindex = true
+	if err := v.se.Index(*doc); err != nil {
+		l.Errorw("failed to store restored document", "error", err)
+		return nil, status.Errorf(codes.Internal,
+			"failed to store restored document: %s", err.Error())
+	}
+
+	l.Info("deleted object restored")
+	return &doc.Object.MD, nil
+}
+
+// Purge permanently removes soft-deleted objects that were deleted at least
+// the given duration ago, and returns the number of objects purged. Objects
+// without a valid deletion time are only purged if olderThan is 0, which
+// empties the trash.
+func (v *V2) Purge(ctx context.Context, olderThan time.Duration) (int, error) {
+	if err := v.writable(); err != nil {
+		return 0, err
+	}
+	hashes, err := v.se.ListMatching(ctx, engine.Query{Deleted: true})
+	if err != nil {
+		v.l.Errorw("failed to list deleted objects", "error", err)
+		return 0, status.Errorf(codes.Internal,
+			"failed to list deleted objects: %s", err.Error())
+	}
+
+	var cutoff = time.Now().Add(-olderThan)
+	var purged = make([]string, 0, len(hashes))
+	defer func() {
+		// unpin once all references to the same content are purged
+		if v.pinContent && len(purged) > 0 {
+			v.unpin(context.Background(), v.l, purged...)
+		}
+	}()
+	for _, hash := range hashes {
+		if ctx.Err() != nil {
+			return len(purged), status.Errorf(codes.Canceled,
+				"purged %d objects before cancellation", len(purged))
+		}
+		var l = v.l.With("hash", hash)
+		if olderThan > 0 {
+			doc, err := v.se.Get(hash)
+			if err != nil {
+				l.Warnw("failed to retrieve deleted document", "error", err)
+				continue
+			}
+			deleted, err := time.Parse(time.RFC3339, doc.Object.MD.DeletedAt)
+			if err != nil || deleted.After(cutoff) {
+				continue
+			}
+		}
+		if err := v.se.Remove(hash); err != nil {
+			l.Warnw("failed to purge deleted document", "error", err)
+			continue
+		}
+		purged = append(purged, hash)
+	}
+	v.l.Infow("deleted objects purged",
+		"older_than", olderThan,
+		"purged", len(purged))
+	return len(purged), nil
+}
+
+// SweepTrash periodically purges soft-deleted objects once they have been in
+// the trash for longer than the configured retention. It blocks until the
+// given context is cancelled.
+func (v *V2) SweepTrash(ctx context.Context, opts TrashOpts) {
+	if opts.Retention <= 0 {
+		return
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+
+	var l = v.l.Named("trash")
+	l.Infow("starting trash sweeper",
+		"interval", opts.Interval,
+		"retention", opts.Retention)
+	var ticker = time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.Info("stopping trash sweeper")
+			return
+		case <-ticker.C:
+			if v.Maintenance() {
+				l.Debug("skipping trash sweep in maintenance mode")
+				continue
+			}
+			if _, err := v.Purge(ctx, opts.Retention); err != nil {
+				l.Warnw("failed to purge trash", "error", err)
+			}
+		}
+	}
+}
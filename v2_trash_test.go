@@ -0,0 +1,177 @@
+package lens
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// found checks if a search for the given query returns the given object,
+// waiting for the engine's queue to flush
+func (s *testServer) found(t *testing.T, query, hash string, want bool) {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		resp, err := s.client.Search(context.Background(), &lensv2.SearchReq{Query: query})
+		var got bool
+		for _, r := range resp.GetResults() {
+			got = got || r.GetDoc().GetHash() == hash
+		}
+		if err == nil && got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Search(%s) = (%v, %v), want %s found = %v", query, resp, err, hash, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// deletedAfterFlush waits for the given object to be flushed with the given
+// deletion state
+func (s *testServer) deletedAfterFlush(t *testing.T, hash string, want bool) {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		obj, err := s.v.GetObject(hash)
+		if err == nil && obj.MD.Deleted == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GetObject(%s) = (%+v, %v), want deleted = %v", hash, obj, err, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestV2_softDelete(t *testing.T) {
+	var s = newTestServer(t, V2Options{SoftDelete: true}, map[string]string{
+		"QmIPFS": "the interplanetary file system is a peer-to-peer distributed file system",
+	})
+	defer s.close()
+	var ctx = context.Background()
+
+	if _, err := s.client.Index(ctx, &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "QmIPFS"}); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	s.found(t, "interplanetary", "QmIPFS", true)
+
+	// deleted objects are retained, but not found
+	if _, err := s.client.Remove(ctx, &lensv2.RemoveReq{Hash: "QmIPFS"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	s.found(t, "interplanetary", "QmIPFS", false)
+	obj, err := s.v.GetObject("QmIPFS")
+	if err != nil {
+		t.Fatalf("GetObject() error = %v", err)
+	}
+	if _, err := time.Parse(time.RFC3339, obj.MD.DeletedAt); !obj.MD.Deleted || err != nil {
+		t.Errorf("GetObject() = %+v, want deleted with deletion time", obj.MD)
+	}
+
+	// retained objects are not purged
+	if purged, err := s.v.Purge(ctx, time.Hour); err != nil || purged != 0 {
+		t.Errorf("Purge(1h) = (%d, %v), want nothing purged", purged, err)
+	}
+
+	// deleted objects can be restored before they are purged
+	md, err := s.v.Restore("QmIPFS")
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if md.Deleted || md.DeletedAt != "" {
+		t.Errorf("Restore() = %+v, want not deleted", md)
+	}
+	s.found(t, "interplanetary", "QmIPFS", true)
+	if _, err := s.v.Restore("QmIPFS"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Restore() of restored object error = %v, want FailedPrecondition", err)
+	}
+
+	// purged objects are gone for good
+	if _, err := s.client.Remove(ctx, &lensv2.RemoveReq{Hash: "QmIPFS"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	s.deletedAfterFlush(t, "QmIPFS", true)
+	if purged, err := s.v.Purge(ctx, 0); err != nil || purged != 1 {
+		t.Errorf("Purge(0) = (%d, %v), want 1 purged", purged, err)
+	}
+	var deadline = time.Now().Add(5 * time.Second)
+	for {
+		_, err := s.v.GetObject("QmIPFS")
+		if status.Code(err) == codes.NotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GetObject() after Purge() error = %v, want NotFound", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := s.v.Restore("QmIPFS"); status.Code(err) != codes.NotFound {
+		t.Errorf("Restore() of purged object error = %v, want NotFound", err)
+	}
+}
+
+func TestV2_softDelete_reindex(t *testing.T) {
+	var s = newTestServer(t, V2Options{SoftDelete: true}, map[string]string{
+		"QmIPFS": "the interplanetary file system is a peer-to-peer distributed file system",
+	})
+	defer s.close()
+	var ctx = context.Background()
+	var req = &lensv2.IndexReq{Type: lensv2.IndexReq_IPLD, Hash: "QmIPFS"}
+
+	if _, err := s.client.Index(ctx, req); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	s.deletedAfterFlush(t, "QmIPFS", false)
+	if _, err := s.client.Remove(ctx, &lensv2.RemoveReq{Hash: "QmIPFS"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	s.deletedAfterFlush(t, "QmIPFS", true)
+
+	// indexing a deleted object restores it
+	if _, err := s.client.Index(ctx, req); err != nil {
+		t.Fatalf("Index() of deleted object error = %v", err)
+	}
+	s.found(t, "interplanetary", "QmIPFS", true)
+}
+
+func TestV2_softDelete_bulk(t *testing.T) {
+	var s = newTestServer(t, V2Options{SoftDelete: true}, map[string]string{
+		"QmIPFS1": "the interplanetary file system is a peer-to-peer distributed file system",
+		"QmIPFS2": "the interplanetary file system is a content-addressed file system",
+	})
+	defer s.close()
+	var ctx = context.Background()
+
+	for _, hash := range []string{"QmIPFS1", "QmIPFS2"} {
+		if _, err := s.client.Index(ctx, &lensv2.IndexReq{
+			Type: lensv2.IndexReq_IPLD,
+			Hash: hash,
+			Tags: []string{"spam"},
+		}); err != nil {
+			t.Fatalf("Index() error = %v", err)
+		}
+		s.deletedAfterFlush(t, hash, false)
+	}
+
+	// bulk removals move objects to the trash as well
+	if removed, err := s.v.RemoveByKeyword(ctx, "spam"); err != nil || removed != 2 {
+		t.Errorf("RemoveByKeyword() = (%d, %v), want 2 removed", removed, err)
+	}
+	for _, hash := range []string{"QmIPFS1", "QmIPFS2"} {
+		s.deletedAfterFlush(t, hash, true)
+		s.found(t, "interplanetary", hash, false)
+	}
+
+	// objects already in the trash are not purged by bulk removals
+	if removed, err := s.v.RemoveByPrefix(ctx, "QmIPFS"); err != nil || removed != 0 {
+		t.Errorf("RemoveByPrefix() = (%d, %v), want none removed", removed, err)
+	}
+	for _, hash := range []string{"QmIPFS1", "QmIPFS2"} {
+		s.deletedAfterFlush(t, hash, true)
+	}
+}
@@ -0,0 +1,176 @@
+package lens
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// propertySourceURL is the property recording where an object was fetched from
+const propertySourceURL = "source_url"
+
+// URLOpts configures indexing of content fetched from URLs
+type URLOpts struct {
+	// AllowedHosts lists hosts content may be fetched from. Entries starting
+	// with '.' also allow all subdomains, ie '.example.com'. Leave empty to
+	// disable indexing URLs.
+	AllowedHosts []string
+	// AllowedSchemes lists URL schemes that may be fetched - defaults to http
+	// and https
+	AllowedSchemes []string
+
+	// MaxSize is the maximum size in bytes of fetched content - defaults to
+	// maxInlineContentLength
+	MaxSize int64
+	// Timeout bounds the time spent fetching content - defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// allowed checks if content may be fetched from the given URL
+func (o URLOpts) allowed(u *url.URL) error {
+	var schemes = o.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	var schemeOK bool
+	for _, s := range schemes {
+		if strings.EqualFold(s, u.Scheme) {
+			schemeOK = true
+			break
+		}
+	}
+	if !schemeOK {
+		return fmt.Errorf("scheme '%s' is not allowed", u.Scheme)
+	}
+	var host = strings.ToLower(u.Hostname())
+	for _, h := range o.AllowedHosts {
+		h = strings.ToLower(h)
+		if host == h || (strings.HasPrefix(h, ".") &&
+			(host == h[1:] || strings.HasSuffix(host, h))) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host '%s' is not allowed", host)
+}
+
+// IndexURLOpts configures IndexURL
+type IndexURLOpts struct {
+	// DisplayName defaults to the last element of the URL path
+	DisplayName string
+	Tags        []string
+	Reindex     bool
+}
+
+// IndexURL fetches content from the given URL, adds it to IPFS, and indexes it
+// under the resulting hash. The URL is recorded in the 'source_url' property
+// of the indexed object. Only URLs allowed by V2Options.URLs may be fetched,
+// including the targets of any redirects.
+func (v *V2) IndexURL(ctx context.Context, rawURL string, opts IndexURLOpts) (*lensv2.IndexResp, error) {
+	if len(v.urls.AllowedHosts) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "indexing URLs is disabled")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid URL: %s", err.Error())
+	}
+	if err := v.urls.allowed(u); err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "URL not allowed: %s", err.Error())
+	}
+	// check before adding content to IPFS, which cannot be undone
+	if err := v.writable(); err != nil {
+		return nil, err
+	}
+	var l = v.l.With("url", u.String())
+
+	content, err := v.fetch(ctx, u)
+	if err != nil {
+		l.Warnw("failed to fetch URL", "error", err)
+		return nil, status.Errorf(codes.Unavailable, "failed to fetch URL: %s", err.Error())
+	}
+	hash, err := v.ipfs.Add(bytes.NewReader(content))
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable,
+			"failed to add content to IPFS: %s", err.Error())
+	}
+
+	var name = opts.DisplayName
+	if name == "" {
+		name = path.Base(u.Path)
+		if name == "." || name == "/" {
+			name = u.Hostname()
+		}
+	}
+	var req = &lensv2.IndexReq{
+		Type:        lensv2.IndexReq_IPLD,
+		Hash:        hash,
+		DisplayName: name,
+		Tags:        opts.Tags,
+		Options:     &lensv2.IndexReq_Options{Reindex: opts.Reindex},
+	}
+	if err := validateIndexReq(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	return v.index(ctx, req, content,
+		map[string]string{propertySourceURL: u.String()}, nil,
+		l.With("hash", hash, "size", len(content)))
+}
+
+// fetch retrieves the content at the given URL, within the configured limits
+func (v *V2) fetch(ctx context.Context, u *url.URL) ([]byte, error) {
+	var maxSize = v.urls.MaxSize
+	if maxSize <= 0 {
+		maxSize = maxInlineContentLength
+	}
+	var timeout = v.urls.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	var client = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("too many redirects")
+			}
+			return v.urls.allowed(req.URL)
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response status '%s'", resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("content exceeds maximum size of %d bytes", maxSize)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("content exceeds maximum size of %d bytes", maxSize)
+	}
+	if len(content) == 0 {
+		return nil, errors.New("no content found")
+	}
+	return content, nil
+}
@@ -0,0 +1,124 @@
+package lens
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/Lens/v2/mocks"
+)
+
+func TestURLOpts_allowed(t *testing.T) {
+	var opts = URLOpts{AllowedHosts: []string{"example.com", ".ipfs.io"}}
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://example.com/doc.txt", false},
+		{"http://EXAMPLE.com:8080/doc.txt", false},
+		{"https://sub.example.com/doc.txt", true},
+		{"https://ipfs.io/doc.txt", false},
+		{"https://gateway.ipfs.io/doc.txt", false},
+		{"https://evilipfs.io/doc.txt", true},
+		{"ftp://example.com/doc.txt", true},
+		{"file:///etc/passwd", true},
+		{"http://169.254.169.254/latest", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := opts.allowed(u); (err != nil) != tt.wantErr {
+				t.Errorf("URLOpts.allowed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestV2_IndexURL(t *testing.T) {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/notes/hello.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	})
+	mux.HandleFunc("/large.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 64))
+	})
+	mux.HandleFunc("/missing.txt", http.NotFound)
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:1/hello.txt", http.StatusFound)
+	})
+	var srv = httptest.NewServer(mux)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	var allowed = URLOpts{AllowedHosts: []string{u.Hostname()}, MaxSize: 32}
+
+	tests := []struct {
+		name        string
+		opts        URLOpts
+		url         string
+		addErr      error
+		wantErrCode codes.Code
+	}{
+		{"disabled", URLOpts{}, srv.URL + "/notes/hello.txt", nil, codes.FailedPrecondition},
+		{"host not allowed", URLOpts{AllowedHosts: []string{"example.com"}},
+			srv.URL + "/notes/hello.txt", nil, codes.PermissionDenied},
+		{"not found", allowed, srv.URL + "/missing.txt", nil, codes.Unavailable},
+		{"too large", allowed, srv.URL + "/large.txt", nil, codes.Unavailable},
+		{"redirect to disallowed host", allowed, srv.URL + "/redirect", nil, codes.Unavailable},
+		{"ipfs unavailable", allowed, srv.URL + "/notes/hello.txt", errors.New("oh no"), codes.Unavailable},
+		{"ok", allowed, srv.URL + "/notes/hello.txt", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{URLs: tt.opts}, ipfs,
+				&mocks.FakeTensorflowAnalyzer{}, se, zap.NewNop().Sugar())
+			ipfs.AddReturns("QmHello", tt.addErr)
+
+			got, err := v.IndexURL(context.Background(), tt.url, IndexURLOpts{})
+			if (err != nil) != (tt.wantErrCode != 0) {
+				t.Errorf("V2.IndexURL() error = %v, wantErr %v", err, (tt.wantErrCode != 0))
+				return
+			}
+			if tt.wantErrCode != 0 {
+				if s := status.Convert(err); s.Code() != tt.wantErrCode {
+					t.Errorf("V2.IndexURL() err code = %s, want %s",
+						s.Code().String(), tt.wantErrCode.String())
+				}
+				return
+			}
+			if got.GetDoc().GetHash() != "QmHello" || got.GetDoc().GetDisplayName() != "hello.txt" {
+				t.Errorf("V2.IndexURL() = %+v", got.GetDoc())
+			}
+			var md = se.IndexArgsForCall(0).Object.MD
+			if md.Properties[propertySourceURL] != tt.url {
+				t.Errorf("V2.IndexURL() stored source %q, want %q",
+					md.Properties[propertySourceURL], tt.url)
+			}
+		})
+	}
+
+	t.Run("maintenance", func(t *testing.T) {
+		var ipfs = &mocks.FakeRTFSManager{}
+		var v = newTestV2(t, V2Options{URLs: allowed}, ipfs,
+			&mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, zap.NewNop().Sugar())
+		v.SetMaintenance(true)
+		if _, err := v.IndexURL(context.Background(), srv.URL+"/notes/hello.txt",
+			IndexURLOpts{}); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("V2.IndexURL() error = %v, want FailedPrecondition", err)
+		}
+		if ipfs.AddCallCount() != 0 {
+			t.Error("V2.IndexURL() added content to IPFS in maintenance mode")
+		}
+	})
+}
@@ -1,12 +1,30 @@
 package lens
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"image/jpeg"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/images"
+	"github.com/RTradeLtd/Lens/v2/analyzer/media"
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
 	"github.com/RTradeLtd/Lens/v2/engine"
 	"github.com/RTradeLtd/Lens/v2/logs"
 	"github.com/RTradeLtd/Lens/v2/models"
@@ -17,6 +35,18 @@ type magnifyOpts struct {
 	DisplayName string
 	Reindex     bool
 	Tags        []string
+
+	// ModelHint selects the image classification model to use
+	ModelHint string
+
+	// Budget bounds the time spent on magnification
+	Budget *budget
+
+	// Contents, if set, is analyzed instead of the object's contents on IPFS
+	Contents []byte
+
+	// Progress, if set, receives updates as the object is analyzed
+	Progress progressFunc
 }
 
 func (v *V2) magnify(hash string, opts magnifyOpts) (content string, metadata *models.MetaDataV2, err error) {
@@ -34,14 +64,165 @@ func (v *V2) magnify(hash string, opts magnifyOpts) (content string, metadata *m
 	var start = time.Now()
 	defer func() { l.Infow("magnification ended", "duration", time.Since(start)) }()
 
-	// retrieve object and detect content type
-	contents, err := v.px.ExtractContents(hash)
+	// content behind a hash cannot change, so previous results can be reused
+	// unless they were produced by a different version of Lens
+	if opts.Reindex && v.reuseAnalysis {
+		if content, md, ok := v.previousAnalysis(hash, opts, l); ok {
+			return content, md, nil
+		}
+	}
+
+	// retrieve object
+	if err := opts.Budget.enter("retrieve"); err != nil {
+		return "", nil, err
+	}
+	var contents = opts.Contents
+	var source string
+	if contents == nil {
+		if err := v.precheck(hash, opts.Budget, l); err != nil {
+			return "", nil, err
+		}
+		if contents, err = v.px.ExtractContents(hash); err != nil {
+			return "", nil, fmt.Errorf("failed to find content for hash '%s'", hash)
+		}
+		if v.recordSource {
+			source = v.ipfs.NodeAddress()
+		}
+	}
+
+	// archive members are indexed individually, and the archive itself is
+	// indexed with the aggregated contents of its members
+	if format := v.detectArchive(contents); format != "" {
+		l.Infow("object retrieved and archive detected",
+			"content_type", format)
+		opts.Progress.report(&IndexUpdate{
+			Stage:    IndexStageDetected,
+			MimeType: format,
+			Category: v.category(format, models.MimeTypeArchive),
+		})
+		if err := v.filter.check(format, v.category(format, models.MimeTypeArchive)); err != nil {
+			return "", nil, v.reject(format, err, l)
+		}
+		merged, tags, err := v.magnifyArchive(hash, format, contents, opts.Reindex, opts.Budget, l)
+		if err != nil {
+			return "", nil, err
+		}
+		return merged, &models.MetaDataV2{
+			DisplayName: opts.DisplayName,
+			MimeType:    format,
+			Category:    v.category(format, models.MimeTypeArchive),
+			Tags:        appendUnique(opts.Tags, tags...),
+
+			Provenance: &models.Provenance{Method: "archive", Source: source},
+		}, nil
+	}
+
+	if err := opts.Budget.enter("analyze"); err != nil {
+		return "", nil, err
+	}
+	a, err := v.analyzeWithProgress(hash, contents, opts.ModelHint, opts.Progress, l)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to find content for hash '%s'", hash)
+		return "", nil, err
+	}
+
+	var tags = append(opts.Tags, a.keywords...)
+	if v.nameTags {
+		tags = appendUnique(tags, wordTags(opts.DisplayName)...)
+	}
+	var name = opts.DisplayName
+	if name == "" {
+		name = a.title
+	}
+	a.provenance.Source = source
+
+	return a.content, &models.MetaDataV2{
+		DisplayName: name,
+		MimeType:    a.contentType,
+		Category:    v.category(a.mimeType, a.category),
+		Tags:        tags,
+		Thumbnail:   a.thumbnail,
+
+		Classification: a.classification,
+		Pages:          a.pages,
+		Truncated:      a.truncated,
+		Sampled:        a.sampled,
+		Location:       a.location,
+		Provenance:     &a.provenance,
+	}, nil
+}
+
+// previousAnalysis returns the indexed content and metadata of the given
+// object, updated with the given options, if it was analyzed by this version
+// of Lens
+func (v *V2) previousAnalysis(hash string, opts magnifyOpts, l *zap.SugaredLogger) (string, *models.MetaDataV2, bool) {
+	if !v.se.IsIndexed(hash) {
+		return "", nil, false
+	}
+	doc, err := v.se.Get(hash)
+	if err != nil || doc == nil || doc.Object == nil {
+		l.Warnw("failed to retrieve previous analysis", "error", err)
+		return "", nil, false
+	}
+	var md = doc.Object.MD
+	if md.Provenance == nil || md.Provenance.LensVersion != v.version {
+		return "", nil, false
+	}
+	if opts.DisplayName != "" {
+		md.DisplayName = opts.DisplayName
 	}
+	md.Tags = appendUnique(append([]string{}, md.Tags...), opts.Tags...)
+	l.Infow("content unchanged - reusing previous analysis",
+		"lens_version", md.Provenance.LensVersion)
+	return doc.Content, &md, true
+}
+
+// analysis denotes the results of analyzing an object's contents
+type analysis struct {
+	contentType string // detected content type, ie 'text/plain; charset=utf-8'
+	mimeType    string // content type without parameters
+	category    models.MimeType
+
+	extractedDocument
+	keywords []string // weighted keywords, set once analysis completes
+
+	thumbnail      string
+	classification string
+
+	pages      int
+	truncated  bool
+	sampled    bool
+	provenance models.Provenance
+}
+
+// maxTIFFPages is the maximum number of pages analyzed in a TIFF image
+const maxTIFFPages = 100
+
+// analyze detects the type of the given contents and extracts text and
+// keywords from it
+func (v *V2) analyze(id string, contents []byte, modelHint string, l *zap.SugaredLogger) (*analysis, error) {
+	return v.analyzeWithProgress(id, contents, modelHint, nil, l)
+}
+
+// analyzeWithProgress is analyze, reporting the detected content type and the
+// extracted contents to progress before keywords are summarized
+func (v *V2) analyzeWithProgress(
+	id string,
+	contents []byte,
+	modelHint string,
+	progress progressFunc,
+	l *zap.SugaredLogger,
+) (*analysis, error) {
+	// detect content type
 	contentType := http.DetectContentType(contents)
 	if contentType == "" {
-		return "", nil, fmt.Errorf("unknown content type for document '%s'", hash)
+		return nil, fmt.Errorf("unknown content type for document '%s'", id)
+	}
+	if strings.HasPrefix(contentType, "application/octet-stream") {
+		if images.IsTIFF(contents) {
+			contentType = "image/tiff"
+		} else if mt := media.Detect(contents); mt != "" {
+			contentType = mt
+		}
 	}
 	l.Infow("object retrieved and content type detected",
 		"content_type", contentType)
@@ -51,70 +232,951 @@ func (v *V2) magnify(hash string, opts magnifyOpts) (content string, metadata *m
 	// the content type
 	var parsed = strings.FieldsFunc(contentType, func(r rune) bool { return (r == ';') })
 	if parsed == nil || len(parsed) == 0 {
-		return "", nil, fmt.Errorf("invalid content type '%s'", contentType)
+		return nil, fmt.Errorf("could not determine content type from '%s'", contentType)
 	}
+	var a = &analysis{contentType: contentType, mimeType: parsed[0]}
+
+	// reject unwanted content before doing any expensive work
+	var detected = v.category(a.mimeType, detectCategory(a.mimeType))
+	if err := v.filter.check(a.mimeType, detected); err != nil {
+		return nil, v.reject(a.mimeType, err, l)
+	}
+	progress.report(&IndexUpdate{
+		Stage:    IndexStageDetected,
+		MimeType: contentType,
+		Category: detected,
+	})
 
 	// scrape for content based on content-type
-	var category models.MimeType
 	switch parsed[0] {
 	case "application/pdf":
-		category = models.MimeTypePDF
-		text, err := v.oc.Analyze(hash, contents, "pdf")
+		a.category = models.MimeTypePDF
+		a.provenance.Method = "pdf"
+		text, info, err := v.oc.PDFToText(id, contents)
 		if err != nil {
-			return "", nil, err
+			return nil, err
+		}
+		a.content, a.sampled = sample(text, v.sampleSize)
+		a.pages = info.Pages
+		a.truncated = info.Truncated
+		for _, title := range info.Outline {
+			a.tags = appendUnique(a.tags, strings.ToLower(title))
 		}
-		content = text
+		if v.pdfImages > 0 {
+			v.analyzePDFImages(id, contents, modelHint, a, l)
+		}
+	case "application/ogg":
+		v.analyzeMedia(contents, a, l)
 	default:
 		var parsed2 = strings.FieldsFunc(contentType, func(r rune) bool { return (r == '/') })
 		if parsed2 == nil || len(parsed2) == 0 {
-			return "", nil, fmt.Errorf("invalid content type '%s'", contentType)
+			return nil, fmt.Errorf("could not determine content type from '%s'", contentType)
 		}
 		switch parsed2[0] {
 		case "text":
-			category = models.MimeTypeDocument
-			content = string(contents)
+			a.category = models.MimeTypeDocument
+			a.provenance.Method = "text"
+			if v.sampleSize > 0 && len(contents) > v.sampleSize+utf8.UTFMax {
+				// avoid copying text that will be discarded
+				contents = contents[:v.sampleSize+utf8.UTFMax]
+			}
+			a.content, a.sampled = sample(string(contents), v.sampleSize)
+			if v.detectLogs {
+				v.analyzeLog(a, l)
+			}
+			var isHTML = a.mimeType == "text/html"
+			a.title = text.Title(a.content, isHTML)
+			a.author = text.Author(a.content, isHTML)
+			if v.links.Anchors || v.links.Domains {
+				a.links = text.Links(a.content, isHTML)
+			}
+			a.emphasis = v.emphasis.Weights(a.content, isHTML)
+			if v.geotags {
+				if lat, lon, ok := text.Location(a.content, isHTML); ok {
+					a.location = &models.Location{Latitude: lat, Longitude: lon}
+				}
+			}
 		case "image":
-			category = models.MimeTypeImage
-			keyword, err := v.tf.Analyze(hash, contents)
+			a.category = models.MimeTypeImage
+			a.provenance.Method = "image"
+			a.provenance.ImageModel = v.tf.Model(modelHint)
+			var err error
+			if a.mimeType == "image/tiff" {
+				err = v.analyzeTIFF(id, contents, modelHint, a, l)
+			} else {
+				err = v.analyzeImage(id, contents, modelHint, a, l)
+			}
 			if err != nil {
-				l.Warnw("failed to categorize image", "error", err)
-				return "", nil, errors.New("failed to categorize image")
+				return nil, err
+			}
+			if v.geotags {
+				a.location = geotag(contents, l)
 			}
 
-			// grab any text in image
-			text, err := v.oc.Analyze(hash, contents, "image")
-			if err != nil {
-				l.Warnw("failed to OCR image", "error", err)
-				content = keyword
-			} else {
-				content = text
+			// generate preview if configured
+			if v.thumbnails.Size > 0 {
+				if a.thumbnail, err = v.thumbnail(contents); err != nil {
+					l.Warnw("failed to generate thumbnail", "error", err)
+				}
 			}
-			opts.Tags = append(opts.Tags, keyword)
+		case "audio", "video":
+			v.analyzeMedia(contents, a, l)
 		default:
-			return "", nil, errors.New("unsupported content type for indexing")
+			return nil, v.reject(a.mimeType,
+				errors.New("unsupported content type for indexing"), l)
 		}
 	}
 
-	return content, &models.MetaDataV2{
-		DisplayName: opts.DisplayName,
-		MimeType:    contentType,
-		Category:    string(category),
-		Tags:        opts.Tags,
-	}, nil
+	if v.hyphenation != nil {
+		a.content = v.hyphenation.Normalize(a.content)
+	}
+	a.content = v.values.Normalize(a.content)
+	if progress != nil {
+		progress(&IndexUpdate{
+			Stage:    IndexStageExtracted,
+			MimeType: contentType,
+			Category: v.category(a.mimeType, a.category),
+			Tags:     v.weighKeywords(&a.extractedDocument, nil),
+		})
+	}
+
+	// extract additional keywords from text - short text is used as is, since
+	// summaries of it are unreliable
+	var body []string
+	var policy = v.policy(v.category(a.mimeType, a.category))
+	if policy.Verbatim && a.content != "" {
+		body = wordTags(a.content)
+	} else if v.sm != nil && a.content != "" && len(a.content) < v.minSummaryLength {
+		body = wordTags(a.content)
+		a.provenance.ShortContent = true
+	} else if v.sm != nil && a.content != "" {
+		var ratio = v.ratio(a.mimeType, v.category(a.mimeType, a.category))
+		if validRatio(policy.SummaryRatio) {
+			ratio = policy.SummaryRatio
+		}
+		var input = a.content
+		if v.maxSummaryInput > 0 {
+			input, a.provenance.SummaryTruncated = sample(input, v.maxSummaryInput)
+		}
+		var keywords = text.SummarizeWeighted(v.sm, input, ratio, a.emphasis)
+		if len(keywords) == 0 && v.smFallback != nil {
+			keywords = text.SummarizeWeighted(v.smFallback, input, ratio, a.emphasis)
+			a.provenance.SummaryFallback = true
+		}
+		body = keywords
+		a.provenance.SummaryRatio = ratio
+	}
+	body = appendUnique(body, v.values.Keywords(a.content)...)
+	if policy.MaxKeywords > 0 && len(body) > policy.MaxKeywords {
+		body = body[:policy.MaxKeywords]
+	}
+	a.keywords = v.weighKeywords(&a.extractedDocument, body)
+
+	return a, nil
 }
 
-// Store is used to store our collected meta data in a formatted object
-func (v *V2) store(hash, content string, md *models.MetaDataV2, reindex bool) error {
-	return v.se.Index(engine.Document{
-		Object: &models.ObjectV2{
-			Hash: hash,
-			MD:   *md,
-		},
-		Content: content,
-		Reindex: reindex,
+// ratio returns the summarization ratio for content of the given mime type and
+// category. Ratios configured for the full mime type take precedence over
+// those for its top-level type, followed by the category and the global ratio.
+func (v *V2) ratio(mimeType, category string) float64 {
+	for _, key := range []string{
+		mimeType,
+		strings.SplitN(mimeType, "/", 2)[0],
+		category,
+	} {
+		if r, ok := v.summaryRatios[key]; ok && validRatio(r) {
+			return r
+		}
+	}
+	if !validRatio(v.summaryRatio) {
+		return text.DefaultRatio
+	}
+	return v.summaryRatio
+}
+
+// validRatio checks if r is a usable summarization ratio, in (0,1]
+func validRatio(r float64) bool { return r > 0 && r <= 1 }
+
+// analyzeLog replaces the content of logs with their messages, so that
+// timestamps and IDs do not dominate keywords, and adds the host and service
+// names they refer to to tags. Other text is left untouched.
+func (v *V2) analyzeLog(a *analysis, l *zap.SugaredLogger) {
+	var format = text.DetectLog(a.content)
+	if format == "" {
+		return
+	}
+	content, tags, err := text.ParseLog(a.content, format)
+	if err != nil {
+		l.Warnw("failed to parse log", "error", err, "format", format)
+		return
+	}
+	l.Infow("log detected", "format", format, "tags", len(tags))
+	a.content = content
+	a.tags = appendUnique(a.tags, tags...)
+	a.provenance.Method = "log"
+}
+
+// LinkOpts configures keyword extraction from hyperlinks in HTML and Markdown
+// documents
+type LinkOpts struct {
+	// Anchors adds words from the text of links to tags
+	Anchors bool
+	// Domains adds the domain names links point to to tags
+	Domains bool
+}
+
+// linkTags extracts tags from the given hyperlinks
+func (v *V2) linkTags(links []text.Link) []string {
+	var tags = make([]string, 0)
+	for _, link := range links {
+		if v.links.Anchors {
+			tags = appendUnique(tags, wordTags(link.Text)...)
+		}
+		if d := link.Domain(); v.links.Domains && d != "" {
+			tags = appendUnique(tags, d)
+		}
+	}
+	return tags
+}
+
+// analyzeImage classifies the given image and extracts any text in it,
+// appending the results to a
+func (v *V2) analyzeImage(id string, contents []byte, modelHint string, a *analysis, l *zap.SugaredLogger) error {
+	// skip images too small to be meaningful, ie tracking pixels
+	if w, h, small := v.belowMinSize(contents); small {
+		if v.minImageSize.Reject {
+			return v.reject(a.mimeType,
+				fmt.Errorf("image of %dx%d pixels is below the minimum size", w, h), l)
+		}
+		l.Infow("image below minimum size - skipping classification",
+			"image.width", w, "image.height", h)
+		return nil
+	}
+
+	keyword, err := v.tf.Analyze(id, contents, modelHint)
+	if err != nil {
+		l.Warnw("failed to categorize image", "error", err)
+		return errors.New("failed to categorize image")
+	}
+
+	// grab any text in image
+	text, err := v.oc.Analyze(id, contents, "image")
+	if err != nil {
+		l.Warnw("failed to OCR image", "error", err)
+		text = keyword
+	}
+	if a.content != "" {
+		a.content += "\n"
+	}
+	a.content += text
+	a.tags = appendUnique(a.tags, keyword)
+	if a.classification == "" {
+		a.classification = keyword
+	}
+	return nil
+}
+
+// belowMinSize checks if the given image is smaller than the configured
+// minimum image size. Images of unknown dimensions are never too small.
+func (v *V2) belowMinSize(contents []byte) (w, h int, small bool) {
+	if v.minImageSize.Width <= 0 && v.minImageSize.Height <= 0 {
+		return 0, 0, false
+	}
+	w, h, err := images.Dimensions(contents)
+	if err != nil {
+		return 0, 0, false
+	}
+	return w, h, w < v.minImageSize.Width || h < v.minImageSize.Height
+}
+
+// classifyImage classifies the given image the same way analyze does, without
+// extracting any text: TIFF images are classified by their first page that can
+// be classified, and images below the minimum size are not classified, in
+// which case the keyword is empty.
+func (v *V2) classifyImage(id string, contents []byte, modelHint string, l *zap.SugaredLogger) (string, error) {
+	var pages = [][]byte{contents}
+	if images.IsTIFF(contents) {
+		t, err := images.ParseTIFF(contents, maxTIFFPages)
+		if err != nil {
+			return "", err
+		}
+		pages = make([][]byte, t.Pages())
+		for i := range pages {
+			pages[i] = t.Page(i)
+		}
+	}
+	var err error
+	for i, page := range pages {
+		if _, _, small := v.belowMinSize(page); small {
+			continue
+		}
+		var keyword string
+		if keyword, err = v.tf.Analyze(id, page, modelHint); err == nil {
+			return keyword, nil
+		}
+		l.Debugw("failed to classify image", "error", err, "tiff.page", i+1)
+	}
+	return "", err
+}
+
+// geotag returns the location recorded in the GPS metadata of the given image,
+// or nil if it has none
+func geotag(contents []byte, l *zap.SugaredLogger) *models.Location {
+	lat, lon, err := images.GPS(contents)
+	if err != nil {
+		if err != images.ErrNoGPS {
+			l.Debugw("failed to read image location", "error", err)
+		}
+		return nil
+	}
+	return &models.Location{Latitude: lat, Longitude: lon}
+}
+
+// analyzeMedia extracts descriptive fields, such as title and artist, from the
+// metadata of audio and video containers. Media without metadata is indexed
+// without content.
+func (v *V2) analyzeMedia(contents []byte, a *analysis, l *zap.SugaredLogger) {
+	a.category = models.MimeTypeMedia
+	a.provenance.Method = "media"
+	md, err := media.Parse(contents)
+	if err != nil {
+		l.Infow("no media metadata found", "error", err)
+		return
+	}
+	a.content = strings.Join(md.Fields(), "\n")
+	a.title = md.Title
+	a.author = md.Artist
+	if g := strings.ToLower(strings.TrimSpace(md.Genre)); g != "" {
+		a.tags = appendUnique(a.tags, g)
+	}
+}
+
+// analyzeTIFF analyzes each page of the given TIFF image, merging the results
+// into a. The image is classified by its first page that could be analyzed.
+func (v *V2) analyzeTIFF(id string, contents []byte, modelHint string, a *analysis, l *zap.SugaredLogger) error {
+	t, err := images.ParseTIFF(contents, maxTIFFPages)
+	if err != nil {
+		return err
+	}
+	if t.Truncated {
+		l.Warnw("image exceeds page limit - remaining pages are skipped",
+			"tiff.max_pages", maxTIFFPages)
+	}
+	a.pages = t.Pages()
+	a.truncated = t.Truncated
+	var analyzed int
+	for i := 0; i < t.Pages(); i++ {
+		if err = v.analyzeImage(id, t.Page(i), modelHint, a, l.With("tiff.page", i+1)); err == nil {
+			analyzed++
+		}
+	}
+	if analyzed == 0 {
+		return err
+	}
+	l.Infow("tiff pages analyzed",
+		"tiff.pages", a.pages,
+		"tiff.analyzed", analyzed)
+	return nil
+}
+
+// analyzePDFImages classifies and extracts text from images embedded in the
+// given PDF, merging the results into a. Images that cannot be analyzed are
+// skipped.
+func (v *V2) analyzePDFImages(id string, contents []byte, modelHint string, a *analysis, l *zap.SugaredLogger) {
+	var figures = images.PDFImages(contents, v.pdfImages)
+	var analyzed int
+	for i, img := range figures {
+		var fig = &analysis{}
+		if err := v.analyzeImage(id, img, modelHint, fig, l.With("pdf.image", i+1)); err != nil {
+			continue
+		}
+		analyzed++
+		a.content += "\n" + fig.content
+		a.tags = appendUnique(a.tags, fig.tags...)
+	}
+	if analyzed > 0 {
+		a.provenance.ImageModel = v.tf.Model(modelHint)
+	}
+	l.Infow("embedded pdf images analyzed",
+		"pdf.images", len(figures),
+		"pdf.images_analyzed", analyzed)
+}
+
+// appendUnique appends values to the given slice that it does not already
+// contain
+func appendUnique(s []string, values ...string) []string {
+	for _, val := range values {
+		var exists bool
+		for _, existing := range s {
+			if existing == val {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			s = append(s, val)
+		}
+	}
+	return s
+}
+
+// minWordTagLength is the minimum length of words extracted as tags
+const minWordTagLength = 3
+
+// wordTags extracts unique lowercase words from text, such as 'tax' and
+// 'return' from the file name '2021-tax-return.pdf'. Numbers and short words
+// are skipped.
+func wordTags(text string) []string {
+	var tags = make([]string, 0)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) < minWordTagLength ||
+			strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		tags = appendUnique(tags, w)
+	}
+	return tags
+}
+
+// mergeTags merges tags that differ only in case into a single tag, using the
+// most common surface form of each tag, or the first seen if several are
+// equally common. Tags are returned in order of first appearance.
+func mergeTags(tags []string) []string {
+	type variants struct {
+		forms  []string
+		counts map[string]int
+	}
+	var order = make([]string, 0, len(tags))
+	var canonical = make(map[string]*variants, len(tags))
+	for _, t := range tags {
+		var key = strings.ToLower(t)
+		var c, ok = canonical[key]
+		if !ok {
+			c = &variants{counts: make(map[string]int)}
+			canonical[key] = c
+			order = append(order, key)
+		}
+		if c.counts[t] == 0 {
+			c.forms = append(c.forms, t)
+		}
+		c.counts[t]++
+	}
+	var merged = make([]string, len(order))
+	for i, key := range order {
+		var c = canonical[key]
+		var best = c.forms[0]
+		for _, f := range c.forms[1:] {
+			if c.counts[f] > c.counts[best] {
+				best = f
+			}
+		}
+		merged[i] = best
+	}
+	return merged
+}
+
+// category returns the configured category for the given mime type, or the
+// given default if no override is configured
+func (v *V2) category(mimeType string, category models.MimeType) string {
+	mimeType = strings.TrimSpace(mimeType)
+	if c := v.categories[mimeType]; c != "" {
+		return c
+	}
+	if c := v.categories[strings.SplitN(mimeType, "/", 2)[0]]; c != "" {
+		return c
+	}
+	return string(category)
+}
+
+// ImageSizeOpts configures the handling of images too small to classify
+type ImageSizeOpts struct {
+	// Width and Height are the minimum dimensions in pixels of images to
+	// classify and run through OCR - leave at 0 for no minimum. Smaller images
+	// are indexed without content or keywords.
+	Width  int
+	Height int
+	// Reject rejects images below the minimum dimensions instead of indexing
+	// them
+	Reject bool
+}
+
+// ThumbnailOpts configures thumbnail generation for indexed images
+type ThumbnailOpts struct {
+	// Size is the maximum width and height of thumbnails - leave at 0 to
+	// disable thumbnail generation
+	Size int
+	// Quality is the JPEG quality of thumbnails, between 1 and 100
+	Quality int
+	// InlineLimit is the maximum size in bytes of thumbnails to store inline as
+	// data URIs - larger thumbnails are added to IPFS
+	InlineLimit int
+	// MaxPixels is the maximum number of pixels of images to generate
+	// thumbnails for - leave at 0 to use images.DefaultMaxPixels
+	MaxPixels int
+}
+
+// thumbnail generates a thumbnail for the given image, and returns a reference
+// to it
+func (v *V2) thumbnail(contents []byte) (string, error) {
+	var quality = v.thumbnails.Quality
+	if quality < 1 || quality > 100 {
+		quality = jpeg.DefaultQuality
+	}
+	thumbnail, err := images.Thumbnail(contents, v.thumbnails.Size, quality, v.thumbnails.MaxPixels)
+	if err != nil {
+		return "", err
+	}
+	if len(thumbnail) <= v.thumbnails.InlineLimit {
+		return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumbnail), nil
+	}
+	hash, err := v.ipfs.Add(bytes.NewReader(thumbnail))
+	if err != nil {
+		return "", fmt.Errorf("failed to add thumbnail to IPFS: %s", err.Error())
+	}
+	return hash, nil
+}
+
+// modelHint infers an image model from the directory an object is in, if the
+// object was requested by path (ie '<hash>/<dir>/<file>')
+func modelHint(hash string) string {
+	var parts = strings.Split(strings.Trim(hash, "/"), "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// reachableOnlyHeader is the request metadata key that overrides
+// V2Options.ExcludeStale for a single search, with a value of 'true' or 'false'
+//
+// TODO: replace with a search option once the LensV2 service definition
+// supports it
+const reachableOnlyHeader = "lens-reachable-only"
+
+// reachableOnly checks if the search request in ctx should exclude objects
+// flagged as unreachable, falling back to the configured default
+func (v *V2) reachableOnly(ctx context.Context) (bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var values = md.Get(reachableOnlyHeader)
+	if len(values) < 1 {
+		return v.excludeStale, nil
+	}
+	reachable, err := strconv.ParseBool(values[len(values)-1])
+	if err != nil {
+		return false, fmt.Errorf("invalid %s header '%s'",
+			reachableOnlyHeader, values[len(values)-1])
+	}
+	return reachable, nil
+}
+
+// categoryBoostHeader is the request metadata key for category boosts of a
+// search, as comma-separated category:boost pairs, ie 'image:1.5'
+//
+// TODO: replace with a search option once the LensV2 service definition
+// supports it
+const categoryBoostHeader = "lens-category-boost"
+
+// categoryBoosts parses the category boosts in the request metadata of ctx
+func categoryBoosts(ctx context.Context) (map[string]float64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var boosts map[string]float64
+	for _, value := range md.Get(categoryBoostHeader) {
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			var sep = strings.LastIndex(pair, ":")
+			if sep < 1 {
+				return nil, fmt.Errorf("invalid category boost '%s'", pair)
+			}
+			boost, err := strconv.ParseFloat(pair[sep+1:], 64)
+			if err != nil || boost <= 0 {
+				return nil, fmt.Errorf("invalid category boost '%s': boost must be a positive number", pair)
+			}
+			if boosts == nil {
+				boosts = make(map[string]float64)
+			}
+			boosts[strings.TrimSpace(pair[:sep])] = boost
+		}
+	}
+	return boosts, nil
+}
+
+// propertiesHeader is the request metadata key for user properties that
+// search results must have, as comma-separated key=value pairs, ie
+// 'project=alpha'. Values are matched exactly. A value that contains commas
+// must be given as the only pair of its header, which may be repeated.
+//
+// TODO: replace with a search option once the LensV2 service definition
+// supports it
+const propertiesHeader = "lens-properties"
+
+// searchProperties parses the required properties in the request metadata of
+// ctx
+func searchProperties(ctx context.Context) (map[string]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var properties map[string]string
+	for _, value := range md.Get(propertiesHeader) {
+		var pairs = []string{value}
+		if strings.Count(value, "=") > 1 {
+			pairs = strings.Split(value, ",")
+		}
+		for _, pair := range pairs {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			var sep = strings.Index(pair, "=")
+			if sep < 1 || strings.TrimSpace(pair[sep+1:]) == "" {
+				return nil, fmt.Errorf("invalid property '%s': must be in the form key=value", pair)
+			}
+			if properties == nil {
+				properties = make(map[string]string)
+			}
+			properties[strings.TrimSpace(pair[:sep])] = strings.TrimSpace(pair[sep+1:])
+		}
+	}
+	return properties, nil
+}
+
+// prefixHeader is the request metadata key that enables prefix matching for a
+// search, with a value of 'true' or 'false' - required words and tags then
+// also match indexed words they are a prefix of, ie 'crypt' matches objects
+// tagged 'cryptography'
+//
+// TODO: replace with a search option once the LensV2 service definition
+// supports it
+const prefixHeader = "lens-prefix"
+
+// prefixSearch checks if the search request in ctx enables prefix matching
+func prefixSearch(ctx context.Context) (bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var values = md.Get(prefixHeader)
+	if len(values) < 1 {
+		return false, nil
+	}
+	prefix, err := strconv.ParseBool(values[len(values)-1])
+	if err != nil {
+		return false, fmt.Errorf("invalid %s header '%s'",
+			prefixHeader, values[len(values)-1])
+	}
+	return prefix, nil
+}
+
+// ifIndexedHeader is the request metadata key that selects what an index
+// request does if its object is already indexed, and does not request a
+// reindex: 'error' (the default) fails the request, 'skip' returns the
+// indexed object without processing it, and 'reindex' reindexes it.
+//
+// The check consults the engine document ID of the object: the hash given in
+// the request, or the derived 'sha256:' hash of inline content. Archive
+// members, stored under '<hash>/<path>', are not consulted.
+//
+// TODO: replace with an index option once the LensV2 service definition
+// supports it
+const ifIndexedHeader = "lens-if-indexed"
+
+// Values of ifIndexedHeader
+const (
+	ifIndexedError   = "error"
+	ifIndexedSkip    = "skip"
+	ifIndexedReindex = "reindex"
+)
+
+// ifIndexed returns the already-indexed behaviour requested in the request
+// metadata of ctx
+func ifIndexed(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var values = md.Get(ifIndexedHeader)
+	if len(values) < 1 {
+		return ifIndexedError, nil
+	}
+	switch v := strings.ToLower(strings.TrimSpace(values[len(values)-1])); v {
+	case ifIndexedError, ifIndexedSkip, ifIndexedReindex:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid %s header '%s': must be one of '%s', '%s', or '%s'",
+			ifIndexedHeader, values[len(values)-1], ifIndexedError, ifIndexedSkip, ifIndexedReindex)
+	}
+}
+
+// newQuery validates the given search request and converts it into an engine
+// query. If the query is truncated, a warning is set in the response trailers
+// of ctx. The request metadata of ctx may override whether stale objects are
+// excluded, and set category boosts.
+func (v *V2) newQuery(ctx context.Context, req *lensv2.SearchReq) (engine.Query, error) {
+	query, err := v.filterQuery(ctx, req)
+	if err != nil {
+		return engine.Query{}, err
+	}
+	var opts = req.GetOptions()
+	if req.GetQuery() == "" &&
+		len(opts.GetCategories()) < 1 &&
+		len(opts.GetHashes()) < 1 &&
+		len(opts.GetMimeTypes()) < 1 &&
+		len(opts.GetRequired()) < 1 &&
+		len(opts.GetTags()) < 1 &&
+		len(query.Properties) < 1 {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"no search parameters provided")
+	}
+	return query, nil
+}
+
+// filterQuery converts the given search request into an engine query like
+// newQuery, but allows requests without search parameters, which match every
+// object when combined with other filters
+func (v *V2) filterQuery(ctx context.Context, req *lensv2.SearchReq) (engine.Query, error) {
+	var opts = req.GetOptions()
+	if err := validateSearchReq(req); err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+
+	// queries must be normalized like indexed text to match it
+	var phrase, terms = v.normalizeQuery(req.GetQuery()), opts.GetRequired()
+	if len(terms) > 0 {
+		terms = make([]string, len(opts.GetRequired()))
+		for i, t := range opts.GetRequired() {
+			terms[i] = v.normalizeQuery(t)
+		}
+	}
+	required, weights, err := parseWeights(terms)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	excludeStale, err := v.reachableOnly(ctx)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	boosts, err := categoryBoosts(ctx)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	properties, err := searchProperties(ctx)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	prefix, err := prefixSearch(ctx)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+
+	var query = engine.Query{
+		Text:       phrase,
+		Required:   required,
+		Weights:    weights,
+		Tags:       opts.GetTags(),
+		Categories: opts.GetCategories(),
+		MimeTypes:  opts.GetMimeTypes(),
+		Hashes:     opts.GetHashes(),
+		Properties: properties,
+
+		CategoryBoosts: boosts,
+
+		ExcludeStale: excludeStale,
+		Prefix:       prefix,
+		Synonyms:     v.synonyms,
+	}
+	warning, err := v.keywords.apply(&query)
+	if err != nil {
+		return engine.Query{}, status.Errorf(codes.InvalidArgument,
+			"invalid request: %s", err.Error())
+	}
+	if warning != "" {
+		v.l.Warnw("search keywords truncated", "warning", warning)
+		// fails outside of gRPC requests, where there is no one to warn
+		grpc.SetTrailer(ctx, metadata.Pairs(warningTrailer, warning))
+	}
+	return query, nil
+}
+
+// normalizeQuery normalizes the given query text like indexed text
+func (v *V2) normalizeQuery(s string) string {
+	s = v.values.Normalize(s)
+	if v.hyphenation != nil {
+		s = v.hyphenation.Normalize(s)
+	}
+	return s
+}
+
+// parseWeights extracts weights from terms in the form 'term^weight'. Terms
+// without a weight are returned as is.
+func parseWeights(terms []string) ([]string, map[string]float64, error) {
+	if len(terms) == 0 {
+		return terms, nil, nil
+	}
+	var weights map[string]float64
+	var out = make([]string, len(terms))
+	for i, t := range terms {
+		var sep = strings.LastIndex(t, "^")
+		if sep < 0 {
+			out[i] = t
+			continue
+		}
+		w, err := strconv.ParseFloat(t[sep+1:], 64)
+		if err != nil || w <= 0 {
+			return nil, nil, fmt.Errorf("invalid weight for term '%s'", t)
+		}
+		out[i] = t[:sep]
+		if weights == nil {
+			weights = make(map[string]float64)
+		}
+		weights[strings.ToLower(strings.TrimSpace(out[i]))] = w
+	}
+	return out, weights, nil
+}
+
+// Store is used to store our collected meta data in a formatted object. Text
+// is sanitized to valid UTF-8 in place before it is stored. Nothing is stored
+// once the given budget has been exceeded, and documents created on behalf of
+// an index job are recorded so that they can be discarded if it is cancelled.
+func (v *V2) store(b *budget, hash, content string, md *models.MetaDataV2, reindex bool) error {
+	sanitizeMetadata(md)
+	if v.mergeTagCase {
+		md.Tags = mergeTags(md.Tags)
+	}
+	v.classify(md)
+	if v.version != "" {
+		if md.Provenance == nil {
+			md.Provenance = &models.Provenance{}
+		}
+		md.Provenance.LensVersion = v.version
+	}
+	if v.isRawText(md.Category) {
+		if md.Provenance == nil {
+			md.Provenance = &models.Provenance{}
+		}
+		md.Provenance.TextMode = models.TextModeRaw
+	}
+	// indexing an object restores it if it was soft-deleted
+	md.Deleted, md.DeletedAt = false, ""
+	content = sanitize(content)
+	if v.storeExtracted && content != "" {
+		// failing to share extracted text should not prevent indexing
+		if extracted, err := v.ipfs.Add(strings.NewReader(content)); err != nil {
+			v.l.Warnw("failed to add extracted text to IPFS",
+				"hash", hash, "error", err)
+		} else {
+			md.ExtractedText = extracted
+		}
+	}
+	// writes of cancelled jobs are discarded, so they must only record
+	// documents that did not exist before
+	var created = !v.se.IsIndexed(hash)
+	return jobWritesFrom(b.context()).write(hash, created, func() error {
+		if err := b.check(); err != nil {
+			return err
+		}
+		return v.se.Index(engine.Document{
+			Object: &models.ObjectV2{
+				Hash: hash,
+				MD:   *md,
+			},
+			Content: content,
+			Reindex: reindex,
+		})
 	})
 }
 
+// isRawText checks if content of the given category, or of one of its parent
+// categories, should be indexed in raw text mode
+func (v *V2) isRawText(category string) bool {
+	if v.policy(category).RawText {
+		return true
+	}
+	for _, c := range v.rawText {
+		if models.InCategory(category, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// sample returns at most size bytes from the start of s, cut at a character
+// boundary, and whether s was cut. A size of 0 or less returns s unchanged.
+func sample(s string, size int) (string, bool) {
+	if size <= 0 || len(s) <= size {
+		return s, false
+	}
+	var end = size
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end], true
+}
+
+// sanitize drops invalid UTF-8 byte sequences from the given string, which
+// would otherwise break serialization of stored objects
+func sanitize(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != utf8.RuneError || size > 1 {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// sanitizeMetadata sanitizes all text fields of the given metadata, dropping
+// tags that are left empty
+func sanitizeMetadata(md *models.MetaDataV2) {
+	md.DisplayName = sanitize(md.DisplayName)
+	md.MimeType = sanitize(md.MimeType)
+	md.Category = sanitize(md.Category)
+	md.Classification = sanitize(md.Classification)
+	var tags = md.Tags[:0]
+	for _, t := range md.Tags {
+		if t = sanitize(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	md.Tags = tags
+	for k, val := range md.Properties {
+		if clean := sanitize(k); clean != k {
+			delete(md.Properties, k)
+			k = clean
+		}
+		md.Properties[k] = sanitize(val)
+	}
+}
+
+// newIndexResp describes an indexed object
+func newIndexResp(hash string, md *models.MetaDataV2) *lensv2.IndexResp {
+	return &lensv2.IndexResp{
+		Doc: &lensv2.Document{
+			Hash:        hash,
+			DisplayName: md.DisplayName,
+			MimeType:    md.MimeType,
+			Category:    md.Category,
+			Tags:        md.Tags,
+		},
+	}
+}
+
+// getStatus converts an error from retrieving a document into a gRPC status,
+// distinguishing missing documents from failures to read the index
+func getStatus(err error) error {
+	if err == engine.ErrNotFound {
+		return status.Errorf(codes.NotFound,
+			"failed to find requested hash: %s", err.Error())
+	}
+	return status.Errorf(codes.Internal,
+		"failed to retrieve requested hash: %s", err.Error())
+}
+
 // Remove is used to remove an indexed object
 func (v *V2) remove(hash string) error {
 	if !v.se.IsIndexed(hash) {
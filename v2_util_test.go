@@ -0,0 +1,982 @@
+package lens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RTradeLtd/grpc/lensv2"
+	"go.uber.org/zap"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/text"
+	"github.com/RTradeLtd/Lens/v2/engine"
+	"github.com/RTradeLtd/Lens/v2/mocks"
+	"github.com/RTradeLtd/Lens/v2/models"
+)
+
+func Test_modelHint(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want string
+	}{
+		{"plain hash", "QmSi9TLyzTXmrLMXDvhztDoX3jghoG3vcRrnPkLvGgfpdW", ""},
+		{"file in root", "QmSi9TLyzTXmrLMXDvhztDoX3jghoG3vcRrnPkLvGgfpdW/scan.jpg", ""},
+		{"file in directory", "/QmSi9TLyzTXmrLMXDvhztDoX3jghoG3vcRrnPkLvGgfpdW/xray/scan.jpg", "xray"},
+		{"nested directories", "QmSi9TLyzTXmrLMXDvhztDoX3jghoG3vcRrnPkLvGgfpdW/a/medical/scan.jpg", "medical"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modelHint(tt.hash); got != tt.want {
+				t.Errorf("modelHint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_thumbnail(t *testing.T) {
+	img, err := ioutil.ReadFile("test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		content     []byte
+		opts        ThumbnailOpts
+		addErr      error
+		wantPrefix  string
+		wantIPFSAdd bool
+		wantErr     bool
+	}{
+		{"not an image",
+			[]byte("hello world"), ThumbnailOpts{Size: 32}, nil, "", false, true},
+		{"inline",
+			img, ThumbnailOpts{Size: 32, InlineLimit: 1 << 20}, nil, "data:image/jpeg;base64,", false, false},
+		{"on ipfs",
+			img, ThumbnailOpts{Size: 32}, nil, "QmThumb", true, false},
+		{"ipfs failure",
+			img, ThumbnailOpts{Size: 32}, errors.New("oh no"), "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var v = newTestV2(t, V2Options{Thumbnails: tt.opts},
+				ipfs,
+				&mocks.FakeTensorflowAnalyzer{},
+				&mocks.FakeSearcher{},
+				zap.NewNop().Sugar())
+			ipfs.AddReturns("QmThumb", tt.addErr)
+
+			got, err := v.thumbnail(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("V2.thumbnail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("V2.thumbnail() = %v, want prefix %v", got, tt.wantPrefix)
+			}
+			if (ipfs.AddCallCount() > 0) != tt.wantIPFSAdd {
+				t.Errorf("V2.thumbnail() added to IPFS = %v, want %v",
+					ipfs.AddCallCount() > 0, tt.wantIPFSAdd)
+			}
+		})
+	}
+}
+
+func TestV2_category(t *testing.T) {
+	var v = newTestV2(t, V2Options{
+		CategoryOverrides: map[string]string{
+			"application/pdf": "document",
+			"image":           "media",
+			"image/gif":       "animation",
+		},
+	}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	tests := []struct {
+		mimeType string
+		category models.MimeType
+		want     string
+	}{
+		{"application/pdf", models.MimeTypePDF, "document"},
+		{"image/png", models.MimeTypeImage, "media"},
+		{"image/gif", models.MimeTypeImage, "animation"},
+		{"text/plain", models.MimeTypeDocument, "document"},
+		{"text/html", models.MimeTypeDocument, models.MimeTypeDocument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mimeType, func(t *testing.T) {
+			if got := v.category(tt.mimeType, tt.category); got != tt.want {
+				t.Errorf("V2.category() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_summarizer(t *testing.T) {
+	var gotRatio float64
+	var v = newTestV2(t, V2Options{
+		Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+			gotRatio = ratio
+			return strings.Fields(s)[:2]
+		}),
+	}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+
+	a, err := v.analyze("asdf", []byte("distributed web search"), "", zap.NewNop().Sugar())
+	if err != nil {
+		t.Errorf("V2.analyze() error = %v", err)
+		return
+	}
+	if !reflect.DeepEqual(a.keywords, []string{"distributed", "web"}) {
+		t.Errorf("V2.analyze() tags = %v, want %v", a.keywords, []string{"distributed", "web"})
+	}
+	if gotRatio != text.DefaultRatio {
+		t.Errorf("Summarize() ratio = %v, want %v", gotRatio, text.DefaultRatio)
+	}
+	var wantProv = models.Provenance{Method: "text", SummaryRatio: text.DefaultRatio}
+	if a.provenance != wantProv {
+		t.Errorf("V2.analyze() provenance = %+v, want %+v", a.provenance, wantProv)
+	}
+}
+
+func TestV2_ratio(t *testing.T) {
+	var ratios = map[string]float64{
+		"application/pdf": 0.1,
+		"text":            0.3,
+		"document":        0.4,
+		"image":           1.5, // invalid ratios are ignored
+	}
+	tests := []struct {
+		name        string
+		globalRatio float64
+		mimeType    string
+		category    string
+		want        float64
+	}{
+		{"full mime type", 0.5, "application/pdf", "pdf", 0.1},
+		{"top-level type", 0.5, "text/html", "document", 0.3},
+		{"category", 0.5, "application/x-custom", "document", 0.4},
+		{"invalid override", 0.5, "image/png", "image", 0.5},
+		{"global ratio", 0.5, "application/x-custom", "unknown", 0.5},
+		{"default ratio", 0, "application/x-custom", "unknown", text.DefaultRatio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{
+				SummaryRatio:  tt.globalRatio,
+				SummaryRatios: ratios,
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			if got := v.ratio(tt.mimeType, tt.category); got != tt.want {
+				t.Errorf("V2.ratio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_summaryFallback(t *testing.T) {
+	var words = text.SummarizerFunc(func(s string, ratio float64) []string {
+		return strings.Fields(s)[:1]
+	})
+	var none = text.SummarizerFunc(func(s string, ratio float64) []string { return nil })
+	tests := []struct {
+		name         string
+		primary      text.Summarizer
+		fallback     text.Summarizer
+		wantTags     []string
+		wantFallback bool
+	}{
+		{"primary succeeds", words, none, []string{"distributed"}, false},
+		{"no fallback", none, nil, nil, false},
+		{"fallback used", none, words, []string{"distributed"}, true},
+		{"both empty", none, none, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{
+				Summarizer:         tt.primary,
+				FallbackSummarizer: tt.fallback,
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte("distributed web search"), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.keywords, tt.wantTags) || a.provenance.SummaryFallback != tt.wantFallback {
+				t.Errorf("V2.analyze() tags = %v, fallback = %v, want %v, %v",
+					a.keywords, a.provenance.SummaryFallback, tt.wantTags, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_summaryInput(t *testing.T) {
+	const content = "distributed web search engine"
+	tests := []struct {
+		name          string
+		max           int
+		wantInput     string
+		wantTruncated bool
+	}{
+		{"no limit", 0, content, false},
+		{"within limit", len(content), content, false},
+		{"truncated", 15, "distributed web", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input string
+			var v = newTestV2(t, V2Options{
+				Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+					input = s
+					return nil
+				}),
+				MaxSummaryInput: tt.max,
+			}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(content), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if input != tt.wantInput || a.provenance.SummaryTruncated != tt.wantTruncated {
+				t.Errorf("V2.analyze() summarized %q (truncated %v), want %q (truncated %v)",
+					input, a.provenance.SummaryTruncated, tt.wantInput, tt.wantTruncated)
+			}
+			if a.content != content {
+				t.Errorf("V2.analyze() content = %q, want all text indexed", a.content)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_shortContent(t *testing.T) {
+	var summarized bool
+	var v = newTestV2(t, V2Options{
+		Summarizer: text.SummarizerFunc(func(s string, ratio float64) []string {
+			summarized = true
+			return nil
+		}),
+		MinSummaryLength: 32,
+	}, &mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+
+	a, err := v.analyze("asdf", []byte("Distributed web, distributed search"), "", zap.NewNop().Sugar())
+	if err != nil || !summarized || a.provenance.ShortContent {
+		t.Errorf("V2.analyze() of long text error = %v, summarized = %v, short = %v",
+			err, summarized, a.provenance.ShortContent)
+	}
+
+	summarized = false
+	a, err = v.analyze("asdf", []byte("Distributed web, distributed"), "", zap.NewNop().Sugar())
+	if err != nil {
+		t.Errorf("V2.analyze() error = %v", err)
+		return
+	}
+	if summarized {
+		t.Error("V2.analyze() summarized short text")
+	}
+	if !reflect.DeepEqual(a.keywords, []string{"distributed", "web"}) || !a.provenance.ShortContent {
+		t.Errorf("V2.analyze() tags = %v, short = %v, want words of text",
+			a.keywords, a.provenance.ShortContent)
+	}
+}
+
+func TestV2_store_provenance(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{Version: "v2.1.0"},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, nil)
+
+	var md = &models.MetaDataV2{
+		Category:   "image",
+		Provenance: &models.Provenance{Method: "image", ImageModel: "inception"},
+	}
+	if err := v.store(nil, "asdf", "cat", md, false); err != nil {
+		t.Errorf("V2.store() error = %v", err)
+		return
+	}
+	var want = models.Provenance{LensVersion: "v2.1.0", Method: "image", ImageModel: "inception"}
+	if got := se.IndexArgsForCall(0).Object.MD.Provenance; got == nil || *got != want {
+		t.Errorf("V2.store() stored provenance %+v, want %+v", got, want)
+	}
+}
+
+func TestV2_store_extracted(t *testing.T) {
+	tests := []struct {
+		name      string
+		store     bool
+		content   string
+		addErr    error
+		wantAdded bool
+		wantHash  string
+	}{
+		{"disabled", false, "cat", nil, false, ""},
+		{"enabled", true, "cat", nil, true, "qwer"},
+		{"no content", true, "", nil, false, ""},
+		{"add failure does not fail indexing", true, "cat", errors.New("oh no"), true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{StoreExtracted: tt.store},
+				ipfs, &mocks.FakeTensorflowAnalyzer{}, se, nil)
+			if tt.addErr != nil {
+				ipfs.AddReturns("", tt.addErr)
+			} else {
+				ipfs.AddReturns("qwer", nil)
+			}
+
+			if err := v.store(nil, "asdf", tt.content, &models.MetaDataV2{}, false); err != nil {
+				t.Errorf("V2.store() error = %v", err)
+				return
+			}
+			if added := ipfs.AddCallCount() > 0; added != tt.wantAdded {
+				t.Errorf("V2.store() added extracted text = %v, want %v", added, tt.wantAdded)
+			}
+			if tt.wantAdded {
+				r, _ := ipfs.AddArgsForCall(0)
+				if b, _ := ioutil.ReadAll(r); string(b) != tt.content {
+					t.Errorf("V2.store() added '%s', want '%s'", string(b), tt.content)
+				}
+			}
+			if got := se.IndexArgsForCall(0).Object.MD.ExtractedText; got != tt.wantHash {
+				t.Errorf("V2.store() stored extracted text hash '%s', want '%s'", got, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestV2_store_rawText(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{RawTextCategories: []string{"Document"}},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, nil)
+
+	tests := []struct {
+		category string
+		want     string
+	}{
+		{"document", models.TextModeRaw},
+		{"image", ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if err := v.store(nil, "asdf", "to be or not to be",
+				&models.MetaDataV2{Category: tt.category}, false); err != nil {
+				t.Errorf("V2.store() error = %v", err)
+				return
+			}
+			var got string
+			if p := se.IndexArgsForCall(i).Object.MD.Provenance; p != nil {
+				got = p.TextMode
+			}
+			if got != tt.want {
+				t.Errorf("V2.store() stored text mode %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_unusualContent(t *testing.T) {
+	var v = newTestV2(t, V2Options{},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	tests := []struct {
+		name     string
+		contents []byte
+		wantErr  bool
+	}{
+		{"nil", nil, false},
+		{"empty", []byte{}, false},
+		{"separators only", []byte("; / ;"), false},
+		{"null bytes", []byte{0, 0, 0, 0}, true},
+		// too short to be detected as an image, so it is analyzed as text
+		{"truncated magic", []byte("\x89PNG"), false},
+		{"binary", []byte{0x01, 0x02, 0x03, 0xff}, true},
+		{"utf-16 byte order mark", []byte{0xff, 0xfe, 0x00, 0x01}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("V2.analyze() panicked: %v", r)
+				}
+			}()
+			_, err := v.analyze("asdf", tt.contents, "", zap.NewNop().Sugar())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("V2.analyze() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_tiff(t *testing.T) {
+	var tf = &mocks.FakeTensorflowAnalyzer{}
+	var v = newTestV2(t, V2Options{},
+		&mocks.FakeRTFSManager{}, tf, &mocks.FakeSearcher{}, nil)
+	var keywords = []string{"fax", "letter", "fax"}
+	tf.AnalyzeStub = func(string, []byte, string) (string, error) {
+		return keywords[tf.AnalyzeCallCount()-1], nil
+	}
+
+	// little-endian TIFF with three empty pages
+	var tiff = []byte{
+		'I', 'I', 42, 0, 8, 0, 0, 0,
+		0, 0, 14, 0, 0, 0,
+		0, 0, 20, 0, 0, 0,
+		0, 0, 0, 0, 0, 0,
+	}
+	a, err := v.analyze("asdf", tiff, "", zap.NewNop().Sugar())
+	if err != nil {
+		t.Errorf("V2.analyze() error = %v", err)
+		return
+	}
+	if a.mimeType != "image/tiff" || a.pages != 3 {
+		t.Errorf("V2.analyze() = %s with %d pages, want image/tiff with 3 pages",
+			a.mimeType, a.pages)
+	}
+	if tf.AnalyzeCallCount() != 3 {
+		t.Errorf("classified %d pages, want 3", tf.AnalyzeCallCount())
+	}
+	if !reflect.DeepEqual(a.keywords, []string{"fax", "letter"}) || a.classification != "fax" {
+		t.Errorf("V2.analyze() tags = %v, classification = %s, want merged page keywords",
+			a.keywords, a.classification)
+	}
+}
+
+func TestV2_analyzePDFImages(t *testing.T) {
+	jpg, err := ioutil.ReadFile("test/assets/image.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tf = &mocks.FakeTensorflowAnalyzer{}
+	tf.AnalyzeReturns("diagram", nil)
+	var v = newTestV2(t, V2Options{PDFImages: 1},
+		&mocks.FakeRTFSManager{}, tf, &mocks.FakeSearcher{}, nil)
+
+	var pdf = []byte("%PDF-1.4\n1 0 obj\n<< /Subtype /Image /Filter /DCTDecode >>\nstream\n")
+	pdf = append(pdf, jpg...)
+	pdf = append(pdf, []byte("\nendstream\nendobj\n")...)
+	pdf = append(pdf, pdf[9:]...) // second image is over the limit
+
+	var a = &analysis{extractedDocument: extractedDocument{content: "body text", tags: []string{"report"}}}
+	v.analyzePDFImages("asdf", pdf, "", a, zap.NewNop().Sugar())
+	if tf.AnalyzeCallCount() != 1 {
+		t.Errorf("classified %d images, want 1", tf.AnalyzeCallCount())
+	}
+	if !reflect.DeepEqual(a.tags, []string{"report", "diagram"}) {
+		t.Errorf("V2.analyzePDFImages() tags = %v, want %v", a.tags, []string{"report", "diagram"})
+	}
+	if !strings.HasPrefix(a.content, "body text\n") || a.classification != "" {
+		t.Errorf("V2.analyzePDFImages() = %+v, want body text kept and no classification", a)
+	}
+}
+
+func TestV2_store_sanitize(t *testing.T) {
+	var se = &mocks.FakeSearcher{}
+	var v = newTestV2(t, V2Options{},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, se, nil)
+
+	var md = &models.MetaDataV2{
+		DisplayName: "report\xff.pdf",
+		Category:    "pdf",
+		Tags:        []string{"caf\xc3\xa9", "\xc3\x28bad", "\xff\xfe"},
+		Properties:  map[string]string{"auth\xffor": "bob\xc0"},
+	}
+	if err := v.store(nil, "asdf", "hello\xed\xa0\x80 world", md, false); err != nil {
+		t.Errorf("V2.store() error = %v", err)
+		return
+	}
+
+	var doc = se.IndexArgsForCall(0)
+	var want = models.MetaDataV2{
+		DisplayName: "report.pdf",
+		Category:    "pdf",
+		Tags:        []string{"café", "(bad"},
+		Properties:  map[string]string{"author": "bob"},
+	}
+	if !reflect.DeepEqual(doc.Object.MD, want) {
+		t.Errorf("V2.store() stored %+v, want %+v", doc.Object.MD, want)
+	}
+	if doc.Content != "hello world" {
+		t.Errorf("V2.store() stored content %q, want %q", doc.Content, "hello world")
+	}
+
+	// stored object should survive a round trip
+	b, err := json.Marshal(doc.Object)
+	if err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+		return
+	}
+	var got models.ObjectV2
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Errorf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.MD, want) {
+		t.Errorf("round trip = %+v, want %+v", got.MD, want)
+	}
+}
+
+func Test_sample(t *testing.T) {
+	tests := []struct {
+		name        string
+		s           string
+		size        int
+		want        string
+		wantSampled bool
+	}{
+		{"disabled", "distributed web", 0, "distributed web", false},
+		{"short enough", "distributed web", 15, "distributed web", false},
+		{"sampled", "distributed web", 11, "distributed", true},
+		{"character boundary", "héllo", 2, "h", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sampled := sample(tt.s, tt.size)
+			if got != tt.want || sampled != tt.wantSampled {
+				t.Errorf("sample() = (%q, %v), want (%q, %v)", got, sampled, tt.want, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_sample(t *testing.T) {
+	var v = newTestV2(t, V2Options{SampleSize: 11},
+		&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+	for _, tt := range []struct {
+		contents    string
+		want        string
+		wantSampled bool
+	}{
+		{"distributed web search", "distributed", true},
+		{"distributed w", "distributed", true},
+		{"distributed", "distributed", false},
+	} {
+		a, err := v.analyze("asdf", []byte(tt.contents), "", zap.NewNop().Sugar())
+		if err != nil {
+			t.Errorf("V2.analyze() error = %v", err)
+			continue
+		}
+		if a.content != tt.want || a.sampled != tt.wantSampled {
+			t.Errorf("V2.analyze(%q) = (%q, %v), want (%q, %v)",
+				tt.contents, a.content, a.sampled, tt.want, tt.wantSampled)
+		}
+	}
+}
+
+func Test_wordTags(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"", []string{}},
+		{"2021-tax-return.pdf", []string{"tax", "return", "pdf"}},
+		{"reports/Q3_Report.TXT", []string{"reports", "report", "txt"}},
+		{"a/b/1234/v2", []string{}},
+		{"café menu.txt", []string{"café", "menu", "txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wordTags(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wordTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_magnify_nameTags(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var v = newTestV2(t, V2Options{NameTags: enabled},
+			&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+		_, md, err := v.magnify("asdf", magnifyOpts{
+			DisplayName: "2021-tax-return.txt",
+			Tags:        []string{"finance"},
+			Contents:    []byte("distributed web"),
+		})
+		if err != nil {
+			t.Errorf("V2.magnify() error = %v", err)
+			continue
+		}
+		var want = []string{"finance"}
+		if enabled {
+			want = append(want, "tax", "return", "txt")
+		}
+		if !reflect.DeepEqual(md.Tags, want) {
+			t.Errorf("V2.magnify() with name tags %v = %v, want %v", enabled, md.Tags, want)
+		}
+	}
+}
+
+func TestV2_magnify_source(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   bool
+		contents []byte
+		want     string
+	}{
+		{"disabled", false, nil, ""},
+		{"retrieved", true, nil, "127.0.0.1:5001"},
+		{"provided", true, []byte("hello world"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			ipfs.NodeAddressReturns("127.0.0.1:5001")
+			ipfs.CatReturns([]byte("hello world"), nil)
+			var v = newTestV2(t, V2Options{RecordSource: tt.record},
+				ipfs, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			_, md, err := v.magnify("asdf", magnifyOpts{Contents: tt.contents})
+			if err != nil {
+				t.Errorf("V2.magnify() error = %v", err)
+				return
+			}
+			if md.Provenance == nil || md.Provenance.Source != tt.want {
+				t.Errorf("V2.magnify() provenance = %+v, want source %q", md.Provenance, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_magnify_reuseAnalysis(t *testing.T) {
+	var stored = &engine.Document{
+		Object: &models.ObjectV2{Hash: "asdf", MD: models.MetaDataV2{
+			DisplayName: "old",
+			Category:    "document",
+			Tags:        []string{"distributed"},
+			Provenance:  &models.Provenance{LensVersion: "v2.1.0", Method: "text"},
+		}},
+		Content: "distributed web",
+	}
+	tests := []struct {
+		name          string
+		reuse         bool
+		version       string
+		wantRetrieved bool
+	}{
+		{"disabled", false, "v2.1.0", true},
+		{"same version", true, "v2.1.0", false},
+		{"different version", true, "v2.2.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ipfs = &mocks.FakeRTFSManager{}
+			var se = &mocks.FakeSearcher{}
+			var v = newTestV2(t, V2Options{ReuseAnalysis: tt.reuse, Version: tt.version},
+				ipfs, &mocks.FakeTensorflowAnalyzer{}, se, nil)
+			ipfs.CatReturns([]byte("distributed web"), nil)
+			se.IsIndexedReturns(true)
+			se.GetReturns(stored, nil)
+
+			content, md, err := v.magnify("asdf", magnifyOpts{
+				DisplayName: "new",
+				Tags:        []string{"web"},
+				Reindex:     true,
+			})
+			if err != nil {
+				t.Errorf("V2.magnify() error = %v", err)
+				return
+			}
+			if retrieved := ipfs.CatCallCount() > 0; retrieved != tt.wantRetrieved {
+				t.Errorf("V2.magnify() retrieved content = %v, want %v", retrieved, tt.wantRetrieved)
+			}
+			if !tt.wantRetrieved {
+				if content != stored.Content || md.DisplayName != "new" ||
+					!reflect.DeepEqual(md.Tags, []string{"distributed", "web"}) {
+					t.Errorf("V2.magnify() = (%q, %+v), want previous analysis", content, md)
+				}
+				if stored.Object.MD.DisplayName != "old" {
+					t.Error("V2.magnify() modified stored metadata")
+				}
+			}
+		})
+	}
+}
+
+func TestV2_analyze_links(t *testing.T) {
+	const doc = `<html><body><a href="/">Home</a> <a href="https://www.ipfs.io/docs">IPFS docs</a> <a href="/">Home</a></body></html>`
+	tests := []struct {
+		name string
+		opts LinkOpts
+		want []string
+	}{
+		{"disabled", LinkOpts{}, nil},
+		{"anchors", LinkOpts{Anchors: true}, []string{"home", "ipfs", "docs"}},
+		{"domains", LinkOpts{Domains: true}, []string{"ipfs.io"}},
+		{"both", LinkOpts{Anchors: true, Domains: true}, []string{"home", "ipfs", "docs", "ipfs.io"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{Links: tt.opts},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(doc), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(a.keywords, tt.want) {
+				t.Errorf("V2.analyze() tags = %v, want %v", a.keywords, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_logs(t *testing.T) {
+	const (
+		syslog = "Jan  2 15:04:05 web01 nginx[123]: upstream timed out\n" +
+			"Jan  2 15:04:06 web01 sshd[456]: session opened\n"
+		prose = "upstream timed out\nsession opened\n"
+	)
+	tests := []struct {
+		name        string
+		detect      bool
+		content     string
+		wantContent string
+		wantTags    []string
+		wantMethod  string
+	}{
+		{"disabled", false, syslog, syslog, nil, "text"},
+		{"log", true, syslog, "upstream timed out\nsession opened",
+			[]string{"web01", "nginx", "sshd"}, "log"},
+		{"not a log", true, prose, prose, nil, "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{DetectLogs: tt.detect},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte(tt.content), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if a.content != tt.wantContent {
+				t.Errorf("V2.analyze() content = %q, want %q", a.content, tt.wantContent)
+			}
+			if !reflect.DeepEqual(a.keywords, tt.wantTags) {
+				t.Errorf("V2.analyze() tags = %v, want %v", a.keywords, tt.wantTags)
+			}
+			if a.provenance.Method != tt.wantMethod {
+				t.Errorf("V2.analyze() method = %s, want %s", a.provenance.Method, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_media(t *testing.T) {
+	// an MPEG audio frame followed by an ID3v1 tag
+	var tagged = append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 128)...)
+	copy(tagged[4:], "TAG")
+	copy(tagged[7:], "So What")
+	copy(tagged[37:], "Miles Davis")
+	tagged[len(tagged)-1] = 8 // jazz
+
+	tests := []struct {
+		name        string
+		contents    []byte
+		wantContent string
+		wantTags    []string
+	}{
+		{"id3v1", tagged, "So What\nMiles Davis\nJazz", []string{"so what", "miles davis", "jazz"}},
+		{"no metadata", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", tt.contents, "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if a.category != models.MimeTypeMedia || a.provenance.Method != "media" {
+				t.Errorf("V2.analyze() category = %v, method = %v", a.category, a.provenance.Method)
+			}
+			if a.content != tt.wantContent || !reflect.DeepEqual(a.keywords, tt.wantTags) {
+				t.Errorf("V2.analyze() = (%q, %v), want (%q, %v)",
+					a.content, a.keywords, tt.wantContent, tt.wantTags)
+			}
+		})
+	}
+}
+
+func Test_mergeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"empty", []string{}, []string{}},
+		{"no variants", []string{"ipfs", "Bitcoin"}, []string{"ipfs", "Bitcoin"}},
+		{"first seen", []string{"Bitcoin", "ipfs", "bitcoin"}, []string{"Bitcoin", "ipfs"}},
+		{"most common", []string{"bitcoin", "Bitcoin", "ipfs", "Bitcoin"}, []string{"Bitcoin", "ipfs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseWeights(t *testing.T) {
+	tests := []struct {
+		name        string
+		terms       []string
+		want        []string
+		wantWeights map[string]float64
+		wantErr     bool
+	}{
+		{"none", nil, nil, nil, false},
+		{"unweighted", []string{"ipfs", "Filecoin"}, []string{"ipfs", "Filecoin"}, nil, false},
+		{"weighted", []string{"ipfs^2", "Filecoin^0.5", "lens"},
+			[]string{"ipfs", "Filecoin", "lens"},
+			map[string]float64{"ipfs": 2, "filecoin": 0.5}, false},
+		{"invalid weight", []string{"ipfs^two"}, nil, nil, true},
+		{"negative weight", []string{"ipfs^-1"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, weights, err := parseWeights(tt.terms)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseWeights() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWeights() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(weights, tt.wantWeights) {
+				t.Errorf("parseWeights() weights = %v, want %v", weights, tt.wantWeights)
+			}
+		})
+	}
+}
+
+func TestV2_hyphenation(t *testing.T) {
+	var en, _ = text.HyphenationFor("en")
+	tests := []struct {
+		name         string
+		hyphenation  *text.Hyphenation
+		wantContent  string
+		wantText     string
+		wantRequired []string
+	}{
+		{"disabled", nil, "inter-\nnational e-mail", "e-mail", []string{"e-mail", "re-enter"}},
+		{"enabled", &en, "international email", "email", []string{"email", "reenter"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{Hyphenation: tt.hyphenation},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte("inter-\nnational e-mail"), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if a.content != tt.wantContent {
+				t.Errorf("V2.analyze() content = %q, want %q", a.content, tt.wantContent)
+			}
+
+			q, err := v.newQuery(context.Background(), &lensv2.SearchReq{
+				Query:   "e-mail",
+				Options: &lensv2.SearchReq_Options{Required: []string{"e-mail^2", "re-enter"}},
+			})
+			if err != nil {
+				t.Errorf("V2.newQuery() error = %v", err)
+				return
+			}
+			if q.Text != tt.wantText || !reflect.DeepEqual(q.Required, tt.wantRequired) ||
+				q.Weights[tt.wantRequired[0]] != 2 {
+				t.Errorf("V2.newQuery() = (%q, %v, %v), want (%q, %v) with weight 2",
+					q.Text, q.Required, q.Weights, tt.wantText, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestV2_analyze_minImageSize(t *testing.T) {
+	var pixel = new(bytes.Buffer)
+	if err := png.Encode(pixel, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		opts           ImageSizeOpts
+		wantClassified bool
+		wantErr        bool
+	}{
+		{"no minimum", ImageSizeOpts{}, true, false},
+		{"above minimum", ImageSizeOpts{Width: 1, Height: 1}, true, false},
+		{"below minimum width", ImageSizeOpts{Width: 2}, false, false},
+		{"below minimum height", ImageSizeOpts{Height: 2}, false, false},
+		{"rejected", ImageSizeOpts{Width: 16, Height: 16, Reject: true}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tf = &mocks.FakeTensorflowAnalyzer{}
+			tf.AnalyzeReturns("pixel", nil)
+			var v = newTestV2(t, V2Options{MinImageSize: tt.opts},
+				&mocks.FakeRTFSManager{}, tf, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", pixel.Bytes(), "", zap.NewNop().Sugar())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("V2.analyze() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if classified := tf.AnalyzeCallCount() > 0; classified != tt.wantClassified {
+				t.Errorf("V2.analyze() classified = %v, want %v", classified, tt.wantClassified)
+			}
+			if tt.wantErr || tt.wantClassified {
+				return
+			}
+			if a.category != models.MimeTypeImage || a.keywords != nil || a.classification != "" {
+				t.Errorf("V2.analyze() = (%s, %v, %q), want image without keywords",
+					a.category, a.keywords, a.classification)
+			}
+		})
+	}
+}
+
+func TestV2_values(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       text.Values
+		wantContent  string
+		wantKeywords []string
+		wantText     string
+		wantRequired []string
+	}{
+		{"disabled", text.Values{},
+			"total $1,234.50 due 1/2/2021", nil,
+			"due 01/02/2021", []string{"$1,234.50"}},
+		{"enabled", text.Values{Numbers: true, Dates: true},
+			"total 1234.5 due 2021-01-02", []string{"2021-01-02", "1234.5"},
+			"due 2021-01-02", []string{"1234.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v = newTestV2(t, V2Options{Values: tt.values},
+				&mocks.FakeRTFSManager{}, &mocks.FakeTensorflowAnalyzer{}, &mocks.FakeSearcher{}, nil)
+			a, err := v.analyze("asdf", []byte("total $1,234.50 due 1/2/2021"), "", zap.NewNop().Sugar())
+			if err != nil {
+				t.Errorf("V2.analyze() error = %v", err)
+				return
+			}
+			if a.content != tt.wantContent || !reflect.DeepEqual(a.keywords, tt.wantKeywords) {
+				t.Errorf("V2.analyze() = (%q, %v), want (%q, %v)",
+					a.content, a.keywords, tt.wantContent, tt.wantKeywords)
+			}
+
+			q, err := v.newQuery(context.Background(), &lensv2.SearchReq{
+				Query:   "due 01/02/2021",
+				Options: &lensv2.SearchReq_Options{Required: []string{"$1,234.50"}},
+			})
+			if err != nil {
+				t.Errorf("V2.newQuery() error = %v", err)
+				return
+			}
+			if q.Text != tt.wantText || !reflect.DeepEqual(q.Required, tt.wantRequired) {
+				t.Errorf("V2.newQuery() = (%q, %v), want (%q, %v)",
+					q.Text, q.Required, tt.wantText, tt.wantRequired)
+			}
+		})
+	}
+}
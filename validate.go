@@ -0,0 +1,85 @@
+package lens
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/images"
+)
+
+// ConfigError lists every problem found while validating configuration
+type ConfigError []error
+
+func (c ConfigError) Error() string {
+	var problems = make([]string, len(c))
+	for i, err := range c {
+		problems[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(problems, "; "))
+}
+
+// ValidateOpts denotes the resources Lens depends on
+type ValidateOpts struct {
+	Models    images.ConfigOpts
+	StorePath string
+
+	// IPFSAddress is the host:port of the IPFS API
+	IPFSAddress string
+	// Timeout bounds the time spent connecting to IPFS - defaults to 5 seconds
+	Timeout time.Duration
+}
+
+// Validate checks that the given resources are usable, so that configuration
+// problems are reported before Lens starts. All resources are checked, and
+// problems are returned together as a ConfigError.
+func Validate(opts ValidateOpts) error {
+	var errs ConfigError
+	if err := opts.Models.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("models: %v", err))
+	}
+	if err := validateStore(opts.StorePath); err != nil {
+		errs = append(errs, fmt.Errorf("index store: %v", err))
+	}
+	if err := validateIPFS(opts.IPFSAddress, opts.Timeout); err != nil {
+		errs = append(errs, fmt.Errorf("ipfs: %v", err))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStore checks that files can be created in the index store directory
+func validateStore(path string) error {
+	if path == "" {
+		return fmt.Errorf("no path provided")
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(path, ".lens-")
+	if err != nil {
+		return fmt.Errorf("path is not writable: %v", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// validateIPFS checks that the IPFS API accepts connections
+func validateIPFS(addr string, timeout time.Duration) error {
+	if addr == "" {
+		return fmt.Errorf("no address provided")
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("unable to reach API at %s: %v", addr, err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,56 @@
+package lens
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RTradeLtd/Lens/v2/analyzer/images"
+)
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lens-validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var file = filepath.Join(dir, "file")
+	ioutil.WriteFile(file, []byte{}, 0644)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	var reachable = lis.Addr().String()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unreachable = closed.Addr().String()
+	closed.Close()
+
+	var valid = ValidateOpts{
+		Models:      images.ConfigOpts{ModelLocation: filepath.Join(dir, "models")},
+		StorePath:   filepath.Join(dir, "index"),
+		IPFSAddress: reachable,
+		Timeout:     time.Second,
+	}
+	var invalid = ValidateOpts{
+		Models:      images.ConfigOpts{ModelLocation: filepath.Join(file, "models")},
+		StorePath:   filepath.Join(file, "index"),
+		IPFSAddress: unreachable,
+		Timeout:     time.Second,
+	}
+
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	err = Validate(invalid)
+	if errs, ok := err.(ConfigError); !ok || len(errs) != 3 {
+		t.Errorf("Validate() error = %v, want all 3 problems reported", err)
+	}
+}